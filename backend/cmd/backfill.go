@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-chat/config"
+	repository_adapters "go-chat/internal/adapters/repository"
+	"go-chat/internal/ports/repository"
+)
+
+const backfillUsage = `usage: go-chat backfill <target>
+
+targets:
+  conversations   rebuild every conversation summary from the messages
+                  table; safe to re-run`
+
+func runBackfillCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(backfillUsage)
+	}
+
+	db, err := config.ConnectToDB(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	uow := repository_adapters.NewGormUnitOfWork(db)
+
+	switch args[0] {
+	case "conversations":
+		var rebuilt int64
+		err := uow.Do(context.Background(), func(ctx context.Context, repos repository.Repositories) error {
+			var err error
+			rebuilt, err = repos.Conversations.RebuildConversations(ctx)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to backfill conversations: %w", err)
+		}
+		fmt.Printf("rebuilt %d conversation summaries\n", rebuilt)
+		return nil
+
+	default:
+		return errors.New(backfillUsage)
+	}
+}
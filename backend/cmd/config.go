@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"go-chat/config"
+)
+
+const configUsage = `usage: go-chat config <command>
+
+commands:
+  print      show the effective configuration with secrets redacted
+  validate   check the configuration and exit`
+
+func runConfigCommand(args []string) error {
+	if len(args) == 0 {
+		return errors.New(configUsage)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "print":
+		if err := cfg.Print(os.Stdout); err != nil {
+			return err
+		}
+		// Still report problems so a broken config is obvious from the dump.
+		if err := cfg.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return nil
+
+	case "validate":
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		fmt.Println("configuration is valid")
+		return nil
+
+	default:
+		return errors.New(configUsage)
+	}
+}
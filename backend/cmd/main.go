@@ -1,23 +1,61 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"go-chat/config"
-	"go-chat/internal/domain"
+	repository_adapters "go-chat/internal/adapters/repository"
 	"go-chat/internal/handlers"
+	"go-chat/internal/middlerware"
 	"go-chat/internal/routes"
+	"go-chat/migrations"
+	"go-chat/pkg"
 
 	"github.com/go-chi/chi/v5"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := runConfigCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatal("Failed to load configuration:", err)
+		log.Fatal("Failed to load configuration: ", err)
+	}
+
+	level, _ := pkg.ParseLogLevel(cfg.Logging.Level)
+	pkg.InitLogger(level, cfg.Logging.Format == "json")
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrateCommand(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "backfill" {
+		if err := runBackfillCommand(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	shutdownTracing, err := pkg.InitTracing(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal("Failed to initialise tracing:", err)
 	}
 
 	// Connect to database
@@ -25,30 +63,102 @@ func main() {
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+	if err := db.Use(repository_adapters.NewMetricsPlugin()); err != nil {
+		log.Fatal("Failed to register database metrics:", err)
+	}
+	if err := db.Use(repository_adapters.NewTracingPlugin()); err != nil {
+		log.Fatal("Failed to register database tracing:", err)
+	}
 
-	// Run auto-migration
-	if err := db.AutoMigrate(&domain.User{}, &domain.Friendship{}, &domain.Message{}); err != nil {
-		log.Fatal("Failed to auto-migrate database:", err)
+	runner, err := migrations.NewRunner(db)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
 	}
 
-	fmt.Println("Database migration completed successfully")
+	// Apply pending schema migrations
+	if cfg.Database.MigrateOnStart {
+		applied, err := runner.Up()
+		if err != nil {
+			log.Fatal("Failed to migrate database:", err)
+		}
+		fmt.Printf("Database migration completed successfully (%d applied)\n", len(applied))
+	}
 
 	// Initialize handlers
-	h := handlers.NewHandlers(db)
+	h, err := handlers.NewHandlers(db, cfg, runner)
+	if err != nil {
+		log.Fatal("Failed to initialize handlers:", err)
+	}
 
 	// Setup router
 	r := chi.NewRouter()
 
 	// Setup routes
-	if err := routes.SetupRoutes(r, db, h); err != nil {
+	limiter := middlerware.NewRateLimiter(cfg.RateLimits)
+	if err := routes.SetupRoutes(r, db, h, cfg, limiter); err != nil {
 		log.Fatal("Failed to setup routes:", err)
 	}
 
 	// Start server
-	addr := fmt.Sprintf(":%s", cfg.Port)
-	fmt.Printf("Server starting on port %s\n", cfg.Port)
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%s", cfg.Server.Port),
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	if err := http.ListenAndServe(addr, r); err != nil {
+	serverErr := make(chan error, 1)
+	go func() {
+		fmt.Printf("Server starting on port %s\n", cfg.Server.Port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	select {
+	case err := <-serverErr:
 		log.Fatal("Failed to start server:", err)
+	case <-ctx.Done():
+	}
+
+	// A second signal during the drain kills the process immediately.
+	stop()
+	fmt.Println("Shutting down...")
+
+	// Fail readiness first and keep serving for the drain delay so load
+	// balancers stop sending traffic, then stop accepting connections and
+	// let in-flight requests finish. Upgraded WebSocket connections are not
+	// tracked by the server and drain below.
+	h.Health.SetDraining()
+	time.Sleep(cfg.Server.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("HTTP shutdown did not complete:", err)
 	}
+
+	if err := h.Shutdown(shutdownCtx); err != nil {
+		log.Println("WebSocket and worker drain did not complete:", err)
+	}
+
+	limiter.Stop()
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		log.Println("Failed to flush traces:", err)
+	}
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			log.Println("Failed to close database pool:", err)
+		}
+	}
+
+	fmt.Println("Server stopped")
 }
@@ -3,43 +3,327 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
 
+	"go-chat/pkg"
+
+	"github.com/glebarez/sqlite"
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// Config is the complete runtime configuration. Values start from
+// defaults(), are overlaid by the optional file named in CONFIG_FILE and
+// finally by environment variables.
 type Config struct {
-	Port   string
-	DB_URL string
+	Env string `yaml:"env" toml:"env"`
+
+	Server     ServerConfig               `yaml:"server" toml:"server"`
+	Database   DatabaseConfig             `yaml:"database" toml:"database"`
+	Auth       AuthConfig                 `yaml:"auth" toml:"auth"`
+	Cookies    CookieConfig               `yaml:"cookies" toml:"cookies"`
+	CORS       CORSConfig                 `yaml:"cors" toml:"cors"`
+	RateLimits map[string]RateLimitConfig `yaml:"rate_limits" toml:"rate_limits"`
+	WebSocket  WebSocketConfig            `yaml:"websocket" toml:"websocket"`
+	Logging    LoggingConfig              `yaml:"logging" toml:"logging"`
+	Tracing    pkg.TracingConfig          `yaml:"tracing" toml:"tracing"`
+	Accounts   AccountsConfig             `yaml:"accounts" toml:"accounts"`
+	Messages   MessagesConfig             `yaml:"messages" toml:"messages"`
 }
 
-func LoadConfig() (*Config, error) {
+type ServerConfig struct {
+	Port              string        `yaml:"port" toml:"port"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// DrainDelay is how long the server keeps serving after readiness starts
+	// failing, so load balancers notice before connections are refused.
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+}
+
+// DatabaseConfig selects the database. Driver is "postgres" or "sqlite"; for
+// SQLite, URL is a file path or ":memory:".
+type DatabaseConfig struct {
+	Driver          string        `yaml:"driver" toml:"driver"`
+	URL             string        `yaml:"url" toml:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+	MigrateOnStart  bool          `yaml:"migrate_on_start" toml:"migrate_on_start"`
+
+	// QueryTimeouts bounds request database work by endpoint type; see
+	// middlerware.QueryTimeouts.
+	QueryTimeouts map[string]time.Duration `yaml:"query_timeouts" toml:"query_timeouts"`
+}
+
+type AuthConfig struct {
+	JWTSecret            string                   `yaml:"jwt_secret" toml:"jwt_secret"`
+	JWTRefreshSecret     string                   `yaml:"jwt_refresh_secret" toml:"jwt_refresh_secret"`
+	AccessTokenTTL       time.Duration            `yaml:"access_token_ttl" toml:"access_token_ttl"`
+	RefreshTokenTTL      time.Duration            `yaml:"refresh_token_ttl" toml:"refresh_token_ttl"`
+	BootstrapAdminEmails []string                 `yaml:"bootstrap_admin_emails" toml:"bootstrap_admin_emails"`
+	OAuthSuccessRedirect string                   `yaml:"oauth_success_redirect" toml:"oauth_success_redirect"`
+	OIDCProviders        []pkg.OIDCProviderConfig `yaml:"oidc_providers" toml:"oidc_providers"`
+}
+
+// CookieConfig controls the auth cookies. Secure is always forced on when
+// Env is "production".
+type CookieConfig struct {
+	Domain   string `yaml:"domain" toml:"domain"`
+	Secure   bool   `yaml:"secure" toml:"secure"`
+	SameSite string `yaml:"same_site" toml:"same_site"`
+}
+
+// CORSConfig controls cross-origin requests. No origins are allowed unless
+// configured; in development the local frontend is allowed by default.
+type CORSConfig struct {
+	AllowedOrigins   []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials"`
+	MaxAge           int      `yaml:"max_age" toml:"max_age"`
+}
+
+type RateLimitConfig struct {
+	RequestsPerMinute int `yaml:"requests_per_minute" toml:"requests_per_minute"`
+	BurstSize         int `yaml:"burst_size" toml:"burst_size"`
+}
+
+type WebSocketConfig struct {
+	ReadLimit       int64         `yaml:"read_limit" toml:"read_limit"`
+	ReadBufferSize  int           `yaml:"read_buffer_size" toml:"read_buffer_size"`
+	WriteBufferSize int           `yaml:"write_buffer_size" toml:"write_buffer_size"`
+	SendBufferSize  int           `yaml:"send_buffer_size" toml:"send_buffer_size"`
+	PongWait        time.Duration `yaml:"pong_wait" toml:"pong_wait"`
+	PingPeriod      time.Duration `yaml:"ping_period" toml:"ping_period"`
+	WriteWait       time.Duration `yaml:"write_wait" toml:"write_wait"`
+	MessageTimeout  time.Duration `yaml:"message_timeout" toml:"message_timeout"`
+	// AllowedOrigins defaults to cors.allowed_origins; when both are empty
+	// only same-origin upgrades are accepted.
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+}
+
+type LoggingConfig struct {
+	Level  string `yaml:"level" toml:"level"`
+	Format string `yaml:"format" toml:"format"`
+}
+
+type AccountsConfig struct {
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" toml:"deletion_grace_period"`
+	// DeletionWorkerInterval is how often accounts past their grace period
+	// are purged.
+	DeletionWorkerInterval time.Duration `yaml:"deletion_worker_interval" toml:"deletion_worker_interval"`
+}
+
+type MessagesConfig struct {
+	// DeleteForEveryoneWindow is how long after sending a sender may still
+	// delete a message for both sides.
+	DeleteForEveryoneWindow time.Duration `yaml:"delete_for_everyone_window" toml:"delete_for_everyone_window"`
+	// SchedulerInterval is how often each replica checks for scheduled
+	// messages that are due, and so roughly how late they may go out.
+	SchedulerInterval time.Duration `yaml:"scheduler_interval" toml:"scheduler_interval"`
+	// PurgeInterval is how often expired disappearing messages are deleted.
+	PurgeInterval time.Duration `yaml:"purge_interval" toml:"purge_interval"`
+}
+
+func defaults() *Config {
+	return &Config{
+		Env: "development",
+		Server: ServerConfig{
+			Port:              "8080",
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      60 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+			DrainDelay:        5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Driver:          "postgres",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			MigrateOnStart:  true,
+			QueryTimeouts: map[string]time.Duration{
+				"default": 5 * time.Second,
+				"search":  10 * time.Second,
+				"export":  30 * time.Second,
+			},
+		},
+		Auth: AuthConfig{
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+		},
+		Cookies: CookieConfig{
+			SameSite: "lax",
+		},
+		CORS: CORSConfig{
+			AllowCredentials: true,
+			MaxAge:           300,
+		},
+		RateLimits: map[string]RateLimitConfig{
+			"auth":    {RequestsPerMinute: 10, BurstSize: 5},
+			"message": {RequestsPerMinute: 60, BurstSize: 10},
+			"search":  {RequestsPerMinute: 30, BurstSize: 5},
+			"friends": {RequestsPerMinute: 20, BurstSize: 5},
+			"default": {RequestsPerMinute: 100, BurstSize: 20},
+		},
+		WebSocket: WebSocketConfig{
+			ReadLimit:       512,
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			SendBufferSize:  256,
+			PongWait:        60 * time.Second,
+			PingPeriod:      54 * time.Second,
+			WriteWait:       10 * time.Second,
+			MessageTimeout:  10 * time.Second,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: pkg.TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "localhost:4318",
+			Insecure:    true,
+			SampleRatio: 1,
+			ServiceName: "go-chat",
+		},
+		Accounts: AccountsConfig{
+			DeletionGracePeriod:    14 * 24 * time.Hour,
+			DeletionWorkerInterval: time.Hour,
+		},
+		Messages: MessagesConfig{
+			DeleteForEveryoneWindow: time.Hour,
+			SchedulerInterval:       10 * time.Second,
+			PurgeInterval:           time.Minute,
+		},
+	}
+}
+
+// Load builds the configuration without validating it.
+func Load() (*Config, error) {
 	err := godotenv.Load()
 	if err != nil {
 		log.Printf("Warning: Error loading .env file: %v", err)
 	}
 
-	cfg := &Config{
-		Port:   getEnvOrDefault("PORT", "8080"),
-		DB_URL: getEnvOrDefault("DB_URL", ""),
+	cfg := defaults()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.IsProduction() {
+		cfg.Cookies.Secure = true
+	}
+	if cfg.Env == "development" && len(cfg.CORS.AllowedOrigins) == 0 {
+		cfg.CORS.AllowedOrigins = []string{"http://localhost:3000"}
+	}
+	// The frontend that may call the API may also open the WebSocket.
+	if len(cfg.WebSocket.AllowedOrigins) == 0 {
+		cfg.WebSocket.AllowedOrigins = cfg.CORS.AllowedOrigins
 	}
 
 	return cfg, nil
 }
 
+// LoadConfig loads and validates the configuration.
+func LoadConfig() (*Config, error) {
+	cfg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if !cfg.IsProduction() && (cfg.Auth.JWTSecret == "" || cfg.Auth.JWTRefreshSecret == "") {
+		log.Println("Warning: JWT secrets not set, using development defaults (not secure for production)")
+		if cfg.Auth.JWTSecret == "" {
+			cfg.Auth.JWTSecret = "your-super-secret-jwt-key-change-this-in-production"
+		}
+		if cfg.Auth.JWTRefreshSecret == "" {
+			cfg.Auth.JWTRefreshSecret = "your-super-secret-refresh-key-change-this-in-production"
+		}
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
 func ConnectToDB(cfg *Config) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(cfg.DB_URL), &gorm.Config{})
+	if cfg.Database.Driver == "sqlite" {
+		return connectToSQLite(cfg.Database.URL)
+	}
+
+	db, err := gorm.Open(postgres.Open(cfg.Database.URL), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+// connectToSQLite opens path with foreign keys enforced. SQLite allows a
+// single writer and gives every connection to ":memory:" its own database,
+// so the pool is pinned to one connection that is never recycled; the pool
+// settings are ignored.
+func connectToSQLite(path string) (*gorm.DB, error) {
+	dsn := path
+	if strings.Contains(dsn, "?") {
+		dsn += "&"
+	} else {
+		dsn += "?"
+	}
+	dsn += "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
+	sqlDB.SetMaxOpenConns(1)
+	sqlDB.SetMaxIdleConns(1)
+	sqlDB.SetConnMaxLifetime(0)
+	sqlDB.SetConnMaxIdleTime(0)
 
 	return db, nil
 }
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
-	return defaultValue
+	return items
 }
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-chat/pkg"
+)
+
+// envReader applies environment overrides and remembers every malformed
+// value so they can be reported together.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) string(key string, target *string) {
+	if value := os.Getenv(key); value != "" {
+		*target = value
+	}
+}
+
+func (e *envReader) list(key string, target *[]string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*target = splitList(value)
+	}
+}
+
+func (e *envReader) bool(key string, target *bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a boolean", key, value))
+		return
+	}
+	*target = parsed
+}
+
+func (e *envReader) int(key string, target *int) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not an integer", key, value))
+		return
+	}
+	*target = parsed
+}
+
+func (e *envReader) float(key string, target *float64) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a number", key, value))
+		return
+	}
+	*target = parsed
+}
+
+// duration accepts Go duration syntax ("90s", "12h") or, for compatibility
+// with the older variables, a bare number counted in unit.
+func (e *envReader) duration(key string, unit time.Duration, target *time.Duration) {
+	value := os.Getenv(key)
+	if value == "" {
+		return
+	}
+	if n, err := strconv.Atoi(value); err == nil {
+		*target = time.Duration(n) * unit
+		return
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: %q is not a duration", key, value))
+		return
+	}
+	*target = parsed
+}
+
+func applyEnv(cfg *Config) error {
+	e := &envReader{}
+
+	e.string("ENV", &cfg.Env)
+
+	e.string("PORT", &cfg.Server.Port)
+	e.duration("SERVER_READ_HEADER_TIMEOUT", time.Second, &cfg.Server.ReadHeaderTimeout)
+	e.duration("SERVER_READ_TIMEOUT", time.Second, &cfg.Server.ReadTimeout)
+	e.duration("SERVER_WRITE_TIMEOUT", time.Second, &cfg.Server.WriteTimeout)
+	e.duration("SERVER_IDLE_TIMEOUT", time.Second, &cfg.Server.IdleTimeout)
+	e.duration("SERVER_SHUTDOWN_TIMEOUT", time.Second, &cfg.Server.ShutdownTimeout)
+	e.duration("SERVER_DRAIN_DELAY", time.Second, &cfg.Server.DrainDelay)
+
+	e.string("DB_DRIVER", &cfg.Database.Driver)
+	e.string("DB_URL", &cfg.Database.URL)
+	e.int("DB_MAX_OPEN_CONNS", &cfg.Database.MaxOpenConns)
+	e.int("DB_MAX_IDLE_CONNS", &cfg.Database.MaxIdleConns)
+	e.duration("DB_CONN_MAX_LIFETIME", time.Second, &cfg.Database.ConnMaxLifetime)
+	e.duration("DB_CONN_MAX_IDLE_TIME", time.Second, &cfg.Database.ConnMaxIdleTime)
+	e.bool("MIGRATE_ON_START", &cfg.Database.MigrateOnStart)
+	for name, timeout := range cfg.Database.QueryTimeouts {
+		e.duration("DB_QUERY_TIMEOUT_"+strings.ToUpper(name), time.Second, &timeout)
+		cfg.Database.QueryTimeouts[name] = timeout
+	}
+
+	e.string("JWT_SECRET", &cfg.Auth.JWTSecret)
+	e.string("JWT_REFRESH_SECRET", &cfg.Auth.JWTRefreshSecret)
+	e.duration("JWT_ACCESS_TTL", time.Minute, &cfg.Auth.AccessTokenTTL)
+	e.duration("JWT_REFRESH_TTL", time.Hour, &cfg.Auth.RefreshTokenTTL)
+	e.list("BOOTSTRAP_ADMIN_EMAILS", &cfg.Auth.BootstrapAdminEmails)
+	e.string("OAUTH_SUCCESS_REDIRECT", &cfg.Auth.OAuthSuccessRedirect)
+	if names, ok := os.LookupEnv("OIDC_PROVIDERS"); ok {
+		cfg.Auth.OIDCProviders = loadOIDCProviders(splitList(names))
+	}
+
+	e.string("COOKIE_DOMAIN", &cfg.Cookies.Domain)
+	e.bool("COOKIE_SECURE", &cfg.Cookies.Secure)
+	e.string("COOKIE_SAMESITE", &cfg.Cookies.SameSite)
+
+	e.list("CORS_ALLOWED_ORIGINS", &cfg.CORS.AllowedOrigins)
+	e.bool("CORS_ALLOW_CREDENTIALS", &cfg.CORS.AllowCredentials)
+	e.int("CORS_MAX_AGE", &cfg.CORS.MaxAge)
+
+	for name, limit := range cfg.RateLimits {
+		prefix := "RATE_LIMIT_" + strings.ToUpper(name) + "_"
+		e.int(prefix+"RPM", &limit.RequestsPerMinute)
+		e.int(prefix+"BURST", &limit.BurstSize)
+		cfg.RateLimits[name] = limit
+	}
+
+	var readLimit int
+	if os.Getenv("WS_READ_LIMIT") != "" {
+		e.int("WS_READ_LIMIT", &readLimit)
+		cfg.WebSocket.ReadLimit = int64(readLimit)
+	}
+	e.int("WS_READ_BUFFER_SIZE", &cfg.WebSocket.ReadBufferSize)
+	e.int("WS_WRITE_BUFFER_SIZE", &cfg.WebSocket.WriteBufferSize)
+	e.int("WS_SEND_BUFFER_SIZE", &cfg.WebSocket.SendBufferSize)
+	e.duration("WS_PONG_WAIT", time.Second, &cfg.WebSocket.PongWait)
+	e.duration("WS_PING_PERIOD", time.Second, &cfg.WebSocket.PingPeriod)
+	e.duration("WS_WRITE_WAIT", time.Second, &cfg.WebSocket.WriteWait)
+	e.duration("WS_MESSAGE_TIMEOUT", time.Second, &cfg.WebSocket.MessageTimeout)
+	e.list("WS_ALLOWED_ORIGINS", &cfg.WebSocket.AllowedOrigins)
+
+	e.string("LOG_LEVEL", &cfg.Logging.Level)
+	e.string("LOG_FORMAT", &cfg.Logging.Format)
+
+	e.string("TRACING_EXPORTER", &cfg.Tracing.Exporter)
+	e.string("TRACING_ENDPOINT", &cfg.Tracing.Endpoint)
+	e.bool("TRACING_INSECURE", &cfg.Tracing.Insecure)
+	e.float("TRACING_SAMPLE_RATIO", &cfg.Tracing.SampleRatio)
+	e.string("TRACING_SERVICE_NAME", &cfg.Tracing.ServiceName)
+
+	e.duration("ACCOUNT_DELETION_GRACE_DAYS", 24*time.Hour, &cfg.Accounts.DeletionGracePeriod)
+	e.duration("ACCOUNT_DELETION_WORKER_INTERVAL_MINUTES", time.Minute, &cfg.Accounts.DeletionWorkerInterval)
+
+	e.duration("MESSAGE_DELETE_FOR_EVERYONE_MINUTES", time.Minute, &cfg.Messages.DeleteForEveryoneWindow)
+	e.duration("MESSAGE_SCHEDULER_INTERVAL_SECONDS", time.Second, &cfg.Messages.SchedulerInterval)
+	e.duration("MESSAGE_PURGE_INTERVAL_SECONDS", time.Second, &cfg.Messages.PurgeInterval)
+
+	return errors.Join(e.errs...)
+}
+
+// loadOIDCProviders reads, for each name in OIDC_PROVIDERS (e.g.
+// "google,sso"), OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET,
+// _REDIRECT_URL and the optional _DISPLAY_NAME and _SCOPES.
+func loadOIDCProviders(names []string) []pkg.OIDCProviderConfig {
+	var providers []pkg.OIDCProviderConfig
+
+	for _, name := range names {
+		name = strings.ToLower(name)
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		provider := pkg.OIDCProviderConfig{
+			Name:         name,
+			DisplayName:  os.Getenv(prefix + "DISPLAY_NAME"),
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+		}
+		if scopes := os.Getenv(prefix + "SCOPES"); scopes != "" {
+			provider.Scopes = strings.Fields(strings.ReplaceAll(scopes, ",", " "))
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers
+}
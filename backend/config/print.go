@@ -0,0 +1,67 @@
+package config
+
+import (
+	"io"
+	"net/url"
+
+	"go-chat/pkg"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of the configuration that is safe to log or print.
+func (c *Config) Redacted() *Config {
+	out := *c
+
+	// A SQLite path carries no credentials.
+	if c.Database.Driver != "sqlite" {
+		out.Database.URL = redactURL(c.Database.URL)
+	}
+	out.Auth.JWTSecret = redactSecret(c.Auth.JWTSecret)
+	out.Auth.JWTRefreshSecret = redactSecret(c.Auth.JWTRefreshSecret)
+
+	out.Auth.OIDCProviders = make([]pkg.OIDCProviderConfig, len(c.Auth.OIDCProviders))
+	for i, p := range c.Auth.OIDCProviders {
+		p.ClientSecret = redactSecret(p.ClientSecret)
+		out.Auth.OIDCProviders[i] = p
+	}
+
+	out.RateLimits = make(map[string]RateLimitConfig, len(c.RateLimits))
+	for name, limit := range c.RateLimits {
+		out.RateLimits[name] = limit
+	}
+
+	return &out
+}
+
+// Print writes the redacted configuration as YAML.
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func redactSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	return redacted
+}
+
+// redactURL hides the password of a URL-style DSN. Key/value DSNs cannot be
+// picked apart reliably, so they are hidden entirely.
+func redactURL(value string) string {
+	if value == "" {
+		return ""
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" {
+		return redacted
+	}
+	return u.Redacted()
+}
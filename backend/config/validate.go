@@ -0,0 +1,194 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go-chat/pkg"
+)
+
+const minSecretLength = 32
+
+// ValidationError lists every problem found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks the configuration as a whole and reports all problems at
+// once rather than stopping at the first.
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	switch c.Env {
+	case "development", "staging", "production", "test":
+	default:
+		add("env must be one of development, staging, production, test (got %q)", c.Env)
+	}
+
+	if port, err := strconv.Atoi(c.Server.Port); err != nil || port < 1 || port > 65535 {
+		add("server.port must be a number between 1 and 65535 (got %q)", c.Server.Port)
+	}
+	if c.Server.ReadHeaderTimeout <= 0 || c.Server.ReadTimeout <= 0 || c.Server.WriteTimeout <= 0 || c.Server.IdleTimeout <= 0 || c.Server.ShutdownTimeout <= 0 {
+		add("server timeouts must be positive")
+	}
+	if c.Server.DrainDelay < 0 {
+		add("server.drain_delay must not be negative")
+	}
+
+	if c.Database.Driver != "postgres" && c.Database.Driver != "sqlite" {
+		add("database.driver (DB_DRIVER) must be postgres or sqlite")
+	}
+	if c.Database.URL == "" {
+		add("database.url (DB_URL) is required")
+	}
+	if c.Database.MaxOpenConns < 1 {
+		add("database.max_open_conns must be at least 1")
+	}
+	if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		add("database.max_idle_conns must be between 0 and max_open_conns")
+	}
+	if _, ok := c.Database.QueryTimeouts["default"]; !ok {
+		add("database.query_timeouts must define a default timeout")
+	}
+	for name, timeout := range c.Database.QueryTimeouts {
+		if timeout <= 0 {
+			add("database.query_timeouts.%s must be positive", name)
+		}
+	}
+
+	if c.IsProduction() {
+		if len(c.Auth.JWTSecret) < minSecretLength {
+			add("auth.jwt_secret (JWT_SECRET) must be at least %d characters in production", minSecretLength)
+		}
+		if len(c.Auth.JWTRefreshSecret) < minSecretLength {
+			add("auth.jwt_refresh_secret (JWT_REFRESH_SECRET) must be at least %d characters in production", minSecretLength)
+		}
+	}
+	if c.Auth.JWTSecret != "" && c.Auth.JWTSecret == c.Auth.JWTRefreshSecret {
+		add("auth.jwt_secret and auth.jwt_refresh_secret must differ")
+	}
+	if c.Auth.AccessTokenTTL <= 0 {
+		add("auth.access_token_ttl must be positive")
+	}
+	if c.Auth.RefreshTokenTTL <= c.Auth.AccessTokenTTL {
+		add("auth.refresh_token_ttl must be longer than auth.access_token_ttl")
+	}
+	if c.Auth.OAuthSuccessRedirect != "" {
+		if u, err := url.Parse(c.Auth.OAuthSuccessRedirect); err != nil || u.Scheme == "" || u.Host == "" {
+			add("auth.oauth_success_redirect must be an absolute URL")
+		}
+	}
+	seen := make(map[string]bool)
+	for _, p := range c.Auth.OIDCProviders {
+		if p.Name == "" {
+			add("auth.oidc_providers: every provider needs a name")
+			continue
+		}
+		if seen[p.Name] {
+			add("auth.oidc_providers: duplicate provider %q", p.Name)
+		}
+		seen[p.Name] = true
+		if p.IssuerURL == "" || p.ClientID == "" || p.RedirectURL == "" {
+			add("auth.oidc_providers: %q is missing issuer, client id or redirect url", p.Name)
+		}
+	}
+
+	switch strings.ToLower(c.Cookies.SameSite) {
+	case "lax", "strict":
+	case "none":
+		if !c.Cookies.Secure {
+			add("cookies.same_site none requires cookies.secure")
+		}
+	default:
+		add("cookies.same_site must be lax, strict or none (got %q)", c.Cookies.SameSite)
+	}
+
+	// Browsers send cookies on credentialed requests, so any site matching a
+	// wildcard could act as the signed-in user.
+	if c.CORS.AllowCredentials {
+		for _, origin := range c.CORS.AllowedOrigins {
+			if strings.Contains(origin, "*") {
+				add("cors.allowed_origins must not contain wildcards when cors.allow_credentials is set (got %q)", origin)
+			}
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		add("cors.max_age must not be negative")
+	}
+
+	if _, ok := c.RateLimits["default"]; !ok {
+		add("rate_limits must define a default limit")
+	}
+	for name, limit := range c.RateLimits {
+		if limit.RequestsPerMinute < 1 || limit.BurstSize < 1 {
+			add("rate_limits.%s: requests_per_minute and burst_size must be at least 1", name)
+		}
+	}
+
+	if c.WebSocket.ReadLimit < 1 || c.WebSocket.ReadBufferSize < 1 || c.WebSocket.WriteBufferSize < 1 || c.WebSocket.SendBufferSize < 1 {
+		add("websocket limits and buffer sizes must be positive")
+	}
+	if c.WebSocket.WriteWait <= 0 || c.WebSocket.PongWait <= 0 || c.WebSocket.MessageTimeout <= 0 {
+		add("websocket.write_wait, websocket.pong_wait and websocket.message_timeout must be positive")
+	}
+	if c.WebSocket.PingPeriod <= 0 || c.WebSocket.PingPeriod >= c.WebSocket.PongWait {
+		add("websocket.ping_period must be positive and shorter than websocket.pong_wait")
+	}
+	if c.IsProduction() && len(c.WebSocket.AllowedOrigins) == 0 && len(c.CORS.AllowedOrigins) == 0 {
+		add("websocket.allowed_origins (WS_ALLOWED_ORIGINS) or cors.allowed_origins (CORS_ALLOWED_ORIGINS) must be set in production")
+	}
+
+	if _, err := pkg.ParseLogLevel(c.Logging.Level); err != nil {
+		add("logging.level: %v", err)
+	}
+	if c.Logging.Format != "json" && c.Logging.Format != "text" {
+		add("logging.format must be json or text (got %q)", c.Logging.Format)
+	}
+
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			add("tracing.endpoint (TRACING_ENDPOINT) is required for the otlp exporter")
+		}
+	default:
+		add("tracing.exporter must be none, stdout or otlp (got %q)", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		add("tracing.sample_ratio must be between 0 and 1")
+	}
+	if c.Tracing.ServiceName == "" {
+		add("tracing.service_name must not be empty")
+	}
+
+	if c.Accounts.DeletionGracePeriod < 0 {
+		add("accounts.deletion_grace_period must not be negative")
+	}
+	if c.Accounts.DeletionWorkerInterval <= 0 {
+		add("accounts.deletion_worker_interval must be positive")
+	}
+
+	if c.Messages.DeleteForEveryoneWindow < 0 {
+		add("messages.delete_for_everyone_window must not be negative")
+	}
+	if c.Messages.SchedulerInterval <= 0 {
+		add("messages.scheduler_interval must be positive")
+	}
+	if c.Messages.PurgeInterval <= 0 {
+		add("messages.purge_interval must be positive")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package memory_adapters
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+)
+
+type auditMemoryRepo struct {
+	store *Store
+}
+
+func NewAuditMemoryRepo(store *Store) repository.AuditRepository {
+	return &auditMemoryRepo{store: store}
+}
+
+// AppendEntry holds the store lock while linking to the chain head, which
+// serializes concurrent appends.
+func (r *auditMemoryRepo) AppendEntry(ctx context.Context, entry *domain.AuditEntry) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	entry.PrevHash = ""
+	if head, ok := r.store.audit[r.store.nextAuditID]; ok {
+		entry.PrevHash = head.Hash
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	// Match what the GORM adapter reads back from Postgres.
+	entry.CreatedAt = entry.CreatedAt.UTC().Truncate(time.Microsecond)
+	entry.Hash = entry.ComputeHash()
+
+	r.store.nextAuditID++
+	entry.ID = r.store.nextAuditID
+
+	stored := *entry
+	r.store.audit[entry.ID] = &stored
+	return nil
+}
+
+func (r *auditMemoryRepo) ListEntries(ctx context.Context, filter domain.AuditFilter, limit, offset int) ([]*domain.AuditEntry, int64, error) {
+	return r.list(func(e *domain.AuditEntry) bool {
+		return (filter.Action == "" || e.Action == filter.Action) &&
+			(filter.ActorID == 0 || (e.ActorID != nil && *e.ActorID == filter.ActorID)) &&
+			(filter.TargetUserID == 0 || (e.TargetUserID != nil && *e.TargetUserID == filter.TargetUserID)) &&
+			(filter.Since == nil || !e.CreatedAt.Before(*filter.Since)) &&
+			(filter.Until == nil || e.CreatedAt.Before(*filter.Until))
+	}, limit, offset)
+}
+
+func (r *auditMemoryRepo) ListUserEntries(ctx context.Context, userID uint, limit, offset int) ([]*domain.AuditEntry, int64, error) {
+	return r.list(func(e *domain.AuditEntry) bool {
+		if e.TargetUserID != nil {
+			return *e.TargetUserID == userID
+		}
+		return e.ActorID != nil && *e.ActorID == userID
+	}, limit, offset)
+}
+
+func (r *auditMemoryRepo) GetEntriesAfter(ctx context.Context, afterID uint, limit int) ([]*domain.AuditEntry, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	entries := []*domain.AuditEntry{}
+	for _, entry := range r.store.audit {
+		if entry.ID > afterID {
+			copied := *entry
+			entries = append(entries, &copied)
+		}
+	}
+	slices.SortFunc(entries, func(a, b *domain.AuditEntry) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return paginate(entries, limit, 0), nil
+}
+
+// list returns a page of the entries matching keep, newest first, and how
+// many match in total.
+func (r *auditMemoryRepo) list(keep func(*domain.AuditEntry) bool, limit, offset int) ([]*domain.AuditEntry, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	entries := []*domain.AuditEntry{}
+	for _, entry := range r.store.audit {
+		if keep(entry) {
+			copied := *entry
+			entries = append(entries, &copied)
+		}
+	}
+	slices.SortFunc(entries, func(a, b *domain.AuditEntry) int {
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return paginate(entries, limit, offset), int64(len(entries)), nil
+}
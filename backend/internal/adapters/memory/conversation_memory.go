@@ -0,0 +1,314 @@
+package memory_adapters
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type conversationMemoryRepo struct {
+	store *Store
+}
+
+func NewConversationMemoryRepo(store *Store) repository.ConversationRepository {
+	return &conversationMemoryRepo{store: store}
+}
+
+func (r *conversationMemoryRepo) RecordMessage(ctx context.Context, message *domain.Message) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.upsert(message.SenderID, message.ReceiverID, message, func(summary *domain.ConversationSummary) {
+		summary.ArchivedAt = nil
+	})
+	r.upsert(message.ReceiverID, message.SenderID, message, func(summary *domain.ConversationSummary) {
+		summary.ArchivedAt = nil
+		summary.UnreadCount++
+	})
+	return nil
+}
+
+func (r *conversationMemoryRepo) GetConversation(ctx context.Context, userID, peerID uint) (*domain.ConversationSummary, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	summary := r.store.conversation(userID, peerID)
+	if summary == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *summary
+	return &copied, nil
+}
+
+func (r *conversationMemoryRepo) MarkConversationRead(ctx context.Context, userID, peerID uint, readAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if summary := r.store.conversation(userID, peerID); summary != nil {
+		summary.LastReadMessageID = summary.LastMessageID
+		summary.LastReadAt = &readAt
+		summary.UnreadCount = 0
+		summary.MarkedUnread = false
+		summary.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *conversationMemoryRepo) SetReadCursor(ctx context.Context, userID, peerID uint, messageID *uint, readAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	summary := r.store.conversation(userID, peerID)
+	if summary == nil {
+		return nil
+	}
+	summary.LastReadMessageID = messageID
+	summary.LastReadAt = &readAt
+	summary.UnreadCount = r.unreadCount(userID, peerID)
+	summary.MarkedUnread = false
+	summary.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *conversationMemoryRepo) SetArchived(ctx context.Context, userID, peerID uint, archivedAt *time.Time) error {
+	return r.set(userID, peerID, func(summary *domain.ConversationSummary) {
+		summary.ArchivedAt = archivedAt
+	})
+}
+
+func (r *conversationMemoryRepo) SetPinned(ctx context.Context, userID, peerID uint, pinnedAt *time.Time) error {
+	return r.set(userID, peerID, func(summary *domain.ConversationSummary) {
+		summary.PinnedAt = pinnedAt
+	})
+}
+
+func (r *conversationMemoryRepo) SetMuted(ctx context.Context, userID, peerID uint, mutedAt, mutedUntil *time.Time) error {
+	return r.set(userID, peerID, func(summary *domain.ConversationSummary) {
+		summary.MutedAt = mutedAt
+		summary.MutedUntil = mutedUntil
+	})
+}
+
+func (r *conversationMemoryRepo) SetMarkedUnread(ctx context.Context, userID, peerID uint, markedUnread bool) error {
+	return r.set(userID, peerID, func(summary *domain.ConversationSummary) {
+		summary.MarkedUnread = markedUnread
+	})
+}
+
+// set applies change to the user's summary of the conversation with peer, if
+// there is one.
+func (r *conversationMemoryRepo) set(userID, peerID uint, change func(*domain.ConversationSummary)) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if summary := r.store.conversation(userID, peerID); summary != nil {
+		change(summary)
+		summary.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *conversationMemoryRepo) GetDisappearingTimer(ctx context.Context, userID1, userID2 uint) (time.Duration, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if timer := r.store.timer(userID1, userID2); timer != nil {
+		return time.Duration(timer.DisappearAfterSeconds) * time.Second, nil
+	}
+	return 0, nil
+}
+
+func (r *conversationMemoryRepo) SetDisappearingTimer(ctx context.Context, userID1, userID2 uint, after time.Duration, setByID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	timer := r.store.timer(userID1, userID2)
+	if timer == nil {
+		r.store.nextTimerID++
+		timer = &domain.ConversationTimer{
+			ID:         r.store.nextTimerID,
+			UserLowID:  min(userID1, userID2),
+			UserHighID: max(userID1, userID2),
+			CreatedAt:  time.Now(),
+		}
+		r.store.timers[timer.ID] = timer
+	}
+	timer.DisappearAfterSeconds = int(after / time.Second)
+	timer.SetByID = setByID
+	timer.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *conversationMemoryRepo) RefreshConversation(ctx context.Context, userID1, userID2 uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	r.refresh(userID1, userID2)
+	r.refresh(userID2, userID1)
+	return nil
+}
+
+func (r *conversationMemoryRepo) ListConversations(ctx context.Context, userID uint, query string, archived bool, limit, offset int) ([]*domain.ConversationSummary, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	summaries := []*domain.ConversationSummary{}
+	for _, summary := range r.store.conversations {
+		if summary.UserID != userID || (summary.ArchivedAt != nil) != archived {
+			continue
+		}
+		peer, ok := r.store.liveUser(summary.PeerID)
+		if !ok {
+			continue
+		}
+		// Like the GORM adapter's subquery, deleted users' names still match.
+		if query != "" {
+			user := r.store.users[summary.PeerID]
+			if !containsFold(user.Name, query) && !containsFold(user.Email, query) {
+				continue
+			}
+		}
+
+		copied := *summary
+		copied.Peer = *peer
+		if peerSummary := r.store.conversation(summary.PeerID, userID); peerSummary != nil {
+			copied.PeerLastReadMessageID = peerSummary.LastReadMessageID
+		}
+		if timer := r.store.timer(userID, summary.PeerID); timer != nil {
+			copied.DisappearAfterSeconds = timer.DisappearAfterSeconds
+		}
+		if summary.LastMessageID != nil {
+			if message, ok := r.store.messages[*summary.LastMessageID]; ok && readable(message) {
+				lastMessage := *message
+				if sender, ok := r.store.liveUser(message.SenderID); ok {
+					lastMessage.Sender = *sender
+				}
+				copied.LastMessage = &lastMessage
+			}
+		}
+		summaries = append(summaries, &copied)
+	}
+
+	slices.SortFunc(summaries, func(a, b *domain.ConversationSummary) int {
+		if (a.PinnedAt == nil) != (b.PinnedAt == nil) {
+			if a.PinnedAt != nil {
+				return -1
+			}
+			return 1
+		}
+		if a.PinnedAt != nil {
+			if c := b.PinnedAt.Compare(*a.PinnedAt); c != 0 {
+				return c
+			}
+		}
+		if c := b.LastActivityAt.Compare(a.LastActivityAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return paginate(summaries, limit, offset), nil
+}
+
+func (r *conversationMemoryRepo) DeleteUserConversations(ctx context.Context, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, summary := range r.store.conversations {
+		if summary.UserID == userID {
+			delete(r.store.conversations, id)
+		}
+	}
+	for id, timer := range r.store.timers {
+		if timer.UserLowID == userID || timer.UserHighID == userID {
+			delete(r.store.timers, id)
+		}
+	}
+	return nil
+}
+
+func (r *conversationMemoryRepo) RebuildConversations(ctx context.Context) (int64, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, summary := range r.store.conversations {
+		r.refresh(summary.UserID, summary.PeerID)
+	}
+	for _, message := range r.store.messages {
+		if message.DeletedAt.Valid {
+			continue
+		}
+		r.refresh(message.SenderID, message.ReceiverID)
+		r.refresh(message.ReceiverID, message.SenderID)
+	}
+	return int64(len(r.store.conversations)), nil
+}
+
+// refresh recomputes the user's summary of the conversation with peer from
+// the live messages they can see, keeping the read cursor. Callers must hold
+// the store lock.
+func (r *conversationMemoryRepo) refresh(userID, peerID uint) {
+	var latest *domain.Message
+	for _, message := range r.store.messages {
+		if message.DeletedAt.Valid || !between(userID, peerID)(message) || !r.store.visibleTo(message, userID) {
+			continue
+		}
+		if latest == nil || compareMessages(message, latest) > 0 {
+			latest = message
+		}
+	}
+
+	if latest == nil {
+		if summary := r.store.conversation(userID, peerID); summary != nil {
+			delete(r.store.conversations, summary.ID)
+		}
+		return
+	}
+	unread := r.unreadCount(userID, peerID)
+	r.upsert(userID, peerID, latest, func(summary *domain.ConversationSummary) {
+		summary.UnreadCount = unread
+	})
+}
+
+// unreadCount counts the visible live messages from peer past the user's
+// read cursor. Callers must hold the store lock.
+func (r *conversationMemoryRepo) unreadCount(userID, peerID uint) int {
+	cursor := r.store.readCursor(userID, peerID)
+	unread := 0
+	for _, message := range r.store.messages {
+		if !message.DeletedAt.Valid && message.SenderID == peerID && message.ReceiverID == userID && message.ID > cursor &&
+			r.store.visibleTo(message, userID) {
+			unread++
+		}
+	}
+	return unread
+}
+
+// upsert points the user's summary of the conversation with peer at message,
+// creating it if needed, and then applies change. Callers must hold the store
+// lock.
+func (r *conversationMemoryRepo) upsert(userID, peerID uint, message *domain.Message, change func(*domain.ConversationSummary)) {
+	now := time.Now()
+	summary := r.store.conversation(userID, peerID)
+	if summary == nil {
+		r.store.nextConversationID++
+		summary = &domain.ConversationSummary{
+			ID:        r.store.nextConversationID,
+			UserID:    userID,
+			PeerID:    peerID,
+			CreatedAt: now,
+		}
+		r.store.conversations[summary.ID] = summary
+	}
+
+	lastMessageID := message.ID
+	summary.LastMessageID = &lastMessageID
+	summary.LastActivityAt = message.CreatedAt
+	summary.UpdatedAt = now
+	change(summary)
+}
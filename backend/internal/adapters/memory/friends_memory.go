@@ -0,0 +1,195 @@
+package memory_adapters
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type friendsMemoryRepo struct {
+	store *Store
+}
+
+func NewFriendsMemoryRepo(store *Store) repository.FriendsRepository {
+	return &friendsMemoryRepo{store: store}
+}
+
+func (r *friendsMemoryRepo) CreateFriendship(ctx context.Context, friendship *domain.Friendship) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// Mirrors the partial unique index on live (requester, addressee) pairs.
+	for _, row := range r.store.friendships {
+		if row.DeletedAt == nil && row.RequesterID == friendship.RequesterID && row.AddresseeID == friendship.AddresseeID {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	now := time.Now()
+	r.store.nextFriendshipID++
+	row := &friendshipRow{Friendship: domain.Friendship{
+		ID:          r.store.nextFriendshipID,
+		RequesterID: friendship.RequesterID,
+		AddresseeID: friendship.AddresseeID,
+		Status:      friendship.Status,
+		BlockedByID: friendship.BlockedByID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}}
+	if row.Status == "" {
+		row.Status = domain.FriendshipPending
+	}
+	r.store.friendships[row.ID] = row
+
+	friendship.ID = row.ID
+	return nil
+}
+
+func (r *friendsMemoryRepo) FindFriendshipByID(ctx context.Context, id uint) (*domain.Friendship, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	row, ok := r.store.friendships[id]
+	if !ok || row.DeletedAt != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.toDomain(row), nil
+}
+
+func (r *friendsMemoryRepo) FindFriendshipBetweenUsers(ctx context.Context, userID1, userID2 uint) (*domain.Friendship, error) {
+	friendships := r.find(func(f *domain.Friendship) bool {
+		return (f.RequesterID == userID1 && f.AddresseeID == userID2) ||
+			(f.RequesterID == userID2 && f.AddresseeID == userID1)
+	})
+	if len(friendships) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return friendships[0], nil
+}
+
+func (r *friendsMemoryRepo) UpdateFriendshipStatus(ctx context.Context, id uint, status domain.FriendshipStatus) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if row, ok := r.store.friendships[id]; ok && row.DeletedAt == nil {
+		row.Status = status
+		row.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *friendsMemoryRepo) BlockFriendship(ctx context.Context, id, blockerID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if row, ok := r.store.friendships[id]; ok && row.DeletedAt == nil {
+		row.Status = domain.FriendshipBlocked
+		row.BlockedByID = &blockerID
+		row.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *friendsMemoryRepo) GetUserFriends(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
+	return r.find(func(f *domain.Friendship) bool {
+		return (f.RequesterID == userID || f.AddresseeID == userID) && f.Status == domain.FriendshipAccepted
+	}), nil
+}
+
+func (r *friendsMemoryRepo) GetPendingFriendRequests(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
+	return r.find(func(f *domain.Friendship) bool {
+		return f.AddresseeID == userID && f.Status == domain.FriendshipPending
+	}), nil
+}
+
+func (r *friendsMemoryRepo) GetSentFriendRequests(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
+	return r.find(func(f *domain.Friendship) bool {
+		return f.RequesterID == userID && f.Status == domain.FriendshipPending
+	}), nil
+}
+
+func (r *friendsMemoryRepo) DeleteFriendship(ctx context.Context, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if row, ok := r.store.friendships[id]; ok && row.DeletedAt == nil {
+		now := time.Now()
+		row.DeletedAt = &now
+	}
+	return nil
+}
+
+func (r *friendsMemoryRepo) AreFriends(ctx context.Context, userID1, userID2 uint) (bool, error) {
+	friendships := r.find(func(f *domain.Friendship) bool {
+		return ((f.RequesterID == userID1 && f.AddresseeID == userID2) ||
+			(f.RequesterID == userID2 && f.AddresseeID == userID1)) &&
+			f.Status == domain.FriendshipAccepted
+	})
+	return len(friendships) > 0, nil
+}
+
+func (r *friendsMemoryRepo) GetAllUserFriendships(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
+	friendships := r.find(func(f *domain.Friendship) bool {
+		return f.RequesterID == userID || f.AddresseeID == userID
+	})
+	slices.SortStableFunc(friendships, func(a, b *domain.Friendship) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return friendships, nil
+}
+
+// DeleteUserFriendships removes the rows outright, soft-deleted ones
+// included, like the GORM adapter's unscoped delete.
+func (r *friendsMemoryRepo) DeleteUserFriendships(ctx context.Context, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, row := range r.store.friendships {
+		if row.RequesterID == userID || row.AddresseeID == userID {
+			delete(r.store.friendships, id)
+		}
+	}
+	return nil
+}
+
+// find returns the live friendships matching keep in ID order, with both
+// users attached.
+func (r *friendsMemoryRepo) find(keep func(*domain.Friendship) bool) []*domain.Friendship {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	friendships := []*domain.Friendship{}
+	for _, row := range r.store.friendships {
+		if row.DeletedAt == nil && keep(&row.Friendship) {
+			friendships = append(friendships, r.toDomain(row))
+		}
+	}
+	slices.SortFunc(friendships, func(a, b *domain.Friendship) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return friendships
+}
+
+// toDomain copies row and preloads its users; deleted users are left nil as
+// GORM's Preload would. Callers must hold the store lock.
+func (r *friendsMemoryRepo) toDomain(row *friendshipRow) *domain.Friendship {
+	friendship := row.Friendship
+	friendship.Requester = nil
+	friendship.Addressee = nil
+
+	if user, ok := r.store.liveUser(row.RequesterID); ok {
+		requester := *user
+		friendship.Requester = &requester
+	}
+	if user, ok := r.store.liveUser(row.AddresseeID); ok {
+		addressee := *user
+		friendship.Addressee = &addressee
+	}
+	return &friendship
+}
@@ -0,0 +1,145 @@
+package memory_adapters
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type identityMemoryRepo struct {
+	store *Store
+}
+
+func NewIdentityMemoryRepo(store *Store) repository.IdentityRepository {
+	return &identityMemoryRepo{store: store}
+}
+
+func (r *identityMemoryRepo) CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.identities {
+		if existing.Provider == identity.Provider && existing.Subject == identity.Subject {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	now := time.Now()
+	r.store.nextIdentityID++
+	identity.ID = r.store.nextIdentityID
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = now
+	}
+	if identity.UpdatedAt.IsZero() {
+		identity.UpdatedAt = now
+	}
+
+	stored := *identity
+	r.store.identities[identity.ID] = &stored
+	return nil
+}
+
+func (r *identityMemoryRepo) FindIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, identity := range r.store.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			copied := *identity
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *identityMemoryRepo) GetUserIdentities(ctx context.Context, userID uint) ([]*domain.UserIdentity, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	identities := []*domain.UserIdentity{}
+	for _, identity := range r.store.identities {
+		if identity.UserID == userID {
+			copied := *identity
+			identities = append(identities, &copied)
+		}
+	}
+	slices.SortFunc(identities, func(a, b *domain.UserIdentity) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return identities, nil
+}
+
+func (r *identityMemoryRepo) DeleteIdentity(ctx context.Context, userID uint, provider string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	deleted := false
+	for id, identity := range r.store.identities {
+		if identity.UserID == userID && identity.Provider == provider {
+			delete(r.store.identities, id)
+			deleted = true
+		}
+	}
+	if !deleted {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *identityMemoryRepo) DeleteUserIdentities(ctx context.Context, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, identity := range r.store.identities {
+		if identity.UserID == userID {
+			delete(r.store.identities, id)
+		}
+	}
+	return nil
+}
+
+func (r *identityMemoryRepo) SaveOAuthState(ctx context.Context, state *domain.OAuthState) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for key, existing := range r.store.oauthStates {
+		if existing.ExpiresAt.Before(now) {
+			delete(r.store.oauthStates, key)
+		}
+	}
+	if _, ok := r.store.oauthStates[state.State]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	if state.CreatedAt.IsZero() {
+		state.CreatedAt = now
+	}
+
+	stored := *state
+	r.store.oauthStates[state.State] = &stored
+	return nil
+}
+
+// ConsumeOAuthState loads and deletes the state in one step so a callback
+// can never be replayed.
+func (r *identityMemoryRepo) ConsumeOAuthState(ctx context.Context, state string) (*domain.OAuthState, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	stored, ok := r.store.oauthStates[state]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	delete(r.store.oauthStates, state)
+	copied := *stored
+	return &copied, nil
+}
@@ -0,0 +1,54 @@
+package memory_adapters
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	"go-chat/internal/ports/repository/repositorytest"
+)
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repositories {
+		return NewRepositories(NewStore())
+	})
+}
+
+func TestUnitOfWorkRollsBackOnError(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	users := NewUserMemoryRepo(store)
+	uow := NewMemoryUnitOfWork(store)
+
+	failure := errors.New("boom")
+	err := uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := repos.Users.Create(ctx, &domain.User{Name: "Alice", Email: "alice@example.com"}); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Do error = %v, want %v", err, failure)
+	}
+	if _, err := users.FindByEmail(ctx, "alice@example.com"); err == nil {
+		t.Fatal("user created inside a failed unit of work was kept")
+	}
+
+	err = uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		return repos.Users.Create(ctx, &domain.User{Name: "Bob", Email: "bob@example.com"})
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	bob, err := users.FindByEmail(ctx, "bob@example.com")
+	if err != nil {
+		t.Fatalf("user created inside a committed unit of work is missing: %v", err)
+	}
+	// IDs handed out by the rolled-back work are reused, as after a
+	// rolled-back transaction the rows never existed.
+	if bob.ID != 1 {
+		t.Errorf("ID = %d, want 1", bob.ID)
+	}
+}
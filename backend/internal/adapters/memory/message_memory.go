@@ -0,0 +1,512 @@
+package memory_adapters
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type messageMemoryRepo struct {
+	store *Store
+}
+
+func NewMessageMemoryRepo(store *Store) repository.MessageRepository {
+	return &messageMemoryRepo{store: store}
+}
+
+func (r *messageMemoryRepo) CreateMessage(ctx context.Context, message *domain.Message) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// Keep timestamps the caller already set; expiry times are based on them.
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+	if message.UpdatedAt.IsZero() {
+		message.UpdatedAt = time.Now()
+	}
+	if message.MessageType == "" {
+		message.MessageType = domain.MessageTypeText
+	}
+
+	r.store.nextMessageID++
+	message.ID = r.store.nextMessageID
+
+	for i := range message.Receipts {
+		r.store.nextReceiptID++
+		receipt := &message.Receipts[i]
+		receipt.ID = r.store.nextReceiptID
+		receipt.MessageID = message.ID
+		receipt.CreatedAt = message.CreatedAt
+		receipt.UpdatedAt = message.CreatedAt
+		stored := *receipt
+		r.store.receipts[receipt.ID] = &stored
+	}
+
+	stored := *message
+	stored.Sender = domain.User{}
+	stored.Receiver = domain.User{}
+	stored.Receipts = nil
+	r.store.messages[message.ID] = &stored
+	return nil
+}
+
+func (r *messageMemoryRepo) GetMessagesBetweenUsers(ctx context.Context, userID, peerID uint, limit, offset int) ([]*domain.Message, error) {
+	messages := r.find(func(m *domain.Message) bool {
+		return between(userID, peerID)(m) && r.store.visibleTo(m, userID)
+	})
+	return paginate(messages, limit, offset), nil
+}
+
+func (r *messageMemoryRepo) MarkMessageDelivered(ctx context.Context, messageID, userID uint, deliveredAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if receipt := r.store.receipt(messageID, userID); receipt != nil && receipt.DeliveredAt == nil {
+		receipt.DeliveredAt = &deliveredAt
+		receipt.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *messageMemoryRepo) MarkMessagesRead(ctx context.Context, senderID, receiverID, upToID uint, readAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, receipt := range r.store.receipts {
+		message, ok := r.store.messages[receipt.MessageID]
+		if !ok || receipt.UserID != receiverID || receipt.ReadAt != nil ||
+			message.SenderID != senderID || message.ReceiverID != receiverID || message.ID > upToID {
+			continue
+		}
+		receipt.ReadAt = &readAt
+		if receipt.DeliveredAt == nil {
+			receipt.DeliveredAt = &readAt
+		}
+		receipt.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *messageMemoryRepo) GetMessageReceipts(ctx context.Context, messageID uint) ([]*domain.MessageReceipt, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	receipts := []*domain.MessageReceipt{}
+	for _, receipt := range r.store.receipts {
+		if receipt.MessageID == messageID {
+			copied := *receipt
+			receipts = append(receipts, &copied)
+		}
+	}
+	slices.SortFunc(receipts, func(a, b *domain.MessageReceipt) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return receipts, nil
+}
+
+func (r *messageMemoryRepo) GetMessageByID(ctx context.Context, messageID uint) (*domain.Message, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	message, ok := r.store.messages[messageID]
+	if !ok || !readable(message) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.toDomain(message), nil
+}
+
+func (r *messageMemoryRepo) DeleteMessage(ctx context.Context, messageID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if message, ok := r.store.messages[messageID]; ok && !message.DeletedAt.Valid {
+		message.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	}
+	return nil
+}
+
+func (r *messageMemoryRepo) HideMessage(ctx context.Context, messageID, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if message, ok := r.store.messages[messageID]; !ok || !r.store.visibleTo(message, userID) {
+		return nil
+	}
+	r.store.nextHiddenID++
+	r.store.hidden[r.store.nextHiddenID] = &domain.HiddenMessage{
+		ID:        r.store.nextHiddenID,
+		MessageID: messageID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	return nil
+}
+
+func (r *messageMemoryRepo) HideConversation(ctx context.Context, userID, peerID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for _, message := range r.store.messages {
+		if message.DeletedAt.Valid || !between(userID, peerID)(message) || !r.store.visibleTo(message, userID) {
+			continue
+		}
+		r.store.nextHiddenID++
+		r.store.hidden[r.store.nextHiddenID] = &domain.HiddenMessage{
+			ID:        r.store.nextHiddenID,
+			MessageID: message.ID,
+			UserID:    userID,
+			CreatedAt: now,
+		}
+	}
+	return nil
+}
+
+func (r *messageMemoryRepo) TombstoneMessage(ctx context.Context, messageID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if message, ok := r.store.messages[messageID]; ok && !message.DeletedAt.Valid {
+		message.Content = domain.TombstoneContent
+		message.MessageType = domain.MessageTypeTombstone
+		message.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+func (r *messageMemoryRepo) GetLatestMessageBetweenUsers(ctx context.Context, userID1, userID2 uint) (*domain.Message, error) {
+	messages := r.find(between(userID1, userID2))
+	if len(messages) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return messages[len(messages)-1], nil
+}
+
+func (r *messageMemoryRepo) GetMessageBefore(ctx context.Context, userID1, userID2, beforeID uint) (*domain.Message, error) {
+	messages := r.find(func(m *domain.Message) bool {
+		return between(userID1, userID2)(m) && m.ID < beforeID
+	})
+	if len(messages) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return slices.MaxFunc(messages, func(a, b *domain.Message) int {
+		return cmp.Compare(a.ID, b.ID)
+	}), nil
+}
+
+// SearchMessages matches content and either participant's name. Like the
+// GORM adapter's joins, names of deleted users still match.
+func (r *messageMemoryRepo) SearchMessages(ctx context.Context, userID uint, query string, limit, offset int) ([]*domain.Message, error) {
+	r.store.mu.RLock()
+	nameMatches := func(id uint) bool {
+		user, ok := r.store.users[id]
+		return ok && containsFold(user.Name, query)
+	}
+	matched := []*domain.Message{}
+	for _, message := range r.store.messages {
+		if !readable(message) || (message.SenderID != userID && message.ReceiverID != userID) || !r.store.visibleTo(message, userID) {
+			continue
+		}
+		if containsFold(message.Content, query) || nameMatches(message.SenderID) || nameMatches(message.ReceiverID) {
+			matched = append(matched, r.toDomain(message))
+		}
+	}
+	r.store.mu.RUnlock()
+
+	slices.SortFunc(matched, func(a, b *domain.Message) int {
+		return compareMessages(b, a)
+	})
+	return paginate(matched, limit, offset), nil
+}
+
+func (r *messageMemoryRepo) GetAllUserMessages(ctx context.Context, userID uint) ([]*domain.Message, error) {
+	return r.find(func(m *domain.Message) bool {
+		return m.SenderID == userID || m.ReceiverID == userID
+	}), nil
+}
+
+// TombstoneUserMessages overwrites the content of everything the user sent,
+// including already soft-deleted rows, while leaving the rows in place.
+func (r *messageMemoryRepo) TombstoneUserMessages(ctx context.Context, senderID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for _, message := range r.store.messages {
+		if message.SenderID == senderID {
+			message.Content = domain.TombstoneContent
+			message.MessageType = domain.MessageTypeTombstone
+			message.UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+func (r *messageMemoryRepo) PinMessage(ctx context.Context, pin *domain.PinnedMessage) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.pin(pin.MessageID) != nil {
+		return false, nil
+	}
+	if pin.CreatedAt.IsZero() {
+		pin.CreatedAt = time.Now()
+	}
+	r.store.nextPinID++
+	pin.ID = r.store.nextPinID
+	stored := *pin
+	r.store.pins[pin.ID] = &stored
+	return true, nil
+}
+
+func (r *messageMemoryRepo) UnpinMessage(ctx context.Context, messageID uint) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	pin := r.store.pin(messageID)
+	if pin == nil {
+		return false, nil
+	}
+	delete(r.store.pins, pin.ID)
+	return true, nil
+}
+
+func (r *messageMemoryRepo) CountPinnedMessages(ctx context.Context, userID1, userID2 uint) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var count int64
+	for _, pin := range r.store.pins {
+		if message, ok := r.store.messages[pin.MessageID]; ok && !message.DeletedAt.Valid && message.MessageType != domain.MessageTypeTombstone && between(userID1, userID2)(message) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetPinnedMessages lists the pinned messages between the users that userID
+// can still see, most recently pinned first.
+func (r *messageMemoryRepo) GetPinnedMessages(ctx context.Context, userID, peerID uint, limit, offset int) ([]*domain.Message, error) {
+	r.store.mu.RLock()
+	type pinned struct {
+		pin     *domain.PinnedMessage
+		message *domain.Message
+	}
+	matched := []pinned{}
+	for _, pin := range r.store.pins {
+		message, ok := r.store.messages[pin.MessageID]
+		if !ok || !readable(message) || !between(userID, peerID)(message) || !r.store.visibleTo(message, userID) {
+			continue
+		}
+		copied := r.toDomain(message)
+		pinnedAt, pinnedByID := pin.CreatedAt, pin.PinnedByID
+		copied.PinnedAt = &pinnedAt
+		copied.PinnedByID = &pinnedByID
+		matched = append(matched, pinned{pin: pin, message: copied})
+	}
+	r.store.mu.RUnlock()
+
+	slices.SortFunc(matched, func(a, b pinned) int {
+		if c := b.pin.CreatedAt.Compare(a.pin.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.pin.ID, a.pin.ID)
+	})
+	messages := []*domain.Message{}
+	for _, p := range paginate(matched, limit, offset) {
+		messages = append(messages, p.message)
+	}
+	return messages, nil
+}
+
+func (r *messageMemoryRepo) StarMessage(ctx context.Context, messageID, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if r.store.star(messageID, userID) != nil {
+		return nil
+	}
+	r.store.nextStarID++
+	r.store.stars[r.store.nextStarID] = &domain.StarredMessage{
+		ID:        r.store.nextStarID,
+		MessageID: messageID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	return nil
+}
+
+func (r *messageMemoryRepo) UnstarMessage(ctx context.Context, messageID, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if star := r.store.star(messageID, userID); star != nil {
+		delete(r.store.stars, star.ID)
+	}
+	return nil
+}
+
+// GetStarredMessages lists the messages the user starred and can still see,
+// most recently starred first.
+func (r *messageMemoryRepo) GetStarredMessages(ctx context.Context, userID uint, limit, offset int) ([]*domain.Message, error) {
+	r.store.mu.RLock()
+	type starred struct {
+		star    *domain.StarredMessage
+		message *domain.Message
+	}
+	matched := []starred{}
+	for _, star := range r.store.stars {
+		message, ok := r.store.messages[star.MessageID]
+		if star.UserID != userID || !ok || !readable(message) ||
+			(message.SenderID != userID && message.ReceiverID != userID) || !r.store.visibleTo(message, userID) {
+			continue
+		}
+		copied := r.toDomain(message)
+		starredAt := star.CreatedAt
+		copied.StarredAt = &starredAt
+		matched = append(matched, starred{star: star, message: copied})
+	}
+	r.store.mu.RUnlock()
+
+	slices.SortFunc(matched, func(a, b starred) int {
+		if c := b.star.CreatedAt.Compare(a.star.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.star.ID, a.star.ID)
+	})
+	messages := []*domain.Message{}
+	for _, s := range paginate(matched, limit, offset) {
+		messages = append(messages, s.message)
+	}
+	return messages, nil
+}
+
+func (r *messageMemoryRepo) DeleteUserMessageState(ctx context.Context, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, star := range r.store.stars {
+		if star.UserID == userID {
+			delete(r.store.stars, id)
+		}
+	}
+	for id, hidden := range r.store.hidden {
+		if hidden.UserID == userID {
+			delete(r.store.hidden, id)
+		}
+	}
+	for id, pin := range r.store.pins {
+		if message, ok := r.store.messages[pin.MessageID]; pin.PinnedByID == userID || (ok && message.SenderID == userID) {
+			delete(r.store.pins, id)
+		}
+	}
+	return nil
+}
+
+func (r *messageMemoryRepo) DeleteExpiredMessages(ctx context.Context, now time.Time, limit int) ([]*domain.Message, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	var expired []*domain.Message
+	for _, message := range r.store.messages {
+		if message.ExpiresAt != nil && !message.ExpiresAt.After(now) {
+			copied := *message
+			expired = append(expired, &copied)
+		}
+	}
+	slices.SortFunc(expired, func(a, b *domain.Message) int {
+		if c := a.ExpiresAt.Compare(*b.ExpiresAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	expired = paginate(expired, limit, 0)
+
+	for _, message := range expired {
+		delete(r.store.messages, message.ID)
+		for id, receipt := range r.store.receipts {
+			if receipt.MessageID == message.ID {
+				delete(r.store.receipts, id)
+			}
+		}
+		for id, hidden := range r.store.hidden {
+			if hidden.MessageID == message.ID {
+				delete(r.store.hidden, id)
+			}
+		}
+		if pin := r.store.pin(message.ID); pin != nil {
+			delete(r.store.pins, pin.ID)
+		}
+		for id, star := range r.store.stars {
+			if star.MessageID == message.ID {
+				delete(r.store.stars, id)
+			}
+		}
+	}
+	return expired, nil
+}
+
+// between matches messages exchanged by two users in either direction.
+func between(userID1, userID2 uint) func(*domain.Message) bool {
+	return func(m *domain.Message) bool {
+		return (m.SenderID == userID1 && m.ReceiverID == userID2) ||
+			(m.SenderID == userID2 && m.ReceiverID == userID1)
+	}
+}
+
+// find returns the readable messages matching keep, oldest first, with
+// sender and receiver attached.
+func (r *messageMemoryRepo) find(keep func(*domain.Message) bool) []*domain.Message {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	messages := []*domain.Message{}
+	for _, message := range r.store.messages {
+		if readable(message) && keep(message) {
+			messages = append(messages, r.toDomain(message))
+		}
+	}
+	slices.SortFunc(messages, compareMessages)
+	return messages
+}
+
+// readable matches messages that are neither soft-deleted nor past their
+// expiry, like the GORM adapter's read scope.
+func readable(message *domain.Message) bool {
+	return !message.DeletedAt.Valid && (message.ExpiresAt == nil || message.ExpiresAt.After(time.Now()))
+}
+
+// compareMessages orders by creation time, breaking ties by ID.
+func compareMessages(a, b *domain.Message) int {
+	if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// toDomain copies message, derives its read state from the receiver's read
+// cursor and receipt and preloads its users; deleted users are left
+// zero-valued as GORM's Preload would. Callers must hold the store lock.
+func (r *messageMemoryRepo) toDomain(message *domain.Message) *domain.Message {
+	copied := *message
+	copied.IsRead = message.ID <= r.store.readCursor(message.ReceiverID, message.SenderID)
+	if receipt := r.store.receipt(message.ID, message.ReceiverID); receipt != nil {
+		copied.IsDelivered = receipt.DeliveredAt != nil
+		copied.DeliveredAt = receipt.DeliveredAt
+		copied.ReadAt = receipt.ReadAt
+	}
+	if user, ok := r.store.liveUser(message.SenderID); ok {
+		copied.Sender = *user
+	}
+	if user, ok := r.store.liveUser(message.ReceiverID); ok {
+		copied.Receiver = *user
+	}
+	return &copied
+}
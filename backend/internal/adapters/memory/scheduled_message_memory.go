@@ -0,0 +1,149 @@
+package memory_adapters
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type scheduledMessageMemoryRepo struct {
+	store *Store
+}
+
+func NewScheduledMessageMemoryRepo(store *Store) repository.ScheduledMessageRepository {
+	return &scheduledMessageMemoryRepo{store: store}
+}
+
+func (r *scheduledMessageMemoryRepo) CreateScheduledMessage(ctx context.Context, message *domain.ScheduledMessage) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	message.CreatedAt = time.Now()
+	message.UpdatedAt = time.Now()
+	if message.MessageType == "" {
+		message.MessageType = domain.MessageTypeText
+	}
+	if message.Status == "" {
+		message.Status = domain.ScheduledMessagePending
+	}
+
+	r.store.nextScheduledID++
+	message.ID = r.store.nextScheduledID
+
+	stored := *message
+	r.store.scheduled[message.ID] = &stored
+	return nil
+}
+
+func (r *scheduledMessageMemoryRepo) GetScheduledMessage(ctx context.Context, id uint) (*domain.ScheduledMessage, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	message, ok := r.store.scheduled[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *message
+	return &copied, nil
+}
+
+func (r *scheduledMessageMemoryRepo) GetPendingScheduledMessages(ctx context.Context, senderID uint) ([]*domain.ScheduledMessage, error) {
+	return r.find(func(m *domain.ScheduledMessage) bool {
+		return m.SenderID == senderID
+	}), nil
+}
+
+func (r *scheduledMessageMemoryRepo) GetDueScheduledMessages(ctx context.Context, now time.Time, limit int) ([]*domain.ScheduledMessage, error) {
+	messages := r.find(func(m *domain.ScheduledMessage) bool {
+		return !m.SendAt.After(now)
+	})
+	return paginate(messages, limit, 0), nil
+}
+
+func (r *scheduledMessageMemoryRepo) UpdateScheduledMessage(ctx context.Context, id uint, content string, sendAt time.Time) (bool, error) {
+	return r.updatePending(id, func(m *domain.ScheduledMessage) {
+		m.Content = content
+		m.SendAt = sendAt
+	}), nil
+}
+
+func (r *scheduledMessageMemoryRepo) CancelScheduledMessage(ctx context.Context, id uint, cancelledAt time.Time) (bool, error) {
+	return r.updatePending(id, func(m *domain.ScheduledMessage) {
+		m.Status = domain.ScheduledMessageCancelled
+		m.CancelledAt = &cancelledAt
+	}), nil
+}
+
+func (r *scheduledMessageMemoryRepo) CancelUserScheduledMessages(ctx context.Context, userID uint, cancelledAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, message := range r.store.scheduled {
+		if (message.SenderID == userID || message.ReceiverID == userID) && message.Status == domain.ScheduledMessagePending {
+			message.Status = domain.ScheduledMessageCancelled
+			message.CancelledAt = &cancelledAt
+			message.UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+func (r *scheduledMessageMemoryRepo) ClaimScheduledMessage(ctx context.Context, id uint, sentAt time.Time) (bool, error) {
+	return r.updatePending(id, func(m *domain.ScheduledMessage) {
+		m.Status = domain.ScheduledMessageSent
+		m.SentAt = &sentAt
+	}), nil
+}
+
+func (r *scheduledMessageMemoryRepo) RecordScheduledMessageFailure(ctx context.Context, id uint, reason string, maxAttempts int) error {
+	r.updatePending(id, func(m *domain.ScheduledMessage) {
+		m.Attempts++
+		m.LastError = reason
+		if m.Attempts >= maxAttempts {
+			m.Status = domain.ScheduledMessageFailed
+		}
+	})
+	return nil
+}
+
+// find returns copies of the pending messages matching keep, soonest first.
+func (r *scheduledMessageMemoryRepo) find(keep func(*domain.ScheduledMessage) bool) []*domain.ScheduledMessage {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var messages []*domain.ScheduledMessage
+	for _, message := range r.store.scheduled {
+		if message.Status == domain.ScheduledMessagePending && keep(message) {
+			copied := *message
+			messages = append(messages, &copied)
+		}
+	}
+	slices.SortFunc(messages, func(a, b *domain.ScheduledMessage) int {
+		if c := a.SendAt.Compare(b.SendAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return messages
+}
+
+// updatePending applies change to the message if it is still pending and
+// reports whether it was.
+func (r *scheduledMessageMemoryRepo) updatePending(id uint, change func(*domain.ScheduledMessage)) bool {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	message, ok := r.store.scheduled[id]
+	if !ok || message.Status != domain.ScheduledMessagePending {
+		return false
+	}
+	change(message)
+	message.UpdatedAt = time.Now()
+	return true
+}
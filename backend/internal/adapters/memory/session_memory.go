@@ -0,0 +1,107 @@
+package memory_adapters
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type sessionMemoryRepo struct {
+	store *Store
+}
+
+func NewSessionMemoryRepo(store *Store) repository.SessionRepository {
+	return &sessionMemoryRepo{store: store}
+}
+
+func (r *sessionMemoryRepo) CreateSession(ctx context.Context, session *domain.Session) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, ok := r.store.sessions[session.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = time.Now()
+	}
+
+	stored := *session
+	r.store.sessions[session.ID] = &stored
+	return nil
+}
+
+func (r *sessionMemoryRepo) GetSessionByID(ctx context.Context, id string) (*domain.Session, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	session, ok := r.store.sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (r *sessionMemoryRepo) GetUserSessions(ctx context.Context, userID uint, includeInactive bool) ([]*domain.Session, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	now := time.Now()
+	sessions := []*domain.Session{}
+	for _, session := range r.store.sessions {
+		if session.UserID != userID || (!includeInactive && !session.IsActive(now)) {
+			continue
+		}
+		copied := *session
+		sessions = append(sessions, &copied)
+	}
+	slices.SortFunc(sessions, func(a, b *domain.Session) int {
+		if c := b.LastSeenAt.Compare(a.LastSeenAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return sessions, nil
+}
+
+func (r *sessionMemoryRepo) TouchSession(ctx context.Context, id string, seenAt, expiresAt time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session, ok := r.store.sessions[id]; ok {
+		session.LastSeenAt = seenAt
+		session.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (r *sessionMemoryRepo) RevokeSession(ctx context.Context, id string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if session, ok := r.store.sessions[id]; ok && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+func (r *sessionMemoryRepo) RevokeUserSessions(ctx context.Context, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for _, session := range r.store.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			revokedAt := now
+			session.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
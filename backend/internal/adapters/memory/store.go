@@ -0,0 +1,347 @@
+// Package memory_adapters implements the repository ports on top of plain
+// maps. It mirrors the GORM adapters closely enough for service tests and is
+// checked against them by the repositorytest conformance suite.
+package memory_adapters
+
+import (
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"go-chat/internal/domain"
+)
+
+// Store holds the rows shared by the in-memory repositories. Repositories
+// built on the same store see each other's data, the way the GORM ones share
+// a database.
+type Store struct {
+	mu sync.RWMutex
+	// txMu serialises units of work; see UnitOfWork.
+	txMu sync.Mutex
+
+	users         map[uint]*domain.User
+	friendships   map[uint]*friendshipRow
+	messages      map[uint]*domain.Message
+	conversations map[uint]*domain.ConversationSummary
+	receipts      map[uint]*domain.MessageReceipt
+	hidden        map[uint]*domain.HiddenMessage
+	scheduled     map[uint]*domain.ScheduledMessage
+	timers        map[uint]*domain.ConversationTimer
+	pins          map[uint]*domain.PinnedMessage
+	stars         map[uint]*domain.StarredMessage
+	identities    map[uint]*domain.UserIdentity
+	oauthStates   map[string]*domain.OAuthState
+	tokens        map[uint]*domain.PersonalAccessToken
+	sessions      map[string]*domain.Session
+	audit         map[uint]*domain.AuditEntry
+
+	nextUserID         uint
+	nextFriendshipID   uint
+	nextMessageID      uint
+	nextConversationID uint
+	nextReceiptID      uint
+	nextHiddenID       uint
+	nextScheduledID    uint
+	nextTimerID        uint
+	nextPinID          uint
+	nextStarID         uint
+	nextIdentityID     uint
+	nextTokenID        uint
+	nextAuditID        uint
+}
+
+// friendshipRow adds the soft-delete column the domain type does not carry.
+type friendshipRow struct {
+	domain.Friendship
+	DeletedAt *time.Time
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:         make(map[uint]*domain.User),
+		friendships:   make(map[uint]*friendshipRow),
+		messages:      make(map[uint]*domain.Message),
+		conversations: make(map[uint]*domain.ConversationSummary),
+		receipts:      make(map[uint]*domain.MessageReceipt),
+		hidden:        make(map[uint]*domain.HiddenMessage),
+		scheduled:     make(map[uint]*domain.ScheduledMessage),
+		timers:        make(map[uint]*domain.ConversationTimer),
+		pins:          make(map[uint]*domain.PinnedMessage),
+		stars:         make(map[uint]*domain.StarredMessage),
+		identities:    make(map[uint]*domain.UserIdentity),
+		oauthStates:   make(map[string]*domain.OAuthState),
+		tokens:        make(map[uint]*domain.PersonalAccessToken),
+		sessions:      make(map[string]*domain.Session),
+		audit:         make(map[uint]*domain.AuditEntry),
+	}
+}
+
+type snapshot struct {
+	users         map[uint]*domain.User
+	friendships   map[uint]*friendshipRow
+	messages      map[uint]*domain.Message
+	conversations map[uint]*domain.ConversationSummary
+	receipts      map[uint]*domain.MessageReceipt
+	hidden        map[uint]*domain.HiddenMessage
+	scheduled     map[uint]*domain.ScheduledMessage
+	timers        map[uint]*domain.ConversationTimer
+	pins          map[uint]*domain.PinnedMessage
+	stars         map[uint]*domain.StarredMessage
+	identities    map[uint]*domain.UserIdentity
+	oauthStates   map[string]*domain.OAuthState
+	tokens        map[uint]*domain.PersonalAccessToken
+	sessions      map[string]*domain.Session
+	audit         map[uint]*domain.AuditEntry
+
+	nextUserID         uint
+	nextFriendshipID   uint
+	nextMessageID      uint
+	nextConversationID uint
+	nextReceiptID      uint
+	nextHiddenID       uint
+	nextScheduledID    uint
+	nextTimerID        uint
+	nextPinID          uint
+	nextStarID         uint
+	nextIdentityID     uint
+	nextTokenID        uint
+	nextAuditID        uint
+}
+
+// snapshot copies every row so a failed unit of work can be rolled back.
+func (s *Store) snapshot() snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snap := snapshot{
+		users:              make(map[uint]*domain.User, len(s.users)),
+		friendships:        make(map[uint]*friendshipRow, len(s.friendships)),
+		messages:           make(map[uint]*domain.Message, len(s.messages)),
+		conversations:      make(map[uint]*domain.ConversationSummary, len(s.conversations)),
+		receipts:           make(map[uint]*domain.MessageReceipt, len(s.receipts)),
+		hidden:             make(map[uint]*domain.HiddenMessage, len(s.hidden)),
+		scheduled:          make(map[uint]*domain.ScheduledMessage, len(s.scheduled)),
+		timers:             make(map[uint]*domain.ConversationTimer, len(s.timers)),
+		pins:               make(map[uint]*domain.PinnedMessage, len(s.pins)),
+		stars:              make(map[uint]*domain.StarredMessage, len(s.stars)),
+		identities:         make(map[uint]*domain.UserIdentity, len(s.identities)),
+		oauthStates:        make(map[string]*domain.OAuthState, len(s.oauthStates)),
+		tokens:             make(map[uint]*domain.PersonalAccessToken, len(s.tokens)),
+		sessions:           make(map[string]*domain.Session, len(s.sessions)),
+		audit:              make(map[uint]*domain.AuditEntry, len(s.audit)),
+		nextUserID:         s.nextUserID,
+		nextFriendshipID:   s.nextFriendshipID,
+		nextMessageID:      s.nextMessageID,
+		nextConversationID: s.nextConversationID,
+		nextReceiptID:      s.nextReceiptID,
+		nextHiddenID:       s.nextHiddenID,
+		nextScheduledID:    s.nextScheduledID,
+		nextTimerID:        s.nextTimerID,
+		nextPinID:          s.nextPinID,
+		nextStarID:         s.nextStarID,
+		nextIdentityID:     s.nextIdentityID,
+		nextTokenID:        s.nextTokenID,
+		nextAuditID:        s.nextAuditID,
+	}
+	for id, user := range s.users {
+		copied := *user
+		snap.users[id] = &copied
+	}
+	for id, row := range s.friendships {
+		copied := *row
+		snap.friendships[id] = &copied
+	}
+	for id, message := range s.messages {
+		copied := *message
+		snap.messages[id] = &copied
+	}
+	for id, summary := range s.conversations {
+		copied := *summary
+		snap.conversations[id] = &copied
+	}
+	for id, receipt := range s.receipts {
+		copied := *receipt
+		snap.receipts[id] = &copied
+	}
+	for id, hidden := range s.hidden {
+		copied := *hidden
+		snap.hidden[id] = &copied
+	}
+	for id, message := range s.scheduled {
+		copied := *message
+		snap.scheduled[id] = &copied
+	}
+	for id, timer := range s.timers {
+		copied := *timer
+		snap.timers[id] = &copied
+	}
+	for id, pin := range s.pins {
+		copied := *pin
+		snap.pins[id] = &copied
+	}
+	for id, star := range s.stars {
+		copied := *star
+		snap.stars[id] = &copied
+	}
+	for id, identity := range s.identities {
+		copied := *identity
+		snap.identities[id] = &copied
+	}
+	for key, state := range s.oauthStates {
+		copied := *state
+		snap.oauthStates[key] = &copied
+	}
+	for id, token := range s.tokens {
+		copied := *token
+		snap.tokens[id] = &copied
+	}
+	for id, session := range s.sessions {
+		copied := *session
+		snap.sessions[id] = &copied
+	}
+	for id, entry := range s.audit {
+		copied := *entry
+		snap.audit[id] = &copied
+	}
+	return snap
+}
+
+func (s *Store) restore(snap snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = maps.Clone(snap.users)
+	s.friendships = maps.Clone(snap.friendships)
+	s.messages = maps.Clone(snap.messages)
+	s.conversations = maps.Clone(snap.conversations)
+	s.receipts = maps.Clone(snap.receipts)
+	s.hidden = maps.Clone(snap.hidden)
+	s.scheduled = maps.Clone(snap.scheduled)
+	s.timers = maps.Clone(snap.timers)
+	s.pins = maps.Clone(snap.pins)
+	s.stars = maps.Clone(snap.stars)
+	s.identities = maps.Clone(snap.identities)
+	s.oauthStates = maps.Clone(snap.oauthStates)
+	s.tokens = maps.Clone(snap.tokens)
+	s.sessions = maps.Clone(snap.sessions)
+	s.audit = maps.Clone(snap.audit)
+	s.nextUserID = snap.nextUserID
+	s.nextFriendshipID = snap.nextFriendshipID
+	s.nextMessageID = snap.nextMessageID
+	s.nextConversationID = snap.nextConversationID
+	s.nextReceiptID = snap.nextReceiptID
+	s.nextHiddenID = snap.nextHiddenID
+	s.nextScheduledID = snap.nextScheduledID
+	s.nextTimerID = snap.nextTimerID
+	s.nextPinID = snap.nextPinID
+	s.nextStarID = snap.nextStarID
+	s.nextIdentityID = snap.nextIdentityID
+	s.nextTokenID = snap.nextTokenID
+	s.nextAuditID = snap.nextAuditID
+}
+
+// liveUser returns the user with id unless it is missing or soft-deleted.
+// Callers must hold s.mu.
+func (s *Store) liveUser(id uint) (*domain.User, bool) {
+	user, ok := s.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return nil, false
+	}
+	return user, true
+}
+
+// conversation returns the user's summary of the conversation with peer, or
+// nil. Callers must hold s.mu.
+func (s *Store) conversation(userID, peerID uint) *domain.ConversationSummary {
+	for _, summary := range s.conversations {
+		if summary.UserID == userID && summary.PeerID == peerID {
+			return summary
+		}
+	}
+	return nil
+}
+
+// timer returns the disappearing-message timer shared by the two users, or
+// nil. Callers must hold s.mu.
+func (s *Store) timer(userID1, userID2 uint) *domain.ConversationTimer {
+	low, high := min(userID1, userID2), max(userID1, userID2)
+	for _, timer := range s.timers {
+		if timer.UserLowID == low && timer.UserHighID == high {
+			return timer
+		}
+	}
+	return nil
+}
+
+// pin returns the pin on the message, or nil. Callers must hold s.mu.
+func (s *Store) pin(messageID uint) *domain.PinnedMessage {
+	for _, pin := range s.pins {
+		if pin.MessageID == messageID {
+			return pin
+		}
+	}
+	return nil
+}
+
+// star returns the user's star on the message, or nil. Callers must hold
+// s.mu.
+func (s *Store) star(messageID, userID uint) *domain.StarredMessage {
+	for _, star := range s.stars {
+		if star.MessageID == messageID && star.UserID == userID {
+			return star
+		}
+	}
+	return nil
+}
+
+// readCursor returns the ID up to which the user has read messages from
+// peer, zero when none. Callers must hold s.mu.
+func (s *Store) readCursor(userID, peerID uint) uint {
+	if summary := s.conversation(userID, peerID); summary != nil && summary.LastReadMessageID != nil {
+		return *summary.LastReadMessageID
+	}
+	return 0
+}
+
+// receipt returns the user's receipt for the message, or nil. Callers must
+// hold s.mu.
+func (s *Store) receipt(messageID, userID uint) *domain.MessageReceipt {
+	for _, receipt := range s.receipts {
+		if receipt.MessageID == messageID && receipt.UserID == userID {
+			return receipt
+		}
+	}
+	return nil
+}
+
+// visibleTo reports whether the user has not deleted the message for
+// themselves. Callers must hold s.mu.
+func (s *Store) visibleTo(message *domain.Message, userID uint) bool {
+	for _, hidden := range s.hidden {
+		if hidden.MessageID == message.ID && hidden.UserID == userID {
+			return false
+		}
+	}
+	return true
+}
+
+// containsFold is the in-memory equivalent of ILIKE '%substr%'.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// paginate applies LIMIT/OFFSET the way GORM renders them: a negative limit
+// means no limit, while zero returns nothing.
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset > 0 {
+		if offset >= len(items) {
+			return items[:0]
+		}
+		items = items[offset:]
+	}
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
@@ -0,0 +1,113 @@
+package memory_adapters
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type tokenMemoryRepo struct {
+	store *Store
+}
+
+func NewTokenMemoryRepo(store *Store) repository.TokenRepository {
+	return &tokenMemoryRepo{store: store}
+}
+
+func (r *tokenMemoryRepo) CreateToken(ctx context.Context, token *domain.PersonalAccessToken) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	r.store.nextTokenID++
+	token.ID = r.store.nextTokenID
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	stored := *token
+	r.store.tokens[token.ID] = &stored
+	return nil
+}
+
+func (r *tokenMemoryRepo) FindTokenByHash(ctx context.Context, hash string) (*domain.PersonalAccessToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, token := range r.store.tokens {
+		if token.TokenHash == hash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *tokenMemoryRepo) GetUserTokens(ctx context.Context, userID uint) ([]*domain.PersonalAccessToken, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	tokens := []*domain.PersonalAccessToken{}
+	for _, token := range r.store.tokens {
+		if token.UserID == userID {
+			copied := *token
+			tokens = append(tokens, &copied)
+		}
+	}
+	slices.SortFunc(tokens, func(a, b *domain.PersonalAccessToken) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	return tokens, nil
+}
+
+func (r *tokenMemoryRepo) RevokeToken(ctx context.Context, userID, tokenID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	token, ok := r.store.tokens[tokenID]
+	if !ok || token.UserID != userID || token.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	token.RevokedAt = &now
+	return nil
+}
+
+func (r *tokenMemoryRepo) RevokeUserTokens(ctx context.Context, userID uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range r.store.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			revokedAt := now
+			token.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+func (r *tokenMemoryRepo) TouchToken(ctx context.Context, tokenID uint, usedAt time.Time, ip string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if token, ok := r.store.tokens[tokenID]; ok {
+		token.LastUsedAt = &usedAt
+		token.LastUsedIP = ip
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package memory_adapters
+
+import (
+	"context"
+
+	"go-chat/internal/ports/repository"
+)
+
+type memoryUnitOfWork struct {
+	store *Store
+}
+
+// NewMemoryUnitOfWork returns a unit of work over store. Units of work run
+// one at a time, which trivially gives serializable isolation, and a failed
+// one restores the store to how it was before fn ran.
+func NewMemoryUnitOfWork(store *Store) repository.UnitOfWork {
+	return &memoryUnitOfWork{store: store}
+}
+
+func (u *memoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	u.store.txMu.Lock()
+	defer u.store.txMu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	before := u.store.snapshot()
+	if err := fn(ctx, NewRepositories(u.store)); err != nil {
+		u.store.restore(before)
+		return err
+	}
+	return nil
+}
+
+// NewRepositories returns every memory repository over store, as a unit of
+// work hands them to fn.
+func NewRepositories(store *Store) repository.Repositories {
+	return repository.Repositories{
+		Users:             NewUserMemoryRepo(store),
+		Friends:           NewFriendsMemoryRepo(store),
+		Messages:          NewMessageMemoryRepo(store),
+		Conversations:     NewConversationMemoryRepo(store),
+		Identities:        NewIdentityMemoryRepo(store),
+		Tokens:            NewTokenMemoryRepo(store),
+		Sessions:          NewSessionMemoryRepo(store),
+		Audit:             NewAuditMemoryRepo(store),
+		ScheduledMessages: NewScheduledMessageMemoryRepo(store),
+	}
+}
@@ -0,0 +1,279 @@
+package memory_adapters
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type userMemoryRepo struct {
+	store *Store
+}
+
+func NewUserMemoryRepo(store *Store) repository.UserRepository {
+	return &userMemoryRepo{store: store}
+}
+
+func (r *userMemoryRepo) Create(ctx context.Context, user *domain.User) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// The email constraint covers soft-deleted rows too.
+	for _, existing := range r.store.users {
+		if existing.Email == user.Email {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	now := time.Now()
+	r.store.nextUserID++
+	user.ID = r.store.nextUserID
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+
+	stored := *user
+	r.store.users[user.ID] = &stored
+	return nil
+}
+
+func (r *userMemoryRepo) GetUserByID(ctx context.Context, id uint) (*domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, ok := r.store.liveUser(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (r *userMemoryRepo) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.liveUsers() {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *userMemoryRepo) UpdatePassword(ctx context.Context, id uint, password string) (*domain.User, error) {
+	return r.update(id, func(user *domain.User) {
+		user.Password = password
+		user.PasswordResetRequired = false
+	})
+}
+
+func (r *userMemoryRepo) UpdateUserProfile(ctx context.Context, id uint, name, email string) (*domain.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.liveUser(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	for _, existing := range r.store.users {
+		if existing.ID != id && existing.Email == email {
+			return nil, gorm.ErrDuplicatedKey
+		}
+	}
+
+	user.Name = name
+	user.Email = email
+	user.UpdatedAt = time.Now()
+
+	copied := *user
+	return &copied, nil
+}
+
+func (r *userMemoryRepo) SearchUsers(ctx context.Context, query string, id uint) ([]*domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := []*domain.User{}
+	for _, user := range r.liveUsers() {
+		if user.ID != id && user.AnonymizedAt == nil && containsFold(user.Name, query) {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *userMemoryRepo) GetBotsByOwner(ctx context.Context, ownerID uint) ([]*domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	bots := []*domain.User{}
+	for _, user := range r.liveUsers() {
+		if user.IsBot && user.OwnerID != nil && *user.OwnerID == ownerID {
+			bots = append(bots, user)
+		}
+	}
+	slices.SortStableFunc(bots, func(a, b *domain.User) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return bots, nil
+}
+
+func (r *userMemoryRepo) DeleteUser(ctx context.Context, id uint) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if user, ok := r.store.liveUser(id); ok {
+		user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	}
+	return nil
+}
+
+func (r *userMemoryRepo) ListUsers(ctx context.Context, query string, limit, offset int) ([]*domain.User, int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := []*domain.User{}
+	for _, user := range r.liveUsers() {
+		if query == "" || containsFold(user.Name, query) || containsFold(user.Email, query) {
+			users = append(users, user)
+		}
+	}
+	return paginate(users, limit, offset), int64(len(users)), nil
+}
+
+func (r *userMemoryRepo) UpdateUserRole(ctx context.Context, id uint, role domain.Role) error {
+	_, err := r.update(id, func(user *domain.User) {
+		user.Role = role
+	})
+	return ignoreNotFound(err)
+}
+
+func (r *userMemoryRepo) CountUsersByRole(ctx context.Context, role domain.Role) (int64, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	var count int64
+	for _, user := range r.liveUsers() {
+		if user.EffectiveRole() == role {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *userMemoryRepo) SetUserSuspension(ctx context.Context, id uint, suspendedAt *time.Time, reason string) error {
+	_, err := r.update(id, func(user *domain.User) {
+		user.SuspendedAt = suspendedAt
+		user.SuspensionReason = reason
+	})
+	return ignoreNotFound(err)
+}
+
+func (r *userMemoryRepo) SetPasswordResetRequired(ctx context.Context, id uint, required bool) error {
+	_, err := r.update(id, func(user *domain.User) {
+		user.PasswordResetRequired = required
+	})
+	return ignoreNotFound(err)
+}
+
+func (r *userMemoryRepo) SetReadReceiptsDisabled(ctx context.Context, id uint, disabled bool) error {
+	_, err := r.update(id, func(user *domain.User) {
+		user.ReadReceiptsDisabled = disabled
+	})
+	return ignoreNotFound(err)
+}
+
+func (r *userMemoryRepo) SetNotificationPreferences(ctx context.Context, id uint, preferences domain.NotificationPreferences) error {
+	_, err := r.update(id, func(user *domain.User) {
+		user.Notifications = preferences
+	})
+	return ignoreNotFound(err)
+}
+
+func (r *userMemoryRepo) SetDeletionRequested(ctx context.Context, id uint, requestedAt *time.Time) error {
+	_, err := r.update(id, func(user *domain.User) {
+		user.DeletionRequestedAt = requestedAt
+	})
+	return ignoreNotFound(err)
+}
+
+func (r *userMemoryRepo) GetUsersPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]*domain.User, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	users := []*domain.User{}
+	for _, user := range r.liveUsers() {
+		if user.DeletionRequestedAt != nil && user.DeletionRequestedAt.Before(requestedBefore) && user.AnonymizedAt == nil {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (r *userMemoryRepo) AnonymizeUser(ctx context.Context, id uint, anonymizedAt time.Time) error {
+	_, err := r.update(id, func(user *domain.User) {
+		user.Name = "Deleted User"
+		user.Email = fmt.Sprintf("deleted-%d@deleted.invalid", id)
+		user.Password = ""
+		user.SuspensionReason = ""
+		user.DeletionRequestedAt = nil
+		user.AnonymizedAt = &anonymizedAt
+	})
+	return ignoreNotFound(err)
+}
+
+// liveUsers returns copies of every user that is not soft-deleted, in ID
+// order. Callers must hold the store lock.
+func (r *userMemoryRepo) liveUsers() []*domain.User {
+	users := make([]*domain.User, 0, len(r.store.users))
+	for _, user := range r.store.users {
+		if !user.DeletedAt.Valid {
+			copied := *user
+			users = append(users, &copied)
+		}
+	}
+	slices.SortFunc(users, func(a, b *domain.User) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return users
+}
+
+// update applies change to a live user and bumps UpdatedAt, returning a copy
+// of the result.
+func (r *userMemoryRepo) update(id uint, change func(*domain.User)) (*domain.User, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	user, ok := r.store.liveUser(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	change(user)
+	user.UpdatedAt = time.Now()
+
+	copied := *user
+	return &copied, nil
+}
+
+// ignoreNotFound matches GORM's Model().Where().Update(), which succeeds
+// without touching anything when no row matches.
+func ignoreNotFound(err error) error {
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	return err
+}
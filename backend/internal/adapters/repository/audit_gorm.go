@@ -0,0 +1,99 @@
+package repository_adapters
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+// auditChainLockKey is the Postgres advisory lock taken while appending so
+// two writers never link to the same chain head.
+const auditChainLockKey = 7_426_001
+
+type auditGormRepo struct {
+	db *gorm.DB
+}
+
+func NewAuditGormRepo(db *gorm.DB) repository.AuditRepository {
+	return &auditGormRepo{db: db}
+}
+
+func (r *auditGormRepo) AppendEntry(ctx context.Context, entry *domain.AuditEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockKey).Error; err != nil {
+				return err
+			}
+		}
+
+		var head domain.AuditEntry
+		err := tx.Order("id DESC").Limit(1).Find(&head).Error
+		if err != nil {
+			return err
+		}
+
+		entry.PrevHash = head.Hash
+		if entry.CreatedAt.IsZero() {
+			entry.CreatedAt = time.Now()
+		}
+		// Postgres keeps microseconds; hash what will be read back.
+		entry.CreatedAt = entry.CreatedAt.UTC().Truncate(time.Microsecond)
+		entry.Hash = entry.ComputeHash()
+
+		return tx.Create(entry).Error
+	})
+}
+
+func (r *auditGormRepo) ListEntries(ctx context.Context, filter domain.AuditFilter, limit, offset int) ([]*domain.AuditEntry, int64, error) {
+	var entries []*domain.AuditEntry
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.AuditEntry{})
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetUserID != 0 {
+		query = query.Where("target_user_id = ?", filter.TargetUserID)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *auditGormRepo) ListUserEntries(ctx context.Context, userID uint, limit, offset int) ([]*domain.AuditEntry, int64, error) {
+	var entries []*domain.AuditEntry
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&domain.AuditEntry{}).
+		Where("target_user_id = ? OR (actor_id = ? AND target_user_id IS NULL)", userID, userID)
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&entries).Error
+	return entries, total, err
+}
+
+func (r *auditGormRepo) GetEntriesAfter(ctx context.Context, afterID uint, limit int) ([]*domain.AuditEntry, error) {
+	var entries []*domain.AuditEntry
+	err := r.db.WithContext(ctx).Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&entries).Error
+	return entries, err
+}
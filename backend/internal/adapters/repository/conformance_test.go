@@ -0,0 +1,77 @@
+package repository_adapters
+
+import (
+	"os"
+	"testing"
+
+	"go-chat/config"
+	"go-chat/internal/ports/repository"
+	"go-chat/internal/ports/repository/repositorytest"
+	"go-chat/migrations"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestConformanceSQLite runs the repository conformance suite against a
+// fresh in-memory SQLite database per case.
+func TestConformanceSQLite(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.Repositories {
+		db := openTestDB(t, "sqlite", ":memory:")
+		migrate(t, db)
+		return repositoriesFor(db)
+	})
+}
+
+// TestConformancePostgres runs the suite against Postgres. It needs a
+// disposable database in TEST_DATABASE_URL, which it migrates and truncates
+// between cases.
+func TestConformancePostgres(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	db := openTestDB(t, "postgres", url)
+	migrate(t, db)
+
+	repositorytest.Run(t, func(t *testing.T) repository.Repositories {
+		if err := db.Exec("TRUNCATE users, friendships, messages, o_auth_states, audit_logs RESTART IDENTITY CASCADE").Error; err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return repositoriesFor(db)
+	})
+}
+
+func openTestDB(t *testing.T, driver, url string) *gorm.DB {
+	t.Helper()
+	cfg := &config.Config{Database: config.DatabaseConfig{
+		Driver:       driver,
+		URL:          url,
+		MaxOpenConns: 4,
+		MaxIdleConns: 4,
+	}}
+	db, err := config.ConnectToDB(cfg)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	db.Logger = logger.Discard
+
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func migrate(t *testing.T, db *gorm.DB) {
+	t.Helper()
+	runner, err := migrations.NewRunner(db)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := runner.Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+}
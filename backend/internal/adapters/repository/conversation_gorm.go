@@ -0,0 +1,343 @@
+package repository_adapters
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type conversationGormRepo struct {
+	db *gorm.DB
+}
+
+func NewConversationGormRepo(db *gorm.DB) repository.ConversationRepository {
+	return &conversationGormRepo{db: db}
+}
+
+// summaryKey is the unique (user_id, peer_id) index upserts resolve against.
+var summaryKey = []clause.Column{{Name: "user_id"}, {Name: "peer_id"}}
+
+func (r *conversationGormRepo) RecordMessage(ctx context.Context, message *domain.Message) error {
+	db := r.db.WithContext(ctx)
+
+	if err := r.upsert(db, message.SenderID, message.ReceiverID, message, 0, nil); err != nil {
+		return err
+	}
+	if err := r.upsert(db, message.ReceiverID, message.SenderID, message, 1,
+		gorm.Expr("conversation_summaries.unread_count + 1")); err != nil {
+		return err
+	}
+
+	return db.Model(&domain.ConversationSummary{}).
+		Where("((user_id = ? AND peer_id = ?) OR (user_id = ? AND peer_id = ?)) AND archived_at IS NOT NULL",
+			message.SenderID, message.ReceiverID, message.ReceiverID, message.SenderID).
+		Update("archived_at", nil).Error
+}
+
+func (r *conversationGormRepo) GetConversation(ctx context.Context, userID, peerID uint) (*domain.ConversationSummary, error) {
+	var summary domain.ConversationSummary
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND peer_id = ?", userID, peerID).
+		Take(&summary).Error
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+func (r *conversationGormRepo) MarkConversationRead(ctx context.Context, userID, peerID uint, readAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.ConversationSummary{}).
+		Where("user_id = ? AND peer_id = ?", userID, peerID).
+		Updates(map[string]interface{}{
+			"last_read_message_id": gorm.Expr("last_message_id"),
+			"last_read_at":         readAt,
+			"unread_count":         0,
+			"marked_unread":        false,
+		}).Error
+}
+
+func (r *conversationGormRepo) SetReadCursor(ctx context.Context, userID, peerID uint, messageID *uint, readAt time.Time) error {
+	var cursor uint
+	if messageID != nil {
+		cursor = *messageID
+	}
+
+	return r.db.WithContext(ctx).Model(&domain.ConversationSummary{}).
+		Where("user_id = ? AND peer_id = ?", userID, peerID).
+		Updates(map[string]interface{}{
+			"last_read_message_id": messageID,
+			"last_read_at":         readAt,
+			"unread_count": gorm.Expr(`(SELECT COUNT(*) FROM messages
+				WHERE sender_id = ? AND receiver_id = ? AND id > ? AND deleted_at IS NULL
+					AND NOT EXISTS (SELECT 1 FROM hidden_messages hidden
+						WHERE hidden.message_id = messages.id AND hidden.user_id = ?))`,
+				peerID, userID, cursor, userID),
+			"marked_unread": false,
+		}).Error
+}
+
+func (r *conversationGormRepo) SetArchived(ctx context.Context, userID, peerID uint, archivedAt *time.Time) error {
+	return r.set(ctx, userID, peerID, "archived_at", archivedAt)
+}
+
+func (r *conversationGormRepo) SetPinned(ctx context.Context, userID, peerID uint, pinnedAt *time.Time) error {
+	return r.set(ctx, userID, peerID, "pinned_at", pinnedAt)
+}
+
+func (r *conversationGormRepo) SetMuted(ctx context.Context, userID, peerID uint, mutedAt, mutedUntil *time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.ConversationSummary{}).
+		Where("user_id = ? AND peer_id = ?", userID, peerID).
+		Updates(map[string]interface{}{
+			"muted_at":    mutedAt,
+			"muted_until": mutedUntil,
+		}).Error
+}
+
+func (r *conversationGormRepo) SetMarkedUnread(ctx context.Context, userID, peerID uint, markedUnread bool) error {
+	return r.set(ctx, userID, peerID, "marked_unread", markedUnread)
+}
+
+// set updates one column of the user's summary of the conversation with peer.
+func (r *conversationGormRepo) set(ctx context.Context, userID, peerID uint, column string, value interface{}) error {
+	return r.db.WithContext(ctx).Model(&domain.ConversationSummary{}).
+		Where("user_id = ? AND peer_id = ?", userID, peerID).
+		Update(column, value).Error
+}
+
+func (r *conversationGormRepo) GetDisappearingTimer(ctx context.Context, userID1, userID2 uint) (time.Duration, error) {
+	low, high := pair(userID1, userID2)
+
+	var timer domain.ConversationTimer
+	err := r.db.WithContext(ctx).
+		Where("user_low_id = ? AND user_high_id = ?", low, high).
+		Take(&timer).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(timer.DisappearAfterSeconds) * time.Second, nil
+}
+
+func (r *conversationGormRepo) SetDisappearingTimer(ctx context.Context, userID1, userID2 uint, after time.Duration, setByID uint) error {
+	low, high := pair(userID1, userID2)
+	timer := &domain.ConversationTimer{
+		UserLowID:             low,
+		UserHighID:            high,
+		DisappearAfterSeconds: int(after / time.Second),
+		SetByID:               setByID,
+	}
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_low_id"}, {Name: "user_high_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"disappear_after_seconds": timer.DisappearAfterSeconds,
+			"set_by_id":               setByID,
+			"updated_at":              time.Now(),
+		}),
+	}).Create(timer).Error
+}
+
+// pair orders two user IDs the way conversation timers are keyed.
+func pair(userID1, userID2 uint) (uint, uint) {
+	if userID1 > userID2 {
+		return userID2, userID1
+	}
+	return userID1, userID2
+}
+
+func (r *conversationGormRepo) RefreshConversation(ctx context.Context, userID1, userID2 uint) error {
+	if err := r.refresh(ctx, userID1, userID2); err != nil {
+		return err
+	}
+	return r.refresh(ctx, userID2, userID1)
+}
+
+// refresh recomputes the user's summary of the conversation with peer from
+// the messages they can still see, keeping the read cursor.
+func (r *conversationGormRepo) refresh(ctx context.Context, userID, peerID uint) error {
+	db := r.db.WithContext(ctx)
+
+	var latest domain.Message
+	err := visibleTo(db, userID).
+		Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
+			userID, peerID, peerID, userID).
+		Order("created_at DESC, id DESC").
+		Take(&latest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return db.
+			Where("user_id = ? AND peer_id = ?", userID, peerID).
+			Delete(&domain.ConversationSummary{}).Error
+	}
+	if err != nil {
+		return err
+	}
+
+	var cursor uint
+	if existing, err := r.GetConversation(ctx, userID, peerID); err == nil && existing.LastReadMessageID != nil {
+		cursor = *existing.LastReadMessageID
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	var unread int64
+	err = visibleTo(db.Model(&domain.Message{}), userID).
+		Where("sender_id = ? AND receiver_id = ? AND id > ?", peerID, userID, cursor).
+		Count(&unread).Error
+	if err != nil {
+		return err
+	}
+
+	return r.upsert(db, userID, peerID, &latest, int(unread), unread)
+}
+
+func (r *conversationGormRepo) ListConversations(ctx context.Context, userID uint, query string, archived bool, limit, offset int) ([]*domain.ConversationSummary, error) {
+	var summaries []*domain.ConversationSummary
+
+	db := r.db.WithContext(ctx).
+		Select("conversation_summaries.*, peer_summary.last_read_message_id AS peer_last_read_message_id, COALESCE(timer.disappear_after_seconds, 0) AS disappear_after_seconds").
+		InnerJoins("Peer").
+		// The read, delivery, pin and star state comes from joins of its
+		// own; ToResponse derives is_read from the cursors loaded here
+		// instead. An expired last message is not shown until the purge
+		// refreshes the summary.
+		Joins("LastMessage", r.db.Omit("is_read", "is_delivered", "delivered_at", "read_at", "pinned_at", "pinned_by_id", "starred_at").
+			Where(clause.Or(
+				clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "expires_at"}, Value: nil},
+				clause.Gt{Column: clause.Column{Table: clause.CurrentTable, Name: "expires_at"}, Value: time.Now()},
+			))).
+		Joins("LastMessage.Sender").
+		Joins("LEFT JOIN conversation_summaries peer_summary ON peer_summary.user_id = conversation_summaries.peer_id AND peer_summary.peer_id = conversation_summaries.user_id").
+		Joins("LEFT JOIN conversation_timers timer ON (timer.user_low_id = conversation_summaries.user_id AND timer.user_high_id = conversation_summaries.peer_id) OR (timer.user_low_id = conversation_summaries.peer_id AND timer.user_high_id = conversation_summaries.user_id)").
+		Where("conversation_summaries.user_id = ?", userID)
+
+	if archived {
+		db = db.Where("conversation_summaries.archived_at IS NOT NULL")
+	} else {
+		db = db.Where("conversation_summaries.archived_at IS NULL")
+	}
+
+	if query != "" {
+		searchQuery := fmt.Sprintf("%%%s%%", query)
+		db = db.Where(fmt.Sprintf("conversation_summaries.peer_id IN (SELECT id FROM users WHERE name %[1]s ? OR email %[1]s ?)", ilike(r.db)),
+			searchQuery, searchQuery)
+	}
+
+	err := db.
+		Order("CASE WHEN conversation_summaries.pinned_at IS NULL THEN 1 ELSE 0 END, conversation_summaries.pinned_at DESC").
+		Order("conversation_summaries.last_activity_at DESC, conversation_summaries.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&summaries).Error
+
+	return summaries, err
+}
+
+func (r *conversationGormRepo) DeleteUserConversations(ctx context.Context, userID uint) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Where("user_id = ?", userID).Delete(&domain.ConversationSummary{}).Error; err != nil {
+		return err
+	}
+	return db.Where("user_low_id = ? OR user_high_id = ?", userID, userID).Delete(&domain.ConversationTimer{}).Error
+}
+
+// RebuildConversations keeps existing read cursors, so unread counts are
+// recomputed relative to them, and leaves out messages each user deleted for
+// themselves.
+func (r *conversationGormRepo) RebuildConversations(ctx context.Context) (int64, error) {
+	db := r.db.WithContext(ctx)
+
+	err := db.Exec(`
+		DELETE FROM conversation_summaries
+		WHERE NOT EXISTS (SELECT 1 FROM messages m
+			WHERE m.deleted_at IS NULL
+				AND ((m.sender_id = conversation_summaries.user_id AND m.receiver_id = conversation_summaries.peer_id)
+					OR (m.sender_id = conversation_summaries.peer_id AND m.receiver_id = conversation_summaries.user_id))
+				AND NOT EXISTS (SELECT 1 FROM hidden_messages h
+					WHERE h.message_id = m.id AND h.user_id = conversation_summaries.user_id))
+	`).Error
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	result := db.Exec(`
+		INSERT INTO conversation_summaries
+			(user_id, peer_id, last_message_id, last_activity_at, unread_count, created_at, updated_at)
+		SELECT
+			pairs.user_id,
+			pairs.peer_id,
+			(SELECT m.id FROM messages m
+				WHERE m.deleted_at IS NULL
+					AND ((m.sender_id = pairs.user_id AND m.receiver_id = pairs.peer_id)
+						OR (m.sender_id = pairs.peer_id AND m.receiver_id = pairs.user_id))
+					AND NOT EXISTS (SELECT 1 FROM hidden_messages h
+						WHERE h.message_id = m.id AND h.user_id = pairs.user_id)
+				ORDER BY m.created_at DESC, m.id DESC
+				LIMIT 1),
+			MAX(pairs.created_at),
+			(SELECT COUNT(*) FROM messages m
+				WHERE m.deleted_at IS NULL
+					AND m.sender_id = pairs.peer_id
+					AND m.receiver_id = pairs.user_id
+					AND m.id > COALESCE((SELECT cs.last_read_message_id FROM conversation_summaries cs
+						WHERE cs.user_id = pairs.user_id AND cs.peer_id = pairs.peer_id), 0)
+					AND NOT EXISTS (SELECT 1 FROM hidden_messages h
+						WHERE h.message_id = m.id AND h.user_id = pairs.user_id)),
+			?,
+			?
+		FROM (
+			SELECT m.sender_id AS user_id, m.receiver_id AS peer_id, m.created_at
+			FROM messages m WHERE m.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.user_id = m.sender_id)
+			UNION ALL
+			SELECT m.receiver_id AS user_id, m.sender_id AS peer_id, m.created_at
+			FROM messages m WHERE m.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.user_id = m.receiver_id)
+		) pairs
+		GROUP BY pairs.user_id, pairs.peer_id
+		ON CONFLICT (user_id, peer_id) DO UPDATE SET
+			last_message_id = excluded.last_message_id,
+			last_activity_at = excluded.last_activity_at,
+			unread_count = excluded.unread_count,
+			updated_at = excluded.updated_at
+	`, now, now)
+
+	return result.RowsAffected, result.Error
+}
+
+// upsert points the user's summary of the conversation with peer at message.
+// A new summary starts with the given unread count; an existing one has its
+// count set to unreadUpdate, a value or expression, or kept when it is nil.
+func (r *conversationGormRepo) upsert(db *gorm.DB, userID, peerID uint, message *domain.Message, unread int, unreadUpdate interface{}) error {
+	lastMessageID := message.ID
+	summary := &domain.ConversationSummary{
+		UserID:         userID,
+		PeerID:         peerID,
+		LastMessageID:  &lastMessageID,
+		LastActivityAt: message.CreatedAt,
+		UnreadCount:    unread,
+	}
+
+	updates := map[string]interface{}{
+		"last_message_id":  lastMessageID,
+		"last_activity_at": message.CreatedAt,
+		"updated_at":       time.Now(),
+	}
+	if unreadUpdate != nil {
+		updates["unread_count"] = unreadUpdate
+	}
+
+	return db.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns:   summaryKey,
+		DoUpdates: clause.Assignments(updates),
+	}).Create(summary).Error
+}
@@ -0,0 +1,24 @@
+package repository_adapters
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"gorm.io/gorm"
+)
+
+// ilike returns the case-insensitive LIKE operator for db's dialect. SQLite
+// has no ILIKE, but its LIKE already ignores case for ASCII.
+func ilike(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// dbSystem returns the tracing attribute naming db's database system.
+func dbSystem(db *gorm.DB) attribute.KeyValue {
+	if db.Dialector.Name() == "sqlite" {
+		return semconv.DBSystemSqlite
+	}
+	return semconv.DBSystemPostgreSQL
+}
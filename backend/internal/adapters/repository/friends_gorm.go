@@ -1,6 +1,7 @@
 package repository_adapters
 
 import (
+	"context"
 	"go-chat/internal/domain"
 
 	"gorm.io/gorm"
@@ -11,17 +12,23 @@ type FriendshipModel struct {
 	RequesterID uint                      `gorm:"not null;index"`
 	AddresseeID uint                      `gorm:"not null;index"`
 	Status      domain.FriendshipStatus   `gorm:"not null;default:'pending'"`
+	BlockedByID *uint
 	
 	Requester domain.User `gorm:"foreignKey:RequesterID"`
 	Addressee domain.User `gorm:"foreignKey:AddresseeID"`
 }
 
+func (FriendshipModel) TableName() string {
+	return "friendships"
+}
+
 func toFriendshipModel(f *domain.Friendship) *FriendshipModel {
 	return &FriendshipModel{
 		Model:       gorm.Model{ID: f.ID},
 		RequesterID: f.RequesterID,
 		AddresseeID: f.AddresseeID,
 		Status:      f.Status,
+		BlockedByID: f.BlockedByID,
 	}
 }
 
@@ -31,6 +38,7 @@ func toDomainFriendship(f *FriendshipModel) *domain.Friendship {
 		RequesterID: f.RequesterID,
 		AddresseeID: f.AddresseeID,
 		Status:      f.Status,
+		BlockedByID: f.BlockedByID,
 		CreatedAt:   f.CreatedAt,
 		UpdatedAt:   f.UpdatedAt,
 	}
@@ -57,26 +65,26 @@ func NewFriendsGormRepo(db *gorm.DB) *GormFriendsRepository {
 	return &GormFriendsRepository{db: db}
 }
 
-func (r *GormFriendsRepository) CreateFriendship(friendship *domain.Friendship) error {
+func (r *GormFriendsRepository) CreateFriendship(ctx context.Context, friendship *domain.Friendship) error {
 	model := toFriendshipModel(friendship)
-	if err := r.db.Create(model).Error; err != nil {
+	if err := r.db.WithContext(ctx).Create(model).Error; err != nil {
 		return err
 	}
 	friendship.ID = model.ID
 	return nil
 }
 
-func (r *GormFriendsRepository) FindFriendshipByID(id uint) (*domain.Friendship, error) {
+func (r *GormFriendsRepository) FindFriendshipByID(ctx context.Context, id uint) (*domain.Friendship, error) {
 	var model FriendshipModel
-	if err := r.db.Preload("Requester").Preload("Addressee").First(&model, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).Preload("Requester").Preload("Addressee").First(&model, id).Error; err != nil {
 		return nil, err
 	}
 	return toDomainFriendship(&model), nil
 }
 
-func (r *GormFriendsRepository) FindFriendshipBetweenUsers(userID1, userID2 uint) (*domain.Friendship, error) {
+func (r *GormFriendsRepository) FindFriendshipBetweenUsers(ctx context.Context, userID1, userID2 uint) (*domain.Friendship, error) {
 	var model FriendshipModel
-	if err := r.db.Where(
+	if err := r.db.WithContext(ctx).Where(
 		"(requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)",
 		userID1, userID2, userID2, userID1,
 	).Preload("Requester").Preload("Addressee").First(&model).Error; err != nil {
@@ -85,13 +93,20 @@ func (r *GormFriendsRepository) FindFriendshipBetweenUsers(userID1, userID2 uint
 	return toDomainFriendship(&model), nil
 }
 
-func (r *GormFriendsRepository) UpdateFriendshipStatus(id uint, status domain.FriendshipStatus) error {
-	return r.db.Model(&FriendshipModel{}).Where("id = ?", id).Update("status", status).Error
+func (r *GormFriendsRepository) UpdateFriendshipStatus(ctx context.Context, id uint, status domain.FriendshipStatus) error {
+	return r.db.WithContext(ctx).Model(&FriendshipModel{}).Where("id = ?", id).Update("status", status).Error
 }
 
-func (r *GormFriendsRepository) GetUserFriends(userID uint) ([]*domain.Friendship, error) {
+func (r *GormFriendsRepository) BlockFriendship(ctx context.Context, id, blockerID uint) error {
+	return r.db.WithContext(ctx).Model(&FriendshipModel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":        domain.FriendshipBlocked,
+		"blocked_by_id": blockerID,
+	}).Error
+}
+
+func (r *GormFriendsRepository) GetUserFriends(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
 	var models []FriendshipModel
-	if err := r.db.Where(
+	if err := r.db.WithContext(ctx).Where(
 		"(requester_id = ? OR addressee_id = ?) AND status = ?",
 		userID, userID, domain.FriendshipAccepted,
 	).Preload("Requester").Preload("Addressee").Find(&models).Error; err != nil {
@@ -105,9 +120,9 @@ func (r *GormFriendsRepository) GetUserFriends(userID uint) ([]*domain.Friendshi
 	return friendships, nil
 }
 
-func (r *GormFriendsRepository) GetPendingFriendRequests(userID uint) ([]*domain.Friendship, error) {
+func (r *GormFriendsRepository) GetPendingFriendRequests(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
 	var models []FriendshipModel
-	if err := r.db.Where(
+	if err := r.db.WithContext(ctx).Where(
 		"addressee_id = ? AND status = ?",
 		userID, domain.FriendshipPending,
 	).Preload("Requester").Preload("Addressee").Find(&models).Error; err != nil {
@@ -121,9 +136,9 @@ func (r *GormFriendsRepository) GetPendingFriendRequests(userID uint) ([]*domain
 	return friendships, nil
 }
 
-func (r *GormFriendsRepository) GetSentFriendRequests(userID uint) ([]*domain.Friendship, error) {
+func (r *GormFriendsRepository) GetSentFriendRequests(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
 	var models []FriendshipModel
-	if err := r.db.Where(
+	if err := r.db.WithContext(ctx).Where(
 		"requester_id = ? AND status = ?",
 		userID, domain.FriendshipPending,
 	).Preload("Requester").Preload("Addressee").Find(&models).Error; err != nil {
@@ -137,16 +152,39 @@ func (r *GormFriendsRepository) GetSentFriendRequests(userID uint) ([]*domain.Fr
 	return friendships, nil
 }
 
-func (r *GormFriendsRepository) DeleteFriendship(id uint) error {
-	return r.db.Delete(&FriendshipModel{}, id).Error
+func (r *GormFriendsRepository) DeleteFriendship(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&FriendshipModel{}, id).Error
 }
 
-func (r *GormFriendsRepository) AreFriends(userID1, userID2 uint) (bool, error) {
+func (r *GormFriendsRepository) AreFriends(ctx context.Context, userID1, userID2 uint) (bool, error) {
 	var count int64
-	err := r.db.Model(&FriendshipModel{}).Where(
+	err := r.db.WithContext(ctx).Model(&FriendshipModel{}).Where(
 		"((requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)) AND status = ?",
 		userID1, userID2, userID2, userID1, domain.FriendshipAccepted,
 	).Count(&count).Error
 	
 	return count > 0, err
 }
+
+func (r *GormFriendsRepository) GetAllUserFriendships(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
+	var models []FriendshipModel
+	if err := r.db.WithContext(ctx).Where(
+		"requester_id = ? OR addressee_id = ?",
+		userID, userID,
+	).Preload("Requester").Preload("Addressee").Order("created_at ASC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	friendships := make([]*domain.Friendship, len(models))
+	for i, model := range models {
+		friendships[i] = toDomainFriendship(&model)
+	}
+	return friendships, nil
+}
+
+func (r *GormFriendsRepository) DeleteUserFriendships(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Unscoped().Where(
+		"requester_id = ? OR addressee_id = ?",
+		userID, userID,
+	).Delete(&FriendshipModel{}).Error
+}
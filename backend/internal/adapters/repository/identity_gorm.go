@@ -0,0 +1,80 @@
+package repository_adapters
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type identityGormRepo struct {
+	db *gorm.DB
+}
+
+func NewIdentityGormRepo(db *gorm.DB) repository.IdentityRepository {
+	return &identityGormRepo{db: db}
+}
+
+func (r *identityGormRepo) CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *identityGormRepo) FindIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error) {
+	var identity domain.UserIdentity
+	if err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *identityGormRepo) GetUserIdentities(ctx context.Context, userID uint) ([]*domain.UserIdentity, error) {
+	var identities []*domain.UserIdentity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+func (r *identityGormRepo) DeleteIdentity(ctx context.Context, userID uint, provider string) error {
+	result := r.db.WithContext(ctx).Where("user_id = ? AND provider = ?", userID, provider).Delete(&domain.UserIdentity{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *identityGormRepo) DeleteUserIdentities(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&domain.UserIdentity{}).Error
+}
+
+func (r *identityGormRepo) SaveOAuthState(ctx context.Context, state *domain.OAuthState) error {
+	r.db.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&domain.OAuthState{})
+	return r.db.WithContext(ctx).Create(state).Error
+}
+
+// ConsumeOAuthState loads and deletes the state in one step so a callback
+// can never be replayed.
+func (r *identityGormRepo) ConsumeOAuthState(ctx context.Context, state string) (*domain.OAuthState, error) {
+	var stored domain.OAuthState
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("state = ?", state).First(&stored).Error; err != nil {
+			return err
+		}
+		result := tx.Where("state = ?", state).Delete(&domain.OAuthState{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
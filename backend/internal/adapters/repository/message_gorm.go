@@ -1,12 +1,15 @@
 package repository_adapters
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"go-chat/internal/domain"
 	"go-chat/internal/ports/repository"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type messageGormRepo struct {
@@ -17,22 +20,27 @@ func NewMessageGormRepo(db *gorm.DB) repository.MessageRepository {
 	return &messageGormRepo{db: db}
 }
 
-func (r *messageGormRepo) CreateMessage(message *domain.Message) error {
-	message.CreatedAt = time.Now()
-	message.UpdatedAt = time.Now()
+func (r *messageGormRepo) CreateMessage(ctx context.Context, message *domain.Message) error {
+	// Keep timestamps the caller already set; expiry times are based on them.
+	if message.CreatedAt.IsZero() {
+		message.CreatedAt = time.Now()
+	}
+	if message.UpdatedAt.IsZero() {
+		message.UpdatedAt = time.Now()
+	}
 	
-	return r.db.Create(message).Error
+	return r.db.WithContext(ctx).Create(message).Error
 }
 
-func (r *messageGormRepo) GetMessagesBetweenUsers(userID1, userID2 uint, limit, offset int) ([]*domain.Message, error) {
+func (r *messageGormRepo) GetMessagesBetweenUsers(ctx context.Context, userID, peerID uint, limit, offset int) ([]*domain.Message, error) {
 	var messages []*domain.Message
 	
-	err := r.db.
-		Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)", 
-			userID1, userID2, userID2, userID1).
+	err := withReadState(visibleTo(r.db.WithContext(ctx), userID)).
+		Where("(messages.sender_id = ? AND messages.receiver_id = ?) OR (messages.sender_id = ? AND messages.receiver_id = ?)", 
+			userID, peerID, peerID, userID).
 		Preload("Sender").
 		Preload("Receiver").
-		Order("created_at ASC").
+		Order("messages.created_at ASC").
 		Limit(limit).
 		Offset(offset).
 		Find(&messages).Error
@@ -40,126 +48,82 @@ func (r *messageGormRepo) GetMessagesBetweenUsers(userID1, userID2 uint, limit,
 	return messages, err
 }
 
-func (r *messageGormRepo) GetUserConversations(userID uint) ([]*domain.ConversationResponse, error) {
-	type ConversationUser struct {
-		UserID      uint      `json:"user_id"`
-		Username    string    `json:"username"`
-		FullName    string    `json:"full_name"`
-		LastMsgTime time.Time `json:"last_msg_time"`
-	}
-	
-	var conversationUsers []ConversationUser
-	
-	err := r.db.Raw(`
-		SELECT DISTINCT 
-			CASE 
-				WHEN m.sender_id = ? THEN m.receiver_id 
-				ELSE m.sender_id 
-			END as user_id,
-			u.email as username,
-			u.name as full_name,
-			MAX(m.created_at) as last_msg_time
-		FROM messages m
-		JOIN users u ON u.id = CASE 
-			WHEN m.sender_id = ? THEN m.receiver_id 
-			ELSE m.sender_id 
-		END
-		WHERE (m.sender_id = ? OR m.receiver_id = ?) 
-			AND m.deleted_at IS NULL 
-			AND u.deleted_at IS NULL
-		GROUP BY 
-			CASE 
-				WHEN m.sender_id = ? THEN m.receiver_id 
-				ELSE m.sender_id 
-			END, u.email, u.name
-		ORDER BY last_msg_time DESC
-	`, userID, userID, userID, userID, userID).Scan(&conversationUsers).Error
-	
-	if err != nil {
-		return nil, err
-	}
-	
-	var conversations []*domain.ConversationResponse
-	
-	for _, convUser := range conversationUsers {
-		conv := &domain.ConversationResponse{
-			UserID:   convUser.UserID,
-			Username: convUser.Username,
-			FullName: convUser.FullName,
-		}
-		
-		lastMessage, err := r.GetLatestMessageBetweenUsers(userID, conv.UserID)
-		if err == nil && lastMessage != nil {
-			conv.LastMessage = &domain.MessageResponse{
-				ID:             lastMessage.ID,
-				SenderID:       lastMessage.SenderID,
-				ReceiverID:     lastMessage.ReceiverID,
-				Content:        lastMessage.Content,
-				MessageType:    lastMessage.MessageType,
-				IsRead:         lastMessage.IsRead,
-				IsDelivered:    lastMessage.IsDelivered,
-				CreatedAt:      lastMessage.CreatedAt,
-				UpdatedAt:      lastMessage.UpdatedAt,
-				SenderName:     lastMessage.Sender.Name,
-				SenderUsername: lastMessage.Sender.Email,
-			}
-		}
-		
-		unreadCount, err := r.GetUnreadMessageCount(conv.UserID, userID)
-		if err == nil {
-			conv.UnreadCount = unreadCount
-		}
-		
-		conversations = append(conversations, conv)
-	}
-	
-	return conversations, nil
+func (r *messageGormRepo) MarkMessageDelivered(ctx context.Context, messageID, userID uint, deliveredAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.MessageReceipt{}).
+		Where("message_id = ? AND user_id = ? AND delivered_at IS NULL", messageID, userID).
+		Update("delivered_at", deliveredAt).Error
 }
 
-func (r *messageGormRepo) MarkMessagesAsRead(senderID, receiverID uint) error {
-	return r.db.Model(&domain.Message{}).
-		Where("sender_id = ? AND receiver_id = ? AND is_read = false", senderID, receiverID).
-		Update("is_read", true).Error
+func (r *messageGormRepo) MarkMessagesRead(ctx context.Context, senderID, receiverID, upToID uint, readAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.MessageReceipt{}).
+		Where("user_id = ? AND read_at IS NULL", receiverID).
+		Where("message_id IN (SELECT id FROM messages WHERE sender_id = ? AND receiver_id = ? AND id <= ?)",
+			senderID, receiverID, upToID).
+		Updates(map[string]interface{}{
+			"read_at":      readAt,
+			"delivered_at": gorm.Expr("COALESCE(delivered_at, ?)", readAt),
+		}).Error
 }
 
-func (r *messageGormRepo) MarkMessageAsDelivered(messageID uint) error {
-	return r.db.Model(&domain.Message{}).
-		Where("id = ?", messageID).
-		Update("is_delivered", true).Error
-}
+func (r *messageGormRepo) GetMessageReceipts(ctx context.Context, messageID uint) ([]*domain.MessageReceipt, error) {
+	var receipts []*domain.MessageReceipt
+	err := r.db.WithContext(ctx).
+		Where("message_id = ?", messageID).
+		Order("id ASC").
+		Find(&receipts).Error
 
-func (r *messageGormRepo) GetUnreadMessageCount(senderID, receiverID uint) (int, error) {
-	var count int64
-	err := r.db.Model(&domain.Message{}).
-		Where("sender_id = ? AND receiver_id = ? AND is_read = false", senderID, receiverID).
-		Count(&count).Error
-	
-	return int(count), err
+	return receipts, err
 }
 
-func (r *messageGormRepo) GetMessageByID(messageID uint) (*domain.Message, error) {
+func (r *messageGormRepo) GetMessageByID(ctx context.Context, messageID uint) (*domain.Message, error) {
 	var message domain.Message
-	err := r.db.
+	err := withReadState(r.db.WithContext(ctx)).
 		Preload("Sender").
 		Preload("Receiver").
-		First(&message, messageID).Error
+		Where("messages.id = ?", messageID).
+		First(&message).Error
 	
 	return &message, err
 }
 
-func (r *messageGormRepo) DeleteMessage(messageID uint) error {
-	return r.db.Delete(&domain.Message{}, messageID).Error
+func (r *messageGormRepo) DeleteMessage(ctx context.Context, messageID uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.Message{}, messageID).Error
+}
+
+func (r *messageGormRepo) HideMessage(ctx context.Context, messageID, userID uint) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&domain.HiddenMessage{MessageID: messageID, UserID: userID}).Error
 }
 
-func (r *messageGormRepo) GetLatestMessageBetweenUsers(userID1, userID2 uint) (*domain.Message, error) {
+func (r *messageGormRepo) HideConversation(ctx context.Context, userID, peerID uint) error {
+	return r.db.WithContext(ctx).Exec(`
+		INSERT INTO hidden_messages (message_id, user_id, created_at)
+		SELECT m.id, ?, ? FROM messages m
+		WHERE m.deleted_at IS NULL
+			AND ((m.sender_id = ? AND m.receiver_id = ?) OR (m.sender_id = ? AND m.receiver_id = ?))
+			AND NOT EXISTS (SELECT 1 FROM hidden_messages h WHERE h.message_id = m.id AND h.user_id = ?)
+	`, userID, time.Now(), userID, peerID, peerID, userID, userID).Error
+}
+
+func (r *messageGormRepo) TombstoneMessage(ctx context.Context, messageID uint) error {
+	return r.db.WithContext(ctx).Model(&domain.Message{}).
+		Where("id = ?", messageID).
+		Updates(map[string]interface{}{
+			"content":      domain.TombstoneContent,
+			"message_type": domain.MessageTypeTombstone,
+		}).Error
+}
+
+func (r *messageGormRepo) GetLatestMessageBetweenUsers(ctx context.Context, userID1, userID2 uint) (*domain.Message, error) {
 	var message domain.Message
 	
-	err := r.db.
-		Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)", 
+	err := withReadState(r.db.WithContext(ctx)).
+		Where("(messages.sender_id = ? AND messages.receiver_id = ?) OR (messages.sender_id = ? AND messages.receiver_id = ?)", 
 			userID1, userID2, userID2, userID1).
 		Preload("Sender").
 		Preload("Receiver").
-		Order("created_at DESC").
+		Order("messages.created_at DESC").
 		First(&message).Error
 	
 	if err != nil {
@@ -169,18 +133,37 @@ func (r *messageGormRepo) GetLatestMessageBetweenUsers(userID1, userID2 uint) (*
 	return &message, nil
 }
 
-func (r *messageGormRepo) SearchMessages(userID uint, query string, limit, offset int) ([]*domain.Message, error) {
+func (r *messageGormRepo) GetMessageBefore(ctx context.Context, userID1, userID2, beforeID uint) (*domain.Message, error) {
+	var message domain.Message
+
+	err := withReadState(r.db.WithContext(ctx)).
+		Where("((messages.sender_id = ? AND messages.receiver_id = ?) OR (messages.sender_id = ? AND messages.receiver_id = ?)) AND messages.id < ?",
+			userID1, userID2, userID2, userID1, beforeID).
+		Preload("Sender").
+		Preload("Receiver").
+		Order("messages.id DESC").
+		First(&message).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+func (r *messageGormRepo) SearchMessages(ctx context.Context, userID uint, query string, limit, offset int) ([]*domain.Message, error) {
 	var messages []*domain.Message
 	
 	searchQuery := fmt.Sprintf("%%%s%%", query)
+	op := ilike(r.db)
 	
-	err := r.db.
+	err := withReadState(visibleTo(r.db.WithContext(ctx), userID)).
 		Joins("LEFT JOIN users sender ON messages.sender_id = sender.id").
 		Joins("LEFT JOIN users receiver ON messages.receiver_id = receiver.id").
-		Where(`(messages.sender_id = ? OR messages.receiver_id = ?) AND 
-			   (messages.content ILIKE ? OR 
-			    sender.name ILIKE ? OR 
-			    receiver.name ILIKE ?)`, 
+		Where(fmt.Sprintf(`(messages.sender_id = ? OR messages.receiver_id = ?) AND 
+			   (messages.content %[1]s ? OR 
+			    sender.name %[1]s ? OR 
+			    receiver.name %[1]s ?)`, op), 
 			userID, userID, searchQuery, searchQuery, searchQuery).
 		Preload("Sender").
 		Preload("Receiver").
@@ -190,4 +173,169 @@ func (r *messageGormRepo) SearchMessages(userID uint, query string, limit, offse
 		Find(&messages).Error
 	
 	return messages, err
-}
\ No newline at end of file
+}
+
+func (r *messageGormRepo) GetAllUserMessages(ctx context.Context, userID uint) ([]*domain.Message, error) {
+	var messages []*domain.Message
+
+	err := withReadState(r.db.WithContext(ctx)).
+		Where("messages.sender_id = ? OR messages.receiver_id = ?", userID, userID).
+		Preload("Sender").
+		Preload("Receiver").
+		Order("messages.created_at ASC").
+		Find(&messages).Error
+
+	return messages, err
+}
+
+// TombstoneUserMessages overwrites the content of everything the user sent,
+// including already soft-deleted rows, while leaving the rows in place.
+func (r *messageGormRepo) TombstoneUserMessages(ctx context.Context, senderID uint) error {
+	return r.db.WithContext(ctx).Unscoped().Model(&domain.Message{}).
+		Where("sender_id = ?", senderID).
+		Updates(map[string]interface{}{
+			"content":      domain.TombstoneContent,
+			"message_type": domain.MessageTypeTombstone,
+		}).Error
+}
+
+func (r *messageGormRepo) PinMessage(ctx context.Context, pin *domain.PinnedMessage) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(pin)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *messageGormRepo) UnpinMessage(ctx context.Context, messageID uint) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("message_id = ?", messageID).
+		Delete(&domain.PinnedMessage{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *messageGormRepo) CountPinnedMessages(ctx context.Context, userID1, userID2 uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.PinnedMessage{}).
+		Joins("JOIN messages ON messages.id = pinned_messages.message_id AND messages.deleted_at IS NULL").
+		Where("(messages.sender_id = ? AND messages.receiver_id = ?) OR (messages.sender_id = ? AND messages.receiver_id = ?)",
+			userID1, userID2, userID2, userID1).
+		Where("messages.message_type <> ?", domain.MessageTypeTombstone).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *messageGormRepo) GetPinnedMessages(ctx context.Context, userID, peerID uint, limit, offset int) ([]*domain.Message, error) {
+	var messages []*domain.Message
+
+	err := withReadState(visibleTo(r.db.WithContext(ctx), userID), "pin.created_at AS pinned_at", "pin.pinned_by_id AS pinned_by_id").
+		Joins("JOIN pinned_messages pin ON pin.message_id = messages.id").
+		Where("(messages.sender_id = ? AND messages.receiver_id = ?) OR (messages.sender_id = ? AND messages.receiver_id = ?)",
+			userID, peerID, peerID, userID).
+		Preload("Sender").
+		Preload("Receiver").
+		Order("pin.created_at DESC, pin.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&messages).Error
+
+	return messages, err
+}
+
+func (r *messageGormRepo) StarMessage(ctx context.Context, messageID, userID uint) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&domain.StarredMessage{MessageID: messageID, UserID: userID}).Error
+}
+
+func (r *messageGormRepo) UnstarMessage(ctx context.Context, messageID, userID uint) error {
+	return r.db.WithContext(ctx).
+		Where("message_id = ? AND user_id = ?", messageID, userID).
+		Delete(&domain.StarredMessage{}).Error
+}
+
+func (r *messageGormRepo) GetStarredMessages(ctx context.Context, userID uint, limit, offset int) ([]*domain.Message, error) {
+	var messages []*domain.Message
+
+	err := withReadState(visibleTo(r.db.WithContext(ctx), userID), "star.created_at AS starred_at").
+		Joins("JOIN starred_messages star ON star.message_id = messages.id AND star.user_id = ?", userID).
+		Where("messages.sender_id = ? OR messages.receiver_id = ?", userID, userID).
+		Preload("Sender").
+		Preload("Receiver").
+		Order("star.created_at DESC, star.id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&messages).Error
+
+	return messages, err
+}
+
+func (r *messageGormRepo) DeleteUserMessageState(ctx context.Context, userID uint) error {
+	db := r.db.WithContext(ctx)
+	if err := db.Where("user_id = ?", userID).Delete(&domain.StarredMessage{}).Error; err != nil {
+		return err
+	}
+	if err := db.Where("user_id = ?", userID).Delete(&domain.HiddenMessage{}).Error; err != nil {
+		return err
+	}
+	return db.Where("pinned_by_id = ? OR message_id IN (?)", userID,
+		db.Model(&domain.Message{}).Unscoped().Select("id").Where("sender_id = ?", userID)).
+		Delete(&domain.PinnedMessage{}).Error
+}
+
+func (r *messageGormRepo) DeleteExpiredMessages(ctx context.Context, now time.Time, limit int) ([]*domain.Message, error) {
+	db := r.db.WithContext(ctx)
+
+	var expired []*domain.Message
+	err := db.Unscoped().
+		Select("id", "sender_id", "receiver_id", "expires_at").
+		Where("expires_at <= ?", now).
+		Order("expires_at ASC, id ASC").
+		Limit(limit).
+		Find(&expired).Error
+	if err != nil || len(expired) == 0 {
+		return nil, err
+	}
+
+	ids := make([]uint, len(expired))
+	for i, message := range expired {
+		ids[i] = message.ID
+	}
+	if err := db.Where("message_id IN ?", ids).Delete(&domain.MessageReceipt{}).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("message_id IN ?", ids).Delete(&domain.HiddenMessage{}).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("message_id IN ?", ids).Delete(&domain.PinnedMessage{}).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("message_id IN ?", ids).Delete(&domain.StarredMessage{}).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Unscoped().Where("id IN ?", ids).Delete(&domain.Message{}).Error; err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// withReadState selects messages together with their read and delivery
+// state, which is not stored on the row: is_read comes from the receiver's
+// read cursor and the timestamps from the receiver's receipt. Extra columns
+// are selected as well. Disappearing messages past their expiry are left out
+// even before the purge removes them.
+func withReadState(db *gorm.DB, columns ...string) *gorm.DB {
+	return db.
+		Where("messages.expires_at IS NULL OR messages.expires_at > ?", time.Now()).
+		Select(`messages.*,
+			(read_cursor.last_read_message_id IS NOT NULL AND messages.id <= read_cursor.last_read_message_id) AS is_read,
+			(receipt.delivered_at IS NOT NULL) AS is_delivered,
+			receipt.delivered_at AS delivered_at,
+			receipt.read_at AS read_at` + strings.Join(append([]string{""}, columns...), ", ")).
+		Joins("LEFT JOIN conversation_summaries read_cursor ON read_cursor.user_id = messages.receiver_id AND read_cursor.peer_id = messages.sender_id").
+		Joins("LEFT JOIN message_receipts receipt ON receipt.message_id = messages.id AND receipt.user_id = messages.receiver_id")
+}
+
+// visibleTo leaves out the messages userID deleted for themselves.
+func visibleTo(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Where("NOT EXISTS (SELECT 1 FROM hidden_messages hidden WHERE hidden.message_id = messages.id AND hidden.user_id = ?)", userID)
+}
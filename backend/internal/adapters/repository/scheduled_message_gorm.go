@@ -0,0 +1,99 @@
+package repository_adapters
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type scheduledMessageGormRepo struct {
+	db *gorm.DB
+}
+
+func NewScheduledMessageGormRepo(db *gorm.DB) repository.ScheduledMessageRepository {
+	return &scheduledMessageGormRepo{db: db}
+}
+
+func (r *scheduledMessageGormRepo) CreateScheduledMessage(ctx context.Context, message *domain.ScheduledMessage) error {
+	return r.db.WithContext(ctx).Create(message).Error
+}
+
+func (r *scheduledMessageGormRepo) GetScheduledMessage(ctx context.Context, id uint) (*domain.ScheduledMessage, error) {
+	var message domain.ScheduledMessage
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&message).Error; err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (r *scheduledMessageGormRepo) GetPendingScheduledMessages(ctx context.Context, senderID uint) ([]*domain.ScheduledMessage, error) {
+	var messages []*domain.ScheduledMessage
+	err := r.db.WithContext(ctx).
+		Where("sender_id = ? AND status = ?", senderID, domain.ScheduledMessagePending).
+		Order("send_at ASC, id ASC").
+		Find(&messages).Error
+	return messages, err
+}
+
+func (r *scheduledMessageGormRepo) GetDueScheduledMessages(ctx context.Context, now time.Time, limit int) ([]*domain.ScheduledMessage, error) {
+	var messages []*domain.ScheduledMessage
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND send_at <= ?", domain.ScheduledMessagePending, now).
+		Order("send_at ASC, id ASC").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+func (r *scheduledMessageGormRepo) UpdateScheduledMessage(ctx context.Context, id uint, content string, sendAt time.Time) (bool, error) {
+	return r.updatePending(ctx, id, map[string]interface{}{
+		"content": content,
+		"send_at": sendAt,
+	})
+}
+
+func (r *scheduledMessageGormRepo) CancelScheduledMessage(ctx context.Context, id uint, cancelledAt time.Time) (bool, error) {
+	return r.updatePending(ctx, id, map[string]interface{}{
+		"status":       domain.ScheduledMessageCancelled,
+		"cancelled_at": cancelledAt,
+	})
+}
+
+func (r *scheduledMessageGormRepo) CancelUserScheduledMessages(ctx context.Context, userID uint, cancelledAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.ScheduledMessage{}).
+		Where("(sender_id = ? OR receiver_id = ?) AND status = ?", userID, userID, domain.ScheduledMessagePending).
+		Updates(map[string]interface{}{
+			"status":       domain.ScheduledMessageCancelled,
+			"cancelled_at": cancelledAt,
+		}).Error
+}
+
+func (r *scheduledMessageGormRepo) ClaimScheduledMessage(ctx context.Context, id uint, sentAt time.Time) (bool, error) {
+	return r.updatePending(ctx, id, map[string]interface{}{
+		"status":  domain.ScheduledMessageSent,
+		"sent_at": sentAt,
+	})
+}
+
+func (r *scheduledMessageGormRepo) RecordScheduledMessageFailure(ctx context.Context, id uint, reason string, maxAttempts int) error {
+	_, err := r.updatePending(ctx, id, map[string]interface{}{
+		"attempts":   gorm.Expr("attempts + 1"),
+		"last_error": reason,
+		"status": gorm.Expr("CASE WHEN attempts + 1 >= ? THEN ? ELSE status END",
+			maxAttempts, domain.ScheduledMessageFailed),
+	})
+	return err
+}
+
+// updatePending applies updates to the message if it is still pending and
+// reports whether it was.
+func (r *scheduledMessageGormRepo) updatePending(ctx context.Context, id uint, updates map[string]interface{}) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, domain.ScheduledMessagePending).
+		Updates(updates)
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,61 @@
+package repository_adapters
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type sessionGormRepo struct {
+	db *gorm.DB
+}
+
+func NewSessionGormRepo(db *gorm.DB) repository.SessionRepository {
+	return &sessionGormRepo{db: db}
+}
+
+func (r *sessionGormRepo) CreateSession(ctx context.Context, session *domain.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *sessionGormRepo) GetSessionByID(ctx context.Context, id string) (*domain.Session, error) {
+	var session domain.Session
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionGormRepo) GetUserSessions(ctx context.Context, userID uint, includeInactive bool) ([]*domain.Session, error) {
+	var sessions []*domain.Session
+
+	query := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if !includeInactive {
+		query = query.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+
+	err := query.Order("last_seen_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionGormRepo) TouchSession(ctx context.Context, id string, seenAt, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"last_seen_at": seenAt, "expires_at": expiresAt}).Error
+}
+
+func (r *sessionGormRepo) RevokeSession(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&domain.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionGormRepo) RevokeUserSessions(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&domain.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
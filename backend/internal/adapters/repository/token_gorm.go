@@ -0,0 +1,62 @@
+package repository_adapters
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+type tokenGormRepo struct {
+	db *gorm.DB
+}
+
+func NewTokenGormRepo(db *gorm.DB) repository.TokenRepository {
+	return &tokenGormRepo{db: db}
+}
+
+func (r *tokenGormRepo) CreateToken(ctx context.Context, token *domain.PersonalAccessToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *tokenGormRepo) FindTokenByHash(ctx context.Context, hash string) (*domain.PersonalAccessToken, error) {
+	var token domain.PersonalAccessToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *tokenGormRepo) GetUserTokens(ctx context.Context, userID uint) ([]*domain.PersonalAccessToken, error) {
+	var tokens []*domain.PersonalAccessToken
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *tokenGormRepo) RevokeToken(ctx context.Context, userID, tokenID uint) error {
+	result := r.db.WithContext(ctx).Model(&domain.PersonalAccessToken{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *tokenGormRepo) RevokeUserTokens(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Model(&domain.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *tokenGormRepo) TouchToken(ctx context.Context, tokenID uint, usedAt time.Time, ip string) error {
+	return r.db.WithContext(ctx).Model(&domain.PersonalAccessToken{}).
+		Where("id = ?", tokenID).
+		Updates(map[string]interface{}{"last_used_at": usedAt, "last_used_ip": ip}).Error
+}
@@ -0,0 +1,71 @@
+package repository_adapters
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"go-chat/internal/ports/repository"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const maxTransactionAttempts = 3
+
+type gormUnitOfWork struct {
+	db *gorm.DB
+}
+
+func NewGormUnitOfWork(db *gorm.DB) repository.UnitOfWork {
+	return &gormUnitOfWork{db: db}
+}
+
+func (u *gormUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos repository.Repositories) error) error {
+	var err error
+	for attempt := 1; attempt <= maxTransactionAttempts; attempt++ {
+		err = u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(ctx, repositoriesFor(tx))
+		}, &sql.TxOptions{Isolation: sql.LevelSerializable})
+
+		if !isRetryableTxError(err) || attempt == maxTransactionAttempts {
+			return err
+		}
+
+		// Back off briefly, with jitter, so the competing transaction can
+		// finish before we try again.
+		backoff := time.Duration(attempt)*10*time.Millisecond + rand.N(10*time.Millisecond)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+	}
+	return err
+}
+
+func repositoriesFor(tx *gorm.DB) repository.Repositories {
+	return repository.Repositories{
+		Users:             NewUserGormRepo(tx),
+		Friends:           NewFriendsGormRepo(tx),
+		Messages:          NewMessageGormRepo(tx),
+		Conversations:     NewConversationGormRepo(tx),
+		Identities:        NewIdentityGormRepo(tx),
+		Tokens:            NewTokenGormRepo(tx),
+		Sessions:          NewSessionGormRepo(tx),
+		Audit:             NewAuditGormRepo(tx),
+		ScheduledMessages: NewScheduledMessageGormRepo(tx),
+	}
+}
+
+// isRetryableTxError reports serialization failures and deadlocks, the
+// errors Postgres expects clients to handle by retrying the transaction.
+func isRetryableTxError(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
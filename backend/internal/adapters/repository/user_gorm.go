@@ -1,6 +1,10 @@
 package repository_adapters
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	"go-chat/internal/domain"
 
 	"gorm.io/gorm"
@@ -18,61 +22,156 @@ func NewUserGormRepo(db *gorm.DB) *GormUserRepository {
 	return &GormUserRepository{db: db}
 }
 
-func (r *GormUserRepository) Create(user *domain.User) error {
-	return r.db.Create(user).Error
+func (r *GormUserRepository) Create(ctx context.Context, user *domain.User) error {
+	return r.db.WithContext(ctx).Create(user).Error
 }
 
-func (r *GormUserRepository) GetUserByID(id uint) (*domain.User, error) {
+func (r *GormUserRepository) GetUserByID(ctx context.Context, id uint) (*domain.User, error) {
 	var user domain.User
-	if err := r.db.First(&user, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *GormUserRepository) FindByEmail(email string) (*domain.User, error) {
+func (r *GormUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var user domain.User
-	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *GormUserRepository) UpdatePassword(id uint, password string) (*domain.User, error) {
+func (r *GormUserRepository) UpdatePassword(ctx context.Context, id uint, password string) (*domain.User, error) {
 	var user domain.User
-	if err := r.db.First(&user, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 
 	user.Password = password
-	if err := r.db.Save(&user).Error; err != nil {
+	user.PasswordResetRequired = false
+	if err := r.db.WithContext(ctx).Save(&user).Error; err != nil {
 		return nil, err
 	}
 
 	return &user, nil
 }
 
-func (r *GormUserRepository) UpdateUserProfile(id uint, name, email string) (*domain.User, error) {
+func (r *GormUserRepository) UpdateUserProfile(ctx context.Context, id uint, name, email string) (*domain.User, error) {
 	var user domain.User
-	if err := r.db.First(&user, id).Error; err != nil {
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		return nil, err
 	}
 
 	user.Name = name
 	user.Email = email
 
-	if err := r.db.Save(&user).Error; err != nil {
+	if err := r.db.WithContext(ctx).Save(&user).Error; err != nil {
 		return nil, err
 	}
 
 	return &user, nil
 }
 
-func (r *GormUserRepository) SearchUsers(query string, id uint) ([]*domain.User, error) {
+func (r *GormUserRepository) SearchUsers(ctx context.Context, query string, id uint) ([]*domain.User, error) {
 	var users []*domain.User
-	if err := r.db.Where("name ILIKE ? AND id <> ?", "%"+query+"%", id).Find(&users).Error; err != nil {
+	where := fmt.Sprintf("name %s ? AND id <> ? AND anonymized_at IS NULL", ilike(r.db))
+	if err := r.db.WithContext(ctx).Where(where, "%"+query+"%", id).Find(&users).Error; err != nil {
 		return nil, err
 	}
 
 	return users, nil
 }
+
+func (r *GormUserRepository) GetBotsByOwner(ctx context.Context, ownerID uint) ([]*domain.User, error) {
+	var bots []*domain.User
+	if err := r.db.WithContext(ctx).Where("is_bot = ? AND owner_id = ?", true, ownerID).Order("created_at ASC").Find(&bots).Error; err != nil {
+		return nil, err
+	}
+
+	return bots, nil
+}
+
+func (r *GormUserRepository) DeleteUser(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&domain.User{}, id).Error
+}
+
+func (r *GormUserRepository) ListUsers(ctx context.Context, query string, limit, offset int) ([]*domain.User, int64, error) {
+	var users []*domain.User
+	var total int64
+
+	db := r.db.WithContext(ctx).Model(&domain.User{})
+	if query != "" {
+		pattern := "%" + query + "%"
+		op := ilike(r.db)
+		db = db.Where(fmt.Sprintf("name %s ? OR email %s ?", op, op), pattern, pattern)
+	}
+
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := db.Order("id ASC").Limit(limit).Offset(offset).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+func (r *GormUserRepository) UpdateUserRole(ctx context.Context, id uint, role domain.Role) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+func (r *GormUserRepository) SetUserSuspension(ctx context.Context, id uint, suspendedAt *time.Time, reason string) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"suspended_at":      suspendedAt,
+		"suspension_reason": reason,
+	}).Error
+}
+
+func (r *GormUserRepository) SetPasswordResetRequired(ctx context.Context, id uint, required bool) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).Update("password_reset_required", required).Error
+}
+
+func (r *GormUserRepository) SetReadReceiptsDisabled(ctx context.Context, id uint, disabled bool) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).Update("read_receipts_disabled", disabled).Error
+}
+
+func (r *GormUserRepository) SetNotificationPreferences(ctx context.Context, id uint, preferences domain.NotificationPreferences) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"notification_level": preferences.Level,
+		"quiet_hours_start":  preferences.QuietHoursStart,
+		"quiet_hours_end":    preferences.QuietHoursEnd,
+		"timezone":           preferences.Timezone,
+	}).Error
+}
+
+func (r *GormUserRepository) CountUsersByRole(ctx context.Context, role domain.Role) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.User{}).Where("role = ?", role).Count(&count).Error
+	return count, err
+}
+
+func (r *GormUserRepository) SetDeletionRequested(ctx context.Context, id uint, requestedAt *time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).Update("deletion_requested_at", requestedAt).Error
+}
+
+func (r *GormUserRepository) GetUsersPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]*domain.User, error) {
+	var users []*domain.User
+	err := r.db.WithContext(ctx).Where("deletion_requested_at IS NOT NULL AND deletion_requested_at < ? AND anonymized_at IS NULL", requestedBefore).
+		Find(&users).Error
+	return users, err
+}
+
+// AnonymizeUser strips every personal field but keeps the row, so messages
+// the user exchanged still resolve to a (now anonymous) participant.
+func (r *GormUserRepository) AnonymizeUser(ctx context.Context, id uint, anonymizedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"name":                  "Deleted User",
+		"email":                 fmt.Sprintf("deleted-%d@deleted.invalid", id),
+		"password":              "",
+		"suspension_reason":     "",
+		"deletion_requested_at": nil,
+		"anonymized_at":         anonymizedAt,
+	}).Error
+}
@@ -1,29 +1,30 @@
 package websocket_adapters
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"go-chat/config"
 	"go-chat/internal/domain"
 	wsports "go-chat/internal/ports/websocket"
 	"go-chat/internal/service"
 	"go-chat/pkg"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-}
-
 type WSHub struct {
 	clients map[uint]*wsports.WSClient
 
@@ -36,30 +37,89 @@ type WSHub struct {
 	mu sync.RWMutex
 
 	messageService *service.MessageService
+	notifications  *service.NotificationPolicy
+
+	cfg      config.WebSocketConfig
+	upgrader websocket.Upgrader
+
+	// closing is set (under mu) once Shutdown starts; quit stops Run.
+	closing bool
+	quit    chan struct{}
+	running atomic.Bool
 }
 
+// ShutdownCloseReason accompanies the going-away close frame sent on
+// shutdown so clients know to reconnect rather than treat it as an error.
+const ShutdownCloseReason = "server restarting, please reconnect"
+
+var errHubClosing = errors.New("websocket hub is shutting down")
+
 // Ensure WSHub implements WSHandler interface
 var _ wsports.WSHandler = (*WSHub)(nil)
 
-func NewWSHub(messageService *service.MessageService) *WSHub {
+func NewWSHub(messageService *service.MessageService, notifications *service.NotificationPolicy, cfg config.WebSocketConfig) *WSHub {
 	return &WSHub{
 		clients:        make(map[uint]*wsports.WSClient),
 		register:       make(chan *wsports.WSClient),
 		unregister:     make(chan *wsports.WSClient),
 		broadcast:      make(chan *domain.WSMessage),
+		quit:           make(chan struct{}),
 		messageService: messageService,
+		notifications:  notifications,
+		cfg:            cfg,
+		upgrader: websocket.Upgrader{
+			CheckOrigin:     originChecker(cfg.AllowedOrigins),
+			ReadBufferSize:  cfg.ReadBufferSize,
+			WriteBufferSize: cfg.WriteBufferSize,
+		},
+	}
+}
+
+// originChecker accepts exact matches (or "*") of allowed. With nothing
+// allowed only same-origin upgrades pass: the upgrade is authenticated by
+// cookie, so any other site could otherwise open a socket as the user.
+func originChecker(allowed []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		if len(allowed) == 0 {
+			return sameOrigin(r)
+		}
+		origin := r.Header.Get("Origin")
+		for _, a := range allowed {
+			if a == "*" || strings.EqualFold(a, origin) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// sameOrigin reports whether the request's Origin matches its Host. Requests
+// without an Origin do not come from a browser and pass.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
 	}
+	return strings.EqualFold(u.Host, r.Host)
 }
 
 func (h *WSHub) Run() {
+	h.running.Store(true)
+	defer h.running.Store(false)
+
 	for {
 		select {
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client.UserID] = client
+			pkg.WSConnectedClients.Set(float64(len(h.clients)))
 			h.mu.Unlock()
 
-			log.Printf("User %d connected via WebSocket", client.UserID)
+			pkg.InfoContext(client.Ctx, "WebSocket connected")
 
 			h.BroadcastToAll(&domain.WSMessage{
 				Type: domain.WSMessageTypeUserOnline,
@@ -69,14 +129,24 @@ func (h *WSHub) Run() {
 			})
 
 		case client := <-h.unregister:
+			// This is the only place Send is closed. Senders hold mu while
+			// writing to it, so none can see the client once it is removed.
+			// A reconnect may already have replaced the entry.
 			h.mu.Lock()
-			if _, ok := h.clients[client.UserID]; ok {
+			if h.clients[client.UserID] == client {
 				delete(h.clients, client.UserID)
-				close(client.Send)
 			}
+			close(client.Send)
+			pkg.WSConnectedClients.Set(float64(len(h.clients)))
+			closing := h.closing
 			h.mu.Unlock()
 
-			log.Printf("User %d disconnected from WebSocket", client.UserID)
+			pkg.InfoContext(client.Ctx, "WebSocket disconnected")
+
+			// Everyone is leaving during shutdown; presence updates are noise.
+			if closing {
+				continue
+			}
 
 			h.BroadcastToAll(&domain.WSMessage{
 				Type: domain.WSMessageTypeUserOffline,
@@ -87,10 +157,61 @@ func (h *WSHub) Run() {
 
 		case message := <-h.broadcast:
 			h.handleMessage(message)
+
+		case <-h.quit:
+			return
 		}
 	}
 }
 
+// IsRunning reports whether the Run loop is dispatching events.
+func (h *WSHub) IsRunning() bool {
+	return h.running.Load()
+}
+
+// Shutdown sends every client a going-away close frame and waits for the
+// connections to drain before stopping Run. Connections still open when ctx
+// ends are closed forcibly.
+func (h *WSHub) Shutdown(ctx context.Context) error {
+	h.mu.Lock()
+	if h.closing {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closing = true
+	clients := make([]*wsports.WSClient, 0, len(h.clients))
+	for _, client := range h.clients {
+		clients = append(clients, client)
+	}
+	h.mu.Unlock()
+
+	log.Printf("Closing %d WebSocket connections", len(clients))
+
+	closeMsg := websocket.FormatCloseMessage(websocket.CloseGoingAway, ShutdownCloseReason)
+	for _, client := range clients {
+		client.Conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(h.cfg.WriteWait))
+	}
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+
+	var err error
+	for err == nil && len(h.GetOnlineUsers()) > 0 {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	for _, client := range clients {
+		client.Conn.Close()
+	}
+	close(h.quit)
+
+	return err
+}
+
 func (h *WSHub) handleMessage(wsMsg *domain.WSMessage) {
 	switch wsMsg.Type {
 	case domain.WSMessageTypeNewMessage:
@@ -110,31 +231,62 @@ func (h *WSHub) handleMessage(wsMsg *domain.WSMessage) {
 	}
 }
 
-func (h *WSHub) HandleConnection(conn *websocket.Conn, userID uint) error {
+func (h *WSHub) HandleConnection(ctx context.Context, conn *websocket.Conn, userID uint) error {
+	connCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
 	client := &wsports.WSClient{
-		UserID: userID,
-		Conn:   conn,
-		Send:   make(chan *domain.WSMessage, 256),
+		UserID:   userID,
+		Conn:     conn,
+		Send:     make(chan *domain.WSMessage, h.cfg.SendBufferSize),
+		ReadOnly: !canWrite(ctx),
+		Ctx:      connCtx,
+	}
+
+	h.mu.RLock()
+	closing := h.closing
+	h.mu.RUnlock()
+	if closing {
+		cancel()
+		return errHubClosing
 	}
 
-	h.register <- client
+	select {
+	case h.register <- client:
+	case <-h.quit:
+		cancel()
+		return errHubClosing
+	}
 
-	go h.readPump(client)
+	go h.readPump(client, cancel)
 	go h.writePump(client)
 
 	return nil
 }
 
-func (h *WSHub) readPump(client *wsports.WSClient) {
+// canWrite reports whether the upgrade request may act on the user's behalf
+// over the connection. Token-authenticated requests need the messages:write
+// scope for that; RequireScope only checked messages:read for the upgrade.
+func canWrite(ctx context.Context) bool {
+	scopes, isToken := ctx.Value("authScopes").([]string)
+	return !isToken || slices.Contains(scopes, "messages:write")
+}
+
+// readPump owns the connection's lifetime: when it returns, cancel aborts
+// any work still running on behalf of the client.
+func (h *WSHub) readPump(client *wsports.WSClient, cancel context.CancelFunc) {
 	defer func() {
-		h.unregister <- client
+		cancel()
+		select {
+		case h.unregister <- client:
+		case <-h.quit:
+		}
 		client.Conn.Close()
 	}()
 
-	client.Conn.SetReadLimit(512)
-	client.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	client.Conn.SetReadLimit(h.cfg.ReadLimit)
+	client.Conn.SetReadDeadline(time.Now().Add(h.cfg.PongWait))
 	client.Conn.SetPongHandler(func(string) error {
-		client.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+		client.Conn.SetReadDeadline(time.Now().Add(h.cfg.PongWait))
 		return nil
 	})
 
@@ -142,32 +294,65 @@ func (h *WSHub) readPump(client *wsports.WSClient) {
 		_, messageBytes, err := client.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				pkg.WarnContext(client.Ctx, "WebSocket read failed", map[string]interface{}{"error": err.Error()})
 			}
 			break
 		}
 
 		var wsMsg domain.WSMessage
 		if err := json.Unmarshal(messageBytes, &wsMsg); err != nil {
-			log.Printf("Error unmarshaling WebSocket message: %v", err)
+			pkg.WarnContext(client.Ctx, "Invalid WebSocket message", map[string]interface{}{"error": err.Error()})
+			pkg.WSMessagesTotal.WithLabelValues("in", "invalid").Inc()
+			continue
+		}
+		messageType := inboundMetricType(wsMsg.Type)
+		pkg.WSMessagesTotal.WithLabelValues("in", messageType).Inc()
+
+		// Each inbound message starts its own trace, linked to the upgrade
+		// request, since a connection can outlive any sensible trace.
+		ctx, span := pkg.StartRootSpan(client.Ctx, "ws "+messageType,
+			attribute.Int("enduser.id", int(client.UserID)),
+		)
+		ctx, cancelMessage := context.WithTimeout(ctx, h.cfg.MessageTimeout)
+
+		if client.ReadOnly && wsMsg.Type != "ack" {
+			h.sendError(client, "Token is missing required scope: messages:write")
+			cancelMessage()
+			span.End()
 			continue
 		}
 
 		switch wsMsg.Type {
 		case "send_message":
-			h.handleSendMessage(client, &wsMsg)
+			h.handleSendMessage(ctx, client, &wsMsg)
 		case domain.WSMessageTypeTyping:
 			h.handleTyping(client, &wsMsg)
 		case domain.WSMessageTypeStopTyping:
 			h.handleStopTyping(client, &wsMsg)
 		case "mark_read":
-			h.handleMarkAsRead(client, &wsMsg)
+			h.handleMarkAsRead(ctx, client, &wsMsg)
+		case "ack":
+			h.handleAck(ctx, client, &wsMsg)
 		}
+
+		cancelMessage()
+		span.End()
+	}
+}
+
+// inboundMetricType maps client-supplied message types onto the known set so
+// arbitrary input cannot create new metric series.
+func inboundMetricType(messageType string) string {
+	switch messageType {
+	case "send_message", domain.WSMessageTypeTyping, domain.WSMessageTypeStopTyping, "mark_read", "ack":
+		return messageType
+	default:
+		return "unknown"
 	}
 }
 
 func (h *WSHub) writePump(client *wsports.WSClient) {
-	ticker := time.NewTicker(54 * time.Second)
+	ticker := time.NewTicker(h.cfg.PingPeriod)
 	defer func() {
 		ticker.Stop()
 		client.Conn.Close()
@@ -176,7 +361,7 @@ func (h *WSHub) writePump(client *wsports.WSClient) {
 	for {
 		select {
 		case message, ok := <-client.Send:
-			client.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			client.Conn.SetWriteDeadline(time.Now().Add(h.cfg.WriteWait))
 			if !ok {
 				client.Conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
@@ -184,17 +369,18 @@ func (h *WSHub) writePump(client *wsports.WSClient) {
 
 			messageBytes, err := json.Marshal(message)
 			if err != nil {
-				log.Printf("Error marshaling WebSocket message: %v", err)
+				pkg.ErrorContext(client.Ctx, "Error marshaling WebSocket message", err)
 				continue
 			}
 
 			if err := client.Conn.WriteMessage(websocket.TextMessage, messageBytes); err != nil {
-				log.Printf("Error writing WebSocket message: %v", err)
+				pkg.WarnContext(client.Ctx, "WebSocket write failed", map[string]interface{}{"error": err.Error()})
 				return
 			}
+			pkg.WSMessagesTotal.WithLabelValues("out", message.Type).Inc()
 
 		case <-ticker.C:
-			client.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			client.Conn.SetWriteDeadline(time.Now().Add(h.cfg.WriteWait))
 			if err := client.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
@@ -202,7 +388,28 @@ func (h *WSHub) writePump(client *wsports.WSClient) {
 	}
 }
 
-func (h *WSHub) handleSendMessage(client *wsports.WSClient, wsMsg *domain.WSMessage) {
+// sendError reports a failed request back to the client.
+func (h *WSHub) sendError(client *wsports.WSClient, message string) {
+	errorMsg := &domain.WSMessage{
+		Type: "error",
+		Payload: map[string]interface{}{
+			"message": message,
+		},
+	}
+	select {
+	case client.Send <- errorMsg:
+	default:
+		h.dropClient(client)
+	}
+}
+
+// dropClient closes the connection of a client that cannot keep up. Its
+// readPump then fails and unregisters it, which closes Send.
+func (h *WSHub) dropClient(client *wsports.WSClient) {
+	client.Conn.Close()
+}
+
+func (h *WSHub) handleSendMessage(ctx context.Context, client *wsports.WSClient, wsMsg *domain.WSMessage) {
 	payload, ok := wsMsg.Payload.(map[string]interface{})
 	if !ok {
 		return
@@ -228,53 +435,41 @@ func (h *WSHub) handleSendMessage(client *wsports.WSClient, wsMsg *domain.WSMess
 		Content:     content,
 		MessageType: messageType,
 	}
-
-	message, err := h.messageService.SendMessage(client.UserID, req)
-	if err != nil {
-		errorMsg := &domain.WSMessage{
-			Type: "error",
-			Payload: map[string]interface{}{
-				"message": err.Error(),
-			},
-		}
-		select {
-		case client.Send <- errorMsg:
-		default:
-			close(client.Send)
-		}
+	if err := req.Validate(); err != nil {
+		h.sendError(client, err.Error())
 		return
 	}
 
-	if h.IsUserOnline(uint(receiverID)) {
-		h.messageService.MarkMessageAsDelivered(message.ID)
-	}
-
-	wsPayload := &domain.WSMessagePayload{
-		MessageID:      message.ID,
-		SenderID:       message.SenderID,
-		ReceiverID:     message.ReceiverID,
-		Content:        message.Content,
-		MessageType:    message.MessageType,
-		Timestamp:      message.CreatedAt,
-		SenderName:     message.SenderName,
-		SenderUsername: message.SenderUsername,
+	message, err := h.messageService.SendMessage(ctx, client.UserID, req)
+	if err != nil {
+		pkg.RecordSpanError(trace.SpanFromContext(ctx), err)
+		h.sendError(client, err.Error())
+		return
 	}
 
-	broadcastMsg := &domain.WSMessage{
-		Type:    domain.WSMessageTypeNewMessage,
-		Payload: wsPayload,
+	// The message is delivered regardless; the flag only tells the receiver's
+	// client whether to badge or alert. Errors fail open.
+	notify, err := h.notifications.ShouldNotify(ctx, message.ReceiverID, message.SenderID, message.Content)
+	if err != nil {
+		pkg.ErrorContext(ctx, "Failed to apply notification policy", err, map[string]interface{}{
+			"message_id": message.ID,
+		})
+		notify = true
 	}
+	broadcastMsg := message.ToWSMessage(notify)
 
 	h.BroadcastMessage(broadcastMsg, uint(receiverID))
 
+	// The sender's own copy never notifies, which also keeps the receiver's
+	// preferences private.
 	confirmMsg := &domain.WSMessage{
 		Type:    "message_sent",
-		Payload: wsPayload,
+		Payload: message.ToWSMessage(false).Payload,
 	}
 	select {
 	case client.Send <- confirmMsg:
 	default:
-		close(client.Send)
+		h.dropClient(client)
 	}
 }
 
@@ -322,39 +517,77 @@ func (h *WSHub) handleStopTyping(client *wsports.WSClient, wsMsg *domain.WSMessa
 	h.BroadcastMessage(stopTypingMsg, uint(receiverID))
 }
 
-func (h *WSHub) handleMarkAsRead(client *wsports.WSClient, wsMsg *domain.WSMessage) {
+// handleMarkAsRead moves the client's read cursor, either up to message_id
+// or past the whole conversation with sender_id, and tells the peer.
+func (h *WSHub) handleMarkAsRead(ctx context.Context, client *wsports.WSClient, wsMsg *domain.WSMessage) {
 	payload, ok := wsMsg.Payload.(map[string]interface{})
 	if !ok {
 		return
 	}
 
-	senderID, ok := payload["sender_id"].(float64)
+	var cursor *domain.ReadCursor
+	var err error
+	if messageID, ok := payload["message_id"].(float64); ok {
+		cursor, err = h.messageService.MarkReadUpTo(ctx, client.UserID, uint(messageID))
+	} else if senderID, ok := payload["sender_id"].(float64); ok {
+		cursor, err = h.messageService.MarkMessagesAsRead(ctx, uint(senderID), client.UserID)
+	} else {
+		return
+	}
+	if err != nil {
+		pkg.RecordSpanError(trace.SpanFromContext(ctx), err)
+		pkg.ErrorContext(ctx, "Error marking messages as read", err, map[string]interface{}{
+			"user_id": client.UserID,
+		})
+		return
+	}
+
+	if !cursor.Private {
+		h.BroadcastMessage(cursor.ToWSMessage(), cursor.PeerID)
+	}
+}
+
+// handleAck records that the client received message_id and tells its
+// sender.
+func (h *WSHub) handleAck(ctx context.Context, client *wsports.WSClient, wsMsg *domain.WSMessage) {
+	payload, ok := wsMsg.Payload.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	messageID, ok := payload["message_id"].(float64)
 	if !ok {
 		return
 	}
 
-	err := h.messageService.MarkMessagesAsRead(uint(senderID), client.UserID)
+	message, err := h.messageService.AcknowledgeDelivery(ctx, client.UserID, uint(messageID))
 	if err != nil {
-		log.Printf("Error marking messages as read: %v", err)
+		pkg.RecordSpanError(trace.SpanFromContext(ctx), err)
+		pkg.ErrorContext(ctx, "Error acknowledging message delivery", err, map[string]interface{}{
+			"user_id":    client.UserID,
+			"message_id": uint(messageID),
+		})
 		return
 	}
 
-	readMsg := &domain.WSMessage{
-		Type: domain.WSMessageTypeMessageRead,
-		Payload: map[string]interface{}{
-			"sender_id":   uint(senderID),
-			"receiver_id": client.UserID,
+	deliveredMsg := &domain.WSMessage{
+		Type: domain.WSMessageTypeMessageDelivered,
+		Payload: &domain.WSDeliveredPayload{
+			MessageID:   message.ID,
+			SenderID:    message.SenderID,
+			ReceiverID:  message.ReceiverID,
+			DeliveredAt: message.DeliveredAt,
 		},
 	}
 
-	h.BroadcastMessage(readMsg, uint(senderID))
+	h.BroadcastMessage(deliveredMsg, message.SenderID)
 }
 
 func (h *WSHub) BroadcastMessage(message *domain.WSMessage, targetUserID uint) error {
 	h.mu.RLock()
-	client, exists := h.clients[targetUserID]
-	h.mu.RUnlock()
+	defer h.mu.RUnlock()
 
+	client, exists := h.clients[targetUserID]
 	if !exists {
 		return fmt.Errorf("user %d is not connected", targetUserID)
 	}
@@ -363,10 +596,8 @@ func (h *WSHub) BroadcastMessage(message *domain.WSMessage, targetUserID uint) e
 	case client.Send <- message:
 		return nil
 	default:
-		close(client.Send)
-		h.mu.Lock()
-		delete(h.clients, targetUserID)
-		h.mu.Unlock()
+		pkg.WSDroppedMessagesTotal.WithLabelValues(message.Type).Inc()
+		h.dropClient(client)
 		return fmt.Errorf("failed to send message to user %d", targetUserID)
 	}
 }
@@ -375,33 +606,40 @@ func (h *WSHub) BroadcastToAll(message *domain.WSMessage) error {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
-	for userID, client := range h.clients {
+	for _, client := range h.clients {
 		select {
 		case client.Send <- message:
 		default:
-			close(client.Send)
-			delete(h.clients, userID)
+			pkg.WSDroppedMessagesTotal.WithLabelValues(message.Type).Inc()
+			h.dropClient(client)
 		}
 	}
 
 	return nil
 }
 
-func (h *WSHub) DisconnectUser(userID uint) {
-	h.mu.Lock()
-	defer h.mu.Unlock()
-
-	if client, exists := h.clients[userID]; exists {
-		close(client.Send)
-		delete(h.clients, userID)
+// CloseUserConnection sends the user a policy-violation close frame carrying
+// reason, then closes the connection. The client stays listed until its
+// readPump unregisters it. Send is left to the unregister path, since the
+// client's own readPump may still be writing to it.
+func (h *WSHub) CloseUserConnection(userID uint, reason string) {
+	h.mu.RLock()
+	client, exists := h.clients[userID]
+	h.mu.RUnlock()
+	if !exists {
+		return
 	}
+
+	closeMsg := websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason)
+	client.Conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(h.cfg.WriteWait))
+	client.Conn.Close()
 }
 
 func (h *WSHub) SetUserOnline(userID uint) {
 }
 
 func (h *WSHub) SetUserOffline(userID uint) {
-	h.DisconnectUser(userID)
+	h.CloseUserConnection(userID, "")
 }
 
 func (h *WSHub) IsUserOnline(userID uint) bool {
@@ -449,21 +687,40 @@ func (h *WSHub) BroadcastStopTyping(senderID, receiverID uint) error {
 }
 
 func (h *WSHub) ServeWS(w http.ResponseWriter, r *http.Request) {
-	userID, err := pkg.ExtractUserIDFromRequest(r)
-	if err != nil {
+	h.mu.RLock()
+	closing := h.closing
+	h.mu.RUnlock()
+	if closing {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	// RequireAuth has already authenticated the request, whether with a
+	// session or an access token.
+	userID, ok := r.Context().Value("userID").(uint)
+	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	conn, err := upgrader.Upgrade(w, r, nil)
+	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		pkg.WarnContext(r.Context(), "WebSocket upgrade failed", map[string]interface{}{"error": err.Error()})
 		return
 	}
 
-	err = h.HandleConnection(conn, userID)
+	pkg.AddLogFields(r.Context(), map[string]interface{}{"user_id": userID})
+
+	// The request's route field is only valid while the request is being
+	// served, so the connection gets its own logger.
+	logger := pkg.LoggerFromContext(r.Context()).With(map[string]interface{}{
+		"route": r.URL.Path,
+	})
+
+	err = h.HandleConnection(pkg.WithLogger(r.Context(), logger), conn, userID)
 	if err != nil {
-		log.Printf("WebSocket connection error: %v", err)
+		pkg.WarnContext(r.Context(), "WebSocket connection rejected", map[string]interface{}{"error": err.Error()})
 		conn.Close()
 	}
 }
@@ -0,0 +1,230 @@
+package websocket_adapters
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-chat/config"
+	"go-chat/internal/domain"
+
+	"github.com/gorilla/websocket"
+)
+
+func testWSConfig() config.WebSocketConfig {
+	return config.WebSocketConfig{
+		ReadLimit:       512,
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		SendBufferSize:  4,
+		PongWait:        time.Minute,
+		PingPeriod:      50 * time.Second,
+		WriteWait:       time.Second,
+		MessageTimeout:  time.Second,
+	}
+}
+
+// startHub serves hub on a test server that authenticates every upgrade as
+// userID, with a token limited to scopes unless scopes is nil.
+func startHub(t *testing.T, hub *WSHub, userID uint, scopes []string) string {
+	t.Helper()
+	go hub.Run()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "userID", userID)
+		if scopes != nil {
+			ctx = context.WithValue(ctx, "authScopes", scopes)
+		}
+		hub.ServeWS(w, r.WithContext(ctx))
+	}))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		hub.Shutdown(ctx)
+		server.Close()
+	})
+
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCloseUserConnectionWhileSending(t *testing.T) {
+	hub := NewWSHub(nil, nil, testWSConfig())
+	// A read-only token gets every inbound message answered with an error
+	// written from the client's readPump.
+	url := startHub(t, hub, 1, []string{"messages:read"})
+
+	for round := 0; round < 20; round++ {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatalf("round %d: dial: %v", round, err)
+		}
+		waitFor(t, "client to register", func() bool { return hub.IsUserOnline(1) })
+
+		stop := make(chan struct{})
+		var wg sync.WaitGroup
+
+		// Broadcasts and readPump's error replies race the disconnect; the
+		// small send buffer also exercises the dropped-client path.
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				hub.BroadcastMessage(&domain.WSMessage{Type: domain.WSMessageTypeTyping}, 1)
+				hub.BroadcastToAll(&domain.WSMessage{Type: domain.WSMessageTypeUserOnline})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if err := conn.WriteJSON(&domain.WSMessage{Type: domain.WSMessageTypeTyping}); err != nil {
+					return
+				}
+			}
+		}()
+
+		var closeErr error
+		readDone := make(chan struct{})
+		go func() {
+			defer close(readDone)
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					closeErr = err
+					return
+				}
+			}
+		}()
+
+		time.Sleep(5 * time.Millisecond)
+		hub.CloseUserConnection(1, "")
+
+		<-readDone
+		close(stop)
+		wg.Wait()
+		conn.Close()
+
+		// The client may be dropped for falling behind before the close
+		// frame is written, so any close ends the round.
+		if closeErr == nil {
+			t.Fatalf("round %d: connection still open after CloseUserConnection", round)
+		}
+		waitFor(t, "client to unregister", func() bool { return !hub.IsUserOnline(1) })
+	}
+}
+
+func TestCloseUserConnectionSendsReason(t *testing.T) {
+	hub := NewWSHub(nil, nil, testWSConfig())
+	url := startHub(t, hub, 1, []string{"messages:read"})
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	waitFor(t, "client to register", func() bool { return hub.IsUserOnline(1) })
+
+	hub.CloseUserConnection(1, "account suspended")
+
+	// Skip the client's own presence broadcast.
+	for err == nil {
+		_, _, err = conn.ReadMessage()
+	}
+	if !websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+		t.Fatalf("read error = %v, want a policy-violation close", err)
+	}
+	if ce := err.(*websocket.CloseError); ce.Text != "account suspended" {
+		t.Errorf("close reason = %q, want %q", ce.Text, "account suspended")
+	}
+	waitFor(t, "client to unregister", func() bool { return !hub.IsUserOnline(1) })
+}
+
+func TestSendMessageRejectsServerTypes(t *testing.T) {
+	// The hub has no message service, so a request that got past
+	// validation would panic rather than pass.
+	hub := NewWSHub(nil, nil, testWSConfig())
+	url := startHub(t, hub, 1, nil)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	for _, messageType := range []string{domain.MessageTypeTombstone, domain.MessageTypeSystem} {
+		err := conn.WriteJSON(&domain.WSMessage{
+			Type: "send_message",
+			Payload: map[string]interface{}{
+				"receiver_id":  2,
+				"content":      domain.TombstoneContent,
+				"message_type": messageType,
+			},
+		})
+		if err != nil {
+			t.Fatalf("write: %v", err)
+		}
+
+		var reply domain.WSMessage
+		for reply.Type != "error" {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			if err := conn.ReadJSON(&reply); err != nil {
+				t.Fatalf("%s: read: %v", messageType, err)
+			}
+		}
+		payload, _ := reply.Payload.(map[string]interface{})
+		if want := `unsupported message_type "` + messageType + `"`; payload["message"] != want {
+			t.Errorf("%s: error = %v, want %q", messageType, payload["message"], want)
+		}
+	}
+}
+
+func TestOriginChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		origin  string
+		want    bool
+	}{
+		{name: "same origin with nothing allowed", origin: "http://chat.example.com", want: true},
+		{name: "cross origin with nothing allowed", origin: "https://evil.example", want: false},
+		{name: "no origin with nothing allowed", want: true},
+		{name: "listed origin", allowed: []string{"https://app.example.com"}, origin: "https://app.example.com", want: true},
+		{name: "unlisted origin", allowed: []string{"https://app.example.com"}, origin: "https://evil.example", want: false},
+		{name: "wildcard", allowed: []string{"*"}, origin: "https://evil.example", want: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://chat.example.com/ws", nil)
+			if tc.origin != "" {
+				r.Header.Set("Origin", tc.origin)
+			}
+			if got := originChecker(tc.allowed)(r); got != tc.want {
+				t.Errorf("originChecker(%v) for %q = %v, want %v", tc.allowed, tc.origin, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditLogin               AuditAction = "auth.login"
+	AuditLoginFailed         AuditAction = "auth.login_failed"
+	AuditLogout              AuditAction = "auth.logout"
+	AuditOAuthLogin          AuditAction = "auth.oauth_login"
+	AuditProviderLinked      AuditAction = "auth.provider_linked"
+	AuditProviderUnlinked    AuditAction = "auth.provider_unlinked"
+	AuditPasswordChanged     AuditAction = "user.password_changed"
+	AuditProfileUpdated      AuditAction = "user.profile_updated"
+	AuditEmailChanged        AuditAction = "user.email_changed"
+	AuditDataExported        AuditAction = "user.data_exported"
+	AuditDeletionRequested   AuditAction = "user.deletion_requested"
+	AuditTokenCreated        AuditAction = "token.created"
+	AuditTokenRevoked        AuditAction = "token.revoked"
+	AuditUserBlocked         AuditAction = "friends.user_blocked"
+	AuditUserSuspended       AuditAction = "admin.user_suspended"
+	AuditUserReinstated      AuditAction = "admin.user_reinstated"
+	AuditPasswordResetForced AuditAction = "admin.password_reset_forced"
+	AuditRoleChanged         AuditAction = "admin.role_changed"
+	AuditSessionsRevoked     AuditAction = "admin.sessions_revoked"
+	AuditMessageModerated    AuditAction = "admin.message_deleted"
+	AuditLoggingChanged      AuditAction = "admin.logging_changed"
+)
+
+// AuditEntry is one append-only record. Each entry's Hash covers its own
+// fields plus the previous entry's hash, so editing or removing any row
+// breaks every hash after it.
+type AuditEntry struct {
+	ID           uint        `json:"id" gorm:"primaryKey"`
+	Action       AuditAction `json:"action" gorm:"not null;index"`
+	ActorID      *uint       `json:"actor_id,omitempty" gorm:"index"`
+	TargetUserID *uint       `json:"target_user_id,omitempty" gorm:"index"`
+	TargetType   string      `json:"target_type,omitempty"`
+	TargetID     string      `json:"target_id,omitempty"`
+	Success      bool        `json:"success"`
+	IPAddress    string      `json:"ip_address,omitempty"`
+	UserAgent    string      `json:"user_agent,omitempty"`
+	RequestID    string      `json:"request_id,omitempty"`
+	Metadata     string      `json:"metadata,omitempty" gorm:"type:text"`
+	PrevHash     string      `json:"prev_hash" gorm:"size:64;not null"`
+	Hash         string      `json:"hash" gorm:"size:64;not null;uniqueIndex"`
+	CreatedAt    time.Time   `json:"created_at" gorm:"index"`
+}
+
+func (AuditEntry) TableName() string {
+	return "audit_logs"
+}
+
+func (e *AuditEntry) ComputeHash() string {
+	optional := func(id *uint) string {
+		if id == nil {
+			return ""
+		}
+		return fmt.Sprint(*id)
+	}
+
+	payload := strings.Join([]string{
+		e.PrevHash,
+		string(e.Action),
+		optional(e.ActorID),
+		optional(e.TargetUserID),
+		e.TargetType,
+		e.TargetID,
+		fmt.Sprint(e.Success),
+		e.IPAddress,
+		e.UserAgent,
+		e.RequestID,
+		e.Metadata,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}, "\x1f")
+
+	sum := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditContext carries who did something and from where, as seen by the
+// HTTP layer.
+type AuditContext struct {
+	ActorID   uint
+	IPAddress string
+	UserAgent string
+	RequestID string
+}
+
+type AuditFilter struct {
+	Action       AuditAction
+	ActorID      uint
+	TargetUserID uint
+	Since        *time.Time
+	Until        *time.Time
+}
+
+type AuditVerification struct {
+	Valid          bool   `json:"valid"`
+	EntriesChecked int    `json:"entries_checked"`
+	BrokenAtID     uint   `json:"broken_at_id,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// AuditEvent describes what happened; AuditContext describes who and where.
+type AuditEvent struct {
+	Action       AuditAction
+	TargetUserID uint
+	TargetType   string
+	TargetID     string
+	Failed       bool
+	Metadata     map[string]interface{}
+}
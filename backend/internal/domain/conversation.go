@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ConversationSummary is one user's view of a direct conversation. It is kept
+// up to date as messages are sent, read and deleted, so conversation lists
+// are served without aggregating the messages table.
+type ConversationSummary struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"not null"`
+	PeerID         uint      `json:"peer_id" gorm:"not null"`
+	LastMessageID  *uint     `json:"last_message_id"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	UnreadCount    int       `json:"unread_count" gorm:"not null;default:0"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// LastReadMessageID is the user's read cursor: every message from the
+	// peer with an ID up to and including it has been read.
+	LastReadMessageID *uint      `json:"last_read_message_id"`
+	LastReadAt        *time.Time `json:"last_read_at"`
+	// PeerLastReadMessageID is the peer's read cursor, loaded alongside the
+	// summary when listing conversations.
+	PeerLastReadMessageID *uint `json:"peer_last_read_message_id" gorm:"->"`
+	// DisappearAfterSeconds is the conversation's disappearing-message timer,
+	// also loaded when listing conversations.
+	DisappearAfterSeconds int `json:"disappear_after_seconds" gorm:"->"`
+
+	// ArchivedAt hides the conversation from the user's main list until the
+	// next message in it; PinnedAt keeps it above unpinned ones.
+	ArchivedAt *time.Time `json:"archived_at"`
+	PinnedAt   *time.Time `json:"pinned_at"`
+	// MarkedUnread is set by the user to come back to the conversation and
+	// cleared when they next read it.
+	MarkedUnread bool `json:"marked_unread" gorm:"not null;default:false"`
+	// MutedAt silences notifications from the conversation until MutedUntil,
+	// or for good when that is nil.
+	MutedAt    *time.Time `json:"muted_at"`
+	MutedUntil *time.Time `json:"muted_until"`
+
+	Peer        User     `json:"peer,omitempty" gorm:"foreignKey:PeerID"`
+	LastMessage *Message `json:"last_message,omitempty" gorm:"foreignKey:LastMessageID"`
+}
+
+// ToResponse expects Peer, LastMessage with its sender,
+// PeerLastReadMessageID and DisappearAfterSeconds to be loaded.
+func (c *ConversationSummary) ToResponse() *ConversationResponse {
+	conv := &ConversationResponse{
+		UserID:                c.PeerID,
+		Username:              c.Peer.Email,
+		FullName:              c.Peer.Name,
+		UnreadCount:           c.UnreadCount,
+		LastActivityAt:        c.LastActivityAt,
+		LastReadMessageID:     c.LastReadMessageID,
+		PeerLastReadMessageID: c.PeerLastReadMessageID,
+		ArchivedAt:            c.ArchivedAt,
+		PinnedAt:              c.PinnedAt,
+		MarkedUnread:          c.MarkedUnread,
+		Muted:                 c.IsMuted(time.Now()),
+		DisappearAfterSeconds: c.DisappearAfterSeconds,
+	}
+	if conv.Muted {
+		conv.MutedUntil = c.MutedUntil
+	}
+	// Peers who don't send read receipts keep their cursor to themselves.
+	if c.Peer.ReadReceiptsDisabled {
+		conv.PeerLastReadMessageID = nil
+	}
+	if c.LastMessage != nil {
+		conv.LastMessage = c.LastMessage.ToResponse()
+		// Whoever received the last message, their cursor says if it was read.
+		cursor := conv.PeerLastReadMessageID
+		if c.LastMessage.SenderID == c.PeerID {
+			cursor = c.LastReadMessageID
+		}
+		conv.LastMessage.IsRead = cursor != nil && c.LastMessage.ID <= *cursor
+	}
+	return conv
+}
+
+// IsMuted reports whether the conversation is muted at t.
+func (c *ConversationSummary) IsMuted(t time.Time) bool {
+	return c.MutedAt != nil && (c.MutedUntil == nil || t.Before(*c.MutedUntil))
+}
+
+// Settings returns the user's settings for the conversation.
+func (c *ConversationSummary) Settings() *ConversationSettings {
+	archived := c.ArchivedAt != nil
+	pinned := c.PinnedAt != nil
+	markedUnread := c.MarkedUnread
+	muted := c.IsMuted(time.Now())
+	settings := &ConversationSettings{
+		PeerID:       c.PeerID,
+		Archived:     &archived,
+		Pinned:       &pinned,
+		MarkedUnread: &markedUnread,
+		Muted:        &muted,
+	}
+	if muted {
+		settings.MutedUntil = c.MutedUntil
+	}
+	return settings
+}
+
+// ConversationSettings is one user's organisation of a conversation. In
+// updates, nil fields are left unchanged; muting without MutedUntil mutes
+// until unmuted.
+type ConversationSettings struct {
+	PeerID       uint       `json:"peer_id"`
+	Archived     *bool      `json:"archived,omitempty"`
+	Pinned       *bool      `json:"pinned,omitempty"`
+	MarkedUnread *bool      `json:"marked_unread,omitempty"`
+	Muted        *bool      `json:"muted,omitempty"`
+	MutedUntil   *time.Time `json:"muted_until,omitempty"`
+}
+
+func (s *ConversationSettings) Validate() error {
+	if s.Muted != nil && *s.Muted && s.MutedUntil != nil && !s.MutedUntil.After(time.Now()) {
+		return errors.New("muted_until must be in the future")
+	}
+	return nil
+}
+
+func (c *ConversationSummary) ReadCursor() *ReadCursor {
+	return &ReadCursor{
+		ReaderID:          c.UserID,
+		PeerID:            c.PeerID,
+		LastReadMessageID: c.LastReadMessageID,
+		LastReadAt:        c.LastReadAt,
+		UnreadCount:       c.UnreadCount,
+	}
+}
+
+// ReadCursor is how far a reader has got in a conversation with a peer.
+type ReadCursor struct {
+	ReaderID          uint       `json:"reader_id"`
+	PeerID            uint       `json:"peer_id"`
+	LastReadMessageID *uint      `json:"last_read_message_id"`
+	LastReadAt        *time.Time `json:"last_read_at"`
+	UnreadCount       int        `json:"unread_count"`
+
+	// Private is set when the reader does not send read receipts, in which
+	// case the peer must not be told about the cursor.
+	Private bool `json:"-"`
+}
+
+// ToWSMessage builds the message_read event sent to the peer, whose messages
+// the cursor covers.
+func (c *ReadCursor) ToWSMessage() *WSMessage {
+	return &WSMessage{
+		Type: WSMessageTypeMessageRead,
+		Payload: &WSReadPayload{
+			SenderID:          c.PeerID,
+			ReceiverID:        c.ReaderID,
+			LastReadMessageID: c.LastReadMessageID,
+			ReadAt:            c.LastReadAt,
+		},
+	}
+}
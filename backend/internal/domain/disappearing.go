@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	MinDisappearAfter = time.Minute
+	MaxDisappearAfter = 4 * 7 * 24 * time.Hour
+)
+
+// ConversationTimer is the disappearing-message timer of a direct
+// conversation. Both participants share it, so it is keyed by the pair, with
+// UserLowID the smaller of the two IDs.
+type ConversationTimer struct {
+	ID                    uint      `json:"-" gorm:"primaryKey"`
+	UserLowID             uint      `json:"-" gorm:"not null"`
+	UserHighID            uint      `json:"-" gorm:"not null"`
+	DisappearAfterSeconds int       `json:"disappear_after_seconds" gorm:"not null;default:0"`
+	SetByID               uint      `json:"set_by_id" gorm:"not null"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// DisappearingSettings sets how long messages in the conversation with
+// PeerID last after they are sent; zero turns disappearing messages off.
+type DisappearingSettings struct {
+	PeerID                uint `json:"peer_id"`
+	DisappearAfterSeconds int  `json:"disappear_after_seconds"`
+}
+
+func (s *DisappearingSettings) Validate() error {
+	if s.DisappearAfterSeconds == 0 {
+		return nil
+	}
+	after := s.DisappearAfter()
+	if after < MinDisappearAfter || after > MaxDisappearAfter {
+		return errors.New("disappear_after_seconds must be 0 or between 1 minute and 4 weeks")
+	}
+	return nil
+}
+
+func (s *DisappearingSettings) DisappearAfter() time.Duration {
+	return time.Duration(s.DisappearAfterSeconds) * time.Second
+}
+
+// TimerChangedContent is the system message recording that name set the
+// conversation's timer to after, or turned it off when after is zero.
+func TimerChangedContent(name string, after time.Duration) string {
+	if after == 0 {
+		return fmt.Sprintf("%s turned off disappearing messages", name)
+	}
+	return fmt.Sprintf("%s set disappearing messages to %s", name, describeDuration(after))
+}
+
+// describeDuration writes d in the largest unit that divides it evenly, such
+// as "1 hour" or "3 days".
+func describeDuration(d time.Duration) string {
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"week", 7 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+		{"second", time.Second},
+	}
+	for _, unit := range units {
+		if d%unit.size != 0 {
+			continue
+		}
+		n := int64(d / unit.size)
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit.name)
+		}
+		return fmt.Sprintf("%d %ss", n, unit.name)
+	}
+	return d.String()
+}
+
+// WSExpiredPayload tells a participant which of their messages disappeared,
+// so their client drops them.
+type WSExpiredPayload struct {
+	MessageIDs []uint `json:"message_ids"`
+}
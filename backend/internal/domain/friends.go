@@ -16,6 +16,9 @@ type Friendship struct {
 	RequesterID uint             `json:"requester_id"`
 	AddresseeID uint             `json:"addressee_id"`
 	Status      FriendshipStatus `json:"status"`
+	// BlockedByID is the user who blocked the other, whichever of the two
+	// sent the original request.
+	BlockedByID *uint            `json:"blocked_by_id,omitempty"`
 	CreatedAt   time.Time        `json:"created_at"`
 	UpdatedAt   time.Time        `json:"updated_at"`
 	
@@ -23,6 +26,11 @@ type Friendship struct {
 	Addressee *User `json:"addressee,omitempty"`
 }
 
+// IsBlockedBy reports whether userID has blocked the other user.
+func (f *Friendship) IsBlockedBy(userID uint) bool {
+	return f.Status == FriendshipBlocked && f.BlockedByID != nil && *f.BlockedByID == userID
+}
+
 type FriendRequest struct {
 	UserID uint `json:"user_id"`
 }
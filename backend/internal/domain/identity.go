@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Provider  string    `json:"provider" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject   string    `json:"-" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OAuthState holds the per-attempt secrets of an authorization code flow
+// between the redirect to the provider and the callback.
+type OAuthState struct {
+	State        string    `json:"-" gorm:"primaryKey"`
+	Provider     string    `json:"-" gorm:"not null"`
+	Nonce        string    `json:"-" gorm:"not null"`
+	CodeVerifier string    `json:"-" gorm:"not null"`
+	LinkUserID   uint      `json:"-"`
+	ExpiresAt    time.Time `json:"-" gorm:"not null;index"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+type OAuthProviderResponse struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+}
@@ -1,6 +1,9 @@
 package domain
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"gorm.io/gorm"
@@ -12,20 +15,89 @@ type Message struct {
 	ReceiverID   uint      `json:"receiver_id" gorm:"not null"`
 	Content      string    `json:"content" gorm:"type:text;not null"`
 	MessageType  string    `json:"message_type" gorm:"default:'text'"`
-	IsRead       bool      `json:"is_read" gorm:"default:false"`
-	IsDelivered  bool      `json:"is_delivered" gorm:"default:false"`
+	IsRead       bool      `json:"is_read" gorm:"->"` // derived from the receiver's read cursor
+	IsDelivered  bool      `json:"is_delivered" gorm:"->"` // derived from the receiver's receipt
+	DeliveredAt  *time.Time `json:"delivered_at,omitempty" gorm:"->"`
+	ReadAt       *time.Time `json:"read_at,omitempty" gorm:"->"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+	// ExpiresAt is when a disappearing message is purged for good.
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" gorm:"index"`
+	// PinnedAt, PinnedByID and StarredAt are only loaded when listing
+	// pinned or starred messages.
+	PinnedAt     *time.Time `json:"pinned_at,omitempty" gorm:"->"`
+	PinnedByID   *uint      `json:"pinned_by_id,omitempty" gorm:"->"`
+	StarredAt    *time.Time `json:"starred_at,omitempty" gorm:"->"`
 
 	Sender   User `json:"sender,omitempty" gorm:"foreignKey:SenderID"`
 	Receiver User `json:"receiver,omitempty" gorm:"foreignKey:ReceiverID"`
+
+	// Receipts are created along with the message, one per recipient.
+	Receipts []MessageReceipt `json:"-" gorm:"foreignKey:MessageID"`
+}
+
+// MessageReceipt records when one recipient's client confirmed delivery of a
+// message and when the recipient read it. ReadAt stays nil for recipients
+// who do not send read receipts.
+type MessageReceipt struct {
+	ID          uint       `json:"-" gorm:"primaryKey"`
+	MessageID   uint       `json:"message_id" gorm:"not null"`
+	UserID      uint       `json:"user_id" gorm:"not null"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+	ReadAt      *time.Time `json:"read_at"`
+	CreatedAt   time.Time  `json:"-"`
+	UpdatedAt   time.Time  `json:"-"`
+}
+
+// HiddenMessage marks a message one participant deleted for themselves; it
+// stays visible to the other.
+type HiddenMessage struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	MessageID uint      `json:"message_id" gorm:"not null"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PinnedMessage pins a message to the top of its conversation for both
+// participants.
+type PinnedMessage struct {
+	ID         uint      `json:"-" gorm:"primaryKey"`
+	MessageID  uint      `json:"message_id" gorm:"not null"`
+	PinnedByID uint      `json:"pinned_by_id" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// StarredMessage marks a message one participant starred for themselves.
+type StarredMessage struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	MessageID uint      `json:"message_id" gorm:"not null"`
+	UserID    uint      `json:"user_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type MessageRequest struct {
 	ReceiverID  uint   `json:"receiver_id" binding:"required"`
 	Content     string `json:"content" binding:"required"`
 	MessageType string `json:"message_type,omitempty"`
+	// SendAt schedules the message for later instead of sending it now.
+	SendAt *time.Time `json:"send_at,omitempty"`
+}
+
+// ClientMessageTypes are the message types clients may send. System notices
+// and tombstones are only ever created by the server.
+var ClientMessageTypes = []string{MessageTypeText}
+
+// Validate checks a message request from a client. An empty MessageType
+// defaults to text.
+func (r *MessageRequest) Validate() error {
+	if r.ReceiverID == 0 || r.Content == "" {
+		return errors.New("receiver_id and content are required")
+	}
+	if r.MessageType != "" && !slices.Contains(ClientMessageTypes, r.MessageType) {
+		return fmt.Errorf("unsupported message_type %q", r.MessageType)
+	}
+	return nil
 }
 
 type MessageResponse struct {
@@ -36,19 +108,56 @@ type MessageResponse struct {
 	MessageType string    `json:"message_type"`
 	IsRead      bool      `json:"is_read"`
 	IsDelivered bool      `json:"is_delivered"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	PinnedAt    *time.Time `json:"pinned_at,omitempty"`
+	PinnedByID  *uint     `json:"pinned_by_id,omitempty"`
+	StarredAt   *time.Time `json:"starred_at,omitempty"`
 	
 	SenderName     string `json:"sender_name,omitempty"`
 	SenderUsername string `json:"sender_username,omitempty"`
 }
 
+func (m *Message) ToResponse() *MessageResponse {
+	return &MessageResponse{
+		ID:             m.ID,
+		SenderID:       m.SenderID,
+		ReceiverID:     m.ReceiverID,
+		Content:        m.Content,
+		MessageType:    m.MessageType,
+		IsRead:         m.IsRead,
+		IsDelivered:    m.IsDelivered,
+		DeliveredAt:    m.DeliveredAt,
+		ReadAt:         m.ReadAt,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+		ExpiresAt:      m.ExpiresAt,
+		PinnedAt:       m.PinnedAt,
+		PinnedByID:     m.PinnedByID,
+		StarredAt:      m.StarredAt,
+		SenderName:     m.Sender.Name,
+		SenderUsername: m.Sender.Email,
+	}
+}
+
 type ConversationResponse struct {
 	UserID       uint      `json:"user_id"`
 	Username     string    `json:"username"`
 	FullName     string    `json:"full_name"`
 	LastMessage  *MessageResponse `json:"last_message,omitempty"`
 	UnreadCount  int       `json:"unread_count"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	LastReadMessageID *uint `json:"last_read_message_id"`
+	PeerLastReadMessageID *uint `json:"peer_last_read_message_id"`
+	ArchivedAt *time.Time `json:"archived_at"`
+	PinnedAt *time.Time `json:"pinned_at"`
+	MarkedUnread bool `json:"marked_unread"`
+	Muted bool `json:"muted"`
+	MutedUntil *time.Time `json:"muted_until,omitempty"`
+	DisappearAfterSeconds int `json:"disappear_after_seconds"`
 }
 
 type WSMessage struct {
@@ -65,11 +174,99 @@ type WSMessagePayload struct {
 	Timestamp   time.Time `json:"timestamp"`
 	SenderName  string `json:"sender_name"`
 	SenderUsername string `json:"sender_username"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// Notify is false when the receiver's notification preferences say the
+	// message should arrive silently, without a badge or alert.
+	Notify bool `json:"notify"`
+}
+
+// ToWSMessage builds the new_message event sent to the receiver.
+func (m *MessageResponse) ToWSMessage(notify bool) *WSMessage {
+	return &WSMessage{
+		Type: WSMessageTypeNewMessage,
+		Payload: &WSMessagePayload{
+			MessageID:      m.ID,
+			SenderID:       m.SenderID,
+			ReceiverID:     m.ReceiverID,
+			Content:        m.Content,
+			MessageType:    m.MessageType,
+			Timestamp:      m.CreatedAt,
+			SenderName:     m.SenderName,
+			SenderUsername: m.SenderUsername,
+			ExpiresAt:      m.ExpiresAt,
+			Notify:         notify,
+		},
+	}
+}
+
+// WSReadPayload tells a sender how far the receiver has read their
+// conversation. LastReadMessageID is nil when nothing has been read.
+type WSReadPayload struct {
+	SenderID          uint       `json:"sender_id"`
+	ReceiverID        uint       `json:"receiver_id"`
+	LastReadMessageID *uint      `json:"last_read_message_id"`
+	ReadAt            *time.Time `json:"read_at"`
+}
+
+// WSDeliveredPayload tells a sender that the receiver's client has
+// acknowledged a message.
+type WSDeliveredPayload struct {
+	MessageID   uint       `json:"message_id"`
+	SenderID    uint       `json:"sender_id"`
+	ReceiverID  uint       `json:"receiver_id"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+}
+
+// WSDeletedPayload tells the other participant that a message was deleted
+// for everyone and now reads as a tombstone, or both participants that a
+// moderator removed it.
+type WSDeletedPayload struct {
+	MessageID  uint `json:"message_id"`
+	SenderID   uint `json:"sender_id"`
+	ReceiverID uint `json:"receiver_id"`
 }
 
+// WSPinnedPayload tells the other participant that a message was pinned or
+// unpinned in their conversation.
+type WSPinnedPayload struct {
+	MessageID  uint       `json:"message_id"`
+	SenderID   uint       `json:"sender_id"`
+	ReceiverID uint       `json:"receiver_id"`
+	PinnedByID uint       `json:"pinned_by_id"`
+	PinnedAt   *time.Time `json:"pinned_at,omitempty"`
+}
+
+// ToWSMessage builds the message_pinned event, or message_unpinned when
+// PinnedAt is nil.
+func (p *WSPinnedPayload) ToWSMessage() *WSMessage {
+	messageType := WSMessageTypeMessagePinned
+	if p.PinnedAt == nil {
+		messageType = WSMessageTypeMessageUnpinned
+	}
+	return &WSMessage{Type: messageType, Payload: p}
+}
+
+// MessageTypeTombstone replaces the content of messages deleted for everyone,
+// or whose sender has deleted their account, so the other side still sees a
+// placeholder. MessageTypeSystem marks messages the server posts into a
+// conversation, such as changes to its disappearing-message timer.
+const (
+	MessageTypeText      = "text"
+	MessageTypeTombstone = "tombstone"
+	MessageTypeSystem    = "system"
+
+	TombstoneContent = "This message was deleted"
+)
+
 const (
 	WSMessageTypeNewMessage    = "new_message"
 	WSMessageTypeMessageRead   = "message_read"
+	WSMessageTypeMessageDelivered = "message_delivered"
+	WSMessageTypeMessageDeleted = "message_deleted"
+	WSMessageTypeScheduledMessageSent = "scheduled_message_sent"
+	WSMessageTypeMessagesExpired = "messages_expired"
+	WSMessageTypeMessagePinned = "message_pinned"
+	WSMessageTypeMessageUnpinned = "message_unpinned"
 	WSMessageTypeTyping        = "typing"
 	WSMessageTypeStopTyping    = "stop_typing"
 	WSMessageTypeUserOnline    = "user_online"
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+type NotificationLevel string
+
+const (
+	NotificationLevelAll      NotificationLevel = "all"
+	NotificationLevelMentions NotificationLevel = "mentions"
+	NotificationLevelNone     NotificationLevel = "none"
+)
+
+func (l NotificationLevel) IsValid() bool {
+	switch l {
+	case NotificationLevelAll, NotificationLevelMentions, NotificationLevelNone:
+		return true
+	}
+	return false
+}
+
+// quietHoursLayout is the wall-clock format of quiet hour boundaries.
+const quietHoursLayout = "15:04"
+
+// NotificationPreferences decide which messages notify the user across all
+// channels. Quiet hours run from QuietHoursStart to QuietHoursEnd in the
+// user's timezone, wrapping past midnight when the start is the later time;
+// they are off when both are empty.
+type NotificationPreferences struct {
+	Level           NotificationLevel `json:"level" gorm:"column:notification_level;not null;default:'all'"`
+	QuietHoursStart string            `json:"quiet_hours_start" gorm:"column:quiet_hours_start;not null;default:''"`
+	QuietHoursEnd   string            `json:"quiet_hours_end" gorm:"column:quiet_hours_end;not null;default:''"`
+	Timezone        string            `json:"timezone" gorm:"column:timezone;not null;default:'UTC'"`
+}
+
+func (p NotificationPreferences) Validate() error {
+	if p.Level != "" && !p.Level.IsValid() {
+		return errors.New("notification level must be all, mentions or none")
+	}
+	if (p.QuietHoursStart == "") != (p.QuietHoursEnd == "") {
+		return errors.New("quiet hours need both a start and an end")
+	}
+	if p.QuietHoursStart != "" {
+		start, err := time.Parse(quietHoursLayout, p.QuietHoursStart)
+		if err != nil {
+			return errors.New("quiet hours must be given as HH:MM")
+		}
+		end, err := time.Parse(quietHoursLayout, p.QuietHoursEnd)
+		if err != nil {
+			return errors.New("quiet hours must be given as HH:MM")
+		}
+		if start.Equal(end) {
+			return errors.New("quiet hours must start and end at different times")
+		}
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return errors.New("unknown timezone")
+	}
+	return nil
+}
+
+// EffectiveLevel treats an unset level, as on users created before the
+// preferences existed, as notifying about everything.
+func (p NotificationPreferences) EffectiveLevel() NotificationLevel {
+	if p.Level == "" {
+		return NotificationLevelAll
+	}
+	return p.Level
+}
+
+// InQuietHours reports whether t falls in the quiet hours, in the user's
+// timezone. Unknown timezones fall back to UTC.
+func (p NotificationPreferences) InQuietHours(t time.Time) bool {
+	start, err := time.Parse(quietHoursLayout, p.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse(quietHoursLayout, p.QuietHoursEnd)
+	if err != nil {
+		return false
+	}
+
+	if location, err := time.LoadLocation(p.Timezone); err == nil {
+		t = t.In(location)
+	} else {
+		t = t.UTC()
+	}
+	minutes := func(t time.Time) int { return t.Hour()*60 + t.Minute() }
+	now, from, to := minutes(t), minutes(start), minutes(end)
+	if from < to {
+		return now >= from && now < to
+	}
+	return now >= from || now < to
+}
+
+// MentionedIn reports whether content mentions the user as @name or @email.
+func (u *User) MentionedIn(content string) bool {
+	content = strings.ToLower(content)
+	for _, handle := range []string{u.Name, u.Email} {
+		if handle != "" && strings.Contains(content, "@"+strings.ToLower(handle)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+type ScheduledMessageStatus string
+
+const (
+	ScheduledMessagePending   ScheduledMessageStatus = "pending"
+	ScheduledMessageSent      ScheduledMessageStatus = "sent"
+	ScheduledMessageCancelled ScheduledMessageStatus = "cancelled"
+	ScheduledMessageFailed    ScheduledMessageStatus = "failed"
+)
+
+// ScheduledMessage is a message composed now to be sent at SendAt. It stays
+// pending until the scheduler sends it, the sender cancels it, or sending
+// has failed too many times.
+type ScheduledMessage struct {
+	ID          uint                   `json:"id" gorm:"primaryKey"`
+	SenderID    uint                   `json:"sender_id" gorm:"not null"`
+	ReceiverID  uint                   `json:"receiver_id" gorm:"not null"`
+	Content     string                 `json:"content" gorm:"type:text;not null"`
+	MessageType string                 `json:"message_type" gorm:"default:'text'"`
+	SendAt      time.Time              `json:"send_at" gorm:"not null"`
+	Status      ScheduledMessageStatus `json:"status" gorm:"not null;default:'pending'"`
+	Attempts    int                    `json:"attempts" gorm:"not null;default:0"`
+	LastError   string                 `json:"last_error,omitempty" gorm:"not null;default:''"`
+	SentAt      *time.Time             `json:"sent_at,omitempty"`
+	CancelledAt *time.Time             `json:"cancelled_at,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// Request rebuilds the request the message is sent with.
+func (m *ScheduledMessage) Request() *MessageRequest {
+	return &MessageRequest{
+		ReceiverID:  m.ReceiverID,
+		Content:     m.Content,
+		MessageType: m.MessageType,
+	}
+}
+
+// UpdateScheduledMessageRequest edits a pending scheduled message; omitted
+// fields are left unchanged.
+type UpdateScheduledMessageRequest struct {
+	Content *string    `json:"content,omitempty"`
+	SendAt  *time.Time `json:"send_at,omitempty"`
+}
+
+func (r *UpdateScheduledMessageRequest) Validate(now time.Time) error {
+	if r.Content != nil && *r.Content == "" {
+		return errors.New("content cannot be empty")
+	}
+	if r.SendAt != nil && !r.SendAt.After(now) {
+		return errors.New("send_at must be in the future")
+	}
+	return nil
+}
+
+// WSScheduledSentPayload tells the sender that a scheduled message went out
+// and which message it became.
+type WSScheduledSentPayload struct {
+	ScheduledMessageID uint             `json:"scheduled_message_id"`
+	Message            *MessageResponse `json:"message"`
+}
@@ -5,34 +5,116 @@ import (
 	"gorm.io/gorm"
 )
 
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleUser:      0,
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+func (r Role) IsValid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// AtLeast reports whether r grants every permission of min.
+func (r Role) AtLeast(min Role) bool {
+	return roleRank[r] >= roleRank[min]
+}
+
 type User struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
 	Name      string         `json:"name" gorm:"not null"`
 	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
 	Password  string         `json:"-" gorm:"not null"`
+	IsBot     bool           `json:"is_bot" gorm:"default:false"`
+	OwnerID   *uint          `json:"owner_id,omitempty" gorm:"index"`
+	Role      Role           `json:"role" gorm:"not null;default:'user'"`
+
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	SuspensionReason      string     `json:"suspension_reason,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required" gorm:"default:false"`
+
+	// ReadReceiptsDisabled stops the user's reads being reported to senders.
+	ReadReceiptsDisabled bool `json:"read_receipts_disabled" gorm:"not null;default:false"`
+	Notifications        NotificationPreferences `json:"-" gorm:"embedded"`
+
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty" gorm:"index"`
+	AnonymizedAt        *time.Time `json:"-"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
+func (u *User) EffectiveRole() Role {
+	if u.Role == "" {
+		return RoleUser
+	}
+	return u.Role
+}
+
 type UserResponse struct {
 	ID        uint      `json:"id"`
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
+	IsBot     bool      `json:"is_bot,omitempty"`
+	Role      Role      `json:"role"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type AdminUserResponse struct {
+	UserResponse
+	OwnerID               *uint      `json:"owner_id,omitempty"`
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty"`
+	SuspensionReason      string     `json:"suspension_reason,omitempty"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+}
+
 func (u *User) ToResponse() *UserResponse {
 	return &UserResponse{
 		ID:        u.ID,
 		Name:      u.Name,
 		Email:     u.Email,
+		IsBot:     u.IsBot,
+		Role:      u.EffectiveRole(),
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
 	}
 }
 
+func (u *User) Settings() *UserSettings {
+	disabled := u.ReadReceiptsDisabled
+	notifications := u.Notifications
+	notifications.Level = notifications.EffectiveLevel()
+	if notifications.Timezone == "" {
+		notifications.Timezone = "UTC"
+	}
+	return &UserSettings{ReadReceiptsDisabled: &disabled, Notifications: &notifications}
+}
+
+func (u *User) ToAdminResponse() *AdminUserResponse {
+	return &AdminUserResponse{
+		UserResponse:          *u.ToResponse(),
+		OwnerID:               u.OwnerID,
+		SuspendedAt:           u.SuspendedAt,
+		SuspensionReason:      u.SuspensionReason,
+		PasswordResetRequired: u.PasswordResetRequired,
+	}
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
@@ -49,7 +131,58 @@ type UpdateProfileRequest struct {
 	Email string `json:"email" binding:"required,email"`
 }
 
+// UserSettings are the preferences a user manages for themselves. In an
+// update, omitted fields are left unchanged.
+type UserSettings struct {
+	ReadReceiptsDisabled *bool `json:"read_receipts_disabled"`
+	// Notifications replaces all of the user's notification preferences.
+	Notifications *NotificationPreferences `json:"notifications,omitempty"`
+}
+
+func (s *UserSettings) Validate() error {
+	if s.Notifications != nil {
+		return s.Notifications.Validate()
+	}
+	return nil
+}
+
+// ChangePasswordRequest omits CurrentPassword when the user has no password
+// yet, e.g. after signing up through social login.
 type ChangePasswordRequest struct {
-	CurrentPassword string `json:"current_password" binding:"required"`
+	CurrentPassword string `json:"current_password"`
 	NewPassword     string `json:"new_password" binding:"required,min=6"`
 }
+
+type SuspendUserRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type UpdateRoleRequest struct {
+	Role Role `json:"role" binding:"required"`
+}
+
+type ModerationRequest struct {
+	Reason string `json:"reason"`
+}
+
+// LoggingSettings is the runtime log configuration exposed to admins. Empty
+// fields in an update leave the current value unchanged.
+type LoggingSettings struct {
+	Level  string `json:"level"`
+	Format string `json:"format"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+	Confirm  string `json:"confirm"`
+}
+
+// AccountExport is the personal data archive served by the export endpoint.
+type AccountExport struct {
+	ExportedAt       time.Time          `json:"exported_at"`
+	Profile          *UserResponse      `json:"profile"`
+	Identities       []*UserIdentity    `json:"identities"`
+	Friendships      []*Friendship      `json:"friendships"`
+	MessagesSent     []*MessageResponse `json:"messages_sent"`
+	MessagesReceived []*MessageResponse `json:"messages_received"`
+}
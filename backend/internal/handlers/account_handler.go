@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/middlerware"
+	"go-chat/internal/service"
+	"go-chat/pkg"
+)
+
+type AccountHandler struct {
+	accountService *service.AccountService
+	auditService   *service.AuditService
+	cookies        pkg.CookieSettings
+}
+
+func NewAccountHandler(as *service.AccountService, audit *service.AuditService, cookies pkg.CookieSettings) *AccountHandler {
+	return &AccountHandler{
+		accountService: as,
+		auditService:   audit,
+		cookies:        cookies,
+	}
+}
+
+func (h *AccountHandler) ExportDataHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	export, err := h.accountService.ExportData(r.Context(), userID)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditDataExported,
+		TargetUserID: userID,
+	})
+
+	filename := fmt.Sprintf("go-chat-export-%d-%s.json", userID, export.ExportedAt.Format("20060102"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(export)
+}
+
+func (h *AccountHandler) DeleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req domain.DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	purgeAt, err := h.accountService.RequestDeletion(r.Context(), userID, &req)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditDeletionRequested,
+		TargetUserID: userID,
+		Metadata:     map[string]interface{}{"purge_at": purgeAt.UTC().Format(time.RFC3339)},
+	})
+
+	h.cookies.ClearTokenCookies(w)
+
+	pkg.WriteJSONResponse(w, http.StatusAccepted, map[string]interface{}{
+		"message":  "Account scheduled for deletion. Sign in again before the deletion date to cancel.",
+		"purge_at": purgeAt,
+	})
+}
@@ -0,0 +1,355 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/middlerware"
+	"go-chat/internal/service"
+	"go-chat/pkg"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type AdminHandler struct {
+	adminService *service.AdminService
+	auditService *service.AuditService
+}
+
+func NewAdminHandler(as *service.AdminService, audit *service.AuditService) *AdminHandler {
+	return &AdminHandler{
+		adminService: as,
+		auditService: audit,
+	}
+}
+
+func parseUserIDParam(r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "userID"), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *AdminHandler) ListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	query := r.URL.Query().Get("q")
+
+	users, total, err := h.adminService.ListUsers(r.Context(), query, limit, offset)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to list users")
+		return
+	}
+
+	responses := make([]*domain.AdminUserResponse, len(users))
+	for i, user := range users {
+		responses[i] = user.ToAdminResponse()
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data": responses,
+		"meta": map[string]interface{}{
+			"query":  query,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(responses),
+			"total":  total,
+		},
+	})
+}
+
+func (h *AdminHandler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := parseUserIDParam(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, err := h.adminService.GetUser(r.Context(), targetID)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data": user.ToAdminResponse(),
+	})
+}
+
+func (h *AdminHandler) SuspendUserHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := middlerware.GetUserIDFromContext(r)
+	actorRole, _ := middlerware.GetUserRoleFromContext(r)
+
+	targetID, ok := parseUserIDParam(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req domain.SuspendUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := h.adminService.SuspendUser(r.Context(), actorID, actorRole, targetID, req.Reason)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditUserSuspended,
+		TargetUserID: targetID,
+		Metadata:     map[string]interface{}{"reason": req.Reason},
+	})
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "User suspended",
+		"data":    user.ToAdminResponse(),
+	})
+}
+
+func (h *AdminHandler) ReinstateUserHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := middlerware.GetUserIDFromContext(r)
+	actorRole, _ := middlerware.GetUserRoleFromContext(r)
+
+	targetID, ok := parseUserIDParam(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	user, err := h.adminService.ReinstateUser(r.Context(), actorID, actorRole, targetID)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditUserReinstated,
+		TargetUserID: targetID,
+	})
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "User reinstated",
+		"data":    user.ToAdminResponse(),
+	})
+}
+
+func (h *AdminHandler) ForcePasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := middlerware.GetUserIDFromContext(r)
+	actorRole, _ := middlerware.GetUserRoleFromContext(r)
+
+	targetID, ok := parseUserIDParam(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.adminService.ForcePasswordReset(r.Context(), actorID, actorRole, targetID); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditPasswordResetForced,
+		TargetUserID: targetID,
+	})
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Password reset required on next sign-in",
+	})
+}
+
+func (h *AdminHandler) UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := middlerware.GetUserIDFromContext(r)
+	actorRole, _ := middlerware.GetUserRoleFromContext(r)
+
+	targetID, ok := parseUserIDParam(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req domain.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, err := h.adminService.UpdateUserRole(r.Context(), actorID, actorRole, targetID, req.Role)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditRoleChanged,
+		TargetUserID: targetID,
+		Metadata:     map[string]interface{}{"role": req.Role},
+	})
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Role updated",
+		"data":    user.ToAdminResponse(),
+	})
+}
+
+func (h *AdminHandler) GetUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	targetID, ok := parseUserIDParam(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	includeInactive := r.URL.Query().Get("all") == "true"
+
+	sessions, err := h.adminService.GetUserSessions(r.Context(), targetID, includeInactive)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":  sessions,
+		"count": len(sessions),
+	})
+}
+
+func (h *AdminHandler) RevokeUserSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := middlerware.GetUserIDFromContext(r)
+	actorRole, _ := middlerware.GetUserRoleFromContext(r)
+
+	targetID, ok := parseUserIDParam(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.adminService.RevokeUserSessions(r.Context(), actorID, actorRole, targetID); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditSessionsRevoked,
+		TargetUserID: targetID,
+	})
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Sessions revoked",
+	})
+}
+
+func (h *AdminHandler) DeleteMessageHandler(w http.ResponseWriter, r *http.Request) {
+	actorID, _ := middlerware.GetUserIDFromContext(r)
+
+	messageID, err := strconv.ParseUint(chi.URLParam(r, "messageID"), 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+
+	var req domain.ModerationRequest
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	message, err := h.adminService.DeleteMessage(r.Context(), actorID, uint(messageID), req.Reason)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditMessageModerated,
+		TargetUserID: message.SenderID,
+		TargetType:   "message",
+		TargetID:     strconv.FormatUint(messageID, 10),
+		Metadata:     map[string]interface{}{"reason": req.Reason},
+	})
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Message deleted",
+	})
+}
+
+func currentLoggingSettings() domain.LoggingSettings {
+	format := "text"
+	if pkg.IsStructuredLogging() {
+		format = "json"
+	}
+	return domain.LoggingSettings{
+		Level:  strings.ToLower(pkg.GetLogLevel().String()),
+		Format: format,
+	}
+}
+
+func (h *AdminHandler) GetLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	pkg.WriteJSONResponse(w, http.StatusOK, currentLoggingSettings())
+}
+
+// UpdateLoggingHandler changes the log level and format of the running
+// process. The change is not persisted and is lost on restart.
+func (h *AdminHandler) UpdateLoggingHandler(w http.ResponseWriter, r *http.Request) {
+	var req domain.LoggingSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	level := pkg.GetLogLevel()
+	if req.Level != "" {
+		parsed, err := pkg.ParseLogLevel(req.Level)
+		if err != nil {
+			pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		level = parsed
+	}
+
+	structured := pkg.IsStructuredLogging()
+	switch req.Format {
+	case "":
+	case "json", "text":
+		structured = req.Format == "json"
+	default:
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "format must be json or text")
+		return
+	}
+
+	previous := currentLoggingSettings()
+	pkg.SetLogLevel(level)
+	pkg.SetStructuredLogging(structured)
+	current := currentLoggingSettings()
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action: domain.AuditLoggingChanged,
+		Metadata: map[string]interface{}{
+			"previous": previous,
+			"current":  current,
+		},
+	})
+	pkg.InfoContext(r.Context(), "Log settings changed", map[string]interface{}{
+		"level":  current.Level,
+		"format": current.Format,
+	})
+
+	pkg.WriteJSONResponse(w, http.StatusOK, current)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/middlerware"
+	"go-chat/internal/service"
+	"go-chat/pkg"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(as *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: as}
+}
+
+// auditContext captures the actor and request metadata for audit entries.
+func auditContext(r *http.Request) domain.AuditContext {
+	actorID, _ := middlerware.GetUserIDFromContext(r)
+	return domain.AuditContext{
+		ActorID:   actorID,
+		IPAddress: r.RemoteAddr,
+		UserAgent: r.UserAgent(),
+		RequestID: middleware.GetReqID(r.Context()),
+	}
+}
+
+func parsePagination(r *http.Request, defaultLimit int) (int, int) {
+	limit := defaultLimit
+	offset := 0
+
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+
+	return limit, offset
+}
+
+func (h *AuditHandler) ListEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	limit, offset := parsePagination(r, 50)
+	query := r.URL.Query()
+
+	filter := domain.AuditFilter{
+		Action: domain.AuditAction(query.Get("action")),
+	}
+	if id, err := strconv.ParseUint(query.Get("actor_id"), 10, 32); err == nil {
+		filter.ActorID = uint(id)
+	}
+	if id, err := strconv.ParseUint(query.Get("target_user_id"), 10, 32); err == nil {
+		filter.TargetUserID = uint(id)
+	}
+	if since, err := time.Parse(time.RFC3339, query.Get("since")); err == nil {
+		filter.Since = &since
+	}
+	if until, err := time.Parse(time.RFC3339, query.Get("until")); err == nil {
+		filter.Until = &until
+	}
+
+	entries, total, err := h.auditService.ListEntries(r.Context(), filter, limit, offset)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load audit log")
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data": entries,
+		"meta": map[string]interface{}{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(entries),
+			"total":  total,
+		},
+	})
+}
+
+func (h *AuditHandler) VerifyChainHandler(w http.ResponseWriter, r *http.Request) {
+	result, err := h.auditService.VerifyChain(r.Context())
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if !result.Valid {
+		status = http.StatusConflict
+	}
+
+	pkg.WriteJSONResponse(w, status, map[string]interface{}{
+		"data": result,
+	})
+}
+
+func (h *AuditHandler) SecurityActivityHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	limit, offset := parsePagination(r, 20)
+
+	entries, total, err := h.auditService.ListUserActivity(r.Context(), userID, limit, offset)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to load security activity")
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data": entries,
+		"meta": map[string]interface{}{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(entries),
+			"total":  total,
+		},
+	})
+}
@@ -10,14 +10,18 @@ import (
 )
 
 type AuthHandler struct {
-	authService *service.AuthService
-	userService *service.UserService
+	authService  *service.AuthService
+	userService  *service.UserService
+	auditService *service.AuditService
+	cookies      pkg.CookieSettings
 }
 
-func NewAuthHandler(as *service.AuthService, us *service.UserService) *AuthHandler {
+func NewAuthHandler(as *service.AuthService, us *service.UserService, audit *service.AuditService, cookies pkg.CookieSettings) *AuthHandler {
 	return &AuthHandler{
-		authService: as,
-		userService: us,
+		authService:  as,
+		userService:  us,
+		auditService: audit,
+		cookies:      cookies,
 	}
 }
 
@@ -28,13 +32,13 @@ func (h *AuthHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	tokens, user, err := h.authService.RefreshTokens(cookie.Value)
+	tokens, user, err := h.authService.RefreshTokens(r.Context(), cookie.Value)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	pkg.SetTokenCookies(w, tokens)
+	h.cookies.SetTokenCookies(w, tokens)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -47,7 +51,18 @@ func (h *AuthHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request
 }
 
 func (h *AuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	pkg.ClearTokenCookies(w)
+	if cookie, err := r.Cookie("refresh_token"); err == nil {
+		if userID := h.authService.Logout(r.Context(), cookie.Value); userID != 0 {
+			ac := auditContext(r)
+			ac.ActorID = userID
+			h.auditService.Record(r.Context(), ac, domain.AuditEvent{
+				Action:       domain.AuditLogout,
+				TargetUserID: userID,
+			})
+		}
+	}
+
+	h.cookies.ClearTokenCookies(w)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -62,7 +77,7 @@ func (h *AuthHandler) GetMeHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.authService.GetUserByID(userID)
+	user, err := h.authService.GetUserByID(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "User not found", http.StatusNotFound)
 		return
@@ -101,12 +116,23 @@ func (h *AuthHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err := h.authService.ChangePassword(userID, req.CurrentPassword, req.NewPassword)
+	err := h.authService.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword)
 	if err != nil {
+		h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+			Action:       domain.AuditPasswordChanged,
+			TargetUserID: userID,
+			Failed:       true,
+			Metadata:     map[string]interface{}{"reason": err.Error()},
+		})
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditPasswordChanged,
+		TargetUserID: userID,
+	})
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "Password changed successfully",
@@ -124,7 +150,7 @@ func (h *AuthHandler) CheckEmailHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	exists, err := h.authService.CheckEmailExists(req.Email)
+	exists, err := h.authService.CheckEmailExists(r.Context(), req.Email)
 	if err != nil {
 		http.Error(w, "Failed to check email", http.StatusInternalServerError)
 		return
@@ -13,10 +13,14 @@ import (
 
 type FriendsHandler struct {
 	friendsService *service.FriendsService
+	auditService   *service.AuditService
 }
 
-func NewFriendsHandler(fs *service.FriendsService) *FriendsHandler {
-	return &FriendsHandler{friendsService: fs}
+func NewFriendsHandler(fs *service.FriendsService, audit *service.AuditService) *FriendsHandler {
+	return &FriendsHandler{
+		friendsService: fs,
+		auditService:   audit,
+	}
 }
 
 func (h *FriendsHandler) SendFriendRequestHandler(w http.ResponseWriter, r *http.Request) {
@@ -33,7 +37,7 @@ func (h *FriendsHandler) SendFriendRequestHandler(w http.ResponseWriter, r *http
 		return
 	}
 
-	if err := h.friendsService.SendFriendRequest(userID, req.UserID); err != nil {
+	if err := h.friendsService.SendFriendRequest(r.Context(), userID, req.UserID); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -59,7 +63,7 @@ func (h *FriendsHandler) AcceptFriendRequestHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	if err := h.friendsService.AcceptFriendRequest(uint(friendshipID), userID); err != nil {
+	if err := h.friendsService.AcceptFriendRequest(r.Context(), uint(friendshipID), userID); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -84,7 +88,7 @@ func (h *FriendsHandler) RejectFriendRequestHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	if err := h.friendsService.RejectFriendRequest(uint(friendshipID), userID); err != nil {
+	if err := h.friendsService.RejectFriendRequest(r.Context(), uint(friendshipID), userID); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -109,7 +113,7 @@ func (h *FriendsHandler) RemoveFriendHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := h.friendsService.RemoveFriend(uint(friendshipID), userID); err != nil {
+	if err := h.friendsService.RemoveFriend(r.Context(), uint(friendshipID), userID); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
@@ -133,11 +137,16 @@ func (h *FriendsHandler) BlockUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if err := h.friendsService.BlockUser(userID, req.UserID); err != nil {
+	if err := h.friendsService.BlockUser(r.Context(), userID, req.UserID); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditUserBlocked,
+		TargetUserID: req.UserID,
+	})
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "User blocked successfully",
@@ -151,7 +160,7 @@ func (h *FriendsHandler) GetUserFriendsHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	friends, err := h.friendsService.GetUserFriends(userID)
+	friends, err := h.friendsService.GetUserFriends(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Failed to get friends", http.StatusInternalServerError)
 		return
@@ -171,7 +180,7 @@ func (h *FriendsHandler) GetPendingFriendRequestsHandler(w http.ResponseWriter,
 		return
 	}
 
-	requests, err := h.friendsService.GetPendingFriendRequests(userID)
+	requests, err := h.friendsService.GetPendingFriendRequests(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Failed to get friend requests", http.StatusInternalServerError)
 		return
@@ -191,7 +200,7 @@ func (h *FriendsHandler) GetSentFriendRequestsHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	requests, err := h.friendsService.GetSentFriendRequests(userID)
+	requests, err := h.friendsService.GetSentFriendRequests(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Failed to get sent requests", http.StatusInternalServerError)
 		return
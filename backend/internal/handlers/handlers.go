@@ -1,9 +1,18 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"go-chat/config"
 	repository_adapters "go-chat/internal/adapters/repository"
 	websocket_adapters "go-chat/internal/adapters/websocket"
+	"go-chat/internal/ports/repository"
 	"go-chat/internal/service"
+	"go-chat/migrations"
+	"go-chat/pkg"
 
 	"gorm.io/gorm"
 )
@@ -13,33 +22,137 @@ type Handlers struct {
 	User      *UserHandler
 	Friends   *FriendsHandler
 	Message   *MessageHandler
+	OAuth     *OAuthHandler
+	Token     *TokenHandler
+	Admin     *AdminHandler
+	Audit     *AuditHandler
+	Account   *AccountHandler
+	Health    *HealthHandler
 	WebSocket *websocket_adapters.WSHub
+
+	Accounts     *service.UserService
+	TokenAuth    *service.TokenService
+	AccessTokens *pkg.JWTManager
+	Sessions     repository.SessionRepository
+
+	stopWorkers context.CancelFunc
+	workers     *sync.WaitGroup
 }
 
-func NewHandlers(db *gorm.DB) *Handlers {
+func NewHandlers(db *gorm.DB, cfg *config.Config, runner *migrations.Runner) (*Handlers, error) {
 	userRepo := repository_adapters.NewUserGormRepo(db)
 	friendsRepo := repository_adapters.NewFriendsGormRepo(db)
 	messageRepo := repository_adapters.NewMessageGormRepo(db)
+	conversationRepo := repository_adapters.NewConversationGormRepo(db)
+	identityRepo := repository_adapters.NewIdentityGormRepo(db)
+	tokenRepo := repository_adapters.NewTokenGormRepo(db)
+	sessionRepo := repository_adapters.NewSessionGormRepo(db)
+	auditRepo := repository_adapters.NewAuditGormRepo(db)
+	scheduledRepo := repository_adapters.NewScheduledMessageGormRepo(db)
+	uow := repository_adapters.NewGormUnitOfWork(db)
+
+	jwtManager := pkg.NewJWTManager(cfg.Auth.JWTSecret, cfg.Auth.JWTRefreshSecret, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+	cookies := pkg.CookieSettings{
+		Domain:     cfg.Cookies.Domain,
+		Secure:     cfg.Cookies.Secure,
+		SameSite:   pkg.ParseSameSite(cfg.Cookies.SameSite),
+		AccessTTL:  cfg.Auth.AccessTokenTTL,
+		RefreshTTL: cfg.Auth.RefreshTokenTTL,
+	}
 
 	userService := service.NewUserService(userRepo)
-	authService := service.NewAuthService(userRepo)
-	friendsService := service.NewFriendsService(friendsRepo)
-	messageService := service.NewMessageService(messageRepo, userRepo)
+	authService := service.NewAuthService(userRepo, sessionRepo, jwtManager)
+	friendsService := service.NewFriendsService(friendsRepo, uow)
+	messageService := service.NewMessageService(messageRepo, conversationRepo, userRepo, uow, cfg.Messages.DeleteForEveryoneWindow)
+
+	oidcProviders := make([]*pkg.OIDCProvider, 0, len(cfg.Auth.OIDCProviders))
+	for _, providerCfg := range cfg.Auth.OIDCProviders {
+		oidcProviders = append(oidcProviders, pkg.NewOIDCProvider(providerCfg))
+	}
+	oauthService := service.NewOAuthService(identityRepo, userRepo, authService, oidcProviders)
+	tokenService := service.NewTokenService(tokenRepo, userRepo)
+	auditService := service.NewAuditService(auditRepo)
+
+	notificationPolicy := service.NewNotificationPolicy(userRepo, conversationRepo)
+
+	wsHub := websocket_adapters.NewWSHub(messageService, notificationPolicy, cfg.WebSocket)
 
-	wsHub := websocket_adapters.NewWSHub(messageService)
+	// Promote bootstrap admins before anything starts, so a failure leaves
+	// nothing running.
+	if err := userService.EnsureAdmins(context.Background(), cfg.Auth.BootstrapAdminEmails); err != nil {
+		return nil, err
+	}
 
 	go wsHub.Run()
 
-	authHandler := NewAuthHandler(authService, userService)
-	userHandler := NewUserHandler(userService, authService)
-	friendsHandler := NewFriendsHandler(friendsService)
-	messageHandler := NewMessageHandler(messageService)
+	adminService := service.NewAdminService(userRepo, sessionRepo, messageRepo, wsHub, uow)
+
+	accountService := service.NewAccountService(userRepo, friendsRepo, messageRepo, identityRepo, wsHub, uow, cfg.Accounts.DeletionGracePeriod)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	workers := &sync.WaitGroup{}
+	startWorker := func(run func(ctx context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
+	}
+	startWorker(func(ctx context.Context) { accountService.RunDeletionWorker(ctx, cfg.Accounts.DeletionWorkerInterval) })
+
+	scheduledService := service.NewScheduledMessageService(scheduledRepo, userRepo, notificationPolicy, wsHub, uow)
+	startWorker(func(ctx context.Context) { scheduledService.RunScheduler(ctx, cfg.Messages.SchedulerInterval) })
+
+	disappearingService := service.NewDisappearingMessageService(wsHub, uow)
+	startWorker(func(ctx context.Context) { disappearingService.RunPurgeWorker(ctx, cfg.Messages.PurgeInterval) })
+
+	authHandler := NewAuthHandler(authService, userService, auditService, cookies)
+	userHandler := NewUserHandler(userService, authService, auditService, cookies)
+	friendsHandler := NewFriendsHandler(friendsService, auditService)
+	messageHandler := NewMessageHandler(messageService, scheduledService, disappearingService, wsHub)
+	oauthHandler := NewOAuthHandler(oauthService, auditService, cookies, cfg.Auth.OAuthSuccessRedirect)
+	tokenHandler := NewTokenHandler(tokenService, auditService)
+	adminHandler := NewAdminHandler(adminService, auditService)
+	auditHandler := NewAuditHandler(auditService)
+	accountHandler := NewAccountHandler(accountService, auditService, cookies)
+	healthHandler := NewHealthHandler(db, runner, wsHub)
 
 	return &Handlers{
-		Auth:      authHandler,
-		User:      userHandler,
-		Friends:   friendsHandler,
-		Message:   messageHandler,
-		WebSocket: wsHub,
+		Auth:         authHandler,
+		User:         userHandler,
+		Friends:      friendsHandler,
+		Message:      messageHandler,
+		OAuth:        oauthHandler,
+		Token:        tokenHandler,
+		Admin:        adminHandler,
+		Audit:        auditHandler,
+		Account:      accountHandler,
+		Health:       healthHandler,
+		WebSocket:    wsHub,
+		Accounts:     userService,
+		TokenAuth:    tokenService,
+		AccessTokens: jwtManager,
+		Sessions:     sessionRepo,
+		stopWorkers:  stopWorkers,
+		workers:      workers,
+	}, nil
+}
+
+// Shutdown stops the background workers and drains WebSocket clients, then
+// waits for the workers to return so none is left using the database.
+func (h *Handlers) Shutdown(ctx context.Context) error {
+	h.Health.SetDraining()
+	h.stopWorkers()
+	err := h.WebSocket.Shutdown(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		h.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = errors.Join(err, fmt.Errorf("background workers did not stop: %w", ctx.Err()))
 	}
+	return err
 }
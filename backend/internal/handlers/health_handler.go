@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"go-chat/migrations"
+	"go-chat/pkg"
+
+	"gorm.io/gorm"
+)
+
+const readinessCheckTimeout = 2 * time.Second
+
+// HubStatus is the part of the WebSocket hub readiness cares about.
+type HubStatus interface {
+	IsRunning() bool
+}
+
+type HealthHandler struct {
+	db        *gorm.DB
+	runner    *migrations.Runner
+	hub       HubStatus
+	startedAt time.Time
+	draining  atomic.Bool
+}
+
+type checkResult struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+func NewHealthHandler(db *gorm.DB, runner *migrations.Runner, hub HubStatus) *HealthHandler {
+	return &HealthHandler{
+		db:        db,
+		runner:    runner,
+		hub:       hub,
+		startedAt: time.Now(),
+	}
+}
+
+// SetDraining makes readiness fail so load balancers stop routing new
+// traffic here while the server shuts down.
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// LivenessHandler only reports that the process is serving requests.
+func (h *HealthHandler) LivenessHandler(w http.ResponseWriter, r *http.Request) {
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status":         "ok",
+		"uptime_seconds": int64(time.Since(h.startedAt).Seconds()),
+	})
+}
+
+// ReadinessHandler runs every dependency check and returns 503 if any fails.
+func (h *HealthHandler) ReadinessHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+	defer cancel()
+
+	checks := map[string]*checkResult{
+		"database":   runCheck(func() error { return h.checkDatabase(ctx) }),
+		"migrations": runCheck(func() error { return h.checkMigrations(ctx) }),
+		"websocket":  runCheck(h.checkHub),
+		"draining":   runCheck(h.checkDraining),
+	}
+
+	status, code := "ok", http.StatusOK
+	for _, check := range checks {
+		if check.Status != "ok" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	pkg.WriteJSONResponse(w, code, map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
+
+func (h *HealthHandler) VersionHandler(w http.ResponseWriter, r *http.Request) {
+	pkg.WriteJSONResponse(w, http.StatusOK, pkg.GetBuildInfo())
+}
+
+func runCheck(check func() error) *checkResult {
+	start := time.Now()
+	err := check()
+	result := &checkResult{
+		Status:    "ok",
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = "failing"
+		result.Error = err.Error()
+	}
+	return result
+}
+
+func (h *HealthHandler) checkDatabase(ctx context.Context) error {
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (h *HealthHandler) checkMigrations(ctx context.Context) error {
+	statuses, err := h.runner.StatusContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending := 0
+	for _, s := range statuses {
+		switch {
+		case s.Missing:
+			return fmt.Errorf("applied migration %03d_%s is missing from this build", s.Version, s.Name)
+		case s.Modified:
+			return fmt.Errorf("migration %03d_%s was modified after it was applied", s.Version, s.Name)
+		case !s.Applied:
+			pending++
+		}
+	}
+	if pending > 0 {
+		return fmt.Errorf("%d pending migrations", pending)
+	}
+	return nil
+}
+
+func (h *HealthHandler) checkHub() error {
+	if !h.hub.IsRunning() {
+		return errors.New("websocket hub is not running")
+	}
+	return nil
+}
+
+func (h *HealthHandler) checkDraining() error {
+	if h.draining.Load() {
+		return errors.New("server is shutting down")
+	}
+	return nil
+}
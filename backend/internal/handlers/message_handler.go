@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"go-chat/internal/domain"
+	wsports "go-chat/internal/ports/websocket"
 	"go-chat/internal/service"
 	"go-chat/pkg"
 
@@ -14,11 +16,14 @@ import (
 )
 
 type MessageHandler struct {
-	messageService *service.MessageService
+	messageService      *service.MessageService
+	scheduledService    *service.ScheduledMessageService
+	disappearingService *service.DisappearingMessageService
+	hub                 wsports.WSHandler
 }
 
-func NewMessageHandler(ms *service.MessageService) *MessageHandler {
-	return &MessageHandler{messageService: ms}
+func NewMessageHandler(ms *service.MessageService, scheduled *service.ScheduledMessageService, disappearing *service.DisappearingMessageService, hub wsports.WSHandler) *MessageHandler {
+	return &MessageHandler{messageService: ms, scheduledService: scheduled, disappearingService: disappearing, hub: hub}
 }
 
 func (h *MessageHandler) SendMessageHandler(w http.ResponseWriter, r *http.Request) {
@@ -30,8 +35,8 @@ func (h *MessageHandler) SendMessageHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	
-	if req.ReceiverID == 0 || req.Content == "" {
-		pkg.WriteErrorResponse(w, http.StatusBadRequest, "receiver_id and content are required")
+	if err := req.Validate(); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	
@@ -40,7 +45,22 @@ func (h *MessageHandler) SendMessageHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 	
-	message, err := h.messageService.SendMessage(userID, &req)
+	// With send_at the message is stored and sent later by the scheduler.
+	if req.SendAt != nil {
+		scheduled, err := h.scheduledService.ScheduleMessage(r.Context(), userID, &req)
+		if err != nil {
+			pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		
+		pkg.WriteJSONResponse(w, http.StatusCreated, map[string]interface{}{
+			"message": "Message scheduled successfully",
+			"data":    scheduled,
+		})
+		return
+	}
+	
+	message, err := h.messageService.SendMessage(r.Context(), userID, &req)
 	if err != nil {
 		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -52,6 +72,69 @@ func (h *MessageHandler) SendMessageHandler(w http.ResponseWriter, r *http.Reque
 	})
 }
 
+// GetScheduledMessagesHandler lists the caller's pending scheduled messages.
+func (h *MessageHandler) GetScheduledMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+	
+	messages, err := h.scheduledService.GetScheduledMessages(r.Context(), userID)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data": messages,
+	})
+}
+
+func (h *MessageHandler) UpdateScheduledMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+	
+	scheduledIDStr := chi.URLParam(r, "scheduledID")
+	scheduledID, err := strconv.ParseUint(scheduledIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid scheduled message ID")
+		return
+	}
+	
+	var req domain.UpdateScheduledMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	
+	message, err := h.scheduledService.UpdateScheduledMessage(r.Context(), userID, uint(scheduledID), &req)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Scheduled message updated successfully",
+		"data":    message,
+	})
+}
+
+func (h *MessageHandler) CancelScheduledMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+	
+	scheduledIDStr := chi.URLParam(r, "scheduledID")
+	scheduledID, err := strconv.ParseUint(scheduledIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid scheduled message ID")
+		return
+	}
+	
+	if err := h.scheduledService.CancelScheduledMessage(r.Context(), userID, uint(scheduledID)); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Scheduled message cancelled",
+	})
+}
+
 func (h *MessageHandler) GetMessagesHandler(w http.ResponseWriter, r *http.Request) {
 	currentUserID := r.Context().Value("userID").(uint)
 	
@@ -80,7 +163,7 @@ func (h *MessageHandler) GetMessagesHandler(w http.ResponseWriter, r *http.Reque
 		}
 	}
 	
-	messages, err := h.messageService.GetMessagesBetweenUsers(currentUserID, uint(otherUserID), limit, offset)
+	messages, err := h.messageService.GetMessagesBetweenUsers(r.Context(), currentUserID, uint(otherUserID), limit, offset)
 	if err != nil {
 		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
@@ -99,7 +182,27 @@ func (h *MessageHandler) GetMessagesHandler(w http.ResponseWriter, r *http.Reque
 func (h *MessageHandler) GetConversationsHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(uint)
 	
-	conversations, err := h.messageService.GetUserConversations(userID)
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+	
+	limit := 50
+	offset := 0
+	
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+	
+	if offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+	
+	archived := r.URL.Query().Get("archived") == "true"
+	
+	conversations, err := h.messageService.GetUserConversations(r.Context(), userID, "", archived, limit, offset)
 	if err != nil {
 		pkg.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
@@ -107,6 +210,106 @@ func (h *MessageHandler) GetConversationsHandler(w http.ResponseWriter, r *http.
 	
 	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
 		"data": conversations,
+		"meta": map[string]interface{}{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(conversations),
+		},
+	})
+}
+
+func (h *MessageHandler) UpdateConversationHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID := r.Context().Value("userID").(uint)
+	
+	peerIDStr := chi.URLParam(r, "userID")
+	peerID, err := strconv.ParseUint(peerIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	
+	var req domain.ConversationSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	
+	if err := req.Validate(); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	settings, err := h.messageService.UpdateConversationSettings(r.Context(), currentUserID, uint(peerID), &req)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Conversation updated successfully",
+		"data":    settings,
+	})
+}
+
+// SetDisappearingHandler sets the disappearing-message timer of the
+// conversation for both participants.
+func (h *MessageHandler) SetDisappearingHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID := r.Context().Value("userID").(uint)
+	
+	peerIDStr := chi.URLParam(r, "userID")
+	peerID, err := strconv.ParseUint(peerIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	
+	if uint(peerID) == currentUserID {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Cannot set a timer on a conversation with yourself")
+		return
+	}
+	
+	var req domain.DisappearingSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.PeerID = uint(peerID)
+	
+	notice, err := h.disappearingService.SetTimer(r.Context(), currentUserID, &req)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	// The system message tells the peer about the change.
+	if notice != nil {
+		h.hub.BroadcastMessage(notice.ToWSMessage(false), notice.ReceiverID)
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Disappearing messages updated",
+		"data":    req,
+		"notice":  notice,
+	})
+}
+
+func (h *MessageHandler) ClearConversationHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID := r.Context().Value("userID").(uint)
+	
+	peerIDStr := chi.URLParam(r, "userID")
+	peerID, err := strconv.ParseUint(peerIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	
+	if err := h.messageService.ClearConversation(r.Context(), currentUserID, uint(peerID)); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Conversation cleared successfully",
 	})
 }
 
@@ -120,14 +323,57 @@ func (h *MessageHandler) MarkAsReadHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	
-	err = h.messageService.MarkMessagesAsRead(uint(senderUserID), currentUserID)
+	cursor, err := h.messageService.MarkMessagesAsRead(r.Context(), uint(senderUserID), currentUserID)
 	if err != nil {
 		pkg.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	
+	if !cursor.Private {
+		h.hub.BroadcastMessage(cursor.ToWSMessage(), cursor.PeerID)
+	}
+	
 	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
 		"message": "Messages marked as read",
+		"data":    cursor,
+	})
+}
+
+// MarkReadUpToHandler marks a conversation as read up to and including the
+// given message.
+func (h *MessageHandler) MarkReadUpToHandler(w http.ResponseWriter, r *http.Request) {
+	h.moveReadCursor(w, r, h.messageService.MarkReadUpTo, "Messages marked as read")
+}
+
+// MarkUnreadFromHandler marks the given message and everything after it in
+// its conversation as unread.
+func (h *MessageHandler) MarkUnreadFromHandler(w http.ResponseWriter, r *http.Request) {
+	h.moveReadCursor(w, r, h.messageService.MarkUnreadFrom, "Messages marked as unread")
+}
+
+func (h *MessageHandler) moveReadCursor(w http.ResponseWriter, r *http.Request, move func(ctx context.Context, readerID, messageID uint) (*domain.ReadCursor, error), message string) {
+	currentUserID := r.Context().Value("userID").(uint)
+	
+	messageIDStr := chi.URLParam(r, "messageID")
+	messageID, err := strconv.ParseUint(messageIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+	
+	cursor, err := move(r.Context(), currentUserID, uint(messageID))
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+	
+	if !cursor.Private {
+		h.hub.BroadcastMessage(cursor.ToWSMessage(), cursor.PeerID)
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": message,
+		"data":    cursor,
 	})
 }
 
@@ -141,7 +387,7 @@ func (h *MessageHandler) GetUnreadCountHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 	
-	count, err := h.messageService.GetUnreadMessageCount(uint(senderUserID), currentUserID)
+	count, err := h.messageService.GetUnreadMessageCount(r.Context(), uint(senderUserID), currentUserID)
 	if err != nil {
 		pkg.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
@@ -152,6 +398,28 @@ func (h *MessageHandler) GetUnreadCountHandler(w http.ResponseWriter, r *http.Re
 	})
 }
 
+// GetReceiptsHandler lists when each recipient received and read a message.
+func (h *MessageHandler) GetReceiptsHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID := r.Context().Value("userID").(uint)
+	
+	messageIDStr := chi.URLParam(r, "messageID")
+	messageID, err := strconv.ParseUint(messageIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+	
+	receipts, err := h.messageService.GetMessageReceipts(r.Context(), currentUserID, uint(messageID))
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data": receipts,
+	})
+}
+
 func (h *MessageHandler) DeleteMessageHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(uint)
 	
@@ -162,17 +430,184 @@ func (h *MessageHandler) DeleteMessageHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 	
-	err = h.messageService.DeleteMessage(uint(messageID), userID)
+	// Without ?for=everyone the message is only hidden for the caller.
+	if r.URL.Query().Get("for") != "everyone" {
+		if err := h.messageService.DeleteMessageForMe(r.Context(), uint(messageID), userID); err != nil {
+			pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		
+		pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"message": "Message deleted successfully",
+		})
+		return
+	}
+	
+	message, unpinned, err := h.messageService.DeleteMessageForEveryone(r.Context(), uint(messageID), userID)
 	if err != nil {
 		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	
+	h.hub.BroadcastMessage(&domain.WSMessage{
+		Type: domain.WSMessageTypeMessageDeleted,
+		Payload: &domain.WSDeletedPayload{
+			MessageID:  message.ID,
+			SenderID:   message.SenderID,
+			ReceiverID: message.ReceiverID,
+		},
+	}, message.ReceiverID)
+	if unpinned != nil {
+		h.hub.BroadcastMessage(unpinned.ToWSMessage(), message.ReceiverID)
+	}
+	
 	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"message": "Message deleted successfully",
+		"message": "Message deleted for everyone",
 	})
 }
 
+func (h *MessageHandler) PinMessageHandler(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, h.messageService.PinMessage, "Message pinned")
+}
+
+func (h *MessageHandler) UnpinMessageHandler(w http.ResponseWriter, r *http.Request) {
+	h.setPinned(w, r, h.messageService.UnpinMessage, "Message unpinned")
+}
+
+func (h *MessageHandler) setPinned(w http.ResponseWriter, r *http.Request, set func(ctx context.Context, messageID, userID uint) (*domain.WSPinnedPayload, error), message string) {
+	userID := r.Context().Value("userID").(uint)
+	
+	messageIDStr := chi.URLParam(r, "messageID")
+	messageID, err := strconv.ParseUint(messageIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+	
+	payload, err := set(r.Context(), uint(messageID), userID)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	// Nothing changed when the message was already in that state.
+	if payload != nil {
+		peerID := payload.SenderID
+		if peerID == userID {
+			peerID = payload.ReceiverID
+		}
+		h.hub.BroadcastMessage(payload.ToWSMessage(), peerID)
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": message,
+	})
+}
+
+func (h *MessageHandler) GetPinnedMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID := r.Context().Value("userID").(uint)
+	
+	otherUserIDStr := chi.URLParam(r, "userID")
+	otherUserID, err := strconv.ParseUint(otherUserIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+	
+	limit, offset := pagination(r)
+	
+	messages, err := h.messageService.GetPinnedMessages(r.Context(), currentUserID, uint(otherUserID), limit, offset)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data": messages,
+		"meta": map[string]interface{}{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(messages),
+		},
+	})
+}
+
+func (h *MessageHandler) StarMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+	
+	messageIDStr := chi.URLParam(r, "messageID")
+	messageID, err := strconv.ParseUint(messageIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+	
+	if err := h.messageService.StarMessage(r.Context(), uint(messageID), userID); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Message starred",
+	})
+}
+
+func (h *MessageHandler) UnstarMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value("userID").(uint)
+	
+	messageIDStr := chi.URLParam(r, "messageID")
+	messageID, err := strconv.ParseUint(messageIDStr, 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid message ID")
+		return
+	}
+	
+	if err := h.messageService.UnstarMessage(r.Context(), uint(messageID), userID); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Message unstarred",
+	})
+}
+
+func (h *MessageHandler) GetStarredMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	currentUserID := r.Context().Value("userID").(uint)
+	
+	limit, offset := pagination(r)
+	
+	messages, err := h.messageService.GetStarredMessages(r.Context(), currentUserID, limit, offset)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data": messages,
+		"meta": map[string]interface{}{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(messages),
+		},
+	})
+}
+
+// pagination reads limit and offset from the query string, falling back to
+// 50 and 0 for missing or out-of-range values.
+func pagination(r *http.Request) (int, int) {
+	limit := 50
+	offset := 0
+	
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	if o, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && o >= 0 {
+		offset = o
+	}
+	return limit, offset
+}
+
 func (h *MessageHandler) SearchMessagesHandler(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value("userID").(uint)
 	
@@ -210,7 +645,7 @@ func (h *MessageHandler) SearchMessagesHandler(w http.ResponseWriter, r *http.Re
 		}
 	}
 	
-	messages, err := h.messageService.SearchMessages(userID, query, limit, offset)
+	messages, err := h.messageService.SearchMessages(r.Context(), userID, query, limit, offset)
 	if err != nil {
 		pkg.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
 		return
@@ -241,27 +676,44 @@ func (h *MessageHandler) SearchConversationsHandler(w http.ResponseWriter, r *ht
 		return
 	}
 	
-	conversations, err := h.messageService.GetUserConversations(userID)
-	if err != nil {
-		pkg.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+	if len(query) > 100 {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Search query too long (maximum 100 characters)")
 		return
 	}
 	
-	var filteredConversations []*domain.ConversationResponse
-	queryLower := strings.ToLower(query)
+	limitStr := r.URL.Query().Get("limit")
+	offsetStr := r.URL.Query().Get("offset")
+	
+	limit := 20
+	offset := 0
+	
+	if limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
 	
-	for _, conv := range conversations {
-		if strings.Contains(strings.ToLower(conv.FullName), queryLower) ||
-		   strings.Contains(strings.ToLower(conv.Username), queryLower) {
-			filteredConversations = append(filteredConversations, conv)
+	if offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
 		}
 	}
 	
+	archived := r.URL.Query().Get("archived") == "true"
+	
+	conversations, err := h.messageService.GetUserConversations(r.Context(), userID, query, archived, limit, offset)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	
 	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
-		"data": filteredConversations,
+		"data": conversations,
 		"meta": map[string]interface{}{
-			"query": query,
-			"count": len(filteredConversations),
+			"query":  query,
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(conversations),
 		},
 	})
 }
\ No newline at end of file
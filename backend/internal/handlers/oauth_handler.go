@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/middlerware"
+	"go-chat/internal/service"
+	"go-chat/pkg"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type OAuthHandler struct {
+	oauthService    *service.OAuthService
+	auditService    *service.AuditService
+	cookies         pkg.CookieSettings
+	successRedirect string
+}
+
+func NewOAuthHandler(os *service.OAuthService, audit *service.AuditService, cookies pkg.CookieSettings, successRedirect string) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService:    os,
+		auditService:    audit,
+		cookies:         cookies,
+		successRedirect: successRedirect,
+	}
+}
+
+func (h *OAuthHandler) ListProvidersHandler(w http.ResponseWriter, r *http.Request) {
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"providers": h.oauthService.ListProviders(),
+	})
+}
+
+func (h *OAuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+
+	authURL, state, err := h.oauthService.BeginLogin(r.Context(), provider, 0)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.cookies.SetOAuthStateCookie(w, state.State, state.ExpiresAt)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+func (h *OAuthHandler) CallbackHandler(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	query := r.URL.Query()
+
+	if errCode := query.Get("error"); errCode != "" {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Provider returned error: "+errCode)
+		return
+	}
+
+	code := query.Get("code")
+	state := query.Get("state")
+	if code == "" || state == "" {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "code and state are required")
+		return
+	}
+
+	// The state must come back to the browser that started the flow, or an
+	// attacker could sign the victim in to the attacker's account. It is
+	// checked before CompleteLogin consumes the state.
+	cookie, err := r.Cookie(pkg.OAuthStateCookie)
+	h.cookies.ClearOAuthStateCookie(w)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+			Action:   domain.AuditLoginFailed,
+			Failed:   true,
+			Metadata: map[string]interface{}{"provider": provider, "reason": "state cookie mismatch"},
+		})
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Login state does not match this browser")
+		return
+	}
+
+	user, tokens, err := h.oauthService.CompleteLogin(r.Context(), provider, code, state, r.RemoteAddr, r.UserAgent())
+	if err != nil {
+		h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+			Action:   domain.AuditLoginFailed,
+			Failed:   true,
+			Metadata: map[string]interface{}{"provider": provider, "reason": err.Error()},
+		})
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	ac := auditContext(r)
+	ac.ActorID = user.ID
+
+	// Link flows return no tokens; the user keeps their existing session.
+	if tokens == nil {
+		h.auditService.Record(r.Context(), ac, domain.AuditEvent{
+			Action:       domain.AuditProviderLinked,
+			TargetUserID: user.ID,
+			Metadata:     map[string]interface{}{"provider": provider},
+		})
+
+		if h.successRedirect != "" {
+			http.Redirect(w, r, h.successRedirect+"?linked="+url.QueryEscape(provider), http.StatusFound)
+			return
+		}
+		pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"message":  "Provider linked successfully",
+			"provider": provider,
+			"user":     user.ToResponse(),
+		})
+		return
+	}
+
+	h.auditService.Record(r.Context(), ac, domain.AuditEvent{
+		Action:       domain.AuditOAuthLogin,
+		TargetUserID: user.ID,
+		Metadata:     map[string]interface{}{"provider": provider},
+	})
+
+	h.cookies.SetTokenCookies(w, tokens)
+
+	if h.successRedirect != "" {
+		http.Redirect(w, r, h.successRedirect, http.StatusFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Logged in successfully",
+		"user":          user.ToResponse(),
+		"access_token":  tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+func (h *OAuthHandler) LinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	provider := chi.URLParam(r, "provider")
+
+	authURL, state, err := h.oauthService.BeginLogin(r.Context(), provider, userID)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.cookies.SetOAuthStateCookie(w, state.State, state.ExpiresAt)
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"authorization_url": authURL,
+	})
+}
+
+func (h *OAuthHandler) GetIdentitiesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	identities, err := h.oauthService.GetUserIdentities(r.Context(), userID)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get linked providers")
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"identities": identities,
+		"count":      len(identities),
+	})
+}
+
+func (h *OAuthHandler) UnlinkHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	provider := chi.URLParam(r, "provider")
+
+	if err := h.oauthService.UnlinkProvider(r.Context(), userID, provider); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditProviderUnlinked,
+		TargetUserID: userID,
+		Metadata:     map[string]interface{}{"provider": provider},
+	})
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Provider unlinked successfully",
+	})
+}
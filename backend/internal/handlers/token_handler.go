@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/middlerware"
+	"go-chat/internal/service"
+	"go-chat/pkg"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type TokenHandler struct {
+	tokenService *service.TokenService
+	auditService *service.AuditService
+}
+
+func NewTokenHandler(ts *service.TokenService, audit *service.AuditService) *TokenHandler {
+	return &TokenHandler{
+		tokenService: ts,
+		auditService: audit,
+	}
+}
+
+// targetUserID resolves which account a token route operates on: the
+// caller, or the bot named by the {botID} URL parameter.
+func targetUserID(r *http.Request, userID uint) (uint, bool) {
+	botIDStr := chi.URLParam(r, "botID")
+	if botIDStr == "" {
+		return userID, true
+	}
+
+	botID, err := strconv.ParseUint(botIDStr, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(botID), true
+}
+
+func (h *TokenHandler) CreateTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	target, ok := targetUserID(r, userID)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid bot ID")
+		return
+	}
+
+	var req domain.CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	token, err := h.tokenService.CreateToken(r.Context(), userID, target, &req)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditTokenCreated,
+		TargetUserID: target,
+		TargetType:   "token",
+		TargetID:     strconv.FormatUint(uint64(token.ID), 10),
+		Metadata:     map[string]interface{}{"name": token.Name, "scopes": token.Scopes},
+	})
+
+	pkg.WriteJSONResponse(w, http.StatusCreated, map[string]interface{}{
+		"message": "Token created successfully. Copy it now, it will not be shown again.",
+		"data":    token,
+	})
+}
+
+func (h *TokenHandler) ListTokensHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	target, ok := targetUserID(r, userID)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid bot ID")
+		return
+	}
+
+	tokens, err := h.tokenService.ListTokens(r.Context(), userID, target)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":  tokens,
+		"count": len(tokens),
+	})
+}
+
+func (h *TokenHandler) RevokeTokenHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	target, ok := targetUserID(r, userID)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid bot ID")
+		return
+	}
+
+	tokenID, err := strconv.ParseUint(chi.URLParam(r, "tokenID"), 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid token ID")
+		return
+	}
+
+	if err := h.tokenService.RevokeToken(r.Context(), userID, target, uint(tokenID)); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditTokenRevoked,
+		TargetUserID: target,
+		TargetType:   "token",
+		TargetID:     strconv.FormatUint(tokenID, 10),
+	})
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Token revoked successfully",
+	})
+}
+
+func (h *TokenHandler) CreateBotHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req domain.CreateBotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	bot, err := h.tokenService.CreateBot(r.Context(), userID, req.Name)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusCreated, map[string]interface{}{
+		"message": "Bot created successfully",
+		"data":    bot.ToResponse(),
+	})
+}
+
+func (h *TokenHandler) ListBotsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	bots, err := h.tokenService.ListBots(r.Context(), userID)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to get bots")
+		return
+	}
+
+	responses := make([]*domain.UserResponse, len(bots))
+	for i, bot := range bots {
+		responses[i] = bot.ToResponse()
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"data":  responses,
+		"count": len(responses),
+	})
+}
+
+func (h *TokenHandler) DeleteBotHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		pkg.WriteErrorResponse(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	botID, err := strconv.ParseUint(chi.URLParam(r, "botID"), 10, 32)
+	if err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, "Invalid bot ID")
+		return
+	}
+
+	if err := h.tokenService.DeleteBot(r.Context(), userID, uint(botID)); err != nil {
+		pkg.WriteErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pkg.WriteJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"message": "Bot deleted successfully",
+	})
+}
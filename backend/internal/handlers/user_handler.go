@@ -6,19 +6,22 @@ import (
 	"go-chat/internal/middlerware"
 	"go-chat/internal/service"
 	"go-chat/pkg"
-	"log"
 	"net/http"
 )
 
 type UserHandler struct {
-	userService *service.UserService
-	authService *service.AuthService
+	userService  *service.UserService
+	authService  *service.AuthService
+	auditService *service.AuditService
+	cookies      pkg.CookieSettings
 }
 
-func NewUserHandler(us *service.UserService, as *service.AuthService) *UserHandler {
+func NewUserHandler(us *service.UserService, as *service.AuthService, audit *service.AuditService, cookies pkg.CookieSettings) *UserHandler {
 	return &UserHandler{
-		userService: us,
-		authService: as,
+		userService:  us,
+		authService:  as,
+		auditService: audit,
+		cookies:      cookies,
 	}
 }
 
@@ -30,7 +33,7 @@ func (h *UserHandler) SignupHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if email already exists
-	exists, err := h.authService.CheckEmailExists(req.Email)
+	exists, err := h.authService.CheckEmailExists(r.Context(), req.Email)
 	if err != nil {
 		http.Error(w, "Failed to check email availability", http.StatusInternalServerError)
 		return
@@ -41,7 +44,7 @@ func (h *UserHandler) SignupHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	hashedPassword := pkg.HashPassword(req.Password)
-	err = h.userService.Signup(req.Name, req.Email, string(hashedPassword))
+	err = h.userService.Signup(r.Context(), req.Name, req.Email, string(hashedPassword))
 	if err != nil {
 		http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		return
@@ -61,22 +64,35 @@ func (h *UserHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.authService.AuthenticateUser(req.Email, req.Password)
+	user, err := h.authService.AuthenticateUser(r.Context(), req.Email, req.Password)
 	if err != nil {
+		h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+			Action:   domain.AuditLoginFailed,
+			Failed:   true,
+			Metadata: map[string]interface{}{"email": req.Email, "reason": err.Error()},
+		})
 		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
 
-	tokens, err := h.authService.GenerateTokens(user)
+	tokens, err := h.authService.GenerateTokens(r.Context(), user, r.RemoteAddr, r.UserAgent())
 	if err != nil {
-		log.Printf("Error generating tokens: %v", err)
+		pkg.ErrorContext(r.Context(), "Error generating tokens", err)
 		http.Error(w, "Could not generate authentication tokens", http.StatusInternalServerError)
 		return
 	}
 
-	pkg.SetTokenCookies(w, tokens)
+	h.cookies.SetTokenCookies(w, tokens)
 
-	log.Printf("✅ Email login successful for user: %s (ID: %d)", user.Email, user.ID)
+	ac := auditContext(r)
+	ac.ActorID = user.ID
+	h.auditService.Record(r.Context(), ac, domain.AuditEvent{
+		Action:       domain.AuditLogin,
+		TargetUserID: user.ID,
+	})
+
+	pkg.AddLogFields(r.Context(), map[string]interface{}{"user_id": user.ID})
+	pkg.InfoContext(r.Context(), "Email login successful", map[string]interface{}{"email": user.Email})
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -101,12 +117,31 @@ func (h *UserHandler) UpdateProfileHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	updatedUser, err := h.userService.UpdateProfile(userID, req.Name, req.Email)
+	previous, err := h.userService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	updatedUser, err := h.userService.UpdateProfile(r.Context(), userID, req.Name, req.Email)
 	if err != nil {
 		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
 		return
 	}
 
+	h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+		Action:       domain.AuditProfileUpdated,
+		TargetUserID: userID,
+		Metadata:     map[string]interface{}{"old_name": previous.Name, "new_name": updatedUser.Name},
+	})
+	if previous.Email != updatedUser.Email {
+		h.auditService.Record(r.Context(), auditContext(r), domain.AuditEvent{
+			Action:       domain.AuditEmailChanged,
+			TargetUserID: userID,
+			Metadata:     map[string]interface{}{"old_email": previous.Email, "new_email": updatedUser.Email},
+		})
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Profile updated successfully",
@@ -114,6 +149,56 @@ func (h *UserHandler) UpdateProfileHandler(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+func (h *UserHandler) GetSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.userService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"settings": user.Settings(),
+	})
+}
+
+func (h *UserHandler) UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middlerware.GetUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req domain.UserSettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.userService.UpdateSettings(r.Context(), userID, &req)
+	if err != nil {
+		http.Error(w, "Failed to update settings", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Settings updated successfully",
+		"settings": settings,
+	})
+}
+
 func (h *UserHandler) SearchUsersHandler(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middlerware.GetUserIDFromContext(r)
 	if !ok {
@@ -127,7 +212,7 @@ func (h *UserHandler) SearchUsersHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	users, err := h.userService.SearchUsers(query, userID)
+	users, err := h.userService.SearchUsers(r.Context(), query, userID)
 	if err != nil {
 		http.Error(w, "Search failed", http.StatusInternalServerError)
 		return
@@ -2,11 +2,83 @@ package middlerware
 
 import (
 	"context"
+	"go-chat/internal/domain"
 	"go-chat/pkg"
 	"net/http"
+	"slices"
+	"time"
 )
 
-func RequireAuth(next http.Handler) http.Handler {
+// TokenAuthenticator resolves personal access tokens so RequireAuth can
+// accept them alongside JWTs.
+type TokenAuthenticator interface {
+	AuthenticateToken(ctx context.Context, token, clientIP string) (*domain.User, []string, error)
+}
+
+// AccountLookup loads the current account state (role, suspension) for
+// JWT-authenticated requests.
+type AccountLookup interface {
+	GetUserByID(ctx context.Context, id uint) (*domain.User, error)
+}
+
+// SessionLookup loads the session behind a JWT access token, so revoked
+// sessions stop working before their access tokens expire.
+type SessionLookup interface {
+	GetSessionByID(ctx context.Context, id string) (*domain.Session, error)
+}
+
+// AccessTokenValidator verifies the JWT access tokens issued at login.
+type AccessTokenValidator interface {
+	ValidateAccessToken(token string) (*pkg.Claims, error)
+}
+
+// AuthConfig holds what RequireAuth needs to authenticate requests. Without
+// Tokens, personal access tokens are refused; without AccessTokens, JWTs
+// are. Accounts and Sessions are optional checks on JWT requests.
+type AuthConfig struct {
+	Accounts     AccountLookup
+	Tokens       TokenAuthenticator
+	AccessTokens AccessTokenValidator
+	Sessions     SessionLookup
+}
+
+// Authenticator provides RequireAuth for the configured credentials.
+type Authenticator struct {
+	cfg AuthConfig
+}
+
+func NewAuthenticator(cfg AuthConfig) *Authenticator {
+	return &Authenticator{cfg: cfg}
+}
+
+// AllowPendingPasswordReset lets accounts flagged for a forced password
+// reset through RequireAuth, so they can reach the change-password endpoint.
+func AllowPendingPasswordReset(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), "allowPasswordReset", true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// checkAccount rejects suspended accounts and those with a pending forced
+// password reset, writing the response itself when it returns false.
+func checkAccount(w http.ResponseWriter, r *http.Request, user *domain.User) bool {
+	if user.IsSuspended() {
+		pkg.WriteErrorResponse(w, http.StatusForbidden, "Account suspended")
+		return false
+	}
+
+	if user.PasswordResetRequired {
+		if allowed, _ := r.Context().Value("allowPasswordReset").(bool); !allowed {
+			pkg.WriteErrorResponse(w, http.StatusForbidden, "Password reset required")
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a *Authenticator) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		tokenString, err := pkg.ExtractTokenFromRequest(r)
 		if err != nil {
@@ -14,20 +86,133 @@ func RequireAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		claims, err := pkg.ValidateAccessToken(tokenString)
+		if pkg.IsPersonalAccessToken(tokenString) {
+			if a.cfg.Tokens == nil {
+				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+
+			user, scopes, err := a.cfg.Tokens.AuthenticateToken(r.Context(), tokenString, getClientIP(r))
+			if err != nil {
+				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+
+			if !checkAccount(w, r, user) {
+				return
+			}
+
+			pkg.AddLogFields(r.Context(), map[string]interface{}{"user_id": user.ID, "auth": "token"})
+
+			ctx := context.WithValue(r.Context(), "userID", user.ID)
+			ctx = context.WithValue(ctx, "userEmail", user.Email)
+			ctx = context.WithValue(ctx, "userName", user.Name)
+			ctx = context.WithValue(ctx, "userRole", user.EffectiveRole())
+			ctx = context.WithValue(ctx, "authScopes", scopes)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		if a.cfg.AccessTokens == nil {
+			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := a.cfg.AccessTokens.ValidateAccessToken(tokenString)
 		if err != nil {
 			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
 			return
 		}
 
+		if a.cfg.Sessions != nil {
+			session, err := a.cfg.Sessions.GetSessionByID(r.Context(), claims.SessionID)
+			if err != nil || session.UserID != claims.UserID || !session.IsActive(time.Now()) {
+				http.Error(w, "Session expired or revoked", http.StatusUnauthorized)
+				return
+			}
+		}
+
+		role := domain.RoleUser
+		if a.cfg.Accounts != nil {
+			user, err := a.cfg.Accounts.GetUserByID(r.Context(), claims.UserID)
+			if err != nil {
+				http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+			if !checkAccount(w, r, user) {
+				return
+			}
+			role = user.EffectiveRole()
+		}
+
+		pkg.AddLogFields(r.Context(), map[string]interface{}{"user_id": claims.UserID})
+
 		ctx := context.WithValue(r.Context(), "userID", claims.UserID)
 		ctx = context.WithValue(ctx, "userEmail", claims.Email)
 		ctx = context.WithValue(ctx, "userName", claims.Name)
+		ctx = context.WithValue(ctx, "userRole", role)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// RequireScope limits token-authenticated requests to those whose token
+// grants "<resource>:read" (safe methods) or "<resource>:write" (everything
+// else). Session (JWT) requests are not scope-restricted.
+func RequireScope(resource string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, isToken := GetAuthScopesFromContext(r)
+			if !isToken {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			required := resource + ":write"
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				required = resource + ":read"
+			}
+
+			if !slices.Contains(scopes, required) {
+				pkg.WriteErrorResponse(w, http.StatusForbidden, "Token is missing required scope: "+required)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole admits only users whose role is at least min. It must run
+// after RequireAuth.
+func RequireRole(min domain.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := GetUserRoleFromContext(r)
+			if !ok || !role.AtLeast(min) {
+				pkg.WriteErrorResponse(w, http.StatusForbidden, "Insufficient permissions")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects requests authenticated with a personal access
+// token, for endpoints such as token management that must stay interactive.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, isToken := GetAuthScopesFromContext(r); isToken {
+			pkg.WriteErrorResponse(w, http.StatusForbidden, "This endpoint cannot be used with an access token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func GetUserIDFromContext(r *http.Request) (uint, bool) {
 	userID, ok := r.Context().Value("userID").(uint)
 	return userID, ok
@@ -41,4 +226,14 @@ func GetUserEmailFromContext(r *http.Request) (string, bool) {
 func GetUserNameFromContext(r *http.Request) (string, bool) {
 	name, ok := r.Context().Value("userName").(string)
 	return name, ok
-}
\ No newline at end of file
+}
+
+func GetUserRoleFromContext(r *http.Request) (domain.Role, bool) {
+	role, ok := r.Context().Value("userRole").(domain.Role)
+	return role, ok
+}
+
+func GetAuthScopesFromContext(r *http.Request) ([]string, bool) {
+	scopes, ok := r.Context().Value("authScopes").([]string)
+	return scopes, ok
+}
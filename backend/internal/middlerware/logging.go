@@ -1,11 +1,17 @@
 package middlerware
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
 
 	"go-chat/pkg"
+
+	"github.com/go-chi/chi/middleware"
 )
 
 type LoggingResponseWriter struct {
@@ -37,6 +43,27 @@ func (lrw *LoggingResponseWriter) Write(data []byte) (int, error) {
 	return lrw.ResponseWriter.Write(data)
 }
 
+// Hijack lets WebSocket upgrades pass through the logging middleware.
+func (lrw *LoggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := lrw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	lrw.written = true
+	lrw.statusCode = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+func (lrw *LoggingResponseWriter) Flush() {
+	if flusher, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// RequestLogging installs a request-scoped logger, retrievable with
+// pkg.LoggerFromContext, tagged with the request ID, client IP and route.
+// RequireAuth adds the user ID to it once known, and the access log line
+// written here after the handler returns includes it.
 func RequestLogging() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -44,14 +71,12 @@ func RequestLogging() func(http.Handler) http.Handler {
 
 			lrw := NewLoggingResponseWriter(w)
 
-			var userID uint
-			if uid := r.Context().Value("userID"); uid != nil {
-				if id, ok := uid.(uint); ok {
-					userID = id
-				}
-			}
-
-			clientIP := getClientIP(r)
+			logger := pkg.GetLogger().With(map[string]interface{}{
+				"request_id": middleware.GetReqID(r.Context()),
+				"client_ip":  getClientIP(r),
+				"route":      routeField{r},
+			})
+			r = r.WithContext(pkg.WithLogger(r.Context(), logger))
 
 			next.ServeHTTP(lrw, r)
 
@@ -65,24 +90,36 @@ func RequestLogging() func(http.Handler) http.Handler {
 					err = &HTTPError{StatusCode: lrw.statusCode, Message: "Client error"}
 				}
 
-				pkg.LogHTTPError(r.Method, r.URL.Path, clientIP, lrw.statusCode, err, userID)
+				pkg.LogHTTPError(r.Context(), r.Method, r.URL.Path, lrw.statusCode, err)
 			} else {
-				pkg.LogHTTPRequest(r.Method, r.URL.Path, r.UserAgent(), clientIP, userID, duration)
+				pkg.LogHTTPRequest(r.Context(), r.Method, r.URL.Path, r.UserAgent(), lrw.statusCode, duration)
 			}
 
 			if duration > time.Second {
-				pkg.Warn("Slow HTTP request", map[string]interface{}{
-					"method":    r.Method,
-					"path":      r.URL.Path,
-					"duration":  duration.String(),
-					"client_ip": clientIP,
-					"user_id":   userID,
+				pkg.WarnContext(r.Context(), "Slow HTTP request", map[string]interface{}{
+					"method":   r.Method,
+					"path":     r.URL.Path,
+					"duration": duration.String(),
 				})
 			}
 		})
 	}
 }
 
+// routeField renders the matched route pattern when the entry is written
+// rather than when the logger is created, before routing has finished.
+type routeField struct {
+	r *http.Request
+}
+
+func (f routeField) String() string {
+	return routePattern(f.r)
+}
+
+func (f routeField) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.String())
+}
+
 type HTTPError struct {
 	StatusCode int
 	Message    string
@@ -97,11 +134,6 @@ func AuthLogging() func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
 			lrw := NewLoggingResponseWriter(w)
-			clientIP := getClientIP(r)
-
-			email := ""
-			if r.Method == "POST" && r.Header.Get("Content-Type") == "application/json" {
-			}
 
 			next.ServeHTTP(lrw, r)
 
@@ -122,20 +154,13 @@ func AuthLogging() func(http.Handler) http.Handler {
 				event = "auth_request"
 			}
 
-			var userID uint
-			if uid := r.Context().Value("userID"); uid != nil {
-				if id, ok := uid.(uint); ok {
-					userID = id
-				}
-			}
-
-			pkg.LogAuthEvent(event, email, clientIP, userID, success)
+			pkg.LogAuthEvent(r.Context(), event, success)
 
 			if success {
-				pkg.LogHTTPRequest(r.Method, r.URL.Path, r.UserAgent(), clientIP, userID, duration)
+				pkg.LogHTTPRequest(r.Context(), r.Method, r.URL.Path, r.UserAgent(), lrw.statusCode, duration)
 			} else {
 				err := &HTTPError{StatusCode: lrw.statusCode, Message: "Auth failed"}
-				pkg.LogHTTPError(r.Method, r.URL.Path, clientIP, lrw.statusCode, err, userID)
+				pkg.LogHTTPError(r.Context(), r.Method, r.URL.Path, lrw.statusCode, err)
 			}
 		})
 	}
@@ -186,7 +211,7 @@ func (e *PanicError) Error() string {
 	return "panic: " + pkg.ToString(e.Value)
 }
 
-func RateLimitLogging(endpointType string) func(http.Handler) http.Handler {
+func RateLimitLogging(limiter *RateLimiter, endpointType string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			clientIP := getClientIP(r)
@@ -197,7 +222,7 @@ func RateLimitLogging(endpointType string) func(http.Handler) http.Handler {
 				}
 			}
 
-			rateLimitMiddleware := RateLimit(endpointType)
+			rateLimitMiddleware := limiter.Limit(endpointType)
 			
 			var rateLimitExceeded bool
 			wrappedHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,47 @@
+package middlerware
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// QueryTimeouts bounds how long a request's database work may run, by
+// endpoint type; "default" applies to types without their own entry.
+type QueryTimeouts struct {
+	timeouts map[string]time.Duration
+}
+
+// NewQueryTimeouts applies the configured timeouts, falling back to five
+// seconds when "default" is not configured.
+func NewQueryTimeouts(timeouts map[string]time.Duration) *QueryTimeouts {
+	qt := &QueryTimeouts{
+		timeouts: map[string]time.Duration{
+			"default": 5 * time.Second,
+		},
+	}
+	for name, timeout := range timeouts {
+		qt.timeouts[name] = timeout
+	}
+	return qt
+}
+
+// QueryTimeout gives the request context a deadline so queries issued while
+// handling it are cancelled once it passes, as well as when the client goes
+// away. A deadline can only be shortened by nesting, so routes needing a
+// longer one must not sit under a group that already applies a shorter one.
+func (qt *QueryTimeouts) QueryTimeout(endpointType string) func(http.Handler) http.Handler {
+	timeout, ok := qt.timeouts[endpointType]
+	if !ok {
+		timeout = qt.timeouts["default"]
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
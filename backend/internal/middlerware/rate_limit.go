@@ -6,10 +6,15 @@ import (
 	"sync"
 	"time"
 
+	"go-chat/config"
 	"go-chat/pkg"
 )
 
+// RateLimiter limits requests per client IP and per user, with limits set
+// by endpoint type. Stop ends its background cleanup.
 type RateLimiter struct {
+	configs map[string]RateLimitConfig
+
 	ipLimiters map[string]*TokenBucket
 	ipMutex    sync.RWMutex
 
@@ -17,12 +22,14 @@ type RateLimiter struct {
 	userMutex    sync.RWMutex
 
 	cleanupTicker *time.Ticker
+	stop          chan struct{}
+	stopOnce      sync.Once
 }
 
 type TokenBucket struct {
-	capacity     int
-	tokens       int
-	refillRate   int
+	capacity     float64
+	tokens       float64
+	refillRate   float64
 	lastRefill   time.Time
 	mutex        sync.Mutex
 }
@@ -32,46 +39,38 @@ type RateLimitConfig struct {
 	BurstSize         int
 }
 
-var rateLimitConfigs = map[string]RateLimitConfig{
-	"auth": {
-		RequestsPerMinute: 10,
-		BurstSize:         5,
-	},
-	"message": {
-		RequestsPerMinute: 60,
-		BurstSize:         10,
-	},
-	"search": {
-		RequestsPerMinute: 30,
-		BurstSize:         5,
-	},
-	"friends": {
-		RequestsPerMinute: 20,
-		BurstSize:         5,
-	},
-	"default": {
-		RequestsPerMinute: 100,
-		BurstSize:         20,
-	},
-}
-
-var globalRateLimiter *RateLimiter
-
-func InitRateLimiter() {
-	globalRateLimiter = &RateLimiter{
-		ipLimiters:   make(map[string]*TokenBucket),
-		userLimiters: make(map[uint]*TokenBucket),
+// NewRateLimiter applies the per-endpoint limits from configuration;
+// "default" applies to endpoint types without their own entry, and falls
+// back to 100 requests a minute with bursts of 20.
+func NewRateLimiter(configs map[string]config.RateLimitConfig) *RateLimiter {
+	rl := &RateLimiter{
+		configs: map[string]RateLimitConfig{
+			"default": {
+				RequestsPerMinute: 100,
+				BurstSize:         20,
+			},
+		},
+		ipLimiters:    make(map[string]*TokenBucket),
+		userLimiters:  make(map[uint]*TokenBucket),
+		cleanupTicker: time.NewTicker(10 * time.Minute),
+		stop:          make(chan struct{}),
+	}
+	for name, limit := range configs {
+		rl.configs[name] = RateLimitConfig{
+			RequestsPerMinute: limit.RequestsPerMinute,
+			BurstSize:         limit.BurstSize,
+		}
 	}
 
-	globalRateLimiter.cleanupTicker = time.NewTicker(10 * time.Minute)
-	go globalRateLimiter.cleanup()
+	go rl.cleanup()
+	return rl
 }
 
 func NewTokenBucket(config RateLimitConfig) *TokenBucket {
 	return &TokenBucket{
-		capacity:   config.BurstSize,
-		tokens:     config.BurstSize,
-		refillRate: config.RequestsPerMinute / 60,
+		capacity:   float64(config.BurstSize),
+		tokens:     float64(config.BurstSize),
+		refillRate: float64(config.RequestsPerMinute) / 60,
 		lastRefill: time.Now(),
 	}
 }
@@ -83,16 +82,13 @@ func (tb *TokenBucket) Allow() bool {
 	now := time.Now()
 	elapsed := now.Sub(tb.lastRefill).Seconds()
 
-	tokensToAdd := int(elapsed * float64(tb.refillRate))
-	if tokensToAdd > 0 {
-		tb.tokens += tokensToAdd
-		if tb.tokens > tb.capacity {
-			tb.tokens = tb.capacity
-		}
-		tb.lastRefill = now
+	tb.tokens += elapsed * tb.refillRate
+	if tb.tokens > tb.capacity {
+		tb.tokens = tb.capacity
 	}
+	tb.lastRefill = now
 
-	if tb.tokens > 0 {
+	if tb.tokens >= 1 {
 		tb.tokens--
 		return true
 	}
@@ -100,28 +96,27 @@ func (tb *TokenBucket) Allow() bool {
 	return false
 }
 
-func RateLimit(endpointType string) func(http.Handler) http.Handler {
+// Limit rejects requests over the limits for endpointType.
+func (rl *RateLimiter) Limit(endpointType string) func(http.Handler) http.Handler {
+	config, exists := rl.configs[endpointType]
+	if !exists {
+		config = rl.configs["default"]
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if globalRateLimiter == nil {
-				InitRateLimiter()
-			}
-
-			config, exists := rateLimitConfigs[endpointType]
-			if !exists {
-				config = rateLimitConfigs["default"]
-			}
-
 			clientIP := getClientIP(r)
 
-			if !globalRateLimiter.checkIPRateLimit(clientIP, config) {
+			if !rl.checkIPRateLimit(clientIP, config) {
+				pkg.RateLimitRejectionsTotal.WithLabelValues(endpointType, "ip").Inc()
 				pkg.WriteErrorResponse(w, http.StatusTooManyRequests, 
 					"Rate limit exceeded for IP. Try again in a minute.")
 				return
 			}
 
 			if userID := getUserIDFromContext(r); userID != 0 {
-				if !globalRateLimiter.checkUserRateLimit(userID, config) {
+				if !rl.checkUserRateLimit(userID, config) {
+					pkg.RateLimitRejectionsTotal.WithLabelValues(endpointType, "user").Inc()
 					pkg.WriteErrorResponse(w, http.StatusTooManyRequests, 
 						"Rate limit exceeded for user. Try again in a minute.")
 					return
@@ -168,7 +163,13 @@ func (rl *RateLimiter) checkUserRateLimit(userID uint, config RateLimitConfig) b
 }
 
 func (rl *RateLimiter) cleanup() {
-	for range rl.cleanupTicker.C {
+	for {
+		select {
+		case <-rl.stop:
+			return
+		case <-rl.cleanupTicker.C:
+		}
+
 		now := time.Now()
 		cutoff := now.Add(-30 * time.Minute)
 
@@ -244,8 +245,7 @@ func getUserIDFromContext(r *http.Request) uint {
 	return 0
 }
 
-func StopRateLimiter() {
-	if globalRateLimiter != nil && globalRateLimiter.cleanupTicker != nil {
-		globalRateLimiter.cleanupTicker.Stop()
-	}
+func (rl *RateLimiter) Stop() {
+	rl.cleanupTicker.Stop()
+	rl.stopOnce.Do(func() { close(rl.stop) })
 } 
\ No newline at end of file
@@ -50,6 +50,10 @@ var validationRules = map[string]ValidationRules{
 		RequiredFields: []string{"receiver_id", "content"},
 		Sanitize:       true,
 	},
+	"scheduled_message": {
+		MaxBodySize:    10240,
+		Sanitize:       true,
+	},
 	"profile": {
 		MaxBodySize:    2048,
 		NameFields:     []string{"name"},
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+
+	"go-chat/internal/domain"
+)
+
+type AuditRepository interface {
+	// AppendEntry links the entry to the current chain head, hashes it and
+	// stores it. Concurrent appends must be serialized.
+	AppendEntry(ctx context.Context, entry *domain.AuditEntry) error
+
+	ListEntries(ctx context.Context, filter domain.AuditFilter, limit, offset int) ([]*domain.AuditEntry, int64, error)
+
+	ListUserEntries(ctx context.Context, userID uint, limit, offset int) ([]*domain.AuditEntry, int64, error)
+
+	// GetEntriesAfter returns entries in chain order with ID > afterID.
+	GetEntriesAfter(ctx context.Context, afterID uint, limit int) ([]*domain.AuditEntry, error)
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+)
+
+// ConversationRepository maintains the per-user conversation summaries. The
+// summaries are derived from the messages table, so every change to messages
+// must be followed by the matching call here in the same unit of work.
+type ConversationRepository interface {
+	// RecordMessage moves the conversation to the top for both participants,
+	// takes it out of their archives and counts the message as unread for
+	// the receiver.
+	RecordMessage(ctx context.Context, message *domain.Message) error
+
+	// GetConversation returns the user's summary of the conversation with
+	// peer without loading its associations.
+	GetConversation(ctx context.Context, userID, peerID uint) (*domain.ConversationSummary, error)
+
+	// MarkConversationRead moves the user's read cursor to the last message
+	// of the conversation and clears the unread count and MarkedUnread.
+	MarkConversationRead(ctx context.Context, userID, peerID uint, readAt time.Time) error
+
+	// SetReadCursor moves the user's read cursor to messageID, or before
+	// every message when it is nil, recounts the unread messages and clears
+	// MarkedUnread.
+	SetReadCursor(ctx context.Context, userID, peerID uint, messageID *uint, readAt time.Time) error
+
+	// SetArchived archives the user's conversation with peer at archivedAt,
+	// or unarchives it when nil.
+	SetArchived(ctx context.Context, userID, peerID uint, archivedAt *time.Time) error
+
+	// SetPinned pins the user's conversation with peer at pinnedAt, or
+	// unpins it when nil.
+	SetPinned(ctx context.Context, userID, peerID uint, pinnedAt *time.Time) error
+
+	// SetMuted mutes the user's conversation with peer from mutedAt until
+	// mutedUntil, or for good when that is nil, or unmutes it when mutedAt
+	// is nil.
+	SetMuted(ctx context.Context, userID, peerID uint, mutedAt, mutedUntil *time.Time) error
+
+	// SetMarkedUnread sets or clears the user's MarkedUnread flag without
+	// moving their read cursor.
+	SetMarkedUnread(ctx context.Context, userID, peerID uint, markedUnread bool) error
+
+	// GetDisappearingTimer returns how long messages between the two users
+	// last after they are sent, or zero when they do not disappear.
+	GetDisappearingTimer(ctx context.Context, userID1, userID2 uint) (time.Duration, error)
+
+	// SetDisappearingTimer sets the timer shared by both users, recording
+	// who set it; zero turns it off.
+	SetDisappearingTimer(ctx context.Context, userID1, userID2 uint, after time.Duration, setByID uint) error
+
+	// RefreshConversation recomputes both participants' summaries from their
+	// live messages, removing them when none are left. It is for changes
+	// that cannot be applied incrementally, such as deletions.
+	RefreshConversation(ctx context.Context, userID1, userID2 uint) error
+
+	// ListConversations returns the user's archived or unarchived
+	// conversations, pinned ones first, most recently pinned first, and then
+	// most recent first, with the peer, the last message and its sender, and
+	// the disappearing-message timer loaded. A non-empty query keeps those
+	// whose peer's name or email contains it.
+	ListConversations(ctx context.Context, userID uint, query string, archived bool, limit, offset int) ([]*domain.ConversationSummary, error)
+
+	// DeleteUserConversations removes the user's own summaries and the
+	// disappearing-message timers of their conversations. The other
+	// participants keep their summaries.
+	DeleteUserConversations(ctx context.Context, userID uint) error
+
+	// RebuildConversations replaces every summary with one recomputed from
+	// the messages table and returns how many were written.
+	RebuildConversations(ctx context.Context) (int64, error)
+}
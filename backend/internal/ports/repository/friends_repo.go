@@ -1,23 +1,34 @@
 package repository
 
-import "go-chat/internal/domain"
+import (
+	"context"
+
+	"go-chat/internal/domain"
+)
 
 type FriendsRepository interface {
-	CreateFriendship(friendship *domain.Friendship) error
+	CreateFriendship(ctx context.Context, friendship *domain.Friendship) error
+	
+	FindFriendshipByID(ctx context.Context, id uint) (*domain.Friendship, error)
+	
+	FindFriendshipBetweenUsers(ctx context.Context, userID1, userID2 uint) (*domain.Friendship, error)
+	
+	UpdateFriendshipStatus(ctx context.Context, id uint, status domain.FriendshipStatus) error
 	
-	FindFriendshipByID(id uint) (*domain.Friendship, error)
+	// BlockFriendship marks the friendship blocked by blockerID.
+	BlockFriendship(ctx context.Context, id, blockerID uint) error
 	
-	FindFriendshipBetweenUsers(userID1, userID2 uint) (*domain.Friendship, error)
+	GetUserFriends(ctx context.Context, userID uint) ([]*domain.Friendship, error)
 	
-	UpdateFriendshipStatus(id uint, status domain.FriendshipStatus) error
+	GetPendingFriendRequests(ctx context.Context, userID uint) ([]*domain.Friendship, error)
 	
-	GetUserFriends(userID uint) ([]*domain.Friendship, error)
+	GetSentFriendRequests(ctx context.Context, userID uint) ([]*domain.Friendship, error)
 	
-	GetPendingFriendRequests(userID uint) ([]*domain.Friendship, error)
+	DeleteFriendship(ctx context.Context, id uint) error
 	
-	GetSentFriendRequests(userID uint) ([]*domain.Friendship, error)
+	AreFriends(ctx context.Context, userID1, userID2 uint) (bool, error)
 	
-	DeleteFriendship(id uint) error
+	GetAllUserFriendships(ctx context.Context, userID uint) ([]*domain.Friendship, error)
 	
-	AreFriends(userID1, userID2 uint) (bool, error)
+	DeleteUserFriendships(ctx context.Context, userID uint) error
 }
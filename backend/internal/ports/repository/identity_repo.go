@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"go-chat/internal/domain"
+)
+
+type IdentityRepository interface {
+	CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error
+
+	FindIdentity(ctx context.Context, provider, subject string) (*domain.UserIdentity, error)
+
+	GetUserIdentities(ctx context.Context, userID uint) ([]*domain.UserIdentity, error)
+
+	DeleteIdentity(ctx context.Context, userID uint, provider string) error
+
+	DeleteUserIdentities(ctx context.Context, userID uint) error
+
+	SaveOAuthState(ctx context.Context, state *domain.OAuthState) error
+
+	ConsumeOAuthState(ctx context.Context, state string) (*domain.OAuthState, error)
+}
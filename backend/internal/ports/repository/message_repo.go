@@ -1,25 +1,92 @@
 package repository
 
-import "go-chat/internal/domain"
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+)
 
 type MessageRepository interface {
-	CreateMessage(message *domain.Message) error
-	
-	GetMessagesBetweenUsers(userID1, userID2 uint, limit, offset int) ([]*domain.Message, error)
+	CreateMessage(ctx context.Context, message *domain.Message) error
 	
-	GetUserConversations(userID uint) ([]*domain.ConversationResponse, error)
+	// GetMessagesBetweenUsers returns the conversation as userID sees it,
+	// leaving out the messages they deleted for themselves.
+	GetMessagesBetweenUsers(ctx context.Context, userID, peerID uint, limit, offset int) ([]*domain.Message, error)
 	
-	MarkMessagesAsRead(senderID, receiverID uint) error
+	// MarkMessageDelivered stamps the user's receipt for the message unless
+	// it was already delivered.
+	MarkMessageDelivered(ctx context.Context, messageID, userID uint, deliveredAt time.Time) error
+
+	// MarkMessagesRead stamps the receiver's receipts for everything the
+	// sender sent up to and including upToID that was not read yet; reading a
+	// message also marks it delivered.
+	MarkMessagesRead(ctx context.Context, senderID, receiverID, upToID uint, readAt time.Time) error
+
+	GetMessageReceipts(ctx context.Context, messageID uint) ([]*domain.MessageReceipt, error)
 	
-	MarkMessageAsDelivered(messageID uint) error
+	GetMessageByID(ctx context.Context, messageID uint) (*domain.Message, error)
 	
-	GetUnreadMessageCount(senderID, receiverID uint) (int, error)
+	DeleteMessage(ctx context.Context, messageID uint) error
+
+	// HideMessage deletes the message for userID only.
+	HideMessage(ctx context.Context, messageID, userID uint) error
+
+	// HideConversation deletes every message currently exchanged with peer
+	// for userID only.
+	HideConversation(ctx context.Context, userID, peerID uint) error
+
+	// TombstoneMessage deletes the message for everyone, leaving a
+	// placeholder in its place.
+	TombstoneMessage(ctx context.Context, messageID uint) error
 	
-	GetMessageByID(messageID uint) (*domain.Message, error)
+	GetLatestMessageBetweenUsers(ctx context.Context, userID1, userID2 uint) (*domain.Message, error)
+
+	// GetMessageBefore returns the message with the highest ID below
+	// beforeID exchanged by the two users.
+	GetMessageBefore(ctx context.Context, userID1, userID2, beforeID uint) (*domain.Message, error)
 	
-	DeleteMessage(messageID uint) error
+	// SearchMessages leaves out the messages userID deleted for themselves.
+	SearchMessages(ctx context.Context, userID uint, query string, limit, offset int) ([]*domain.Message, error)
 	
-	GetLatestMessageBetweenUsers(userID1, userID2 uint) (*domain.Message, error)
+	GetAllUserMessages(ctx context.Context, userID uint) ([]*domain.Message, error)
 	
-	SearchMessages(userID uint, query string, limit, offset int) ([]*domain.Message, error)
+	TombstoneUserMessages(ctx context.Context, senderID uint) error
+
+	// PinMessage stores the pin unless the message is already pinned, and
+	// reports whether it did.
+	PinMessage(ctx context.Context, pin *domain.PinnedMessage) (bool, error)
+
+	// UnpinMessage reports whether the message was pinned.
+	UnpinMessage(ctx context.Context, messageID uint) (bool, error)
+
+	// CountPinnedMessages counts the pinned messages between the users that
+	// are neither deleted nor tombstones.
+	CountPinnedMessages(ctx context.Context, userID1, userID2 uint) (int64, error)
+
+	// GetPinnedMessages returns the pinned messages of the conversation as
+	// userID sees it, most recently pinned first, with PinnedAt and
+	// PinnedByID loaded.
+	GetPinnedMessages(ctx context.Context, userID, peerID uint, limit, offset int) ([]*domain.Message, error)
+
+	// StarMessage stars the message for userID only; starring twice is a
+	// no-op.
+	StarMessage(ctx context.Context, messageID, userID uint) error
+
+	UnstarMessage(ctx context.Context, messageID, userID uint) error
+
+	// GetStarredMessages returns the messages userID starred and can still
+	// see, most recently starred first, with StarredAt loaded.
+	GetStarredMessages(ctx context.Context, userID uint, limit, offset int) ([]*domain.Message, error)
+
+	// DeleteUserMessageState removes what the user kept about messages for
+	// themselves: their stars and the messages they hid, plus the pins they
+	// set or that are on messages they sent.
+	DeleteUserMessageState(ctx context.Context, userID uint) error
+
+	// DeleteExpiredMessages removes up to limit messages that expired by now
+	// for good, along with their receipts, hidden markers, pins and stars,
+	// and returns them, oldest expiry first. Conversation summaries must be
+	// refreshed afterwards.
+	DeleteExpiredMessages(ctx context.Context, now time.Time, limit int) ([]*domain.Message, error)
 }
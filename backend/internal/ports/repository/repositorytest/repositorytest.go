@@ -0,0 +1,2323 @@
+// Package repositorytest is a conformance suite for implementations of the
+// repository ports. Every adapter is run through the same cases so that
+// in-memory fakes used by service tests behave like the real database.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+
+	"gorm.io/gorm"
+)
+
+// Factory returns repositories over a fresh, empty data set. Every
+// repository must be set and share the same underlying data.
+type Factory func(t *testing.T) repository.Repositories
+
+// Run exercises every repository built by newRepos.
+func Run(t *testing.T, newRepos Factory) {
+	t.Run("Users", func(t *testing.T) { runUserTests(t, newRepos) })
+	t.Run("Friends", func(t *testing.T) { runFriendsTests(t, newRepos) })
+	t.Run("Messages", func(t *testing.T) { runMessageTests(t, newRepos) })
+	t.Run("Conversations", func(t *testing.T) { runConversationTests(t, newRepos) })
+	t.Run("ScheduledMessages", func(t *testing.T) { runScheduledMessageTests(t, newRepos) })
+	t.Run("Identities", func(t *testing.T) { runIdentityTests(t, newRepos) })
+	t.Run("Tokens", func(t *testing.T) { runTokenTests(t, newRepos) })
+	t.Run("Sessions", func(t *testing.T) { runSessionTests(t, newRepos) })
+	t.Run("Audit", func(t *testing.T) { runAuditTests(t, newRepos) })
+}
+
+func runUserTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createUser(t, repos, "Alice", "alice@example.com")
+
+		if user.ID == 0 {
+			t.Fatal("Create did not assign an ID")
+		}
+		if user.Role != domain.RoleUser {
+			t.Errorf("Role = %q, want %q", user.Role, domain.RoleUser)
+		}
+
+		byID, err := repos.Users.GetUserByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if byID.Email != "alice@example.com" {
+			t.Errorf("GetUserByID email = %q", byID.Email)
+		}
+
+		byEmail, err := repos.Users.FindByEmail(ctx, "alice@example.com")
+		if err != nil {
+			t.Fatalf("FindByEmail: %v", err)
+		}
+		if byEmail.ID != user.ID {
+			t.Errorf("FindByEmail ID = %d, want %d", byEmail.ID, user.ID)
+		}
+
+		if _, err := repos.Users.GetUserByID(ctx, user.ID+1000); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetUserByID(missing) error = %v, want ErrRecordNotFound", err)
+		}
+		if _, err := repos.Users.FindByEmail(ctx, "nobody@example.com"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("FindByEmail(missing) error = %v, want ErrRecordNotFound", err)
+		}
+	})
+
+	t.Run("DuplicateEmail", func(t *testing.T) {
+		repos := newRepos(t)
+		createUser(t, repos, "Alice", "alice@example.com")
+
+		err := repos.Users.Create(ctx, &domain.User{Name: "Other", Email: "alice@example.com", Password: "x"})
+		if err == nil {
+			t.Fatal("Create with a duplicate email succeeded")
+		}
+	})
+
+	t.Run("SoftDelete", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		if err := repos.Users.DeleteUser(ctx, alice.ID); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+
+		if _, err := repos.Users.GetUserByID(ctx, alice.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetUserByID(deleted) error = %v, want ErrRecordNotFound", err)
+		}
+		if _, err := repos.Users.FindByEmail(ctx, "alice@example.com"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("FindByEmail(deleted) error = %v, want ErrRecordNotFound", err)
+		}
+
+		users, total, err := repos.Users.ListUsers(ctx, "", 10, 0)
+		if err != nil {
+			t.Fatalf("ListUsers: %v", err)
+		}
+		if total != 1 || len(users) != 1 || users[0].ID != bob.ID {
+			t.Errorf("ListUsers after delete = %v (total %d), want only Bob", userIDs(users), total)
+		}
+	})
+
+	t.Run("UpdatePasswordClearsResetFlag", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createUser(t, repos, "Alice", "alice@example.com")
+
+		if err := repos.Users.SetPasswordResetRequired(ctx, user.ID, true); err != nil {
+			t.Fatalf("SetPasswordResetRequired: %v", err)
+		}
+		updated, err := repos.Users.UpdatePassword(ctx, user.ID, "new-hash")
+		if err != nil {
+			t.Fatalf("UpdatePassword: %v", err)
+		}
+		if updated.Password != "new-hash" || updated.PasswordResetRequired {
+			t.Errorf("UpdatePassword = %q reset=%v, want new-hash reset=false", updated.Password, updated.PasswordResetRequired)
+		}
+	})
+
+	t.Run("ReadReceiptsDisabled", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createUser(t, repos, "Alice", "alice@example.com")
+		if user.ReadReceiptsDisabled {
+			t.Fatal("new user has read receipts disabled")
+		}
+
+		if err := repos.Users.SetReadReceiptsDisabled(ctx, user.ID, true); err != nil {
+			t.Fatalf("SetReadReceiptsDisabled: %v", err)
+		}
+		updated, err := repos.Users.GetUserByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if !updated.ReadReceiptsDisabled {
+			t.Error("ReadReceiptsDisabled not saved")
+		}
+	})
+
+	t.Run("NotificationPreferences", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createUser(t, repos, "Alice", "alice@example.com")
+
+		preferences := domain.NotificationPreferences{
+			Level:           domain.NotificationLevelMentions,
+			QuietHoursStart: "22:00",
+			QuietHoursEnd:   "07:30",
+			Timezone:        "Europe/Berlin",
+		}
+		if err := repos.Users.SetNotificationPreferences(ctx, user.ID, preferences); err != nil {
+			t.Fatalf("SetNotificationPreferences: %v", err)
+		}
+		updated, err := repos.Users.GetUserByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if updated.Notifications != preferences {
+			t.Errorf("Notifications = %+v, want %+v", updated.Notifications, preferences)
+		}
+	})
+
+	t.Run("UpdateUserProfile", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createUser(t, repos, "Alice", "alice@example.com")
+
+		updated, err := repos.Users.UpdateUserProfile(ctx, user.ID, "Alicia", "alicia@example.com")
+		if err != nil {
+			t.Fatalf("UpdateUserProfile: %v", err)
+		}
+		if updated.Name != "Alicia" || updated.Email != "alicia@example.com" {
+			t.Errorf("UpdateUserProfile = %q <%s>", updated.Name, updated.Email)
+		}
+		if _, err := repos.Users.FindByEmail(ctx, "alicia@example.com"); err != nil {
+			t.Errorf("FindByEmail(new email): %v", err)
+		}
+	})
+
+	t.Run("SearchUsers", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice Smith", "alice@example.com")
+		bob := createUser(t, repos, "Bob Smith", "bob@example.com")
+		carol := createUser(t, repos, "Carol Smith", "carol@example.com")
+		dave := createUser(t, repos, "Dave Smith", "dave@example.com")
+		createUser(t, repos, "Erin Jones", "erin@example.com")
+
+		if err := repos.Users.AnonymizeUser(ctx, carol.ID, time.Now()); err != nil {
+			t.Fatalf("AnonymizeUser: %v", err)
+		}
+		if err := repos.Users.DeleteUser(ctx, dave.ID); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+
+		users, err := repos.Users.SearchUsers(ctx, "SMITH", alice.ID)
+		if err != nil {
+			t.Fatalf("SearchUsers: %v", err)
+		}
+		if got := userIDs(users); len(got) != 1 || got[0] != bob.ID {
+			t.Errorf("SearchUsers = %v, want [%d]", got, bob.ID)
+		}
+	})
+
+	t.Run("ListUsers", func(t *testing.T) {
+		repos := newRepos(t)
+		var ids []uint
+		for _, name := range []string{"Anna", "Ben", "Cara", "Dan"} {
+			ids = append(ids, createUser(t, repos, name, name+"@example.com").ID)
+		}
+		createUser(t, repos, "Zed", "zed@other.org")
+
+		users, total, err := repos.Users.ListUsers(ctx, "EXAMPLE", 2, 1)
+		if err != nil {
+			t.Fatalf("ListUsers: %v", err)
+		}
+		if total != 4 {
+			t.Errorf("ListUsers total = %d, want 4", total)
+		}
+		if got := userIDs(users); len(got) != 2 || got[0] != ids[1] || got[1] != ids[2] {
+			t.Errorf("ListUsers page = %v, want %v", got, ids[1:3])
+		}
+
+		users, _, err = repos.Users.ListUsers(ctx, "zed", 10, 0)
+		if err != nil {
+			t.Fatalf("ListUsers by name: %v", err)
+		}
+		if len(users) != 1 || users[0].Name != "Zed" {
+			t.Errorf("ListUsers(zed) = %v", userIDs(users))
+		}
+	})
+
+	t.Run("GetBotsByOwner", func(t *testing.T) {
+		repos := newRepos(t)
+		owner := createUser(t, repos, "Owner", "owner@example.com")
+		other := createUser(t, repos, "Other", "other@example.com")
+
+		older := &domain.User{Name: "Old bot", Email: "old@bots.invalid", Password: "x", IsBot: true, OwnerID: &owner.ID, CreatedAt: time.Now().Add(-time.Hour)}
+		newer := &domain.User{Name: "New bot", Email: "new@bots.invalid", Password: "x", IsBot: true, OwnerID: &owner.ID, CreatedAt: time.Now().Add(-time.Minute)}
+		foreign := &domain.User{Name: "Foreign bot", Email: "foreign@bots.invalid", Password: "x", IsBot: true, OwnerID: &other.ID}
+		for _, bot := range []*domain.User{newer, older, foreign} {
+			if err := repos.Users.Create(ctx, bot); err != nil {
+				t.Fatalf("Create bot: %v", err)
+			}
+		}
+
+		bots, err := repos.Users.GetBotsByOwner(ctx, owner.ID)
+		if err != nil {
+			t.Fatalf("GetBotsByOwner: %v", err)
+		}
+		if got := userIDs(bots); len(got) != 2 || got[0] != older.ID || got[1] != newer.ID {
+			t.Errorf("GetBotsByOwner = %v, want [%d %d]", got, older.ID, newer.ID)
+		}
+	})
+
+	t.Run("RoleAndSuspension", func(t *testing.T) {
+		repos := newRepos(t)
+		user := createUser(t, repos, "Alice", "alice@example.com")
+		suspendedAt := time.Now()
+
+		if err := repos.Users.UpdateUserRole(ctx, user.ID, domain.RoleModerator); err != nil {
+			t.Fatalf("UpdateUserRole: %v", err)
+		}
+		if err := repos.Users.SetUserSuspension(ctx, user.ID, &suspendedAt, "spam"); err != nil {
+			t.Fatalf("SetUserSuspension: %v", err)
+		}
+
+		got, err := repos.Users.GetUserByID(ctx, user.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID: %v", err)
+		}
+		if got.Role != domain.RoleModerator || !got.IsSuspended() || got.SuspensionReason != "spam" {
+			t.Errorf("user = role %q suspended %v reason %q", got.Role, got.IsSuspended(), got.SuspensionReason)
+		}
+
+		if err := repos.Users.SetUserSuspension(ctx, user.ID, nil, ""); err != nil {
+			t.Fatalf("SetUserSuspension(nil): %v", err)
+		}
+		got, _ = repos.Users.GetUserByID(ctx, user.ID)
+		if got.IsSuspended() {
+			t.Error("user still suspended after lifting suspension")
+		}
+
+		deleted := createUser(t, repos, "Bob", "bob@example.com")
+		if err := repos.Users.UpdateUserRole(ctx, deleted.ID, domain.RoleModerator); err != nil {
+			t.Fatalf("UpdateUserRole: %v", err)
+		}
+		if err := repos.Users.DeleteUser(ctx, deleted.ID); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		createUser(t, repos, "Carol", "carol@example.com")
+		for role, want := range map[domain.Role]int64{domain.RoleUser: 1, domain.RoleModerator: 1, domain.RoleAdmin: 0} {
+			if count, err := repos.Users.CountUsersByRole(ctx, role); err != nil || count != want {
+				t.Errorf("CountUsersByRole(%q) = %d, %v, want %d", role, count, err, want)
+			}
+		}
+	})
+
+	t.Run("PendingDeletionAndAnonymize", func(t *testing.T) {
+		repos := newRepos(t)
+		due := createUser(t, repos, "Due", "due@example.com")
+		recent := createUser(t, repos, "Recent", "recent@example.com")
+		createUser(t, repos, "Active", "active@example.com")
+
+		now := time.Now()
+		long := now.Add(-48 * time.Hour)
+		if err := repos.Users.SetDeletionRequested(ctx, due.ID, &long); err != nil {
+			t.Fatalf("SetDeletionRequested: %v", err)
+		}
+		if err := repos.Users.SetDeletionRequested(ctx, recent.ID, &now); err != nil {
+			t.Fatalf("SetDeletionRequested: %v", err)
+		}
+
+		pending, err := repos.Users.GetUsersPendingDeletion(ctx, now.Add(-24*time.Hour))
+		if err != nil {
+			t.Fatalf("GetUsersPendingDeletion: %v", err)
+		}
+		if got := userIDs(pending); len(got) != 1 || got[0] != due.ID {
+			t.Fatalf("GetUsersPendingDeletion = %v, want [%d]", got, due.ID)
+		}
+
+		if err := repos.Users.AnonymizeUser(ctx, due.ID, now); err != nil {
+			t.Fatalf("AnonymizeUser: %v", err)
+		}
+		got, err := repos.Users.GetUserByID(ctx, due.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID(anonymized): %v", err)
+		}
+		if got.Name != "Deleted User" || got.Email == "due@example.com" || got.Password != "" || got.DeletionRequestedAt != nil {
+			t.Errorf("anonymized user = %q <%s>", got.Name, got.Email)
+		}
+
+		pending, _ = repos.Users.GetUsersPendingDeletion(ctx, now.Add(-24*time.Hour))
+		if len(pending) != 0 {
+			t.Errorf("anonymized user still pending deletion")
+		}
+	})
+}
+
+func runFriendsTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateAndFind", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		friendship := createFriendship(t, repos, alice.ID, bob.ID, domain.FriendshipPending)
+		if friendship.ID == 0 {
+			t.Fatal("CreateFriendship did not assign an ID")
+		}
+
+		found, err := repos.Friends.FindFriendshipByID(ctx, friendship.ID)
+		if err != nil {
+			t.Fatalf("FindFriendshipByID: %v", err)
+		}
+		if found.Status != domain.FriendshipPending {
+			t.Errorf("Status = %q, want pending", found.Status)
+		}
+		if found.Requester == nil || found.Requester.ID != alice.ID || found.Addressee == nil || found.Addressee.ID != bob.ID {
+			t.Errorf("FindFriendshipByID did not load both users")
+		}
+
+		// The pair is found whichever way round it is asked for.
+		reversed, err := repos.Friends.FindFriendshipBetweenUsers(ctx, bob.ID, alice.ID)
+		if err != nil {
+			t.Fatalf("FindFriendshipBetweenUsers: %v", err)
+		}
+		if reversed.ID != friendship.ID {
+			t.Errorf("FindFriendshipBetweenUsers ID = %d, want %d", reversed.ID, friendship.ID)
+		}
+
+		if _, err := repos.Friends.FindFriendshipByID(ctx, friendship.ID+1000); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("FindFriendshipByID(missing) error = %v, want ErrRecordNotFound", err)
+		}
+	})
+
+	t.Run("DuplicatePair", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		createFriendship(t, repos, alice.ID, bob.ID, domain.FriendshipPending)
+
+		err := repos.Friends.CreateFriendship(ctx, &domain.Friendship{RequesterID: alice.ID, AddresseeID: bob.ID, Status: domain.FriendshipPending})
+		if err == nil {
+			t.Fatal("CreateFriendship for an existing pair succeeded")
+		}
+	})
+
+	t.Run("StatusFiltering", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+		dave := createUser(t, repos, "Dave", "dave@example.com")
+
+		accepted := createFriendship(t, repos, alice.ID, bob.ID, domain.FriendshipPending)
+		if err := repos.Friends.UpdateFriendshipStatus(ctx, accepted.ID, domain.FriendshipAccepted); err != nil {
+			t.Fatalf("UpdateFriendshipStatus: %v", err)
+		}
+		incoming := createFriendship(t, repos, carol.ID, alice.ID, domain.FriendshipPending)
+		outgoing := createFriendship(t, repos, alice.ID, dave.ID, domain.FriendshipPending)
+		createFriendship(t, repos, bob.ID, carol.ID, domain.FriendshipBlocked)
+
+		friends, err := repos.Friends.GetUserFriends(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetUserFriends: %v", err)
+		}
+		if got := friendshipIDs(friends); len(got) != 1 || got[0] != accepted.ID {
+			t.Errorf("GetUserFriends = %v, want [%d]", got, accepted.ID)
+		}
+
+		pending, err := repos.Friends.GetPendingFriendRequests(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetPendingFriendRequests: %v", err)
+		}
+		if got := friendshipIDs(pending); len(got) != 1 || got[0] != incoming.ID {
+			t.Errorf("GetPendingFriendRequests = %v, want [%d]", got, incoming.ID)
+		}
+
+		sent, err := repos.Friends.GetSentFriendRequests(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetSentFriendRequests: %v", err)
+		}
+		if got := friendshipIDs(sent); len(got) != 1 || got[0] != outgoing.ID {
+			t.Errorf("GetSentFriendRequests = %v, want [%d]", got, outgoing.ID)
+		}
+
+		for _, tc := range []struct {
+			a, b uint
+			want bool
+		}{
+			{alice.ID, bob.ID, true},
+			{bob.ID, alice.ID, true},
+			{alice.ID, carol.ID, false},
+			{bob.ID, carol.ID, false},
+		} {
+			got, err := repos.Friends.AreFriends(ctx, tc.a, tc.b)
+			if err != nil {
+				t.Fatalf("AreFriends: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("AreFriends(%d, %d) = %v, want %v", tc.a, tc.b, got, tc.want)
+			}
+		}
+
+		all, err := repos.Friends.GetAllUserFriendships(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetAllUserFriendships: %v", err)
+		}
+		if len(all) != 3 {
+			t.Errorf("GetAllUserFriendships returned %d, want 3", len(all))
+		}
+	})
+
+	t.Run("BlockFriendship", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		friendship := createFriendship(t, repos, alice.ID, bob.ID, domain.FriendshipAccepted)
+
+		if err := repos.Friends.BlockFriendship(ctx, friendship.ID, bob.ID); err != nil {
+			t.Fatalf("BlockFriendship: %v", err)
+		}
+
+		found, err := repos.Friends.FindFriendshipBetweenUsers(ctx, alice.ID, bob.ID)
+		if err != nil {
+			t.Fatalf("FindFriendshipBetweenUsers: %v", err)
+		}
+		if found.RequesterID != alice.ID || !found.IsBlockedBy(bob.ID) || found.IsBlockedBy(alice.ID) {
+			t.Errorf("friendship after block = %+v, want requested by Alice and blocked by Bob", found)
+		}
+	})
+
+	t.Run("DeleteFriendship", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		friendship := createFriendship(t, repos, alice.ID, bob.ID, domain.FriendshipAccepted)
+
+		if err := repos.Friends.DeleteFriendship(ctx, friendship.ID); err != nil {
+			t.Fatalf("DeleteFriendship: %v", err)
+		}
+		if _, err := repos.Friends.FindFriendshipByID(ctx, friendship.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("FindFriendshipByID(deleted) error = %v, want ErrRecordNotFound", err)
+		}
+		if friends, _ := repos.Friends.AreFriends(ctx, alice.ID, bob.ID); friends {
+			t.Error("AreFriends still true after delete")
+		}
+
+		// A removed friendship does not block a new request between the pair.
+		createFriendship(t, repos, alice.ID, bob.ID, domain.FriendshipPending)
+	})
+
+	t.Run("DeleteUserFriendships", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+		createFriendship(t, repos, alice.ID, bob.ID, domain.FriendshipAccepted)
+		createFriendship(t, repos, carol.ID, alice.ID, domain.FriendshipPending)
+		kept := createFriendship(t, repos, bob.ID, carol.ID, domain.FriendshipAccepted)
+
+		if err := repos.Friends.DeleteUserFriendships(ctx, alice.ID); err != nil {
+			t.Fatalf("DeleteUserFriendships: %v", err)
+		}
+
+		all, err := repos.Friends.GetAllUserFriendships(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetAllUserFriendships: %v", err)
+		}
+		if len(all) != 0 {
+			t.Errorf("GetAllUserFriendships after delete = %v", friendshipIDs(all))
+		}
+		if _, err := repos.Friends.FindFriendshipByID(ctx, kept.ID); err != nil {
+			t.Errorf("unrelated friendship was removed: %v", err)
+		}
+	})
+}
+
+func runMessageTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("Conversation", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+
+		first := sendMessage(t, repos, alice.ID, bob.ID, "first")
+		second := sendMessage(t, repos, bob.ID, alice.ID, "second")
+		third := sendMessage(t, repos, alice.ID, bob.ID, "third")
+		sendMessage(t, repos, alice.ID, carol.ID, "elsewhere")
+
+		messages, err := repos.Messages.GetMessagesBetweenUsers(ctx, bob.ID, alice.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetMessagesBetweenUsers: %v", err)
+		}
+		if got := messageIDs(messages); len(got) != 3 || got[0] != first.ID || got[1] != second.ID || got[2] != third.ID {
+			t.Errorf("GetMessagesBetweenUsers = %v, want oldest first", got)
+		}
+		if messages[0].Sender.Name != "Alice" || messages[0].Receiver.Name != "Bob" {
+			t.Errorf("sender/receiver not loaded: %q -> %q", messages[0].Sender.Name, messages[0].Receiver.Name)
+		}
+		if messages[0].MessageType != domain.MessageTypeText {
+			t.Errorf("MessageType = %q, want default %q", messages[0].MessageType, domain.MessageTypeText)
+		}
+
+		page, err := repos.Messages.GetMessagesBetweenUsers(ctx, alice.ID, bob.ID, 1, 1)
+		if err != nil {
+			t.Fatalf("GetMessagesBetweenUsers page: %v", err)
+		}
+		if got := messageIDs(page); len(got) != 1 || got[0] != second.ID {
+			t.Errorf("GetMessagesBetweenUsers(limit 1, offset 1) = %v, want [%d]", got, second.ID)
+		}
+
+		latest, err := repos.Messages.GetLatestMessageBetweenUsers(ctx, bob.ID, alice.ID)
+		if err != nil {
+			t.Fatalf("GetLatestMessageBetweenUsers: %v", err)
+		}
+		if latest.ID != third.ID {
+			t.Errorf("GetLatestMessageBetweenUsers = %d, want %d", latest.ID, third.ID)
+		}
+
+		if err := repos.Messages.DeleteMessage(ctx, third.ID); err != nil {
+			t.Fatalf("DeleteMessage: %v", err)
+		}
+		if _, err := repos.Messages.GetMessageByID(ctx, third.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetMessageByID(deleted) error = %v, want ErrRecordNotFound", err)
+		}
+		latest, err = repos.Messages.GetLatestMessageBetweenUsers(ctx, alice.ID, bob.ID)
+		if err != nil {
+			t.Fatalf("GetLatestMessageBetweenUsers: %v", err)
+		}
+		if latest.ID != second.ID {
+			t.Errorf("GetLatestMessageBetweenUsers after delete = %d, want %d", latest.ID, second.ID)
+		}
+	})
+
+	t.Run("ReadAndDelivered", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		first := recordMessage(t, repos, alice.ID, bob.ID, "one")
+		recordMessage(t, repos, alice.ID, bob.ID, "two")
+		reply := recordMessage(t, repos, bob.ID, alice.ID, "reply")
+
+		if err := repos.Conversations.MarkConversationRead(ctx, bob.ID, alice.ID, time.Now()); err != nil {
+			t.Fatalf("MarkConversationRead: %v", err)
+		}
+		messages, err := repos.Messages.GetMessagesBetweenUsers(ctx, alice.ID, bob.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetMessagesBetweenUsers: %v", err)
+		}
+		for _, message := range messages {
+			// Bob's cursor covers everything; Alice hasn't read his reply.
+			if want := message.ID != reply.ID; message.IsRead != want {
+				t.Errorf("message %d IsRead = %v, want %v", message.ID, message.IsRead, want)
+			}
+		}
+
+		if err := repos.Messages.MarkMessageDelivered(ctx, first.ID, bob.ID, time.Now()); err != nil {
+			t.Fatalf("MarkMessageDelivered: %v", err)
+		}
+		got, err := repos.Messages.GetMessageByID(ctx, first.ID)
+		if err != nil {
+			t.Fatalf("GetMessageByID: %v", err)
+		}
+		if !got.IsDelivered || !got.IsRead {
+			t.Errorf("message delivered=%v read=%v, want both", got.IsDelivered, got.IsRead)
+		}
+
+		before, err := repos.Messages.GetMessageBefore(ctx, bob.ID, alice.ID, reply.ID)
+		if err != nil {
+			t.Fatalf("GetMessageBefore: %v", err)
+		}
+		if before.ID != reply.ID-1 {
+			t.Errorf("GetMessageBefore(%d) = %d, want %d", reply.ID, before.ID, reply.ID-1)
+		}
+		if _, err := repos.Messages.GetMessageBefore(ctx, alice.ID, bob.ID, first.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetMessageBefore(first) error = %v, want ErrRecordNotFound", err)
+		}
+	})
+
+	t.Run("Receipts", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		first := sendMessage(t, repos, alice.ID, bob.ID, "one")
+		second := sendMessage(t, repos, alice.ID, bob.ID, "two")
+		third := sendMessage(t, repos, alice.ID, bob.ID, "three")
+
+		deliveredAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+		for _, at := range []time.Time{deliveredAt, deliveredAt.Add(time.Minute)} {
+			if err := repos.Messages.MarkMessageDelivered(ctx, first.ID, bob.ID, at); err != nil {
+				t.Fatalf("MarkMessageDelivered: %v", err)
+			}
+		}
+		readAt := time.Now().Truncate(time.Second)
+		if err := repos.Messages.MarkMessagesRead(ctx, alice.ID, bob.ID, second.ID, readAt); err != nil {
+			t.Fatalf("MarkMessagesRead: %v", err)
+		}
+
+		for _, tc := range []struct {
+			id                      uint
+			wantDelivered, wantRead *time.Time
+		}{
+			{first.ID, &deliveredAt, &readAt},
+			{second.ID, &readAt, &readAt},
+			{third.ID, nil, nil},
+		} {
+			receipts, err := repos.Messages.GetMessageReceipts(ctx, tc.id)
+			if err != nil {
+				t.Fatalf("GetMessageReceipts: %v", err)
+			}
+			if len(receipts) != 1 || receipts[0].UserID != bob.ID {
+				t.Fatalf("GetMessageReceipts(%d) = %+v, want one for Bob", tc.id, receipts)
+			}
+			if !sameTime(receipts[0].DeliveredAt, tc.wantDelivered) || !sameTime(receipts[0].ReadAt, tc.wantRead) {
+				t.Errorf("receipt %d delivered=%v read=%v, want %v and %v", tc.id, receipts[0].DeliveredAt, receipts[0].ReadAt, tc.wantDelivered, tc.wantRead)
+			}
+
+			message, err := repos.Messages.GetMessageByID(ctx, tc.id)
+			if err != nil {
+				t.Fatalf("GetMessageByID: %v", err)
+			}
+			if message.IsDelivered != (tc.wantDelivered != nil) || !sameTime(message.DeliveredAt, tc.wantDelivered) || !sameTime(message.ReadAt, tc.wantRead) {
+				t.Errorf("message %d delivered=%v at %v read at %v, want %v and %v", tc.id, message.IsDelivered, message.DeliveredAt, message.ReadAt, tc.wantDelivered, tc.wantRead)
+			}
+		}
+	})
+
+	t.Run("SearchMessages", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob Banana", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+
+		byContent := sendMessage(t, repos, carol.ID, alice.ID, "I like BANANAS")
+		byName := sendMessage(t, repos, alice.ID, bob.ID, "hello")
+		sendMessage(t, repos, alice.ID, carol.ID, "nothing to see")
+		sendMessage(t, repos, bob.ID, carol.ID, "banana for carol only")
+
+		messages, err := repos.Messages.SearchMessages(ctx, alice.ID, "banana", 10, 0)
+		if err != nil {
+			t.Fatalf("SearchMessages: %v", err)
+		}
+		if got := messageIDs(messages); len(got) != 2 || got[0] != byName.ID || got[1] != byContent.ID {
+			t.Errorf("SearchMessages = %v, want newest first [%d %d]", got, byName.ID, byContent.ID)
+		}
+	})
+
+	t.Run("TombstoneUserMessages", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		sendMessage(t, repos, alice.ID, bob.ID, "secret")
+		reply := sendMessage(t, repos, bob.ID, alice.ID, "reply")
+
+		if err := repos.Messages.TombstoneUserMessages(ctx, alice.ID); err != nil {
+			t.Fatalf("TombstoneUserMessages: %v", err)
+		}
+
+		messages, err := repos.Messages.GetAllUserMessages(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetAllUserMessages: %v", err)
+		}
+		if len(messages) != 2 {
+			t.Fatalf("GetAllUserMessages returned %d, want 2", len(messages))
+		}
+		for _, message := range messages {
+			tombstoned := message.Content == domain.TombstoneContent && message.MessageType == domain.MessageTypeTombstone
+			if message.ID == reply.ID && tombstoned {
+				t.Error("other user's message was tombstoned")
+			}
+			if message.ID != reply.ID && !tombstoned {
+				t.Errorf("message %d = %q, want tombstone", message.ID, message.Content)
+			}
+		}
+	})
+
+	t.Run("HideAndTombstone", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		hidden := sendMessage(t, repos, alice.ID, bob.ID, "hide me")
+		kept := sendMessage(t, repos, bob.ID, alice.ID, "hide me not")
+
+		// Hiding twice is a no-op.
+		for i := 0; i < 2; i++ {
+			if err := repos.Messages.HideMessage(ctx, hidden.ID, alice.ID); err != nil {
+				t.Fatalf("HideMessage: %v", err)
+			}
+		}
+
+		for _, tc := range []struct {
+			userID, peerID uint
+			want           []uint
+		}{
+			{alice.ID, bob.ID, []uint{kept.ID}},
+			{bob.ID, alice.ID, []uint{hidden.ID, kept.ID}},
+		} {
+			history, err := repos.Messages.GetMessagesBetweenUsers(ctx, tc.userID, tc.peerID, 10, 0)
+			if err != nil {
+				t.Fatalf("GetMessagesBetweenUsers: %v", err)
+			}
+			if got := messageIDs(history); !slices.Equal(got, tc.want) {
+				t.Errorf("history for %d = %v, want %v", tc.userID, got, tc.want)
+			}
+
+			found, err := repos.Messages.SearchMessages(ctx, tc.userID, "hide me", 10, 0)
+			if err != nil {
+				t.Fatalf("SearchMessages: %v", err)
+			}
+			if len(found) != len(tc.want) {
+				t.Errorf("search for %d = %v, want %d results", tc.userID, messageIDs(found), len(tc.want))
+			}
+		}
+
+		if err := repos.Messages.TombstoneMessage(ctx, kept.ID); err != nil {
+			t.Fatalf("TombstoneMessage: %v", err)
+		}
+		message, err := repos.Messages.GetMessageByID(ctx, kept.ID)
+		if err != nil {
+			t.Fatalf("GetMessageByID: %v", err)
+		}
+		if message.Content != domain.TombstoneContent || message.MessageType != domain.MessageTypeTombstone {
+			t.Errorf("message after TombstoneMessage = %q (%s), want tombstone", message.Content, message.MessageType)
+		}
+	})
+
+	t.Run("DeleteExpiredMessages", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		now := time.Now().UTC()
+
+		expiring := func(content string, expiresAt time.Time) *domain.Message {
+			t.Helper()
+			message := &domain.Message{
+				SenderID:   alice.ID,
+				ReceiverID: bob.ID,
+				Content:    content,
+				ExpiresAt:  &expiresAt,
+				Receipts:   []domain.MessageReceipt{{UserID: bob.ID}},
+			}
+			if err := repos.Messages.CreateMessage(ctx, message); err != nil {
+				t.Fatalf("CreateMessage: %v", err)
+			}
+			if err := repos.Conversations.RecordMessage(ctx, message); err != nil {
+				t.Fatalf("RecordMessage: %v", err)
+			}
+			return message
+		}
+		kept := recordMessage(t, repos, bob.ID, alice.ID, "forever")
+		hidden := expiring("hidden", now.Add(-time.Minute))
+		deleted := expiring("deleted", now.Add(-time.Hour))
+		pending := expiring("not yet", now.Add(time.Hour))
+
+		if err := repos.Messages.HideMessage(ctx, hidden.ID, alice.ID); err != nil {
+			t.Fatalf("HideMessage: %v", err)
+		}
+		if err := repos.Messages.DeleteMessage(ctx, deleted.ID); err != nil {
+			t.Fatalf("DeleteMessage: %v", err)
+		}
+		if _, err := repos.Messages.PinMessage(ctx, &domain.PinnedMessage{MessageID: hidden.ID, PinnedByID: bob.ID}); err != nil {
+			t.Fatalf("PinMessage: %v", err)
+		}
+		if err := repos.Messages.StarMessage(ctx, hidden.ID, bob.ID); err != nil {
+			t.Fatalf("StarMessage: %v", err)
+		}
+
+		first, err := repos.Messages.DeleteExpiredMessages(ctx, now, 1)
+		if err != nil {
+			t.Fatalf("DeleteExpiredMessages: %v", err)
+		}
+		if got, want := messageIDs(first), []uint{deleted.ID}; !slices.Equal(got, want) {
+			t.Errorf("DeleteExpiredMessages(limit 1) = %v, want %v", got, want)
+		}
+		rest, err := repos.Messages.DeleteExpiredMessages(ctx, now, 10)
+		if err != nil {
+			t.Fatalf("DeleteExpiredMessages: %v", err)
+		}
+		if got, want := messageIDs(rest), []uint{hidden.ID}; !slices.Equal(got, want) {
+			t.Errorf("DeleteExpiredMessages = %v, want %v", got, want)
+		}
+		if len(rest) == 1 && (rest[0].SenderID != alice.ID || rest[0].ReceiverID != bob.ID) {
+			t.Errorf("expired message between %d and %d, want %d and %d", rest[0].SenderID, rest[0].ReceiverID, alice.ID, bob.ID)
+		}
+
+		// Gone for good, soft-deleted ones included.
+		again, err := repos.Messages.DeleteExpiredMessages(ctx, now, 10)
+		if err != nil {
+			t.Fatalf("DeleteExpiredMessages: %v", err)
+		}
+		if len(again) != 0 {
+			t.Errorf("DeleteExpiredMessages found %v again", messageIDs(again))
+		}
+		receipts, err := repos.Messages.GetMessageReceipts(ctx, hidden.ID)
+		if err != nil {
+			t.Fatalf("GetMessageReceipts: %v", err)
+		}
+		if len(receipts) != 0 {
+			t.Errorf("receipts of a purged message = %+v", receipts)
+		}
+		if count, err := repos.Messages.CountPinnedMessages(ctx, alice.ID, bob.ID); err != nil || count != 0 {
+			t.Errorf("CountPinnedMessages after purge = %d, %v, want 0", count, err)
+		}
+		if starred, err := repos.Messages.GetStarredMessages(ctx, bob.ID, 10, 0); err != nil || len(starred) != 0 {
+			t.Errorf("GetStarredMessages after purge = %v, %v, want none", messageIDs(starred), err)
+		}
+
+		if err := repos.Conversations.RefreshConversation(ctx, alice.ID, bob.ID); err != nil {
+			t.Fatalf("RefreshConversation: %v", err)
+		}
+		for _, userID := range []uint{alice.ID, bob.ID} {
+			history, err := repos.Messages.GetMessagesBetweenUsers(ctx, userID, alice.ID+bob.ID-userID, 10, 0)
+			if err != nil {
+				t.Fatalf("GetMessagesBetweenUsers: %v", err)
+			}
+			if got, want := messageIDs(history), []uint{kept.ID, pending.ID}; !slices.Equal(got, want) {
+				t.Errorf("history for %d = %v, want %v", userID, got, want)
+			}
+		}
+		if summary := assertSummary(t, repos, bob.ID, alice.ID, 1); summary != nil && (summary.LastMessageID == nil || *summary.LastMessageID != pending.ID) {
+			t.Errorf("LastMessageID = %v, want %d", summary.LastMessageID, pending.ID)
+		}
+	})
+
+	t.Run("ExpiredBeforePurge", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		kept := recordMessage(t, repos, bob.ID, alice.ID, "secret forever")
+		expiresAt := time.Now().Add(-time.Second)
+		expired := &domain.Message{
+			SenderID:   alice.ID,
+			ReceiverID: bob.ID,
+			Content:    "secret gone",
+			ExpiresAt:  &expiresAt,
+			Receipts:   []domain.MessageReceipt{{UserID: bob.ID}},
+		}
+		if err := repos.Messages.CreateMessage(ctx, expired); err != nil {
+			t.Fatalf("CreateMessage: %v", err)
+		}
+		if err := repos.Conversations.RecordMessage(ctx, expired); err != nil {
+			t.Fatalf("RecordMessage: %v", err)
+		}
+		if _, err := repos.Messages.PinMessage(ctx, &domain.PinnedMessage{MessageID: expired.ID, PinnedByID: bob.ID}); err != nil {
+			t.Fatalf("PinMessage: %v", err)
+		}
+		if err := repos.Messages.StarMessage(ctx, expired.ID, bob.ID); err != nil {
+			t.Fatalf("StarMessage: %v", err)
+		}
+
+		if _, err := repos.Messages.GetMessageByID(ctx, expired.ID); err == nil {
+			t.Error("GetMessageByID returned an expired message")
+		}
+		history, err := repos.Messages.GetMessagesBetweenUsers(ctx, bob.ID, alice.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetMessagesBetweenUsers: %v", err)
+		}
+		if got, want := messageIDs(history), []uint{kept.ID}; !slices.Equal(got, want) {
+			t.Errorf("history = %v, want %v", got, want)
+		}
+		latest, err := repos.Messages.GetLatestMessageBetweenUsers(ctx, alice.ID, bob.ID)
+		if err != nil || latest.ID != kept.ID {
+			t.Errorf("GetLatestMessageBetweenUsers = %v, %v, want %d", latest, err, kept.ID)
+		}
+		found, err := repos.Messages.SearchMessages(ctx, bob.ID, "secret", 10, 0)
+		if err != nil {
+			t.Fatalf("SearchMessages: %v", err)
+		}
+		if got, want := messageIDs(found), []uint{kept.ID}; !slices.Equal(got, want) {
+			t.Errorf("SearchMessages = %v, want %v", got, want)
+		}
+		if pinned, err := repos.Messages.GetPinnedMessages(ctx, bob.ID, alice.ID, 10, 0); err != nil || len(pinned) != 0 {
+			t.Errorf("GetPinnedMessages = %v, %v, want none", messageIDs(pinned), err)
+		}
+		if starred, err := repos.Messages.GetStarredMessages(ctx, bob.ID, 10, 0); err != nil || len(starred) != 0 {
+			t.Errorf("GetStarredMessages = %v, %v, want none", messageIDs(starred), err)
+		}
+		if summary := assertSummary(t, repos, bob.ID, alice.ID, 1); summary != nil && summary.LastMessage != nil {
+			t.Errorf("conversation preview shows expired message %d", summary.LastMessage.ID)
+		}
+	})
+
+	t.Run("DeleteUserMessageState", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		fromAlice := sendMessage(t, repos, alice.ID, bob.ID, "from alice")
+		fromBob := sendMessage(t, repos, bob.ID, alice.ID, "from bob")
+		kept := sendMessage(t, repos, bob.ID, alice.ID, "kept")
+		for _, pin := range []*domain.PinnedMessage{
+			{MessageID: fromAlice.ID, PinnedByID: bob.ID},
+			{MessageID: fromBob.ID, PinnedByID: alice.ID},
+			{MessageID: kept.ID, PinnedByID: bob.ID},
+		} {
+			if _, err := repos.Messages.PinMessage(ctx, pin); err != nil {
+				t.Fatalf("PinMessage: %v", err)
+			}
+		}
+		for _, star := range []struct{ messageID, userID uint }{{fromBob.ID, alice.ID}, {fromAlice.ID, bob.ID}} {
+			if err := repos.Messages.StarMessage(ctx, star.messageID, star.userID); err != nil {
+				t.Fatalf("StarMessage: %v", err)
+			}
+		}
+		for _, hide := range []struct{ messageID, userID uint }{{fromBob.ID, alice.ID}, {kept.ID, bob.ID}} {
+			if err := repos.Messages.HideMessage(ctx, hide.messageID, hide.userID); err != nil {
+				t.Fatalf("HideMessage: %v", err)
+			}
+		}
+
+		if err := repos.Messages.DeleteUserMessageState(ctx, alice.ID); err != nil {
+			t.Fatalf("DeleteUserMessageState: %v", err)
+		}
+
+		pins, err := repos.Messages.GetPinnedMessages(ctx, bob.ID, alice.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetPinnedMessages: %v", err)
+		}
+		// Bob hid kept, so he does not see it among the pins, but it stays pinned.
+		if count, err := repos.Messages.CountPinnedMessages(ctx, alice.ID, bob.ID); err != nil || count != 1 || len(pins) != 0 {
+			t.Errorf("pins after DeleteUserMessageState = %d (%v), %v, want only Bob's pin of kept", count, messageIDs(pins), err)
+		}
+		if starred, err := repos.Messages.GetStarredMessages(ctx, alice.ID, 10, 0); err != nil || len(starred) != 0 {
+			t.Errorf("Alice's stars = %v, %v, want none", messageIDs(starred), err)
+		}
+		if starred, err := repos.Messages.GetStarredMessages(ctx, bob.ID, 10, 0); err != nil || len(starred) != 1 {
+			t.Errorf("Bob's stars = %v, %v, want his star kept", messageIDs(starred), err)
+		}
+		history, err := repos.Messages.GetMessagesBetweenUsers(ctx, alice.ID, bob.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetMessagesBetweenUsers: %v", err)
+		}
+		if got, want := messageIDs(history), []uint{fromAlice.ID, fromBob.ID, kept.ID}; !slices.Equal(got, want) {
+			t.Errorf("Alice's history = %v, want %v with nothing hidden", got, want)
+		}
+		history, err = repos.Messages.GetMessagesBetweenUsers(ctx, bob.ID, alice.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetMessagesBetweenUsers: %v", err)
+		}
+		if got, want := messageIDs(history), []uint{fromAlice.ID, fromBob.ID}; !slices.Equal(got, want) {
+			t.Errorf("Bob's history = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Pins", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+
+		first := sendMessage(t, repos, alice.ID, bob.ID, "first")
+		second := sendMessage(t, repos, bob.ID, alice.ID, "second")
+		hidden := sendMessage(t, repos, alice.ID, bob.ID, "hidden")
+		deleted := sendMessage(t, repos, alice.ID, bob.ID, "deleted")
+		other := sendMessage(t, repos, alice.ID, carol.ID, "elsewhere")
+
+		for _, pin := range []struct{ messageID, pinnedByID uint }{
+			{first.ID, alice.ID},
+			{second.ID, bob.ID},
+			{hidden.ID, bob.ID},
+			{deleted.ID, alice.ID},
+			{other.ID, carol.ID},
+		} {
+			pinned, err := repos.Messages.PinMessage(ctx, &domain.PinnedMessage{MessageID: pin.messageID, PinnedByID: pin.pinnedByID})
+			if err != nil || !pinned {
+				t.Fatalf("PinMessage(%d) = %v, %v", pin.messageID, pinned, err)
+			}
+		}
+		// Pinning again changes nothing.
+		if pinned, err := repos.Messages.PinMessage(ctx, &domain.PinnedMessage{MessageID: first.ID, PinnedByID: bob.ID}); err != nil || pinned {
+			t.Errorf("PinMessage(pinned) = %v, %v, want false", pinned, err)
+		}
+		if err := repos.Messages.HideMessage(ctx, hidden.ID, alice.ID); err != nil {
+			t.Fatalf("HideMessage: %v", err)
+		}
+		if err := repos.Messages.DeleteMessage(ctx, deleted.ID); err != nil {
+			t.Fatalf("DeleteMessage: %v", err)
+		}
+
+		// Hidden messages still count against the limit; deleted ones do not.
+		count, err := repos.Messages.CountPinnedMessages(ctx, bob.ID, alice.ID)
+		if err != nil || count != 3 {
+			t.Errorf("CountPinnedMessages = %d, %v, want 3", count, err)
+		}
+
+		for _, tc := range []struct {
+			userID, peerID uint
+			want           []uint
+		}{
+			{alice.ID, bob.ID, []uint{second.ID, first.ID}},
+			{bob.ID, alice.ID, []uint{hidden.ID, second.ID, first.ID}},
+		} {
+			pins, err := repos.Messages.GetPinnedMessages(ctx, tc.userID, tc.peerID, 10, 0)
+			if err != nil {
+				t.Fatalf("GetPinnedMessages: %v", err)
+			}
+			if got := messageIDs(pins); !slices.Equal(got, tc.want) {
+				t.Errorf("pins for %d = %v, want %v", tc.userID, got, tc.want)
+			}
+		}
+
+		page, err := repos.Messages.GetPinnedMessages(ctx, alice.ID, bob.ID, 1, 1)
+		if err != nil {
+			t.Fatalf("GetPinnedMessages: %v", err)
+		}
+		if len(page) != 1 || page[0].ID != first.ID {
+			t.Fatalf("second page = %v, want [%d]", messageIDs(page), first.ID)
+		}
+		if page[0].PinnedAt == nil || page[0].PinnedByID == nil || *page[0].PinnedByID != alice.ID {
+			t.Errorf("pin = %v by %v, want pinned by %d", page[0].PinnedAt, page[0].PinnedByID, alice.ID)
+		}
+		if page[0].Sender.Name != "Alice" || page[0].Receiver.Name != "Bob" {
+			t.Errorf("pinned message users = %q, %q", page[0].Sender.Name, page[0].Receiver.Name)
+		}
+
+		unpinned, err := repos.Messages.UnpinMessage(ctx, first.ID)
+		if err != nil || !unpinned {
+			t.Errorf("UnpinMessage = %v, %v, want true", unpinned, err)
+		}
+		if unpinned, err := repos.Messages.UnpinMessage(ctx, first.ID); err != nil || unpinned {
+			t.Errorf("UnpinMessage(unpinned) = %v, %v, want false", unpinned, err)
+		}
+		if count, err := repos.Messages.CountPinnedMessages(ctx, alice.ID, bob.ID); err != nil || count != 2 {
+			t.Errorf("CountPinnedMessages after unpin = %d, %v, want 2", count, err)
+		}
+
+		// Tombstones do not count against the limit either.
+		if err := repos.Messages.TombstoneMessage(ctx, second.ID); err != nil {
+			t.Fatalf("TombstoneMessage: %v", err)
+		}
+		if count, err := repos.Messages.CountPinnedMessages(ctx, alice.ID, bob.ID); err != nil || count != 1 {
+			t.Errorf("CountPinnedMessages after tombstone = %d, %v, want 1", count, err)
+		}
+	})
+
+	t.Run("Stars", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+
+		first := sendMessage(t, repos, alice.ID, bob.ID, "first")
+		second := sendMessage(t, repos, carol.ID, alice.ID, "second")
+		hidden := sendMessage(t, repos, bob.ID, alice.ID, "hidden")
+
+		for _, messageID := range []uint{first.ID, second.ID, hidden.ID, first.ID} {
+			if err := repos.Messages.StarMessage(ctx, messageID, alice.ID); err != nil {
+				t.Fatalf("StarMessage(%d): %v", messageID, err)
+			}
+		}
+		if err := repos.Messages.HideMessage(ctx, hidden.ID, alice.ID); err != nil {
+			t.Fatalf("HideMessage: %v", err)
+		}
+
+		starred, err := repos.Messages.GetStarredMessages(ctx, alice.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetStarredMessages: %v", err)
+		}
+		if got, want := messageIDs(starred), []uint{second.ID, first.ID}; !slices.Equal(got, want) {
+			t.Fatalf("starred = %v, want %v", got, want)
+		}
+		if starred[0].StarredAt == nil || starred[0].Sender.Name != "Carol" {
+			t.Errorf("starred message = %v from %q", starred[0].StarredAt, starred[0].Sender.Name)
+		}
+
+		// Stars are private.
+		if others, err := repos.Messages.GetStarredMessages(ctx, bob.ID, 10, 0); err != nil || len(others) != 0 {
+			t.Errorf("GetStarredMessages(bob) = %v, %v, want none", messageIDs(others), err)
+		}
+
+		if err := repos.Messages.UnstarMessage(ctx, second.ID, alice.ID); err != nil {
+			t.Fatalf("UnstarMessage: %v", err)
+		}
+		if err := repos.Messages.UnstarMessage(ctx, second.ID, alice.ID); err != nil {
+			t.Fatalf("UnstarMessage(unstarred): %v", err)
+		}
+		starred, err = repos.Messages.GetStarredMessages(ctx, alice.ID, 1, 0)
+		if err != nil {
+			t.Fatalf("GetStarredMessages: %v", err)
+		}
+		if got, want := messageIDs(starred), []uint{first.ID}; !slices.Equal(got, want) {
+			t.Errorf("starred after unstar = %v, want %v", got, want)
+		}
+	})
+}
+
+func runConversationTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("RecordAndList", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+		dave := createUser(t, repos, "Dave", "dave@example.com")
+
+		recordMessage(t, repos, bob.ID, alice.ID, "hi from bob")
+		recordMessage(t, repos, alice.ID, dave.ID, "hi dave")
+		recordMessage(t, repos, carol.ID, alice.ID, "hi from carol")
+		last := recordMessage(t, repos, carol.ID, alice.ID, "still there?")
+
+		// Conversations with deleted users disappear.
+		if err := repos.Users.DeleteUser(ctx, dave.ID); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+
+		summaries, err := repos.Conversations.ListConversations(ctx, alice.ID, "", false, 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversations: %v", err)
+		}
+		if got := peerIDs(summaries); len(got) != 2 || got[0] != carol.ID || got[1] != bob.ID {
+			t.Fatalf("ListConversations = %v, want most recent first [%d %d]", got, carol.ID, bob.ID)
+		}
+
+		carolConv := summaries[0]
+		if carolConv.Peer.Email != "carol@example.com" || carolConv.Peer.Name != "Carol" {
+			t.Errorf("peer = %q <%s>", carolConv.Peer.Name, carolConv.Peer.Email)
+		}
+		if carolConv.UnreadCount != 2 {
+			t.Errorf("UnreadCount = %d, want 2", carolConv.UnreadCount)
+		}
+		if carolConv.LastMessage == nil || carolConv.LastMessage.ID != last.ID || carolConv.LastMessage.Sender.Name != "Carol" {
+			t.Errorf("LastMessage = %+v, want message %d from Carol", carolConv.LastMessage, last.ID)
+		}
+
+		// The sender's own side is not unread.
+		carolSide, err := repos.Conversations.ListConversations(ctx, carol.ID, "", false, 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversations: %v", err)
+		}
+		if len(carolSide) != 1 || carolSide[0].PeerID != alice.ID || carolSide[0].UnreadCount != 0 {
+			t.Errorf("sender's summary = %+v, want one read conversation with Alice", carolSide)
+		}
+
+		page, err := repos.Conversations.ListConversations(ctx, alice.ID, "", false, 1, 1)
+		if err != nil {
+			t.Fatalf("ListConversations page: %v", err)
+		}
+		if got := peerIDs(page); len(got) != 1 || got[0] != bob.ID {
+			t.Errorf("ListConversations(limit 1, offset 1) = %v, want [%d]", got, bob.ID)
+		}
+
+		matched, err := repos.Conversations.ListConversations(ctx, alice.ID, "BOB@", false, 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversations query: %v", err)
+		}
+		if got := peerIDs(matched); len(got) != 1 || got[0] != bob.ID {
+			t.Errorf("ListConversations(query) = %v, want [%d]", got, bob.ID)
+		}
+	})
+
+	t.Run("ReadCursors", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		first := recordMessage(t, repos, bob.ID, alice.ID, "one")
+		second := recordMessage(t, repos, bob.ID, alice.ID, "two")
+		recordMessage(t, repos, alice.ID, bob.ID, "reply")
+
+		readAt := time.Now()
+		if err := repos.Conversations.MarkConversationRead(ctx, alice.ID, bob.ID, readAt); err != nil {
+			t.Fatalf("MarkConversationRead: %v", err)
+		}
+		summary := assertSummary(t, repos, alice.ID, bob.ID, 0)
+		if summary != nil && (summary.LastReadMessageID == nil || *summary.LastReadMessageID != *summary.LastMessageID || summary.LastReadAt == nil) {
+			t.Errorf("cursor after MarkConversationRead = %v at %v, want last message %v", summary.LastReadMessageID, summary.LastReadAt, *summary.LastMessageID)
+		}
+		summary = assertSummary(t, repos, bob.ID, alice.ID, 1)
+		if summary != nil && (summary.PeerLastReadMessageID == nil || *summary.PeerLastReadMessageID != *summary.LastMessageID) {
+			t.Errorf("PeerLastReadMessageID = %v, want %d", summary.PeerLastReadMessageID, *summary.LastMessageID)
+		}
+
+		if err := repos.Conversations.SetReadCursor(ctx, alice.ID, bob.ID, &first.ID, readAt); err != nil {
+			t.Fatalf("SetReadCursor: %v", err)
+		}
+		assertSummary(t, repos, alice.ID, bob.ID, 1)
+		got, err := repos.Messages.GetMessageByID(ctx, second.ID)
+		if err != nil {
+			t.Fatalf("GetMessageByID: %v", err)
+		}
+		if got.IsRead {
+			t.Errorf("message %d IsRead after moving the cursor back before it", second.ID)
+		}
+
+		if err := repos.Conversations.SetReadCursor(ctx, alice.ID, bob.ID, nil, readAt); err != nil {
+			t.Fatalf("SetReadCursor(nil): %v", err)
+		}
+		assertSummary(t, repos, alice.ID, bob.ID, 2)
+
+		// Deleting a read message leaves the cursor where it was.
+		if err := repos.Conversations.SetReadCursor(ctx, alice.ID, bob.ID, &second.ID, readAt); err != nil {
+			t.Fatalf("SetReadCursor: %v", err)
+		}
+		if err := repos.Messages.DeleteMessage(ctx, first.ID); err != nil {
+			t.Fatalf("DeleteMessage: %v", err)
+		}
+		if err := repos.Conversations.RefreshConversation(ctx, alice.ID, bob.ID); err != nil {
+			t.Fatalf("RefreshConversation: %v", err)
+		}
+		cursor, err := repos.Conversations.GetConversation(ctx, alice.ID, bob.ID)
+		if err != nil {
+			t.Fatalf("GetConversation: %v", err)
+		}
+		if cursor.LastReadMessageID == nil || *cursor.LastReadMessageID != second.ID || cursor.UnreadCount != 0 {
+			t.Errorf("cursor after refresh = %v with %d unread, want %d with 0", cursor.LastReadMessageID, cursor.UnreadCount, second.ID)
+		}
+
+		if _, err := repos.Conversations.GetConversation(ctx, alice.ID, alice.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetConversation(missing) error = %v, want ErrRecordNotFound", err)
+		}
+	})
+
+	t.Run("RefreshConversation", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		first := recordMessage(t, repos, alice.ID, bob.ID, "first")
+		second := recordMessage(t, repos, alice.ID, bob.ID, "second")
+
+		if err := repos.Messages.DeleteMessage(ctx, second.ID); err != nil {
+			t.Fatalf("DeleteMessage: %v", err)
+		}
+		if err := repos.Conversations.RefreshConversation(ctx, bob.ID, alice.ID); err != nil {
+			t.Fatalf("RefreshConversation: %v", err)
+		}
+		summary := assertSummary(t, repos, bob.ID, alice.ID, 1)
+		if summary != nil && (summary.LastMessage == nil || summary.LastMessage.ID != first.ID) {
+			t.Errorf("LastMessage after delete = %+v, want %d", summary.LastMessage, first.ID)
+		}
+
+		if err := repos.Messages.DeleteMessage(ctx, first.ID); err != nil {
+			t.Fatalf("DeleteMessage: %v", err)
+		}
+		if err := repos.Conversations.RefreshConversation(ctx, alice.ID, bob.ID); err != nil {
+			t.Fatalf("RefreshConversation: %v", err)
+		}
+		for _, userID := range []uint{alice.ID, bob.ID} {
+			summaries, err := repos.Conversations.ListConversations(ctx, userID, "", false, 10, 0)
+			if err != nil {
+				t.Fatalf("ListConversations: %v", err)
+			}
+			if len(summaries) != 0 {
+				t.Errorf("user %d still has conversations %v after every message was deleted", userID, peerIDs(summaries))
+			}
+		}
+	})
+
+	t.Run("HiddenMessages", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		first := recordMessage(t, repos, alice.ID, bob.ID, "first")
+		second := recordMessage(t, repos, alice.ID, bob.ID, "second")
+
+		// Bob hides the latest message: only his side falls back to the
+		// earlier one and stops counting it as unread.
+		if err := repos.Messages.HideMessage(ctx, second.ID, bob.ID); err != nil {
+			t.Fatalf("HideMessage: %v", err)
+		}
+		if err := repos.Conversations.RefreshConversation(ctx, alice.ID, bob.ID); err != nil {
+			t.Fatalf("RefreshConversation: %v", err)
+		}
+		summary := assertSummary(t, repos, bob.ID, alice.ID, 1)
+		if summary != nil && (summary.LastMessage == nil || summary.LastMessage.ID != first.ID) {
+			t.Errorf("Bob's LastMessage = %+v, want %d", summary.LastMessage, first.ID)
+		}
+		summary = assertSummary(t, repos, alice.ID, bob.ID, 0)
+		if summary != nil && (summary.LastMessage == nil || summary.LastMessage.ID != second.ID) {
+			t.Errorf("Alice's LastMessage = %+v, want %d", summary.LastMessage, second.ID)
+		}
+
+		if err := repos.Conversations.SetReadCursor(ctx, bob.ID, alice.ID, nil, time.Now()); err != nil {
+			t.Fatalf("SetReadCursor: %v", err)
+		}
+		assertSummary(t, repos, bob.ID, alice.ID, 1)
+
+		// Once Bob has hidden everything the conversation leaves his list,
+		// and a rebuild keeps it that way.
+		if err := repos.Messages.HideMessage(ctx, first.ID, bob.ID); err != nil {
+			t.Fatalf("HideMessage: %v", err)
+		}
+		if err := repos.Conversations.RefreshConversation(ctx, alice.ID, bob.ID); err != nil {
+			t.Fatalf("RefreshConversation: %v", err)
+		}
+		if _, err := repos.Conversations.RebuildConversations(ctx); err != nil {
+			t.Fatalf("RebuildConversations: %v", err)
+		}
+		summaries, err := repos.Conversations.ListConversations(ctx, bob.ID, "", false, 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversations: %v", err)
+		}
+		if len(summaries) != 0 {
+			t.Errorf("Bob still has conversations %v after hiding every message", peerIDs(summaries))
+		}
+		assertSummary(t, repos, alice.ID, bob.ID, 0)
+	})
+
+	t.Run("Settings", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+		dave := createUser(t, repos, "Dave", "dave@example.com")
+
+		recordMessage(t, repos, bob.ID, alice.ID, "oldest")
+		recordMessage(t, repos, carol.ID, alice.ID, "older")
+		recordMessage(t, repos, dave.ID, alice.ID, "newest")
+
+		// Pinned conversations come first, the most recently pinned on top.
+		pinnedAt := time.Now().Add(-time.Minute)
+		for _, peerID := range []uint{bob.ID, carol.ID} {
+			pinnedAt = pinnedAt.Add(time.Second)
+			at := pinnedAt
+			if err := repos.Conversations.SetPinned(ctx, alice.ID, peerID, &at); err != nil {
+				t.Fatalf("SetPinned: %v", err)
+			}
+		}
+		list := func(archived bool) []uint {
+			t.Helper()
+			summaries, err := repos.Conversations.ListConversations(ctx, alice.ID, "", archived, 10, 0)
+			if err != nil {
+				t.Fatalf("ListConversations: %v", err)
+			}
+			return peerIDs(summaries)
+		}
+		if got, want := list(false), []uint{carol.ID, bob.ID, dave.ID}; !slices.Equal(got, want) {
+			t.Errorf("ListConversations = %v, want pinned first %v", got, want)
+		}
+		if err := repos.Conversations.SetPinned(ctx, alice.ID, carol.ID, nil); err != nil {
+			t.Fatalf("SetPinned: %v", err)
+		}
+		if got, want := list(false), []uint{bob.ID, dave.ID, carol.ID}; !slices.Equal(got, want) {
+			t.Errorf("ListConversations after unpin = %v, want %v", got, want)
+		}
+
+		// Archived conversations are listed apart until the next message.
+		archivedAt := time.Now()
+		if err := repos.Conversations.SetArchived(ctx, alice.ID, dave.ID, &archivedAt); err != nil {
+			t.Fatalf("SetArchived: %v", err)
+		}
+		if got, want := list(false), []uint{bob.ID, carol.ID}; !slices.Equal(got, want) {
+			t.Errorf("ListConversations = %v, want %v", got, want)
+		}
+		if got, want := list(true), []uint{dave.ID}; !slices.Equal(got, want) {
+			t.Errorf("ListConversations(archived) = %v, want %v", got, want)
+		}
+		recordMessage(t, repos, alice.ID, dave.ID, "back again")
+		if got := list(true); len(got) != 0 {
+			t.Errorf("ListConversations(archived) after a new message = %v, want none", got)
+		}
+
+		// Marking unread sticks until the conversation is next read.
+		if err := repos.Conversations.SetMarkedUnread(ctx, alice.ID, bob.ID, true); err != nil {
+			t.Fatalf("SetMarkedUnread: %v", err)
+		}
+		summary, err := repos.Conversations.GetConversation(ctx, alice.ID, bob.ID)
+		if err != nil {
+			t.Fatalf("GetConversation: %v", err)
+		}
+		if !summary.MarkedUnread || summary.PinnedAt == nil {
+			t.Errorf("summary = %+v, want pinned and marked unread", summary)
+		}
+		if err := repos.Conversations.MarkConversationRead(ctx, alice.ID, bob.ID, time.Now()); err != nil {
+			t.Fatalf("MarkConversationRead: %v", err)
+		}
+		summary, err = repos.Conversations.GetConversation(ctx, alice.ID, bob.ID)
+		if err != nil {
+			t.Fatalf("GetConversation: %v", err)
+		}
+		if summary.MarkedUnread {
+			t.Error("MarkedUnread still set after reading")
+		}
+	})
+
+	t.Run("Mute", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		recordMessage(t, repos, bob.ID, alice.ID, "noisy")
+
+		mutedAt := time.Now()
+		mutedUntil := mutedAt.Add(8 * time.Hour)
+		if err := repos.Conversations.SetMuted(ctx, alice.ID, bob.ID, &mutedAt, &mutedUntil); err != nil {
+			t.Fatalf("SetMuted: %v", err)
+		}
+		summary, err := repos.Conversations.GetConversation(ctx, alice.ID, bob.ID)
+		if err != nil {
+			t.Fatalf("GetConversation: %v", err)
+		}
+		if !sameTime(summary.MutedAt, &mutedAt) || !sameTime(summary.MutedUntil, &mutedUntil) {
+			t.Errorf("muted %v until %v, want %v until %v", summary.MutedAt, summary.MutedUntil, mutedAt, mutedUntil)
+		}
+		if !summary.IsMuted(mutedAt.Add(time.Hour)) || summary.IsMuted(mutedUntil) {
+			t.Error("IsMuted does not follow MutedUntil")
+		}
+
+		// Mutes belong to one side and outlast new messages.
+		recordMessage(t, repos, bob.ID, alice.ID, "still noisy")
+		if other := assertSummary(t, repos, bob.ID, alice.ID, 0); other != nil && other.MutedAt != nil {
+			t.Error("muting also muted the peer's side")
+		}
+		if summary := assertSummary(t, repos, alice.ID, bob.ID, 2); summary != nil && summary.MutedAt == nil {
+			t.Error("mute lost after a new message")
+		}
+
+		if err := repos.Conversations.SetMuted(ctx, alice.ID, bob.ID, nil, nil); err != nil {
+			t.Fatalf("SetMuted: %v", err)
+		}
+		summary, err = repos.Conversations.GetConversation(ctx, alice.ID, bob.ID)
+		if err != nil {
+			t.Fatalf("GetConversation: %v", err)
+		}
+		if summary.MutedAt != nil || summary.MutedUntil != nil {
+			t.Errorf("muted %v until %v after unmuting", summary.MutedAt, summary.MutedUntil)
+		}
+	})
+
+	t.Run("DeleteUserConversations", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+
+		recordMessage(t, repos, alice.ID, bob.ID, "hi bob")
+		recordMessage(t, repos, bob.ID, carol.ID, "hi carol")
+		if err := repos.Conversations.SetDisappearingTimer(ctx, alice.ID, bob.ID, time.Hour, alice.ID); err != nil {
+			t.Fatalf("SetDisappearingTimer: %v", err)
+		}
+		if err := repos.Conversations.SetDisappearingTimer(ctx, bob.ID, carol.ID, time.Hour, bob.ID); err != nil {
+			t.Fatalf("SetDisappearingTimer: %v", err)
+		}
+
+		if err := repos.Conversations.DeleteUserConversations(ctx, alice.ID); err != nil {
+			t.Fatalf("DeleteUserConversations: %v", err)
+		}
+
+		if _, err := repos.Conversations.GetConversation(ctx, alice.ID, bob.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Alice's summary error = %v, want ErrRecordNotFound", err)
+		}
+		assertSummary(t, repos, bob.ID, alice.ID, 1)
+		if after, err := repos.Conversations.GetDisappearingTimer(ctx, alice.ID, bob.ID); err != nil || after != 0 {
+			t.Errorf("Alice and Bob's timer = %v, %v, want none", after, err)
+		}
+		if after, err := repos.Conversations.GetDisappearingTimer(ctx, bob.ID, carol.ID); err != nil || after != time.Hour {
+			t.Errorf("Bob and Carol's timer = %v, %v, want 1h", after, err)
+		}
+	})
+
+	t.Run("DisappearingTimer", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+		recordMessage(t, repos, bob.ID, alice.ID, "psst")
+		recordMessage(t, repos, carol.ID, alice.ID, "hello")
+
+		if after, err := repos.Conversations.GetDisappearingTimer(ctx, alice.ID, bob.ID); err != nil || after != 0 {
+			t.Errorf("GetDisappearingTimer before setting = %v, %v, want 0", after, err)
+		}
+
+		// The timer is shared, whichever side sets or reads it.
+		if err := repos.Conversations.SetDisappearingTimer(ctx, bob.ID, alice.ID, time.Hour, bob.ID); err != nil {
+			t.Fatalf("SetDisappearingTimer: %v", err)
+		}
+		if err := repos.Conversations.SetDisappearingTimer(ctx, alice.ID, bob.ID, 24*time.Hour, alice.ID); err != nil {
+			t.Fatalf("SetDisappearingTimer: %v", err)
+		}
+		for _, tc := range []struct{ userID, peerID uint }{{alice.ID, bob.ID}, {bob.ID, alice.ID}} {
+			if after, err := repos.Conversations.GetDisappearingTimer(ctx, tc.userID, tc.peerID); err != nil || after != 24*time.Hour {
+				t.Errorf("GetDisappearingTimer(%d, %d) = %v, %v, want 24h", tc.userID, tc.peerID, after, err)
+			}
+		}
+		if after, err := repos.Conversations.GetDisappearingTimer(ctx, alice.ID, carol.ID); err != nil || after != 0 {
+			t.Errorf("GetDisappearingTimer(other conversation) = %v, %v, want 0", after, err)
+		}
+
+		summaries, err := repos.Conversations.ListConversations(ctx, alice.ID, "", false, 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversations: %v", err)
+		}
+		for _, summary := range summaries {
+			want := 0
+			if summary.PeerID == bob.ID {
+				want = 24 * 60 * 60
+			}
+			if summary.DisappearAfterSeconds != want {
+				t.Errorf("DisappearAfterSeconds with %d = %d, want %d", summary.PeerID, summary.DisappearAfterSeconds, want)
+			}
+		}
+
+		if err := repos.Conversations.SetDisappearingTimer(ctx, alice.ID, bob.ID, 0, alice.ID); err != nil {
+			t.Fatalf("SetDisappearingTimer: %v", err)
+		}
+		if after, err := repos.Conversations.GetDisappearingTimer(ctx, bob.ID, alice.ID); err != nil || after != 0 {
+			t.Errorf("GetDisappearingTimer after turning off = %v, %v, want 0", after, err)
+		}
+	})
+
+	t.Run("HideConversation", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+
+		recordMessage(t, repos, alice.ID, bob.ID, "one")
+		recordMessage(t, repos, bob.ID, alice.ID, "two")
+		recordMessage(t, repos, carol.ID, alice.ID, "elsewhere")
+
+		// Clearing twice is a no-op.
+		for i := 0; i < 2; i++ {
+			if err := repos.Messages.HideConversation(ctx, alice.ID, bob.ID); err != nil {
+				t.Fatalf("HideConversation: %v", err)
+			}
+		}
+		if err := repos.Conversations.RefreshConversation(ctx, alice.ID, bob.ID); err != nil {
+			t.Fatalf("RefreshConversation: %v", err)
+		}
+
+		history, err := repos.Messages.GetMessagesBetweenUsers(ctx, alice.ID, bob.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetMessagesBetweenUsers: %v", err)
+		}
+		if len(history) != 0 {
+			t.Errorf("Alice's history = %v, want none", messageIDs(history))
+		}
+		history, err = repos.Messages.GetMessagesBetweenUsers(ctx, bob.ID, alice.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetMessagesBetweenUsers: %v", err)
+		}
+		if len(history) != 2 {
+			t.Errorf("Bob's history = %v, want both messages", messageIDs(history))
+		}
+		summaries, err := repos.Conversations.ListConversations(ctx, alice.ID, "", false, 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversations: %v", err)
+		}
+		if got := peerIDs(summaries); len(got) != 1 || got[0] != carol.ID {
+			t.Errorf("Alice's conversations = %v, want only Carol", got)
+		}
+		assertSummary(t, repos, bob.ID, alice.ID, 1)
+		assertSummary(t, repos, alice.ID, carol.ID, 1)
+
+		// New messages show up as usual.
+		next := recordMessage(t, repos, bob.ID, alice.ID, "three")
+		history, err = repos.Messages.GetMessagesBetweenUsers(ctx, alice.ID, bob.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetMessagesBetweenUsers: %v", err)
+		}
+		if got := messageIDs(history); len(got) != 1 || got[0] != next.ID {
+			t.Errorf("Alice's history after a new message = %v, want [%d]", got, next.ID)
+		}
+	})
+
+	t.Run("RebuildConversations", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+
+		// Messages from before summaries existed, plus a stale summary.
+		sendMessage(t, repos, bob.ID, alice.ID, "one")
+		sendMessage(t, repos, alice.ID, bob.ID, "two")
+		last := sendMessage(t, repos, bob.ID, alice.ID, "three")
+		deleted := sendMessage(t, repos, carol.ID, alice.ID, "gone")
+		if err := repos.Conversations.RecordMessage(ctx, deleted); err != nil {
+			t.Fatalf("RecordMessage: %v", err)
+		}
+		if err := repos.Messages.DeleteMessage(ctx, deleted.ID); err != nil {
+			t.Fatalf("DeleteMessage: %v", err)
+		}
+
+		rebuilt, err := repos.Conversations.RebuildConversations(ctx)
+		if err != nil {
+			t.Fatalf("RebuildConversations: %v", err)
+		}
+		if rebuilt != 2 {
+			t.Errorf("RebuildConversations = %d, want 2", rebuilt)
+		}
+
+		summary := assertSummary(t, repos, alice.ID, bob.ID, 2)
+		if summary != nil && (summary.LastMessage == nil || summary.LastMessage.ID != last.ID) {
+			t.Errorf("LastMessage = %+v, want %d", summary.LastMessage, last.ID)
+		}
+		assertSummary(t, repos, bob.ID, alice.ID, 1)
+
+		summaries, err := repos.Conversations.ListConversations(ctx, alice.ID, "", false, 10, 0)
+		if err != nil {
+			t.Fatalf("ListConversations: %v", err)
+		}
+		if got := peerIDs(summaries); len(got) != 1 {
+			t.Errorf("ListConversations after rebuild = %v, want only Bob", got)
+		}
+	})
+}
+
+func runScheduledMessageTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+	// Times are stored in UTC, as the service does.
+	now := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("PendingAndDue", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+
+		later := scheduleMessage(t, repos, alice.ID, bob.ID, "later", now.Add(time.Hour))
+		due := scheduleMessage(t, repos, alice.ID, bob.ID, "due", now.Add(-time.Minute))
+		overdue := scheduleMessage(t, repos, alice.ID, bob.ID, "overdue", now.Add(-time.Hour))
+		scheduleMessage(t, repos, bob.ID, alice.ID, "from bob", now.Add(time.Hour))
+
+		if due.ID == 0 {
+			t.Fatal("CreateScheduledMessage did not assign an ID")
+		}
+		if due.Status != domain.ScheduledMessagePending {
+			t.Errorf("Status = %q, want %q", due.Status, domain.ScheduledMessagePending)
+		}
+
+		pending, err := repos.ScheduledMessages.GetPendingScheduledMessages(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetPendingScheduledMessages: %v", err)
+		}
+		if got, want := scheduledIDs(pending), []uint{overdue.ID, due.ID, later.ID}; !slices.Equal(got, want) {
+			t.Errorf("GetPendingScheduledMessages = %v, want %v", got, want)
+		}
+
+		dueNow, err := repos.ScheduledMessages.GetDueScheduledMessages(ctx, now, 10)
+		if err != nil {
+			t.Fatalf("GetDueScheduledMessages: %v", err)
+		}
+		if got, want := scheduledIDs(dueNow), []uint{overdue.ID, due.ID}; !slices.Equal(got, want) {
+			t.Errorf("GetDueScheduledMessages = %v, want %v", got, want)
+		}
+
+		limited, err := repos.ScheduledMessages.GetDueScheduledMessages(ctx, now, 1)
+		if err != nil {
+			t.Fatalf("GetDueScheduledMessages: %v", err)
+		}
+		if got, want := scheduledIDs(limited), []uint{overdue.ID}; !slices.Equal(got, want) {
+			t.Errorf("GetDueScheduledMessages(limit 1) = %v, want %v", got, want)
+		}
+
+		if _, err := repos.ScheduledMessages.GetScheduledMessage(ctx, later.ID+1000); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetScheduledMessage(missing) error = %v, want ErrRecordNotFound", err)
+		}
+	})
+
+	t.Run("UpdateAndCancel", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		message := scheduleMessage(t, repos, alice.ID, bob.ID, "draft", now.Add(time.Hour))
+
+		sendAt := now.Add(2 * time.Hour)
+		updated, err := repos.ScheduledMessages.UpdateScheduledMessage(ctx, message.ID, "final", sendAt)
+		if err != nil || !updated {
+			t.Fatalf("UpdateScheduledMessage = %v, %v", updated, err)
+		}
+		stored, err := repos.ScheduledMessages.GetScheduledMessage(ctx, message.ID)
+		if err != nil {
+			t.Fatalf("GetScheduledMessage: %v", err)
+		}
+		if stored.Content != "final" || !stored.SendAt.Equal(sendAt) {
+			t.Errorf("after update = %q at %v, want %q at %v", stored.Content, stored.SendAt, "final", sendAt)
+		}
+
+		cancelled, err := repos.ScheduledMessages.CancelScheduledMessage(ctx, message.ID, now)
+		if err != nil || !cancelled {
+			t.Fatalf("CancelScheduledMessage = %v, %v", cancelled, err)
+		}
+		stored, err = repos.ScheduledMessages.GetScheduledMessage(ctx, message.ID)
+		if err != nil {
+			t.Fatalf("GetScheduledMessage: %v", err)
+		}
+		if stored.Status != domain.ScheduledMessageCancelled || !sameTime(stored.CancelledAt, &now) {
+			t.Errorf("after cancel = %q at %v", stored.Status, stored.CancelledAt)
+		}
+
+		if updated, err := repos.ScheduledMessages.UpdateScheduledMessage(ctx, message.ID, "too late", sendAt); err != nil || updated {
+			t.Errorf("UpdateScheduledMessage(cancelled) = %v, %v, want false", updated, err)
+		}
+		if cancelled, err := repos.ScheduledMessages.CancelScheduledMessage(ctx, message.ID, now); err != nil || cancelled {
+			t.Errorf("CancelScheduledMessage(cancelled) = %v, %v, want false", cancelled, err)
+		}
+		if claimed, err := repos.ScheduledMessages.ClaimScheduledMessage(ctx, message.ID, now); err != nil || claimed {
+			t.Errorf("ClaimScheduledMessage(cancelled) = %v, %v, want false", claimed, err)
+		}
+		pending, err := repos.ScheduledMessages.GetPendingScheduledMessages(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetPendingScheduledMessages: %v", err)
+		}
+		if len(pending) != 0 {
+			t.Errorf("GetPendingScheduledMessages after cancel = %v, want none", scheduledIDs(pending))
+		}
+	})
+
+	t.Run("ClaimOnce", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		message := scheduleMessage(t, repos, alice.ID, bob.ID, "hello", now.Add(-time.Minute))
+
+		claimed, err := repos.ScheduledMessages.ClaimScheduledMessage(ctx, message.ID, now)
+		if err != nil || !claimed {
+			t.Fatalf("ClaimScheduledMessage = %v, %v", claimed, err)
+		}
+		if claimed, err := repos.ScheduledMessages.ClaimScheduledMessage(ctx, message.ID, now); err != nil || claimed {
+			t.Errorf("second ClaimScheduledMessage = %v, %v, want false", claimed, err)
+		}
+
+		stored, err := repos.ScheduledMessages.GetScheduledMessage(ctx, message.ID)
+		if err != nil {
+			t.Fatalf("GetScheduledMessage: %v", err)
+		}
+		if stored.Status != domain.ScheduledMessageSent || !sameTime(stored.SentAt, &now) {
+			t.Errorf("after claim = %q at %v", stored.Status, stored.SentAt)
+		}
+		due, err := repos.ScheduledMessages.GetDueScheduledMessages(ctx, now, 10)
+		if err != nil {
+			t.Fatalf("GetDueScheduledMessages: %v", err)
+		}
+		if len(due) != 0 {
+			t.Errorf("GetDueScheduledMessages after claim = %v, want none", scheduledIDs(due))
+		}
+	})
+
+	t.Run("CancelUserScheduledMessages", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		carol := createUser(t, repos, "Carol", "carol@example.com")
+
+		outgoing := scheduleMessage(t, repos, alice.ID, bob.ID, "from alice", now.Add(time.Hour))
+		incoming := scheduleMessage(t, repos, carol.ID, alice.ID, "to alice", now.Add(time.Hour))
+		sent := scheduleMessage(t, repos, alice.ID, carol.ID, "already sent", now.Add(-time.Minute))
+		unrelated := scheduleMessage(t, repos, bob.ID, carol.ID, "elsewhere", now.Add(time.Hour))
+		if claimed, err := repos.ScheduledMessages.ClaimScheduledMessage(ctx, sent.ID, now); err != nil || !claimed {
+			t.Fatalf("ClaimScheduledMessage = %v, %v", claimed, err)
+		}
+
+		if err := repos.ScheduledMessages.CancelUserScheduledMessages(ctx, alice.ID, now); err != nil {
+			t.Fatalf("CancelUserScheduledMessages: %v", err)
+		}
+
+		for _, tc := range []struct {
+			id   uint
+			want domain.ScheduledMessageStatus
+		}{
+			{outgoing.ID, domain.ScheduledMessageCancelled},
+			{incoming.ID, domain.ScheduledMessageCancelled},
+			{sent.ID, domain.ScheduledMessageSent},
+			{unrelated.ID, domain.ScheduledMessagePending},
+		} {
+			stored, err := repos.ScheduledMessages.GetScheduledMessage(ctx, tc.id)
+			if err != nil {
+				t.Fatalf("GetScheduledMessage: %v", err)
+			}
+			if stored.Status != tc.want {
+				t.Errorf("message %d = %q, want %q", tc.id, stored.Status, tc.want)
+			}
+			if tc.want == domain.ScheduledMessageCancelled && !sameTime(stored.CancelledAt, &now) {
+				t.Errorf("message %d cancelled at %v, want %v", tc.id, stored.CancelledAt, now)
+			}
+		}
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		message := scheduleMessage(t, repos, alice.ID, bob.ID, "hello", now.Add(-time.Minute))
+
+		if err := repos.ScheduledMessages.RecordScheduledMessageFailure(ctx, message.ID, "first", 2); err != nil {
+			t.Fatalf("RecordScheduledMessageFailure: %v", err)
+		}
+		stored, err := repos.ScheduledMessages.GetScheduledMessage(ctx, message.ID)
+		if err != nil {
+			t.Fatalf("GetScheduledMessage: %v", err)
+		}
+		if stored.Status != domain.ScheduledMessagePending || stored.Attempts != 1 || stored.LastError != "first" {
+			t.Errorf("after one failure = %q, %d attempts, %q", stored.Status, stored.Attempts, stored.LastError)
+		}
+
+		if err := repos.ScheduledMessages.RecordScheduledMessageFailure(ctx, message.ID, "second", 2); err != nil {
+			t.Fatalf("RecordScheduledMessageFailure: %v", err)
+		}
+		stored, err = repos.ScheduledMessages.GetScheduledMessage(ctx, message.ID)
+		if err != nil {
+			t.Fatalf("GetScheduledMessage: %v", err)
+		}
+		if stored.Status != domain.ScheduledMessageFailed || stored.Attempts != 2 || stored.LastError != "second" {
+			t.Errorf("after two failures = %q, %d attempts, %q", stored.Status, stored.Attempts, stored.LastError)
+		}
+		due, err := repos.ScheduledMessages.GetDueScheduledMessages(ctx, now, 10)
+		if err != nil {
+			t.Fatalf("GetDueScheduledMessages: %v", err)
+		}
+		if len(due) != 0 {
+			t.Errorf("GetDueScheduledMessages after giving up = %v, want none", scheduledIDs(due))
+		}
+	})
+}
+
+func runIdentityTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("LinkAndUnlink", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		now := time.Now().UTC()
+
+		google := &domain.UserIdentity{UserID: alice.ID, Provider: "google", Subject: "g-1", Email: "alice@gmail.com", CreatedAt: now.Add(-time.Hour)}
+		github := &domain.UserIdentity{UserID: alice.ID, Provider: "github", Subject: "gh-1", CreatedAt: now}
+		for _, identity := range []*domain.UserIdentity{github, google} {
+			if err := repos.Identities.CreateIdentity(ctx, identity); err != nil {
+				t.Fatalf("CreateIdentity: %v", err)
+			}
+		}
+		if err := repos.Identities.CreateIdentity(ctx, &domain.UserIdentity{UserID: bob.ID, Provider: "google", Subject: "g-1"}); err == nil {
+			t.Error("CreateIdentity accepted a provider subject that is already linked")
+		}
+
+		found, err := repos.Identities.FindIdentity(ctx, "google", "g-1")
+		if err != nil {
+			t.Fatalf("FindIdentity: %v", err)
+		}
+		if found.UserID != alice.ID || found.Email != "alice@gmail.com" {
+			t.Errorf("FindIdentity = %+v, want Alice's Google identity", found)
+		}
+		if _, err := repos.Identities.FindIdentity(ctx, "github", "g-1"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("FindIdentity(other provider) error = %v, want ErrRecordNotFound", err)
+		}
+
+		identities, err := repos.Identities.GetUserIdentities(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetUserIdentities: %v", err)
+		}
+		if len(identities) != 2 || identities[0].Provider != "google" || identities[1].Provider != "github" {
+			t.Errorf("GetUserIdentities = %+v, want google then github", identities)
+		}
+
+		if err := repos.Identities.DeleteIdentity(ctx, bob.ID, "google"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("DeleteIdentity(not linked) error = %v, want ErrRecordNotFound", err)
+		}
+		if err := repos.Identities.DeleteIdentity(ctx, alice.ID, "google"); err != nil {
+			t.Fatalf("DeleteIdentity: %v", err)
+		}
+		if err := repos.Identities.DeleteUserIdentities(ctx, alice.ID); err != nil {
+			t.Fatalf("DeleteUserIdentities: %v", err)
+		}
+		identities, err = repos.Identities.GetUserIdentities(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetUserIdentities: %v", err)
+		}
+		if len(identities) != 0 {
+			t.Errorf("identities after deleting them = %+v", identities)
+		}
+	})
+
+	t.Run("OAuthStates", func(t *testing.T) {
+		repos := newRepos(t)
+		now := time.Now().UTC()
+
+		expired := &domain.OAuthState{State: "old", Provider: "google", Nonce: "n0", CodeVerifier: "v0", ExpiresAt: now.Add(-time.Minute)}
+		if err := repos.Identities.SaveOAuthState(ctx, expired); err != nil {
+			t.Fatalf("SaveOAuthState: %v", err)
+		}
+		state := &domain.OAuthState{State: "s1", Provider: "google", Nonce: "n1", CodeVerifier: "v1", LinkUserID: 7, ExpiresAt: now.Add(10 * time.Minute)}
+		if err := repos.Identities.SaveOAuthState(ctx, state); err != nil {
+			t.Fatalf("SaveOAuthState: %v", err)
+		}
+
+		// Saving a state clears out expired ones.
+		if _, err := repos.Identities.ConsumeOAuthState(ctx, "old"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("ConsumeOAuthState(expired) error = %v, want ErrRecordNotFound", err)
+		}
+
+		consumed, err := repos.Identities.ConsumeOAuthState(ctx, "s1")
+		if err != nil {
+			t.Fatalf("ConsumeOAuthState: %v", err)
+		}
+		if consumed.Nonce != "n1" || consumed.CodeVerifier != "v1" || consumed.LinkUserID != 7 || consumed.Provider != "google" {
+			t.Errorf("ConsumeOAuthState = %+v", consumed)
+		}
+		if _, err := repos.Identities.ConsumeOAuthState(ctx, "s1"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("second ConsumeOAuthState error = %v, want ErrRecordNotFound", err)
+		}
+	})
+}
+
+func runTokenTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateFindRevoke", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		now := time.Now().UTC()
+
+		older := &domain.PersonalAccessToken{UserID: alice.ID, CreatedBy: alice.ID, Name: "ci", Prefix: "gct_a", TokenHash: "hash-a", Scopes: "messages:read", CreatedAt: now.Add(-time.Hour)}
+		newer := &domain.PersonalAccessToken{UserID: alice.ID, CreatedBy: alice.ID, Name: "bot", Prefix: "gct_b", TokenHash: "hash-b", Scopes: "messages:read,messages:write", CreatedAt: now}
+		for _, token := range []*domain.PersonalAccessToken{older, newer} {
+			if err := repos.Tokens.CreateToken(ctx, token); err != nil {
+				t.Fatalf("CreateToken: %v", err)
+			}
+		}
+		if err := repos.Tokens.CreateToken(ctx, &domain.PersonalAccessToken{UserID: bob.ID, CreatedBy: bob.ID, Name: "dup", Prefix: "gct_c", TokenHash: "hash-a", Scopes: "users:read"}); err == nil {
+			t.Error("CreateToken accepted a duplicate hash")
+		}
+
+		found, err := repos.Tokens.FindTokenByHash(ctx, "hash-b")
+		if err != nil {
+			t.Fatalf("FindTokenByHash: %v", err)
+		}
+		if found.ID != newer.ID || !slices.Equal(found.ScopeList(), []string{"messages:read", "messages:write"}) {
+			t.Errorf("FindTokenByHash = %+v", found)
+		}
+		if _, err := repos.Tokens.FindTokenByHash(ctx, "missing"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("FindTokenByHash(missing) error = %v, want ErrRecordNotFound", err)
+		}
+
+		tokens, err := repos.Tokens.GetUserTokens(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetUserTokens: %v", err)
+		}
+		if len(tokens) != 2 || tokens[0].ID != newer.ID || tokens[1].ID != older.ID {
+			t.Errorf("GetUserTokens = %+v, want newest first", tokens)
+		}
+
+		usedAt := now.Add(time.Minute)
+		if err := repos.Tokens.TouchToken(ctx, older.ID, usedAt, "203.0.113.7"); err != nil {
+			t.Fatalf("TouchToken: %v", err)
+		}
+		found, err = repos.Tokens.FindTokenByHash(ctx, "hash-a")
+		if err != nil {
+			t.Fatalf("FindTokenByHash: %v", err)
+		}
+		if !sameTime(found.LastUsedAt, &usedAt) || found.LastUsedIP != "203.0.113.7" {
+			t.Errorf("touched token = %v from %q", found.LastUsedAt, found.LastUsedIP)
+		}
+
+		if err := repos.Tokens.RevokeToken(ctx, bob.ID, older.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("RevokeToken(other user) error = %v, want ErrRecordNotFound", err)
+		}
+		if err := repos.Tokens.RevokeToken(ctx, alice.ID, older.ID); err != nil {
+			t.Fatalf("RevokeToken: %v", err)
+		}
+		if err := repos.Tokens.RevokeToken(ctx, alice.ID, older.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("RevokeToken(revoked) error = %v, want ErrRecordNotFound", err)
+		}
+
+		if err := repos.Tokens.RevokeUserTokens(ctx, alice.ID); err != nil {
+			t.Fatalf("RevokeUserTokens: %v", err)
+		}
+		tokens, err = repos.Tokens.GetUserTokens(ctx, alice.ID)
+		if err != nil {
+			t.Fatalf("GetUserTokens: %v", err)
+		}
+		for _, token := range tokens {
+			if token.IsActive(time.Now()) {
+				t.Errorf("token %d still active after RevokeUserTokens", token.ID)
+			}
+		}
+	})
+}
+
+func runSessionTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("CreateTouchRevoke", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		now := time.Now().UTC()
+
+		sessions := []*domain.Session{
+			{ID: "laptop", UserID: alice.ID, UserAgent: "Firefox", LastSeenAt: now.Add(-time.Hour), ExpiresAt: now.Add(24 * time.Hour)},
+			{ID: "phone", UserID: alice.ID, UserAgent: "Safari", LastSeenAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(24 * time.Hour)},
+			{ID: "stale", UserID: alice.ID, LastSeenAt: now.Add(-48 * time.Hour), ExpiresAt: now.Add(-time.Hour)},
+			{ID: "bobs", UserID: bob.ID, LastSeenAt: now, ExpiresAt: now.Add(24 * time.Hour)},
+		}
+		for _, session := range sessions {
+			if err := repos.Sessions.CreateSession(ctx, session); err != nil {
+				t.Fatalf("CreateSession: %v", err)
+			}
+		}
+
+		sessionIDs := func(includeInactive bool) []string {
+			t.Helper()
+			found, err := repos.Sessions.GetUserSessions(ctx, alice.ID, includeInactive)
+			if err != nil {
+				t.Fatalf("GetUserSessions: %v", err)
+			}
+			ids := []string{}
+			for _, session := range found {
+				ids = append(ids, session.ID)
+			}
+			return ids
+		}
+		if got, want := sessionIDs(false), []string{"laptop", "phone"}; !slices.Equal(got, want) {
+			t.Errorf("active sessions = %v, want %v", got, want)
+		}
+		if got, want := sessionIDs(true), []string{"laptop", "phone", "stale"}; !slices.Equal(got, want) {
+			t.Errorf("all sessions = %v, want %v", got, want)
+		}
+
+		if err := repos.Sessions.TouchSession(ctx, "phone", now, now.Add(48*time.Hour)); err != nil {
+			t.Fatalf("TouchSession: %v", err)
+		}
+		if got, want := sessionIDs(false), []string{"phone", "laptop"}; !slices.Equal(got, want) {
+			t.Errorf("active sessions after touch = %v, want %v", got, want)
+		}
+
+		if err := repos.Sessions.RevokeSession(ctx, "phone"); err != nil {
+			t.Fatalf("RevokeSession: %v", err)
+		}
+		phone, err := repos.Sessions.GetSessionByID(ctx, "phone")
+		if err != nil {
+			t.Fatalf("GetSessionByID: %v", err)
+		}
+		if phone.RevokedAt == nil || phone.IsActive(time.Now()) {
+			t.Errorf("revoked session = %+v, want inactive", phone)
+		}
+		if _, err := repos.Sessions.GetSessionByID(ctx, "missing"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetSessionByID(missing) error = %v, want ErrRecordNotFound", err)
+		}
+
+		if err := repos.Sessions.RevokeUserSessions(ctx, alice.ID); err != nil {
+			t.Fatalf("RevokeUserSessions: %v", err)
+		}
+		if got := sessionIDs(false); len(got) != 0 {
+			t.Errorf("active sessions after RevokeUserSessions = %v", got)
+		}
+		bobs, err := repos.Sessions.GetSessionByID(ctx, "bobs")
+		if err != nil {
+			t.Fatalf("GetSessionByID: %v", err)
+		}
+		if !bobs.IsActive(time.Now()) {
+			t.Error("RevokeUserSessions revoked another user's session")
+		}
+	})
+}
+
+func runAuditTests(t *testing.T, newRepos Factory) {
+	ctx := context.Background()
+
+	t.Run("ChainAndFilters", func(t *testing.T) {
+		repos := newRepos(t)
+		alice := createUser(t, repos, "Alice", "alice@example.com")
+		bob := createUser(t, repos, "Bob", "bob@example.com")
+		// Whole seconds, so the window bounds match the stored times exactly.
+		start := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+
+		entries := []*domain.AuditEntry{
+			{Action: domain.AuditLogin, ActorID: &alice.ID, Success: true, CreatedAt: start},
+			{Action: domain.AuditLoginFailed, TargetUserID: &bob.ID, CreatedAt: start.Add(time.Minute)},
+			{Action: domain.AuditUserSuspended, ActorID: &alice.ID, TargetUserID: &bob.ID, Success: true, CreatedAt: start.Add(2 * time.Minute)},
+			{Action: domain.AuditLogin, ActorID: &bob.ID, Success: true, CreatedAt: start.Add(3 * time.Minute)},
+		}
+		for _, entry := range entries {
+			if err := repos.Audit.AppendEntry(ctx, entry); err != nil {
+				t.Fatalf("AppendEntry: %v", err)
+			}
+		}
+
+		chain, err := repos.Audit.GetEntriesAfter(ctx, 0, 10)
+		if err != nil {
+			t.Fatalf("GetEntriesAfter: %v", err)
+		}
+		if len(chain) != len(entries) {
+			t.Fatalf("chain has %d entries, want %d", len(chain), len(entries))
+		}
+		prev := ""
+		for i, entry := range chain {
+			if entry.ID != entries[i].ID || entry.PrevHash != prev || entry.Hash != entry.ComputeHash() {
+				t.Errorf("entry %d = %+v, want it linked to %q with a valid hash", i, entry, prev)
+			}
+			prev = entry.Hash
+		}
+		rest, err := repos.Audit.GetEntriesAfter(ctx, entries[1].ID, 1)
+		if err != nil {
+			t.Fatalf("GetEntriesAfter: %v", err)
+		}
+		if len(rest) != 1 || rest[0].ID != entries[2].ID {
+			t.Errorf("GetEntriesAfter(limit 1) = %+v, want entry %d", rest, entries[2].ID)
+		}
+
+		auditIDs := func(found []*domain.AuditEntry) []uint {
+			ids := []uint{}
+			for _, entry := range found {
+				ids = append(ids, entry.ID)
+			}
+			return ids
+		}
+		since := start.Add(time.Minute)
+		until := start.Add(3 * time.Minute)
+		for _, tc := range []struct {
+			name   string
+			filter domain.AuditFilter
+			want   []uint
+		}{
+			{"all", domain.AuditFilter{}, []uint{entries[3].ID, entries[2].ID, entries[1].ID, entries[0].ID}},
+			{"action", domain.AuditFilter{Action: domain.AuditLogin}, []uint{entries[3].ID, entries[0].ID}},
+			{"actor", domain.AuditFilter{ActorID: alice.ID}, []uint{entries[2].ID, entries[0].ID}},
+			{"target", domain.AuditFilter{TargetUserID: bob.ID}, []uint{entries[2].ID, entries[1].ID}},
+			{"window", domain.AuditFilter{Since: &since, Until: &until}, []uint{entries[2].ID, entries[1].ID}},
+		} {
+			found, total, err := repos.Audit.ListEntries(ctx, tc.filter, 10, 0)
+			if err != nil {
+				t.Fatalf("ListEntries(%s): %v", tc.name, err)
+			}
+			if got := auditIDs(found); !slices.Equal(got, tc.want) || total != int64(len(tc.want)) {
+				t.Errorf("ListEntries(%s) = %v of %d, want %v", tc.name, got, total, tc.want)
+			}
+		}
+
+		page, total, err := repos.Audit.ListEntries(ctx, domain.AuditFilter{}, 1, 1)
+		if err != nil {
+			t.Fatalf("ListEntries: %v", err)
+		}
+		if got := auditIDs(page); !slices.Equal(got, []uint{entries[2].ID}) || total != 4 {
+			t.Errorf("second page = %v of %d, want [%d] of 4", got, total, entries[2].ID)
+		}
+
+		// Entries about a user, and those they did without a target.
+		found, total, err := repos.Audit.ListUserEntries(ctx, bob.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("ListUserEntries: %v", err)
+		}
+		if got, want := auditIDs(found), []uint{entries[3].ID, entries[2].ID, entries[1].ID}; !slices.Equal(got, want) || total != 3 {
+			t.Errorf("ListUserEntries(bob) = %v of %d, want %v", got, total, want)
+		}
+		found, _, err = repos.Audit.ListUserEntries(ctx, alice.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("ListUserEntries: %v", err)
+		}
+		if got, want := auditIDs(found), []uint{entries[0].ID}; !slices.Equal(got, want) {
+			t.Errorf("ListUserEntries(alice) = %v, want %v", got, want)
+		}
+	})
+}
+
+func createUser(t *testing.T, repos repository.Repositories, name, email string) *domain.User {
+	t.Helper()
+	user := &domain.User{Name: name, Email: email, Password: "hash"}
+	if err := repos.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("Create user %s: %v", email, err)
+	}
+	return user
+}
+
+func createFriendship(t *testing.T, repos repository.Repositories, requesterID, addresseeID uint, status domain.FriendshipStatus) *domain.Friendship {
+	t.Helper()
+	friendship := &domain.Friendship{RequesterID: requesterID, AddresseeID: addresseeID, Status: status}
+	if err := repos.Friends.CreateFriendship(context.Background(), friendship); err != nil {
+		t.Fatalf("CreateFriendship(%d, %d): %v", requesterID, addresseeID, err)
+	}
+	return friendship
+}
+
+func sendMessage(t *testing.T, repos repository.Repositories, senderID, receiverID uint, content string) *domain.Message {
+	t.Helper()
+	message := &domain.Message{
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Content:    content,
+		Receipts:   []domain.MessageReceipt{{UserID: receiverID}},
+	}
+	if err := repos.Messages.CreateMessage(context.Background(), message); err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	// Keep creation times strictly increasing on clocks coarser than the
+	// database's microsecond precision.
+	time.Sleep(time.Millisecond)
+	return message
+}
+
+// recordMessage sends a message and records it in the conversation summaries,
+// as MessageService does.
+func recordMessage(t *testing.T, repos repository.Repositories, senderID, receiverID uint, content string) *domain.Message {
+	t.Helper()
+	message := sendMessage(t, repos, senderID, receiverID, content)
+	if err := repos.Conversations.RecordMessage(context.Background(), message); err != nil {
+		t.Fatalf("RecordMessage: %v", err)
+	}
+	return message
+}
+
+func scheduleMessage(t *testing.T, repos repository.Repositories, senderID, receiverID uint, content string, sendAt time.Time) *domain.ScheduledMessage {
+	t.Helper()
+	message := &domain.ScheduledMessage{SenderID: senderID, ReceiverID: receiverID, Content: content, SendAt: sendAt}
+	if err := repos.ScheduledMessages.CreateScheduledMessage(context.Background(), message); err != nil {
+		t.Fatalf("CreateScheduledMessage: %v", err)
+	}
+	return message
+}
+
+// assertSummary checks the user's unread count in the conversation with peer,
+// archived or not, and returns the summary, or nil if there is none.
+func assertSummary(t *testing.T, repos repository.Repositories, userID, peerID uint, wantUnread int) *domain.ConversationSummary {
+	t.Helper()
+	var summaries []*domain.ConversationSummary
+	for _, archived := range []bool{false, true} {
+		listed, err := repos.Conversations.ListConversations(context.Background(), userID, "", archived, -1, 0)
+		if err != nil {
+			t.Fatalf("ListConversations: %v", err)
+		}
+		summaries = append(summaries, listed...)
+	}
+	for _, summary := range summaries {
+		if summary.PeerID == peerID {
+			if summary.UnreadCount != wantUnread {
+				t.Errorf("UnreadCount(%d with %d) = %d, want %d", userID, peerID, summary.UnreadCount, wantUnread)
+			}
+			return summary
+		}
+	}
+	t.Errorf("user %d has no conversation with %d", userID, peerID)
+	return nil
+}
+
+// sameTime compares optional timestamps, ignoring location and monotonic
+// clock readings.
+func sameTime(got, want *time.Time) bool {
+	if got == nil || want == nil {
+		return got == want
+	}
+	return got.Equal(*want)
+}
+
+func userIDs(users []*domain.User) []uint {
+	ids := make([]uint, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+func friendshipIDs(friendships []*domain.Friendship) []uint {
+	ids := make([]uint, len(friendships))
+	for i, friendship := range friendships {
+		ids[i] = friendship.ID
+	}
+	return ids
+}
+
+func messageIDs(messages []*domain.Message) []uint {
+	ids := make([]uint, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	return ids
+}
+
+func peerIDs(summaries []*domain.ConversationSummary) []uint {
+	ids := make([]uint, len(summaries))
+	for i, summary := range summaries {
+		ids[i] = summary.PeerID
+	}
+	return ids
+}
+
+func scheduledIDs(messages []*domain.ScheduledMessage) []uint {
+	ids := make([]uint, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	return ids
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+)
+
+// ScheduledMessageRepository stores messages waiting to be sent. Changes to
+// a scheduled message only apply while it is pending and report whether
+// they did, so the scheduler and its sender cannot both act on it.
+type ScheduledMessageRepository interface {
+	CreateScheduledMessage(ctx context.Context, message *domain.ScheduledMessage) error
+
+	GetScheduledMessage(ctx context.Context, id uint) (*domain.ScheduledMessage, error)
+
+	// GetPendingScheduledMessages returns the sender's pending messages,
+	// soonest first.
+	GetPendingScheduledMessages(ctx context.Context, senderID uint) ([]*domain.ScheduledMessage, error)
+
+	// GetDueScheduledMessages returns up to limit pending messages due by
+	// now, soonest first.
+	GetDueScheduledMessages(ctx context.Context, now time.Time, limit int) ([]*domain.ScheduledMessage, error)
+
+	UpdateScheduledMessage(ctx context.Context, id uint, content string, sendAt time.Time) (bool, error)
+
+	CancelScheduledMessage(ctx context.Context, id uint, cancelledAt time.Time) (bool, error)
+
+	// CancelUserScheduledMessages cancels every pending message the user
+	// sent or was going to receive.
+	CancelUserScheduledMessages(ctx context.Context, userID uint, cancelledAt time.Time) error
+
+	// ClaimScheduledMessage marks the message sent. Only one caller can
+	// claim a message; the send itself must happen in the same unit of work.
+	ClaimScheduledMessage(ctx context.Context, id uint, sentAt time.Time) (bool, error)
+
+	// RecordScheduledMessageFailure counts a failed attempt to send the
+	// message, giving up on it once it has failed maxAttempts times.
+	RecordScheduledMessageFailure(ctx context.Context, id uint, reason string, maxAttempts int) error
+}
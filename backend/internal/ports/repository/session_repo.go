@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *domain.Session) error
+
+	GetSessionByID(ctx context.Context, id string) (*domain.Session, error)
+
+	GetUserSessions(ctx context.Context, userID uint, includeInactive bool) ([]*domain.Session, error)
+
+	TouchSession(ctx context.Context, id string, seenAt, expiresAt time.Time) error
+
+	RevokeSession(ctx context.Context, id string) error
+
+	RevokeUserSessions(ctx context.Context, userID uint) error
+}
@@ -0,0 +1,22 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+)
+
+type TokenRepository interface {
+	CreateToken(ctx context.Context, token *domain.PersonalAccessToken) error
+
+	FindTokenByHash(ctx context.Context, hash string) (*domain.PersonalAccessToken, error)
+
+	GetUserTokens(ctx context.Context, userID uint) ([]*domain.PersonalAccessToken, error)
+
+	RevokeToken(ctx context.Context, userID, tokenID uint) error
+
+	RevokeUserTokens(ctx context.Context, userID uint) error
+
+	TouchToken(ctx context.Context, tokenID uint, usedAt time.Time, ip string) error
+}
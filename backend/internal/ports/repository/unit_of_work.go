@@ -0,0 +1,24 @@
+package repository
+
+import "context"
+
+// Repositories is the set of repositories bound to a single unit of work.
+type Repositories struct {
+	Users             UserRepository
+	Friends           FriendsRepository
+	Messages          MessageRepository
+	Conversations     ConversationRepository
+	Identities        IdentityRepository
+	Tokens            TokenRepository
+	Sessions          SessionRepository
+	Audit             AuditRepository
+	ScheduledMessages ScheduledMessageRepository
+}
+
+type UnitOfWork interface {
+	// Do runs fn in a serializable transaction using the repositories it is
+	// given, committing when fn returns nil and rolling back otherwise.
+	// Serialization failures are retried, so fn may run more than once and
+	// must not have effects outside those repositories.
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
@@ -1,12 +1,29 @@
 package repository
 
-import "go-chat/internal/domain"
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+)
 
 type UserRepository interface {
-	Create(user *domain.User) error
-	GetUserByID(id uint) (*domain.User, error)
-	FindByEmail(email string) (*domain.User, error)
-	UpdatePassword(id uint, password string) (*domain.User, error)
-	UpdateUserProfile(id uint, name, email string) (*domain.User, error)
-	SearchUsers(query string, id uint) ([]*domain.User, error)
+	Create(ctx context.Context, user *domain.User) error
+	GetUserByID(ctx context.Context, id uint) (*domain.User, error)
+	FindByEmail(ctx context.Context, email string) (*domain.User, error)
+	UpdatePassword(ctx context.Context, id uint, password string) (*domain.User, error)
+	UpdateUserProfile(ctx context.Context, id uint, name, email string) (*domain.User, error)
+	SearchUsers(ctx context.Context, query string, id uint) ([]*domain.User, error)
+	GetBotsByOwner(ctx context.Context, ownerID uint) ([]*domain.User, error)
+	DeleteUser(ctx context.Context, id uint) error
+	ListUsers(ctx context.Context, query string, limit, offset int) ([]*domain.User, int64, error)
+	UpdateUserRole(ctx context.Context, id uint, role domain.Role) error
+	CountUsersByRole(ctx context.Context, role domain.Role) (int64, error)
+	SetUserSuspension(ctx context.Context, id uint, suspendedAt *time.Time, reason string) error
+	SetPasswordResetRequired(ctx context.Context, id uint, required bool) error
+	SetReadReceiptsDisabled(ctx context.Context, id uint, disabled bool) error
+	SetNotificationPreferences(ctx context.Context, id uint, preferences domain.NotificationPreferences) error
+	SetDeletionRequested(ctx context.Context, id uint, requestedAt *time.Time) error
+	GetUsersPendingDeletion(ctx context.Context, requestedBefore time.Time) ([]*domain.User, error)
+	AnonymizeUser(ctx context.Context, id uint, anonymizedAt time.Time) error
 }
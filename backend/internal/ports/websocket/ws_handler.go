@@ -1,14 +1,18 @@
 package websocket
 
 import (
+	"context"
+
 	"go-chat/internal/domain"
 
 	"github.com/gorilla/websocket"
 )
 
 type WSHandler interface {
-	HandleConnection(conn *websocket.Conn, userID uint) error
-	DisconnectUser(userID uint)
+	HandleConnection(ctx context.Context, conn *websocket.Conn, userID uint) error
+	// CloseUserConnection ends the user's connection with a close frame
+	// carrying reason, e.g. when their account is suspended.
+	CloseUserConnection(userID uint, reason string)
 
 	BroadcastMessage(message *domain.WSMessage, targetUserID uint) error
 	BroadcastToAll(message *domain.WSMessage) error
@@ -26,4 +30,14 @@ type WSClient struct {
 	UserID uint
 	Conn   *websocket.Conn
 	Send   chan *domain.WSMessage
+
+	// ReadOnly clients receive events but may not send messages, typing
+	// indicators or read receipts, e.g. those authenticated with a token
+	// that lacks the messages:write scope.
+	ReadOnly bool
+
+	// Ctx carries the values of the upgrade request, including its trace
+	// and logger, and is cancelled when the connection closes rather than
+	// when the upgrade request returns.
+	Ctx context.Context
 }
@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"go-chat/config"
+	"go-chat/internal/domain"
 	"go-chat/internal/handlers"
 	"go-chat/internal/middlerware"
 	"go-chat/pkg"
@@ -11,16 +13,25 @@ import (
 	"gorm.io/gorm"
 )
 
-func SetupRoutes(r chi.Router, db *gorm.DB, h *handlers.Handlers) error {
-	pkg.InitLogger(pkg.INFO, true)
-	middlerware.InitRateLimiter()
+// SetupRoutes mounts the API on r. The caller owns limiter and stops it on
+// shutdown.
+func SetupRoutes(r chi.Router, db *gorm.DB, h *handlers.Handlers, cfg *config.Config, limiter *middlerware.RateLimiter) error {
+	timeouts := middlerware.NewQueryTimeouts(cfg.Database.QueryTimeouts)
+	auth := middlerware.NewAuthenticator(middlerware.AuthConfig{
+		Accounts:     h.Accounts,
+		Tokens:       h.TokenAuth,
+		AccessTokens: h.AccessTokens,
+		Sessions:     h.Sessions,
+	})
 
-	r.Use(middlerware.Cors())
+	r.Use(middlerware.Cors(cfg.CORS))
 
 	r.Use(middlerware.SecurityHeaders)
 	r.Use(middlerware.RecoveryLogging())
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	r.Use(middlerware.Tracing)
+	r.Use(middlerware.Metrics)
 	r.Use(middlerware.RequestLogging())
 	r.Use(middleware.Recoverer)
 
@@ -28,8 +39,14 @@ func SetupRoutes(r chi.Router, db *gorm.DB, h *handlers.Handlers) error {
 		w.Write([]byte("Chat API"))
 	})
 
+	r.Get("/healthz", h.Health.LivenessHandler)
+	r.Get("/readyz", h.Health.ReadinessHandler)
+	r.Get("/version", h.Health.VersionHandler)
+	r.Handle("/metrics", pkg.MetricsHandler())
+
 	r.Route("/api/auth", func(r chi.Router) {
-		r.Use(middlerware.RateLimit("auth"))
+		r.Use(limiter.Limit("auth"))
+		r.Use(timeouts.QueryTimeout("default"))
 		r.Use(middlerware.AuthLogging())
 
 		r.With(middlerware.ValidateRequest("signup")).Post("/signup", h.User.SignupHandler)
@@ -38,29 +55,61 @@ func SetupRoutes(r chi.Router, db *gorm.DB, h *handlers.Handlers) error {
 		r.Post("/logout", h.Auth.LogoutHandler)
 		r.Post("/check-email", h.Auth.CheckEmailHandler)
 
+		r.Get("/oauth/providers", h.OAuth.ListProvidersHandler)
+		r.Get("/oauth/{provider}/login", h.OAuth.LoginHandler)
+		r.Get("/oauth/{provider}/callback", h.OAuth.CallbackHandler)
+
 		r.Group(func(r chi.Router) {
-			r.Use(middlerware.RequireAuth)
-			r.Get("/me", h.Auth.GetMeHandler)
+			r.Use(auth.RequireAuth)
+			r.With(middlerware.RequireScope("users")).Get("/me", h.Auth.GetMeHandler)
 			r.Get("/validate", h.Auth.ValidateTokenHandler)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(middlerware.AllowPendingPasswordReset)
+			r.Use(auth.RequireAuth)
+			r.Use(middlerware.RequireSession)
 			r.With(middlerware.ValidateRequest("default")).Post("/change-password", h.Auth.ChangePasswordHandler)
 		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireAuth)
+			r.Use(middlerware.RequireSession)
+
+			r.Get("/identities", h.OAuth.GetIdentitiesHandler)
+			r.Post("/oauth/{provider}/link", h.OAuth.LinkHandler)
+			r.Delete("/identities/{provider}", h.OAuth.UnlinkHandler)
+		})
 	})
 
 	// User routes
 	r.Route("/api/users", func(r chi.Router) {
-		r.Use(middlerware.RateLimit("default"))
+		r.Use(limiter.Limit("default"))
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireAuth)
+			r.Use(middlerware.RequireScope("users"))
+			r.With(timeouts.QueryTimeout("default"), middlerware.ValidateRequest("profile")).Put("/profile", h.User.UpdateProfileHandler)
+			r.With(timeouts.QueryTimeout("default")).Get("/me/settings", h.User.GetSettingsHandler)
+			r.With(timeouts.QueryTimeout("default"), middlerware.ValidateRequest("default")).Put("/me/settings", h.User.UpdateSettingsHandler)
+			r.With(limiter.Limit("search"), timeouts.QueryTimeout("search")).Get("/search", h.User.SearchUsersHandler)
+			r.With(timeouts.QueryTimeout("default")).Get("/me/security-activity", h.Audit.SecurityActivityHandler)
+		})
+
 		r.Group(func(r chi.Router) {
-			r.Use(middlerware.RequireAuth)
-			r.With(middlerware.ValidateRequest("profile")).Put("/profile", h.User.UpdateProfileHandler)
-			r.With(middlerware.RateLimit("search")).Get("/search", h.User.SearchUsersHandler)
+			r.Use(auth.RequireAuth)
+			r.Use(middlerware.RequireSession)
+			r.With(timeouts.QueryTimeout("export")).Get("/me/export", h.Account.ExportDataHandler)
+			r.With(timeouts.QueryTimeout("default"), middlerware.ValidateRequest("default")).Delete("/me", h.Account.DeleteAccountHandler)
 		})
 	})
 
 	// Friends routes
 	r.Route("/api/friends", func(r chi.Router) {
-		r.Use(middlerware.RateLimit("friends"))
+		r.Use(limiter.Limit("friends"))
+		r.Use(timeouts.QueryTimeout("default"))
 		r.Group(func(r chi.Router) {
-			r.Use(middlerware.RequireAuth)
+			r.Use(auth.RequireAuth)
+			r.Use(middlerware.RequireScope("friends"))
 
 			r.Get("/", h.Friends.GetUserFriendsHandler)
 			r.With(middlerware.ValidateRequest("friend_request")).Post("/request", h.Friends.SendFriendRequestHandler)
@@ -75,22 +124,100 @@ func SetupRoutes(r chi.Router, db *gorm.DB, h *handlers.Handlers) error {
 
 	// Message routes
 	r.Route("/api/messages", func(r chi.Router) {
-		r.Use(middlerware.RateLimit("message"))
+		r.Use(limiter.Limit("message"))
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.RequireAuth)
+			r.Use(middlerware.RequireScope("messages"))
+
+			r.Group(func(r chi.Router) {
+				r.Use(timeouts.QueryTimeout("default"))
+				r.With(middlerware.ValidateRequest("message")).Post("/", h.Message.SendMessageHandler)
+				r.Get("/scheduled", h.Message.GetScheduledMessagesHandler)
+				r.With(middlerware.ValidateRequest("scheduled_message")).Put("/scheduled/{scheduledID}", h.Message.UpdateScheduledMessageHandler)
+				r.Delete("/scheduled/{scheduledID}", h.Message.CancelScheduledMessageHandler)
+				r.Get("/conversations", h.Message.GetConversationsHandler)
+				r.Put("/conversations/{userID}", h.Message.UpdateConversationHandler)
+				r.Delete("/conversations/{userID}/messages", h.Message.ClearConversationHandler)
+				r.Put("/conversations/{userID}/disappearing", h.Message.SetDisappearingHandler)
+				r.Get("/starred", h.Message.GetStarredMessagesHandler)
+				r.Get("/{userID}", h.Message.GetMessagesHandler)
+				r.Get("/{userID}/pins", h.Message.GetPinnedMessagesHandler)
+				r.Put("/read/{userID}", h.Message.MarkAsReadHandler)
+				r.Put("/{messageID}/read", h.Message.MarkReadUpToHandler)
+				r.Put("/{messageID}/unread", h.Message.MarkUnreadFromHandler)
+				r.Get("/{messageID}/receipts", h.Message.GetReceiptsHandler)
+				r.Get("/unread/{userID}", h.Message.GetUnreadCountHandler)
+				r.Delete("/{messageID}", h.Message.DeleteMessageHandler)
+				r.Put("/{messageID}/pin", h.Message.PinMessageHandler)
+				r.Delete("/{messageID}/pin", h.Message.UnpinMessageHandler)
+				r.Put("/{messageID}/star", h.Message.StarMessageHandler)
+				r.Delete("/{messageID}/star", h.Message.UnstarMessageHandler)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(limiter.Limit("search"))
+				r.Use(timeouts.QueryTimeout("search"))
+				r.Get("/conversations/search", h.Message.SearchConversationsHandler)
+				r.Get("/search", h.Message.SearchMessagesHandler)
+			})
+		})
+	})
+
+	// Personal access token and bot management routes
+	r.Route("/api/tokens", func(r chi.Router) {
+		r.Use(limiter.Limit("default"))
+		r.Use(timeouts.QueryTimeout("default"))
+		r.Use(auth.RequireAuth)
+		r.Use(middlerware.RequireSession)
+
+		r.Get("/", h.Token.ListTokensHandler)
+		r.With(middlerware.ValidateRequest("default")).Post("/", h.Token.CreateTokenHandler)
+		r.Delete("/{tokenID}", h.Token.RevokeTokenHandler)
+	})
+
+	r.Route("/api/bots", func(r chi.Router) {
+		r.Use(limiter.Limit("default"))
+		r.Use(timeouts.QueryTimeout("default"))
+		r.Use(auth.RequireAuth)
+		r.Use(middlerware.RequireSession)
+
+		r.Get("/", h.Token.ListBotsHandler)
+		r.With(middlerware.ValidateRequest("default")).Post("/", h.Token.CreateBotHandler)
+		r.Delete("/{botID}", h.Token.DeleteBotHandler)
+		r.Get("/{botID}/tokens", h.Token.ListTokensHandler)
+		r.With(middlerware.ValidateRequest("default")).Post("/{botID}/tokens", h.Token.CreateTokenHandler)
+		r.Delete("/{botID}/tokens/{tokenID}", h.Token.RevokeTokenHandler)
+	})
+
+	// Admin and moderation routes
+	r.Route("/api/admin", func(r chi.Router) {
+		r.Use(limiter.Limit("default"))
+		r.Use(timeouts.QueryTimeout("default"))
+		r.Use(auth.RequireAuth)
+		r.Use(middlerware.RequireSession)
+		r.Use(middlerware.RequireRole(domain.RoleModerator))
+
+		r.Get("/users", h.Admin.ListUsersHandler)
+		r.Get("/users/{userID}", h.Admin.GetUserHandler)
+		r.With(middlerware.ValidateRequest("default")).Post("/users/{userID}/suspend", h.Admin.SuspendUserHandler)
+		r.Post("/users/{userID}/reinstate", h.Admin.ReinstateUserHandler)
+		r.Delete("/messages/{messageID}", h.Admin.DeleteMessageHandler)
 
 		r.Group(func(r chi.Router) {
-			r.Use(middlerware.RequireAuth)
-			r.With(middlerware.ValidateRequest("message")).Post("/", h.Message.SendMessageHandler)
-			r.Get("/conversations", h.Message.GetConversationsHandler)
-			r.With(middlerware.RateLimit("search")).Get("/conversations/search", h.Message.SearchConversationsHandler)
-			r.Get("/{userID}", h.Message.GetMessagesHandler)
-			r.Put("/read/{userID}", h.Message.MarkAsReadHandler)
-			r.Get("/unread/{userID}", h.Message.GetUnreadCountHandler)
-			r.Delete("/{messageID}", h.Message.DeleteMessageHandler)
-			r.With(middlerware.RateLimit("search")).Get("/search", h.Message.SearchMessagesHandler)
+			r.Use(middlerware.RequireRole(domain.RoleAdmin))
+			r.Post("/users/{userID}/force-password-reset", h.Admin.ForcePasswordResetHandler)
+			r.With(middlerware.ValidateRequest("default")).Put("/users/{userID}/role", h.Admin.UpdateRoleHandler)
+			r.Get("/users/{userID}/sessions", h.Admin.GetUserSessionsHandler)
+			r.Delete("/users/{userID}/sessions", h.Admin.RevokeUserSessionsHandler)
+			r.Get("/audit", h.Audit.ListEntriesHandler)
+			r.Get("/audit/verify", h.Audit.VerifyChainHandler)
+			r.Get("/logging", h.Admin.GetLoggingHandler)
+			r.Put("/logging", h.Admin.UpdateLoggingHandler)
 		})
 	})
 
-	r.With(middlerware.RateLimit("default")).Get("/ws", h.WebSocket.ServeWS)
+	r.With(limiter.Limit("default"), auth.RequireAuth, middlerware.RequireScope("messages")).Get("/ws", h.WebSocket.ServeWS)
 
 	return nil
 }
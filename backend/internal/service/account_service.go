@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	wsports "go-chat/internal/ports/websocket"
+	"go-chat/pkg"
+)
+
+const deleteConfirmation = "DELETE"
+
+type AccountService struct {
+	userRepo     repository.UserRepository
+	friendsRepo  repository.FriendsRepository
+	messageRepo  repository.MessageRepository
+	identityRepo repository.IdentityRepository
+	hub          wsports.WSHandler
+	uow          repository.UnitOfWork
+
+	gracePeriod time.Duration
+}
+
+func NewAccountService(
+	userRepo repository.UserRepository,
+	friendsRepo repository.FriendsRepository,
+	messageRepo repository.MessageRepository,
+	identityRepo repository.IdentityRepository,
+	hub wsports.WSHandler,
+	uow repository.UnitOfWork,
+	gracePeriod time.Duration,
+) *AccountService {
+	return &AccountService{
+		userRepo:     userRepo,
+		friendsRepo:  friendsRepo,
+		messageRepo:  messageRepo,
+		identityRepo: identityRepo,
+		hub:          hub,
+		uow:          uow,
+		gracePeriod:  gracePeriod,
+	}
+}
+
+func (s *AccountService) GracePeriod() time.Duration {
+	return s.gracePeriod
+}
+
+func (s *AccountService) ExportData(ctx context.Context, userID uint) (*domain.AccountExport, error) {
+	ctx, span := pkg.StartSpan(ctx, "AccountService.ExportData")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	identities, err := s.identityRepo.GetUserIdentities(ctx, userID)
+	if err != nil {
+		return nil, errors.New("failed to export linked providers")
+	}
+
+	friendships, err := s.friendsRepo.GetAllUserFriendships(ctx, userID)
+	if err != nil {
+		return nil, errors.New("failed to export friendships")
+	}
+
+	messages, err := s.messageRepo.GetAllUserMessages(ctx, userID)
+	if err != nil {
+		return nil, errors.New("failed to export messages")
+	}
+
+	export := &domain.AccountExport{
+		ExportedAt:       time.Now().UTC(),
+		Profile:          user.ToResponse(),
+		Identities:       identities,
+		Friendships:      friendships,
+		MessagesSent:     []*domain.MessageResponse{},
+		MessagesReceived: []*domain.MessageResponse{},
+	}
+
+	for _, msg := range messages {
+		if msg.SenderID == userID {
+			export.MessagesSent = append(export.MessagesSent, msg.ToResponse())
+		} else {
+			export.MessagesReceived = append(export.MessagesReceived, msg.ToResponse())
+		}
+	}
+
+	return export, nil
+}
+
+// RequestDeletion schedules the account for anonymization after the grace
+// period, signs it out everywhere and revokes its personal access tokens.
+// Signing in again cancels the request.
+func (s *AccountService) RequestDeletion(ctx context.Context, userID uint, req *domain.DeleteAccountRequest) (time.Time, error) {
+	ctx, span := pkg.StartSpan(ctx, "AccountService.RequestDeletion")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return time.Time{}, errors.New("user not found")
+	}
+
+	if user.IsBot {
+		return time.Time{}, errors.New("bots are deleted by their owner")
+	}
+
+	// Accounts created through a provider have no password to confirm with.
+	if user.Password != "" {
+		if !pkg.ComparePassword(req.Password, []byte(user.Password)) {
+			return time.Time{}, errors.New("password is incorrect")
+		}
+	} else if req.Confirm != deleteConfirmation {
+		return time.Time{}, errors.New(`type "DELETE" in the confirm field to delete your account`)
+	}
+
+	if user.DeletionRequestedAt != nil {
+		return user.DeletionRequestedAt.Add(s.gracePeriod), errors.New("account deletion is already scheduled")
+	}
+
+	now := time.Now()
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if err := repos.Users.SetDeletionRequested(ctx, userID, &now); err != nil {
+			return err
+		}
+		if err := repos.Sessions.RevokeUserSessions(ctx, userID); err != nil {
+			return err
+		}
+		return repos.Tokens.RevokeUserTokens(ctx, userID)
+	})
+	if err != nil {
+		pkg.ErrorContext(ctx, "Failed to schedule account deletion", err, map[string]interface{}{
+			"user_id": userID,
+		})
+		return time.Time{}, errors.New("failed to schedule account deletion")
+	}
+	s.hub.CloseUserConnection(userID, "account deletion requested")
+
+	return now.Add(s.gracePeriod), nil
+}
+
+func (s *AccountService) CancelDeletion(ctx context.Context, userID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "AccountService.CancelDeletion")
+	defer span.End()
+
+	return s.userRepo.SetDeletionRequested(ctx, userID, nil)
+}
+
+// PurgeDueAccounts anonymizes every account whose grace period has ended.
+func (s *AccountService) PurgeDueAccounts(ctx context.Context) (int, error) {
+	ctx, span := pkg.StartSpan(ctx, "AccountService.PurgeDueAccounts")
+	defer span.End()
+
+	due, err := s.userRepo.GetUsersPendingDeletion(ctx, time.Now().Add(-s.gracePeriod))
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, user := range due {
+		if err := s.purgeAccount(ctx, user.ID); err != nil {
+			pkg.ErrorContext(ctx, "Failed to purge deleted account", err, map[string]interface{}{
+				"user_id": user.ID,
+			})
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+func (s *AccountService) purgeAccount(ctx context.Context, userID uint) error {
+	// Everything is removed in one transaction; a failure leaves the account
+	// untouched and the request pending for a retry.
+	now := time.Now()
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		bots, err := repos.Users.GetBotsByOwner(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, bot := range bots {
+			if err := repos.Tokens.RevokeUserTokens(ctx, bot.ID); err != nil {
+				return err
+			}
+			if err := repos.ScheduledMessages.CancelUserScheduledMessages(ctx, bot.ID, now); err != nil {
+				return err
+			}
+			if err := repos.Users.DeleteUser(ctx, bot.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := repos.Tokens.RevokeUserTokens(ctx, userID); err != nil {
+			return err
+		}
+		if err := repos.Sessions.RevokeUserSessions(ctx, userID); err != nil {
+			return err
+		}
+		if err := repos.Identities.DeleteUserIdentities(ctx, userID); err != nil {
+			return err
+		}
+		if err := repos.Friends.DeleteUserFriendships(ctx, userID); err != nil {
+			return err
+		}
+		if err := repos.ScheduledMessages.CancelUserScheduledMessages(ctx, userID, now); err != nil {
+			return err
+		}
+		if err := repos.Messages.TombstoneUserMessages(ctx, userID); err != nil {
+			return err
+		}
+		if err := repos.Messages.DeleteUserMessageState(ctx, userID); err != nil {
+			return err
+		}
+		if err := repos.Conversations.DeleteUserConversations(ctx, userID); err != nil {
+			return err
+		}
+
+		return repos.Users.AnonymizeUser(ctx, userID, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.hub.CloseUserConnection(userID, "account deleted")
+	pkg.InfoContext(ctx, "Deleted account purged", map[string]interface{}{"user_id": userID})
+	return nil
+}
+
+// RunDeletionWorker purges due accounts on every tick until ctx is done.
+func (s *AccountService) RunDeletionWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.PurgeDueAccounts(ctx); err != nil {
+			pkg.ErrorContext(ctx, "Account deletion worker failed", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/pkg"
+)
+
+type accountFixture struct {
+	baseFixture
+	service *AccountService
+}
+
+func newAccountFixture(t *testing.T) *accountFixture {
+	t.Helper()
+	f := &accountFixture{baseFixture: newBaseFixture(t)}
+	f.service = NewAccountService(f.repos.Users, f.repos.Friends, f.repos.Messages, f.repos.Identities, f.hub, f.uow, time.Hour)
+	return f
+}
+
+func TestRequestDeletionRevokesCredentials(t *testing.T) {
+	ctx := context.Background()
+	f := newAccountFixture(t)
+
+	if _, err := f.repos.Users.UpdatePassword(ctx, f.alice.ID, string(pkg.HashPassword("password123"))); err != nil {
+		t.Fatal(err)
+	}
+	session := &domain.Session{ID: "laptop", UserID: f.alice.ID, LastSeenAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}
+	if err := f.repos.Sessions.CreateSession(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+	token := &domain.PersonalAccessToken{UserID: f.alice.ID, CreatedBy: f.alice.ID, Name: "ci", Prefix: "gct_a", TokenHash: "hash-a", Scopes: domain.ScopeMessagesRead}
+	if err := f.repos.Tokens.CreateToken(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := f.service.RequestDeletion(ctx, f.alice.ID, &domain.DeleteAccountRequest{Password: "wrong"}); err == nil {
+		t.Fatal("RequestDeletion accepted a wrong password")
+	}
+	if _, err := f.service.RequestDeletion(ctx, f.alice.ID, &domain.DeleteAccountRequest{Password: "password123"}); err != nil {
+		t.Fatalf("RequestDeletion: %v", err)
+	}
+
+	stored, err := f.repos.Sessions.GetSessionByID(ctx, session.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.IsActive(time.Now()) {
+		t.Error("session still active during the grace period")
+	}
+	revoked, err := f.repos.Tokens.FindTokenByHash(ctx, token.TokenHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revoked.IsActive(time.Now()) {
+		t.Error("personal access token still active during the grace period")
+	}
+	if !slices.Equal(f.hub.disconnected, []uint{f.alice.ID}) {
+		t.Errorf("disconnected = %v, want Alice", f.hub.disconnected)
+	}
+}
+
+func TestPurgeDueAccounts(t *testing.T) {
+	ctx := context.Background()
+	f := newAccountFixture(t)
+	carol := createTestUser(t, f.repos.Users, "Carol", "carol@example.com")
+	messages := NewMessageService(f.repos.Messages, f.repos.Conversations, f.repos.Users, f.uow, time.Hour)
+
+	fromAlice, err := messages.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "from alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromBob, err := messages.SendMessage(ctx, f.bob.ID, &domain.MessageRequest{ReceiverID: f.alice.ID, Content: "from bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both pins go: Alice set one and the other is on her message.
+	if _, err := messages.PinMessage(ctx, fromAlice.ID, f.bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := messages.PinMessage(ctx, fromBob.ID, f.alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := messages.StarMessage(ctx, fromBob.ID, f.alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := messages.StarMessage(ctx, fromAlice.ID, f.bob.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := messages.DeleteMessageForMe(ctx, fromBob.ID, f.alice.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.repos.Conversations.SetDisappearingTimer(ctx, f.alice.ID, f.bob.ID, time.Hour, f.alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	future := time.Now().Add(time.Hour).UTC()
+	outgoing := &domain.ScheduledMessage{SenderID: f.alice.ID, ReceiverID: f.bob.ID, Content: "later", SendAt: future}
+	incoming := &domain.ScheduledMessage{SenderID: carol.ID, ReceiverID: f.alice.ID, Content: "later", SendAt: future}
+	unrelated := &domain.ScheduledMessage{SenderID: carol.ID, ReceiverID: f.bob.ID, Content: "later", SendAt: future}
+	for _, message := range []*domain.ScheduledMessage{outgoing, incoming, unrelated} {
+		if err := f.repos.ScheduledMessages.CreateScheduledMessage(ctx, message); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	requestedAt := time.Now().Add(-2 * time.Hour)
+	if err := f.repos.Users.SetDeletionRequested(ctx, f.alice.ID, &requestedAt); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := f.service.PurgeDueAccounts(ctx)
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDueAccounts = %d, %v, want 1", purged, err)
+	}
+
+	for _, tc := range []struct {
+		message *domain.ScheduledMessage
+		want    domain.ScheduledMessageStatus
+	}{
+		{outgoing, domain.ScheduledMessageCancelled},
+		{incoming, domain.ScheduledMessageCancelled},
+		{unrelated, domain.ScheduledMessagePending},
+	} {
+		stored, err := f.repos.ScheduledMessages.GetScheduledMessage(ctx, tc.message.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Status != tc.want {
+			t.Errorf("scheduled message %d from %d to %d = %q, want %q", stored.ID, stored.SenderID, stored.ReceiverID, stored.Status, tc.want)
+		}
+	}
+
+	if starred, err := f.repos.Messages.GetStarredMessages(ctx, f.alice.ID, 10, 0); err != nil || len(starred) != 0 {
+		t.Errorf("Alice's stars after purge = %d, %v, want none", len(starred), err)
+	}
+	if starred, err := f.repos.Messages.GetStarredMessages(ctx, f.bob.ID, 10, 0); err != nil || len(starred) != 1 {
+		t.Errorf("Bob's stars after purge = %d, %v, want his star kept", len(starred), err)
+	}
+	if count, err := f.repos.Messages.CountPinnedMessages(ctx, f.alice.ID, f.bob.ID); err != nil || count != 0 {
+		t.Errorf("pins after purge = %d, %v, want none", count, err)
+	}
+	if _, err := f.repos.Conversations.GetConversation(ctx, f.alice.ID, f.bob.ID); err == nil {
+		t.Error("Alice's conversation summary survived the purge")
+	}
+	if _, err := f.repos.Conversations.GetConversation(ctx, f.bob.ID, f.alice.ID); err != nil {
+		t.Errorf("Bob's conversation summary: %v", err)
+	}
+	if after, err := f.repos.Conversations.GetDisappearingTimer(ctx, f.alice.ID, f.bob.ID); err != nil || after != 0 {
+		t.Errorf("timer after purge = %v, %v, want none", after, err)
+	}
+}
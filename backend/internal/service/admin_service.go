@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	wsports "go-chat/internal/ports/websocket"
+	"go-chat/pkg"
+)
+
+var (
+	errLastAdmin               = errors.New("cannot remove the last admin")
+	errInsufficientPermissions = errors.New("insufficient permissions for this user")
+)
+
+type AdminService struct {
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	messageRepo repository.MessageRepository
+	hub         wsports.WSHandler
+	uow         repository.UnitOfWork
+}
+
+func NewAdminService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, messageRepo repository.MessageRepository, hub wsports.WSHandler, uow repository.UnitOfWork) *AdminService {
+	return &AdminService{
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		messageRepo: messageRepo,
+		hub:         hub,
+		uow:         uow,
+	}
+}
+
+func (s *AdminService) ListUsers(ctx context.Context, query string, limit, offset int) ([]*domain.User, int64, error) {
+	ctx, span := pkg.StartSpan(ctx, "AdminService.ListUsers")
+	defer span.End()
+
+	return s.userRepo.ListUsers(ctx, strings.TrimSpace(query), limit, offset)
+}
+
+func (s *AdminService) GetUser(ctx context.Context, userID uint) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "AdminService.GetUser")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
+
+// loadManageableUser returns the target if the actor outranks them; nobody
+// may act on themselves or on a peer.
+func (s *AdminService) loadManageableUser(ctx context.Context, actorID uint, actorRole domain.Role, targetID uint) (*domain.User, error) {
+	target, err := s.loadOtherUser(ctx, actorID, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if target.EffectiveRole().AtLeast(actorRole) {
+		return nil, errInsufficientPermissions
+	}
+
+	return target, nil
+}
+
+// loadOtherUser returns the target unless it is the actor.
+func (s *AdminService) loadOtherUser(ctx context.Context, actorID, targetID uint) (*domain.User, error) {
+	if actorID == targetID {
+		return nil, errors.New("cannot perform this action on your own account")
+	}
+
+	target, err := s.userRepo.GetUserByID(ctx, targetID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	return target, nil
+}
+
+func (s *AdminService) SuspendUser(ctx context.Context, actorID uint, actorRole domain.Role, targetID uint, reason string) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "AdminService.SuspendUser")
+	defer span.End()
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a suspension reason is required")
+	}
+
+	target, err := s.loadManageableUser(ctx, actorID, actorRole, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if target.IsSuspended() {
+		return nil, errors.New("user is already suspended")
+	}
+
+	now := time.Now()
+	if err := s.userRepo.SetUserSuspension(ctx, targetID, &now, reason); err != nil {
+		return nil, errors.New("failed to suspend user")
+	}
+
+	if err := s.sessionRepo.RevokeUserSessions(ctx, targetID); err != nil {
+		pkg.ErrorContext(ctx, "Failed to revoke sessions of suspended user", err, map[string]interface{}{
+			"user_id": targetID,
+		})
+	}
+	s.hub.CloseUserConnection(targetID, "account suspended")
+
+	// Bot tokens stop working with the owner's suspension; end their open
+	// connections too.
+	bots, err := s.userRepo.GetBotsByOwner(ctx, targetID)
+	if err != nil {
+		pkg.ErrorContext(ctx, "Failed to load bots of suspended user", err, map[string]interface{}{
+			"user_id": targetID,
+		})
+	}
+	for _, bot := range bots {
+		s.hub.CloseUserConnection(bot.ID, "owner suspended")
+	}
+
+	target.SuspendedAt = &now
+	target.SuspensionReason = reason
+	return target, nil
+}
+
+func (s *AdminService) ReinstateUser(ctx context.Context, actorID uint, actorRole domain.Role, targetID uint) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "AdminService.ReinstateUser")
+	defer span.End()
+
+	target, err := s.loadManageableUser(ctx, actorID, actorRole, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !target.IsSuspended() {
+		return nil, errors.New("user is not suspended")
+	}
+
+	if err := s.userRepo.SetUserSuspension(ctx, targetID, nil, ""); err != nil {
+		return nil, errors.New("failed to reinstate user")
+	}
+
+	target.SuspendedAt = nil
+	target.SuspensionReason = ""
+	return target, nil
+}
+
+// ForcePasswordReset signs the user out everywhere and blocks every
+// endpoint except change-password until they pick a new password.
+func (s *AdminService) ForcePasswordReset(ctx context.Context, actorID uint, actorRole domain.Role, targetID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "AdminService.ForcePasswordReset")
+	defer span.End()
+
+	if _, err := s.loadManageableUser(ctx, actorID, actorRole, targetID); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.SetPasswordResetRequired(ctx, targetID, true); err != nil {
+		return errors.New("failed to flag password reset")
+	}
+
+	if err := s.sessionRepo.RevokeUserSessions(ctx, targetID); err != nil {
+		return errors.New("failed to revoke sessions")
+	}
+	s.hub.CloseUserConnection(targetID, "password reset required")
+
+	return nil
+}
+
+// UpdateUserRole changes the role of a user the actor outranks. Admins may
+// also change each other's roles, the only way to demote an admin, but the
+// last admin is never demoted, so the admin API always stays reachable.
+func (s *AdminService) UpdateUserRole(ctx context.Context, actorID uint, actorRole domain.Role, targetID uint, role domain.Role) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "AdminService.UpdateUserRole")
+	defer span.End()
+
+	if !role.IsValid() {
+		return nil, errors.New("invalid role")
+	}
+
+	target, err := s.loadOtherUser(ctx, actorID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if actorRole != domain.RoleAdmin && target.EffectiveRole().AtLeast(actorRole) {
+		return nil, errInsufficientPermissions
+	}
+	if target.IsBot && role != domain.RoleUser {
+		return nil, errors.New("bots cannot be granted elevated roles")
+	}
+
+	// Counting and demoting share a transaction so the count cannot go stale
+	// before the update.
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if target.EffectiveRole() == domain.RoleAdmin && role != domain.RoleAdmin {
+			admins, err := repos.Users.CountUsersByRole(ctx, domain.RoleAdmin)
+			if err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return errLastAdmin
+			}
+		}
+		return repos.Users.UpdateUserRole(ctx, targetID, role)
+	})
+	if errors.Is(err, errLastAdmin) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New("failed to update role")
+	}
+
+	target.Role = role
+	return target, nil
+}
+
+func (s *AdminService) GetUserSessions(ctx context.Context, targetID uint, includeInactive bool) ([]*domain.Session, error) {
+	ctx, span := pkg.StartSpan(ctx, "AdminService.GetUserSessions")
+	defer span.End()
+
+	if _, err := s.userRepo.GetUserByID(ctx, targetID); err != nil {
+		return nil, errors.New("user not found")
+	}
+	return s.sessionRepo.GetUserSessions(ctx, targetID, includeInactive)
+}
+
+func (s *AdminService) RevokeUserSessions(ctx context.Context, actorID uint, actorRole domain.Role, targetID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "AdminService.RevokeUserSessions")
+	defer span.End()
+
+	if _, err := s.loadManageableUser(ctx, actorID, actorRole, targetID); err != nil {
+		return err
+	}
+
+	if err := s.sessionRepo.RevokeUserSessions(ctx, targetID); err != nil {
+		return errors.New("failed to revoke sessions")
+	}
+	s.hub.CloseUserConnection(targetID, "sessions revoked")
+
+	return nil
+}
+
+func (s *AdminService) DeleteMessage(ctx context.Context, actorID, messageID uint, reason string) (*domain.Message, error) {
+	ctx, span := pkg.StartSpan(ctx, "AdminService.DeleteMessage")
+	defer span.End()
+
+	message, err := s.messageRepo.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, errors.New("message not found")
+	}
+
+	var unpinned *domain.WSPinnedPayload
+	err = s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		unpinned = nil
+
+		if err := repos.Messages.DeleteMessage(ctx, messageID); err != nil {
+			return err
+		}
+		wasPinned, err := repos.Messages.UnpinMessage(ctx, messageID)
+		if err != nil {
+			return err
+		}
+		if wasPinned {
+			unpinned = &domain.WSPinnedPayload{
+				MessageID:  message.ID,
+				SenderID:   message.SenderID,
+				ReceiverID: message.ReceiverID,
+				PinnedByID: actorID,
+			}
+		}
+		return repos.Conversations.RefreshConversation(ctx, message.SenderID, message.ReceiverID)
+	})
+	if err != nil {
+		return nil, errors.New("failed to delete message")
+	}
+
+	// Both participants' clients still show the message.
+	deleted := &domain.WSMessage{
+		Type: domain.WSMessageTypeMessageDeleted,
+		Payload: &domain.WSDeletedPayload{
+			MessageID:  message.ID,
+			SenderID:   message.SenderID,
+			ReceiverID: message.ReceiverID,
+		},
+	}
+	for _, userID := range []uint{message.SenderID, message.ReceiverID} {
+		s.hub.BroadcastMessage(deleted, userID)
+		if unpinned != nil {
+			s.hub.BroadcastMessage(unpinned.ToWSMessage(), userID)
+		}
+	}
+
+	pkg.InfoContext(ctx, "Message removed by moderator", map[string]interface{}{
+		"moderator_id": actorID,
+		"message_id":   messageID,
+		"sender_id":    message.SenderID,
+		"reason":       reason,
+	})
+
+	return message, nil
+}
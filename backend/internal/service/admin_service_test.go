@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go-chat/internal/domain"
+)
+
+type adminFixture struct {
+	baseFixture
+	service *AdminService
+
+	admin *domain.User
+}
+
+func newAdminFixture(t *testing.T) *adminFixture {
+	t.Helper()
+	f := &adminFixture{baseFixture: newBaseFixture(t)}
+	f.service = NewAdminService(f.repos.Users, f.repos.Sessions, f.repos.Messages, f.hub, f.uow)
+	f.admin = f.createAdmin(t, "Admin", "admin@example.com")
+	return f
+}
+
+func (f *adminFixture) createAdmin(t *testing.T, name, email string) *domain.User {
+	t.Helper()
+	user := createTestUser(t, f.repos.Users, name, email)
+	if err := f.repos.Users.UpdateUserRole(context.Background(), user.ID, domain.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestUpdateUserRole(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	otherAdmin := f.createAdmin(t, "Other Admin", "other@example.com")
+
+	tests := []struct {
+		name      string
+		actorID   uint
+		actorRole domain.Role
+		targetID  uint
+		role      domain.Role
+		wantErr   string
+	}{
+		{name: "invalid role", targetID: f.alice.ID, role: "owner", wantErr: "invalid role"},
+		{name: "own account", targetID: f.admin.ID, role: domain.RoleUser, wantErr: "cannot perform this action on your own account"},
+		{name: "unknown user", targetID: f.alice.ID + 100, role: domain.RoleModerator, wantErr: "user not found"},
+		{name: "promote", targetID: f.alice.ID, role: domain.RoleModerator},
+		{name: "moderator acting on an admin", actorID: f.alice.ID, actorRole: domain.RoleModerator, targetID: otherAdmin.ID, role: domain.RoleUser, wantErr: "insufficient permissions for this user"},
+		{name: "demote peer admin", targetID: otherAdmin.ID, role: domain.RoleModerator},
+		// The demoted admin's request still carries the admin role.
+		{name: "last admin", actorID: otherAdmin.ID, targetID: f.admin.ID, role: domain.RoleUser, wantErr: "cannot remove the last admin"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actorID, actorRole := f.admin.ID, domain.RoleAdmin
+			if tc.actorID != 0 {
+				actorID = tc.actorID
+			}
+			if tc.actorRole != "" {
+				actorRole = tc.actorRole
+			}
+			updated, err := f.service.UpdateUserRole(ctx, actorID, actorRole, tc.targetID, tc.role)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateUserRole: %v", err)
+			}
+
+			stored, err := f.repos.Users.GetUserByID(ctx, tc.targetID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Role != tc.role || stored.Role != tc.role {
+				t.Errorf("role = %q, stored %q, want %q", updated.Role, stored.Role, tc.role)
+			}
+		})
+	}
+
+	if admins, err := f.repos.Users.CountUsersByRole(ctx, domain.RoleAdmin); err != nil || admins != 1 {
+		t.Errorf("admins = %d, %v, want 1", admins, err)
+	}
+}
+
+func TestSuspendUserCutsOffBots(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	tokens := NewTokenService(f.repos.Tokens, f.repos.Users)
+
+	bot, err := tokens.CreateBot(ctx, f.alice.ID, "Helper")
+	if err != nil {
+		t.Fatalf("CreateBot: %v", err)
+	}
+	token, err := tokens.CreateToken(ctx, f.alice.ID, bot.ID, &domain.CreateTokenRequest{Name: "ci", Scopes: []string{domain.ScopeMessagesRead}})
+	if err != nil {
+		t.Fatalf("CreateToken: %v", err)
+	}
+
+	if _, _, err := tokens.AuthenticateToken(ctx, token.Token, "127.0.0.1"); err != nil {
+		t.Fatalf("AuthenticateToken before suspension: %v", err)
+	}
+
+	if _, err := f.service.SuspendUser(ctx, f.admin.ID, domain.RoleAdmin, f.alice.ID, "spam"); err != nil {
+		t.Fatalf("SuspendUser: %v", err)
+	}
+
+	if _, _, err := tokens.AuthenticateToken(ctx, token.Token, "127.0.0.1"); err == nil || err.Error() != "bot owner is suspended" {
+		t.Errorf("AuthenticateToken after suspension error = %v, want bot owner is suspended", err)
+	}
+	if !slices.Contains(f.hub.disconnected, bot.ID) {
+		t.Errorf("disconnected = %v, want the bot %d", f.hub.disconnected, bot.ID)
+	}
+}
+
+func TestDeleteMessageNotifiesParticipants(t *testing.T) {
+	ctx := context.Background()
+	f := newAdminFixture(t)
+	messageService := NewMessageService(f.repos.Messages, f.repos.Conversations, f.repos.Users, f.uow, time.Hour)
+
+	sent, err := messageService.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "spam"})
+	if err != nil {
+		t.Fatalf("SendMessage: %v", err)
+	}
+	if _, err := messageService.PinMessage(ctx, sent.ID, f.bob.ID); err != nil {
+		t.Fatalf("PinMessage: %v", err)
+	}
+
+	if _, err := f.service.DeleteMessage(ctx, f.admin.ID, sent.ID, "spam"); err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+
+	if count, err := f.repos.Messages.CountPinnedMessages(ctx, f.alice.ID, f.bob.ID); err != nil || count != 0 {
+		t.Errorf("CountPinnedMessages = %d, %v, want 0", count, err)
+	}
+	for _, userID := range []uint{f.alice.ID, f.bob.ID} {
+		var types []string
+		for _, message := range f.hub.sent[userID] {
+			types = append(types, message.Type)
+		}
+		want := []string{domain.WSMessageTypeMessageDeleted, domain.WSMessageTypeMessageUnpinned}
+		if !slices.Equal(types, want) {
+			t.Errorf("events for %d = %v, want %v", userID, types, want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	"go-chat/pkg"
+)
+
+const auditVerifyBatchSize = 500
+
+type AuditService struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record persists an audit entry. Failures are logged rather than returned
+// so auditing never breaks the action being audited.
+func (s *AuditService) Record(ctx context.Context, ac domain.AuditContext, event domain.AuditEvent) {
+	ctx, span := pkg.StartSpan(ctx, "AuditService.Record")
+	defer span.End()
+
+	entry := &domain.AuditEntry{
+		Action:     event.Action,
+		TargetType: event.TargetType,
+		TargetID:   event.TargetID,
+		Success:    !event.Failed,
+		IPAddress:  ac.IPAddress,
+		UserAgent:  ac.UserAgent,
+		RequestID:  ac.RequestID,
+	}
+
+	if ac.ActorID != 0 {
+		actorID := ac.ActorID
+		entry.ActorID = &actorID
+	}
+	if event.TargetUserID != 0 {
+		targetUserID := event.TargetUserID
+		entry.TargetUserID = &targetUserID
+	}
+
+	if len(event.Metadata) > 0 {
+		if data, err := json.Marshal(event.Metadata); err == nil {
+			entry.Metadata = string(data)
+		}
+	}
+
+	if err := s.repo.AppendEntry(ctx, entry); err != nil {
+		pkg.ErrorContext(ctx, "Failed to write audit entry", err, map[string]interface{}{
+			"action":     event.Action,
+			"actor_id":   ac.ActorID,
+			"request_id": ac.RequestID,
+		})
+	}
+}
+
+func (s *AuditService) ListEntries(ctx context.Context, filter domain.AuditFilter, limit, offset int) ([]*domain.AuditEntry, int64, error) {
+	ctx, span := pkg.StartSpan(ctx, "AuditService.ListEntries")
+	defer span.End()
+
+	return s.repo.ListEntries(ctx, filter, limit, offset)
+}
+
+// ListUserActivity returns the entries concerning a user. Details about
+// other actors (such as a moderator's IP address) are withheld.
+func (s *AuditService) ListUserActivity(ctx context.Context, userID uint, limit, offset int) ([]*domain.AuditEntry, int64, error) {
+	ctx, span := pkg.StartSpan(ctx, "AuditService.ListUserActivity")
+	defer span.End()
+
+	entries, total, err := s.repo.ListUserEntries(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, entry := range entries {
+		if entry.ActorID == nil || *entry.ActorID != userID {
+			entry.ActorID = nil
+			entry.IPAddress = ""
+			entry.UserAgent = ""
+		}
+	}
+
+	return entries, total, nil
+}
+
+// VerifyChain walks the whole log in order and reports the first entry
+// whose hash or back-link does not match.
+func (s *AuditService) VerifyChain(ctx context.Context) (*domain.AuditVerification, error) {
+	ctx, span := pkg.StartSpan(ctx, "AuditService.VerifyChain")
+	defer span.End()
+
+	result := &domain.AuditVerification{Valid: true}
+
+	var lastID uint
+	prevHash := ""
+
+	for {
+		entries, err := s.repo.GetEntriesAfter(ctx, lastID, auditVerifyBatchSize)
+		if err != nil {
+			return nil, errors.New("failed to read audit log")
+		}
+
+		for _, entry := range entries {
+			result.EntriesChecked++
+
+			if entry.PrevHash != prevHash {
+				result.Valid = false
+				result.BrokenAtID = entry.ID
+				result.Reason = "previous hash does not match; an entry was removed or reordered"
+				return result, nil
+			}
+			if entry.ComputeHash() != entry.Hash {
+				result.Valid = false
+				result.BrokenAtID = entry.ID
+				result.Reason = "entry hash does not match its contents"
+				return result, nil
+			}
+
+			prevHash = entry.Hash
+			lastID = entry.ID
+		}
+
+		if len(entries) < auditVerifyBatchSize {
+			return result, nil
+		}
+	}
+}
@@ -1,24 +1,33 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"go-chat/internal/domain"
 	"go-chat/internal/ports/repository"
 	"go-chat/pkg"
+	"time"
 )
 
 type AuthService struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	sessionRepo repository.SessionRepository
+	jwt         *pkg.JWTManager
 }
 
-func NewAuthService(userRepo repository.UserRepository) *AuthService {
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, jwt *pkg.JWTManager) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		sessionRepo: sessionRepo,
+		jwt:         jwt,
 	}
 }
 
-func (s *AuthService) AuthenticateUser(email, password string) (*domain.User, error) {
-	user, err := s.userRepo.FindByEmail(email)
+func (s *AuthService) AuthenticateUser(ctx context.Context, email, password string) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "AuthService.AuthenticateUser")
+	defer span.End()
+
+	user, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil {
 		return nil, errors.New("invalid credentials")
 	}
@@ -27,25 +36,78 @@ func (s *AuthService) AuthenticateUser(email, password string) (*domain.User, er
 		return nil, errors.New("invalid credentials")
 	}
 
+	if user.IsSuspended() {
+		return nil, errors.New("account suspended")
+	}
+
+	// Signing in during the deletion grace period cancels the deletion.
+	if user.DeletionRequestedAt != nil {
+		if err := s.userRepo.SetDeletionRequested(ctx, user.ID, nil); err != nil {
+			return nil, errors.New("failed to cancel pending account deletion")
+		}
+		user.DeletionRequestedAt = nil
+	}
+
 	return user, nil
 }
 
-func (s *AuthService) GenerateTokens(user *domain.User) (*pkg.TokenPair, error) {
-	return pkg.GenerateTokenPair(user.ID, user.Email, user.Name)
+// GenerateTokens starts a new session for the user and issues its first
+// token pair.
+func (s *AuthService) GenerateTokens(ctx context.Context, user *domain.User, clientIP, userAgent string) (*pkg.TokenPair, error) {
+	ctx, span := pkg.StartSpan(ctx, "AuthService.GenerateTokens")
+	defer span.End()
+
+	sessionID, err := pkg.RandomToken(24)
+	if err != nil {
+		return nil, errors.New("failed to create session")
+	}
+
+	now := time.Now()
+	session := &domain.Session{
+		ID:         sessionID,
+		UserID:     user.ID,
+		IPAddress:  clientIP,
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.jwt.RefreshTokenTTL()),
+	}
+	if err := s.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, errors.New("failed to create session")
+	}
+
+	return s.jwt.GenerateTokenPair(user.ID, user.Email, user.Name, session.ID)
 }
 
-func (s *AuthService) RefreshTokens(refreshToken string) (*pkg.TokenPair, *domain.User, error) {
-	refreshClaims, err := pkg.ValidateRefreshToken(refreshToken)
+func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*pkg.TokenPair, *domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "AuthService.RefreshTokens")
+	defer span.End()
+
+	refreshClaims, err := s.jwt.ValidateRefreshToken(refreshToken)
 	if err != nil {
 		return nil, nil, errors.New("invalid or expired refresh token")
 	}
 
-	user, err := s.userRepo.GetUserByID(refreshClaims.UserID)
+	session, err := s.sessionRepo.GetSessionByID(ctx, refreshClaims.ID)
+	now := time.Now()
+	if err != nil || session.UserID != refreshClaims.UserID || !session.IsActive(now) {
+		return nil, nil, errors.New("session expired or revoked")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, refreshClaims.UserID)
 	if err != nil {
 		return nil, nil, errors.New("user not found")
 	}
 
-	tokens, err := s.GenerateTokens(user)
+	if user.IsSuspended() {
+		s.sessionRepo.RevokeSession(ctx, session.ID)
+		return nil, nil, errors.New("account suspended")
+	}
+
+	if err := s.sessionRepo.TouchSession(ctx, session.ID, now, now.Add(s.jwt.RefreshTokenTTL())); err != nil {
+		return nil, nil, errors.New("failed to refresh session")
+	}
+
+	tokens, err := s.jwt.GenerateTokenPair(user.ID, user.Email, user.Name, session.ID)
 	if err != nil {
 		return nil, nil, errors.New("failed to generate tokens")
 	}
@@ -53,22 +115,43 @@ func (s *AuthService) RefreshTokens(refreshToken string) (*pkg.TokenPair, *domai
 	return tokens, user, nil
 }
 
+// Logout revokes the session behind a refresh token and returns its user.
+// Invalid tokens are ignored since the cookies are cleared regardless.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) uint {
+	ctx, span := pkg.StartSpan(ctx, "AuthService.Logout")
+	defer span.End()
+
+	refreshClaims, err := s.jwt.ValidateRefreshToken(refreshToken)
+	if err != nil || refreshClaims.ID == "" {
+		return 0
+	}
+	if err := s.sessionRepo.RevokeSession(ctx, refreshClaims.ID); err != nil {
+		return 0
+	}
+	return refreshClaims.UserID
+}
+
 func (s *AuthService) ValidateAccessToken(token string) (*pkg.Claims, error) {
-	return pkg.ValidateAccessToken(token)
+	return s.jwt.ValidateAccessToken(token)
 }
 
-func (s *AuthService) ChangePassword(userID uint, currentPassword, newPassword string) error {
-	user, err := s.userRepo.GetUserByID(userID)
+// ChangePassword replaces the user's password. Accounts created through
+// social login have none yet and set their first without currentPassword.
+func (s *AuthService) ChangePassword(ctx context.Context, userID uint, currentPassword, newPassword string) error {
+	ctx, span := pkg.StartSpan(ctx, "AuthService.ChangePassword")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return errors.New("user not found")
 	}
 
-	if !pkg.ComparePassword(currentPassword, []byte(user.Password)) {
+	if user.Password != "" && !pkg.ComparePassword(currentPassword, []byte(user.Password)) {
 		return errors.New("current password is incorrect")
 	}
 
 	hashedNewPassword := pkg.HashPassword(newPassword)
-	_, err = s.userRepo.UpdatePassword(userID, string(hashedNewPassword))
+	_, err = s.userRepo.UpdatePassword(ctx, userID, string(hashedNewPassword))
 	if err != nil {
 		return errors.New("failed to update password")
 	}
@@ -76,25 +159,34 @@ func (s *AuthService) ChangePassword(userID uint, currentPassword, newPassword s
 	return nil
 }
 
-func (s *AuthService) CheckEmailExists(email string) (bool, error) {
-	_, err := s.userRepo.FindByEmail(email)
+func (s *AuthService) CheckEmailExists(ctx context.Context, email string) (bool, error) {
+	ctx, span := pkg.StartSpan(ctx, "AuthService.CheckEmailExists")
+	defer span.End()
+
+	_, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil {
 		return false, nil
 	}
 	return true, nil
 }
 
-func (s *AuthService) GetUserByID(userID uint) (*domain.User, error) {
-	return s.userRepo.GetUserByID(userID)
+func (s *AuthService) GetUserByID(ctx context.Context, userID uint) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "AuthService.GetUserByID")
+	defer span.End()
+
+	return s.userRepo.GetUserByID(ctx, userID)
 }
 
-func (s *AuthService) ValidateTokenAndGetUser(token string) (*domain.User, error) {
+func (s *AuthService) ValidateTokenAndGetUser(ctx context.Context, token string) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "AuthService.ValidateTokenAndGetUser")
+	defer span.End()
+
 	claims, err := s.ValidateAccessToken(token)
 	if err != nil {
 		return nil, err
 	}
 
-	user, err := s.userRepo.GetUserByID(claims.UserID)
+	user, err := s.userRepo.GetUserByID(ctx, claims.UserID)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
@@ -0,0 +1,384 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	memory_adapters "go-chat/internal/adapters/memory"
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	"go-chat/pkg"
+
+	"gorm.io/gorm"
+)
+
+// fakeSessionRepo is a minimal SessionRepository for the auth tests.
+type fakeSessionRepo struct {
+	mu       sync.Mutex
+	sessions map[string]*domain.Session
+}
+
+var _ repository.SessionRepository = (*fakeSessionRepo)(nil)
+
+func newFakeSessionRepo() *fakeSessionRepo {
+	return &fakeSessionRepo{sessions: make(map[string]*domain.Session)}
+}
+
+func (r *fakeSessionRepo) CreateSession(ctx context.Context, session *domain.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *session
+	r.sessions[session.ID] = &copied
+	return nil
+}
+
+func (r *fakeSessionRepo) GetSessionByID(ctx context.Context, id string) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (r *fakeSessionRepo) GetUserSessions(ctx context.Context, userID uint, includeInactive bool) ([]*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sessions []*domain.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && (includeInactive || session.IsActive(time.Now())) {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeSessionRepo) TouchSession(ctx context.Context, id string, seenAt, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if session, ok := r.sessions[id]; ok {
+		session.LastSeenAt = seenAt
+		session.ExpiresAt = expiresAt
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) RevokeSession(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if session, ok := r.sessions[id]; ok && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+func (r *fakeSessionRepo) RevokeUserSessions(ctx context.Context, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+type authFixture struct {
+	service  *AuthService
+	users    repository.UserRepository
+	sessions *fakeSessionRepo
+}
+
+func newAuthFixture(t *testing.T) *authFixture {
+	t.Helper()
+	store := memory_adapters.NewStore()
+	users := memory_adapters.NewUserMemoryRepo(store)
+	sessions := newFakeSessionRepo()
+	jwt := pkg.NewJWTManager("access-secret", "refresh-secret", time.Minute, time.Hour)
+	return &authFixture{
+		service:  NewAuthService(users, sessions, jwt),
+		users:    users,
+		sessions: sessions,
+	}
+}
+
+func (f *authFixture) createUser(t *testing.T, email, password string) *domain.User {
+	t.Helper()
+	user := &domain.User{Name: "Test User", Email: email, Password: string(pkg.HashPassword(password))}
+	if err := f.users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func TestAuthenticateUser(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, f *authFixture, user *domain.User)
+		email    string
+		password string
+		wantErr  string
+	}{
+		{
+			name:     "valid credentials",
+			email:    "alice@example.com",
+			password: "correct-horse",
+		},
+		{
+			name:     "wrong password",
+			email:    "alice@example.com",
+			password: "wrong",
+			wantErr:  "invalid credentials",
+		},
+		{
+			name:     "unknown email",
+			email:    "nobody@example.com",
+			password: "correct-horse",
+			wantErr:  "invalid credentials",
+		},
+		{
+			name: "suspended account",
+			setup: func(t *testing.T, f *authFixture, user *domain.User) {
+				now := time.Now()
+				if err := f.users.SetUserSuspension(ctx, user.ID, &now, "spam"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			email:    "alice@example.com",
+			password: "correct-horse",
+			wantErr:  "account suspended",
+		},
+		{
+			name: "pending deletion is cancelled",
+			setup: func(t *testing.T, f *authFixture, user *domain.User) {
+				now := time.Now()
+				if err := f.users.SetDeletionRequested(ctx, user.ID, &now); err != nil {
+					t.Fatal(err)
+				}
+			},
+			email:    "alice@example.com",
+			password: "correct-horse",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newAuthFixture(t)
+			user := f.createUser(t, "alice@example.com", "correct-horse")
+			if tc.setup != nil {
+				tc.setup(t, f, user)
+			}
+
+			got, err := f.service.AuthenticateUser(ctx, tc.email, tc.password)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AuthenticateUser: %v", err)
+			}
+			if got.ID != user.ID {
+				t.Errorf("user ID = %d, want %d", got.ID, user.ID)
+			}
+
+			stored, err := f.users.GetUserByID(ctx, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.DeletionRequestedAt != nil || got.DeletionRequestedAt != nil {
+				t.Error("pending deletion was not cancelled")
+			}
+		})
+	}
+}
+
+func TestRefreshTokens(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, f *authFixture, user *domain.User, tokens *pkg.TokenPair)
+		token   func(tokens *pkg.TokenPair) string
+		wantErr string
+	}{
+		{
+			name: "valid session",
+		},
+		{
+			name:    "malformed token",
+			token:   func(*pkg.TokenPair) string { return "not-a-token" },
+			wantErr: "invalid or expired refresh token",
+		},
+		{
+			name:    "access token is not a refresh token",
+			token:   func(tokens *pkg.TokenPair) string { return tokens.AccessToken },
+			wantErr: "invalid or expired refresh token",
+		},
+		{
+			name: "revoked session",
+			setup: func(t *testing.T, f *authFixture, user *domain.User, tokens *pkg.TokenPair) {
+				if err := f.sessions.RevokeUserSessions(ctx, user.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "session expired or revoked",
+		},
+		{
+			name: "deleted user",
+			setup: func(t *testing.T, f *authFixture, user *domain.User, tokens *pkg.TokenPair) {
+				if err := f.users.DeleteUser(ctx, user.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "user not found",
+		},
+		{
+			name: "suspended user",
+			setup: func(t *testing.T, f *authFixture, user *domain.User, tokens *pkg.TokenPair) {
+				now := time.Now()
+				if err := f.users.SetUserSuspension(ctx, user.ID, &now, "spam"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "account suspended",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newAuthFixture(t)
+			user := f.createUser(t, "alice@example.com", "correct-horse")
+			tokens, err := f.service.GenerateTokens(ctx, user, "127.0.0.1", "test")
+			if err != nil {
+				t.Fatalf("GenerateTokens: %v", err)
+			}
+			if tc.setup != nil {
+				tc.setup(t, f, user, tokens)
+			}
+
+			refreshToken := tokens.RefreshToken
+			if tc.token != nil {
+				refreshToken = tc.token(tokens)
+			}
+
+			refreshed, got, err := f.service.RefreshTokens(ctx, refreshToken)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RefreshTokens: %v", err)
+			}
+			if got.ID != user.ID || refreshed.AccessToken == "" {
+				t.Errorf("RefreshTokens returned user %d, access token %q", got.ID, refreshed.AccessToken)
+			}
+		})
+	}
+}
+
+func TestRefreshTokensRevokesSessionOfSuspendedUser(t *testing.T) {
+	ctx := context.Background()
+	f := newAuthFixture(t)
+	user := f.createUser(t, "alice@example.com", "correct-horse")
+	tokens, err := f.service.GenerateTokens(ctx, user, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("GenerateTokens: %v", err)
+	}
+
+	now := time.Now()
+	if err := f.users.SetUserSuspension(ctx, user.ID, &now, "spam"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := f.service.RefreshTokens(ctx, tokens.RefreshToken); err == nil {
+		t.Fatal("RefreshTokens succeeded for a suspended user")
+	}
+
+	sessions, err := f.sessions.GetUserSessions(ctx, user.ID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 0 {
+		t.Errorf("%d sessions still active after refresh by suspended user", len(sessions))
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		userID   func(user *domain.User) uint
+		current  string
+		wantErr  string
+		wantPass string
+
+		// noPassword creates the user as social login does.
+		noPassword bool
+	}{
+		{
+			name:     "correct current password",
+			current:  "correct-horse",
+			wantPass: "battery-staple",
+		},
+		{
+			name:     "wrong current password",
+			current:  "wrong",
+			wantErr:  "current password is incorrect",
+			wantPass: "correct-horse",
+		},
+		{
+			name:       "first password after social login",
+			noPassword: true,
+			wantPass:   "battery-staple",
+		},
+		{
+			name:     "unknown user",
+			userID:   func(user *domain.User) uint { return user.ID + 1 },
+			current:  "correct-horse",
+			wantErr:  "user not found",
+			wantPass: "correct-horse",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newAuthFixture(t)
+			user := f.createUser(t, "alice@example.com", "correct-horse")
+			if tc.noPassword {
+				if _, err := f.users.UpdatePassword(ctx, user.ID, ""); err != nil {
+					t.Fatal(err)
+				}
+			}
+			userID := user.ID
+			if tc.userID != nil {
+				userID = tc.userID(user)
+			}
+
+			err := f.service.ChangePassword(ctx, userID, tc.current, "battery-staple")
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("ChangePassword: %v", err)
+			}
+
+			if _, err := f.service.AuthenticateUser(ctx, "alice@example.com", tc.wantPass); err != nil {
+				t.Errorf("cannot sign in with %q afterwards: %v", tc.wantPass, err)
+			}
+		})
+	}
+}
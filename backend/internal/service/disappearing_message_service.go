@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	wsports "go-chat/internal/ports/websocket"
+	"go-chat/pkg"
+)
+
+// expiredBatchSize caps how many expired messages are purged per unit of
+// work.
+const expiredBatchSize = 500
+
+// DisappearingMessageService manages the disappearing-message timers of
+// conversations and purges the messages that have expired under them.
+type DisappearingMessageService struct {
+	hub wsports.WSHandler
+	uow repository.UnitOfWork
+	now func() time.Time
+}
+
+func NewDisappearingMessageService(hub wsports.WSHandler, uow repository.UnitOfWork) *DisappearingMessageService {
+	return &DisappearingMessageService{hub: hub, uow: uow, now: time.Now}
+}
+
+// SetTimer sets the timer of the conversation between userID and
+// settings.PeerID for both of them and posts a system message recording the
+// change, which it returns. It returns nil when the timer is unchanged.
+func (s *DisappearingMessageService) SetTimer(ctx context.Context, userID uint, settings *domain.DisappearingSettings) (*domain.MessageResponse, error) {
+	ctx, span := pkg.StartSpan(ctx, "DisappearingMessageService.SetTimer")
+	defer span.End()
+
+	if err := settings.Validate(); err != nil {
+		return nil, err
+	}
+	after := settings.DisappearAfter()
+
+	var notice *domain.MessageResponse
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		notice = nil
+
+		current, err := repos.Conversations.GetDisappearingTimer(ctx, userID, settings.PeerID)
+		if err != nil {
+			return err
+		}
+		if current == after {
+			return nil
+		}
+
+		user, err := repos.Users.GetUserByID(ctx, userID)
+		if err != nil {
+			return errors.New("user not found")
+		}
+		notice, err = sendMessage(ctx, repos, userID, &domain.MessageRequest{
+			ReceiverID:  settings.PeerID,
+			Content:     domain.TimerChangedContent(user.Name, after),
+			MessageType: domain.MessageTypeSystem,
+		})
+		if err != nil {
+			return err
+		}
+		return repos.Conversations.SetDisappearingTimer(ctx, userID, settings.PeerID, after, userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notice, nil
+}
+
+// PurgeExpiredMessages deletes every message whose timer has run out for
+// good, refreshes the affected conversations and tells connected
+// participants which messages to drop. It returns how many were purged.
+func (s *DisappearingMessageService) PurgeExpiredMessages(ctx context.Context) (int, error) {
+	ctx, span := pkg.StartSpan(ctx, "DisappearingMessageService.PurgeExpiredMessages")
+	defer span.End()
+
+	now := s.now().UTC()
+	purged := 0
+	for {
+		var expired []*domain.Message
+		err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+			var err error
+			expired, err = repos.Messages.DeleteExpiredMessages(ctx, now, expiredBatchSize)
+			if err != nil {
+				return err
+			}
+
+			refreshed := make(map[[2]uint]bool)
+			for _, message := range expired {
+				key := [2]uint{min(message.SenderID, message.ReceiverID), max(message.SenderID, message.ReceiverID)}
+				if refreshed[key] {
+					continue
+				}
+				refreshed[key] = true
+				if err := repos.Conversations.RefreshConversation(ctx, message.SenderID, message.ReceiverID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return purged, err
+		}
+
+		s.notifyExpired(expired)
+		purged += len(expired)
+		if len(expired) < expiredBatchSize || ctx.Err() != nil {
+			return purged, nil
+		}
+	}
+}
+
+// notifyExpired tells both participants of each message that it is gone.
+func (s *DisappearingMessageService) notifyExpired(expired []*domain.Message) {
+	byUser := make(map[uint][]uint)
+	for _, message := range expired {
+		byUser[message.SenderID] = append(byUser[message.SenderID], message.ID)
+		byUser[message.ReceiverID] = append(byUser[message.ReceiverID], message.ID)
+	}
+	for userID, messageIDs := range byUser {
+		s.hub.BroadcastMessage(&domain.WSMessage{
+			Type:    domain.WSMessageTypeMessagesExpired,
+			Payload: &domain.WSExpiredPayload{MessageIDs: messageIDs},
+		}, userID)
+	}
+}
+
+// RunPurgeWorker purges expired messages on every tick until ctx is done.
+func (s *DisappearingMessageService) RunPurgeWorker(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.PurgeExpiredMessages(ctx); err != nil {
+			pkg.ErrorContext(ctx, "Disappearing message purge failed", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"go-chat/internal/domain"
+)
+
+type disappearingFixture struct {
+	baseFixture
+	service  *DisappearingMessageService
+	messages *MessageService
+}
+
+func newDisappearingFixture(t *testing.T) *disappearingFixture {
+	t.Helper()
+	f := &disappearingFixture{baseFixture: newBaseFixture(t)}
+	f.service = NewDisappearingMessageService(f.hub, f.uow)
+	f.messages = NewMessageService(f.repos.Messages, f.repos.Conversations, f.repos.Users, f.uow, time.Hour)
+	return f
+}
+
+func (f *disappearingFixture) send(t *testing.T, senderID, receiverID uint, content string) *domain.MessageResponse {
+	t.Helper()
+	message, err := f.messages.SendMessage(context.Background(), senderID, &domain.MessageRequest{ReceiverID: receiverID, Content: content})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestSetDisappearingTimer(t *testing.T) {
+	ctx := context.Background()
+	f := newDisappearingFixture(t)
+
+	if _, err := f.service.SetTimer(ctx, f.alice.ID, &domain.DisappearingSettings{PeerID: f.bob.ID, DisappearAfterSeconds: 30}); err == nil {
+		t.Error("SetTimer accepted a 30 second timer")
+	}
+	if _, err := f.service.SetTimer(ctx, f.alice.ID, &domain.DisappearingSettings{PeerID: f.bob.ID + 100, DisappearAfterSeconds: 3600}); err == nil {
+		t.Error("SetTimer accepted an unknown peer")
+	}
+
+	before := f.send(t, f.alice.ID, f.bob.ID, "before")
+	if before.ExpiresAt != nil {
+		t.Errorf("message without a timer expires at %v", before.ExpiresAt)
+	}
+
+	// Either participant may set it.
+	notice, err := f.service.SetTimer(ctx, f.bob.ID, &domain.DisappearingSettings{PeerID: f.alice.ID, DisappearAfterSeconds: 3600})
+	if err != nil {
+		t.Fatalf("SetTimer: %v", err)
+	}
+	if notice == nil || notice.MessageType != domain.MessageTypeSystem || notice.Content != "Bob set disappearing messages to 1 hour" {
+		t.Fatalf("notice = %+v", notice)
+	}
+	if notice.ExpiresAt != nil {
+		t.Errorf("timer notice expires at %v", notice.ExpiresAt)
+	}
+
+	for _, senderID := range []uint{f.alice.ID, f.bob.ID} {
+		message := f.send(t, senderID, f.alice.ID+f.bob.ID-senderID, "secret")
+		if message.ExpiresAt == nil || !message.ExpiresAt.Equal(message.CreatedAt.Add(time.Hour)) {
+			t.Errorf("message from %d created %v expires at %v, want an hour later", senderID, message.CreatedAt, message.ExpiresAt)
+		}
+	}
+
+	notice, err = f.service.SetTimer(ctx, f.alice.ID, &domain.DisappearingSettings{PeerID: f.bob.ID, DisappearAfterSeconds: 3600})
+	if err != nil || notice != nil {
+		t.Errorf("SetTimer(unchanged) = %+v, %v, want no notice", notice, err)
+	}
+
+	notice, err = f.service.SetTimer(ctx, f.alice.ID, &domain.DisappearingSettings{PeerID: f.bob.ID})
+	if err != nil {
+		t.Fatalf("SetTimer: %v", err)
+	}
+	if notice == nil || notice.Content != "Alice turned off disappearing messages" {
+		t.Fatalf("notice = %+v", notice)
+	}
+	if after := f.send(t, f.alice.ID, f.bob.ID, "after"); after.ExpiresAt != nil {
+		t.Errorf("message after turning the timer off expires at %v", after.ExpiresAt)
+	}
+}
+
+func TestTimerNoticeIsKept(t *testing.T) {
+	ctx := context.Background()
+	f := newDisappearingFixture(t)
+
+	notice, err := f.service.SetTimer(ctx, f.alice.ID, &domain.DisappearingSettings{PeerID: f.bob.ID, DisappearAfterSeconds: 3600})
+	if err != nil {
+		t.Fatalf("SetTimer: %v", err)
+	}
+
+	if _, _, err := f.messages.DeleteMessageForEveryone(ctx, notice.ID, f.alice.ID); err == nil || err.Error() != "system messages cannot be deleted" {
+		t.Errorf("DeleteMessageForEveryone error = %v", err)
+	}
+	for _, userID := range []uint{f.alice.ID, f.bob.ID} {
+		if _, err := f.messages.PinMessage(ctx, notice.ID, userID); err == nil || err.Error() != "system messages cannot be pinned" {
+			t.Errorf("PinMessage by %d error = %v", userID, err)
+		}
+	}
+
+	stored, err := f.repos.Messages.GetMessageByID(ctx, notice.ID)
+	if err != nil {
+		t.Fatalf("GetMessageByID: %v", err)
+	}
+	if stored.MessageType != domain.MessageTypeSystem || stored.Content != notice.Content {
+		t.Errorf("notice = %q %q, want it unchanged", stored.MessageType, stored.Content)
+	}
+}
+
+func TestPurgeExpiredMessages(t *testing.T) {
+	ctx := context.Background()
+	f := newDisappearingFixture(t)
+
+	kept := f.send(t, f.alice.ID, f.bob.ID, "before")
+	notice, err := f.service.SetTimer(ctx, f.alice.ID, &domain.DisappearingSettings{PeerID: f.bob.ID, DisappearAfterSeconds: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := f.send(t, f.alice.ID, f.bob.ID, "one")
+	second := f.send(t, f.bob.ID, f.alice.ID, "two")
+
+	purged, err := f.service.PurgeExpiredMessages(ctx)
+	if err != nil || purged != 0 {
+		t.Fatalf("PurgeExpiredMessages before expiry = %d, %v, want 0", purged, err)
+	}
+
+	f.service.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	purged, err = f.service.PurgeExpiredMessages(ctx)
+	if err != nil || purged != 2 {
+		t.Fatalf("PurgeExpiredMessages = %d, %v, want 2", purged, err)
+	}
+
+	history, err := f.repos.Messages.GetMessagesBetweenUsers(ctx, f.alice.ID, f.bob.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for _, message := range history {
+		ids = append(ids, message.ID)
+	}
+	if want := []uint{kept.ID, notice.ID}; !slices.Equal(ids, want) {
+		t.Errorf("history = %v, want %v", ids, want)
+	}
+
+	conversations, err := f.messages.GetUserConversations(ctx, f.bob.ID, "", false, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conversations) != 1 || conversations[0].LastMessage == nil || conversations[0].LastMessage.ID != notice.ID {
+		t.Errorf("Bob's conversations = %+v, want the notice as the last message", conversations)
+	}
+	if conversations[0].DisappearAfterSeconds != 60 {
+		t.Errorf("DisappearAfterSeconds = %d, want 60", conversations[0].DisappearAfterSeconds)
+	}
+
+	for _, userID := range []uint{f.alice.ID, f.bob.ID} {
+		sent := f.hub.sent[userID]
+		if len(sent) != 1 || sent[0].Type != domain.WSMessageTypeMessagesExpired {
+			t.Fatalf("broadcasts to %d = %+v, want one messages_expired", userID, sent)
+		}
+		got := sent[0].Payload.(*domain.WSExpiredPayload).MessageIDs
+		slices.Sort(got)
+		if want := []uint{first.ID, second.ID}; !slices.Equal(got, want) {
+			t.Errorf("expired IDs for %d = %v, want %v", userID, got, want)
+		}
+	}
+}
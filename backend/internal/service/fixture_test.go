@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	memory_adapters "go-chat/internal/adapters/memory"
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	wsports "go-chat/internal/ports/websocket"
+)
+
+// baseFixture is what the service fixtures build on: memory repositories
+// over one store, a unit of work over the same store, a recording hub and
+// two users.
+type baseFixture struct {
+	repos repository.Repositories
+	uow   repository.UnitOfWork
+	hub   *recordingHub
+
+	alice, bob *domain.User
+}
+
+func newBaseFixture(t *testing.T) baseFixture {
+	t.Helper()
+	store := memory_adapters.NewStore()
+	f := baseFixture{
+		repos: memory_adapters.NewRepositories(store),
+		uow:   memory_adapters.NewMemoryUnitOfWork(store),
+		hub:   &recordingHub{},
+	}
+	f.alice = createTestUser(t, f.repos.Users, "Alice", "alice@example.com")
+	f.bob = createTestUser(t, f.repos.Users, "Bob", "bob@example.com")
+	return f
+}
+
+func createTestUser(t *testing.T, users repository.UserRepository, name, email string) *domain.User {
+	t.Helper()
+	user := &domain.User{Name: name, Email: email, Password: "hash"}
+	if err := users.Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+// recordingHub records broadcasts and disconnects instead of writing to
+// connections.
+type recordingHub struct {
+	wsports.WSHandler
+
+	mu           sync.Mutex
+	sent         map[uint][]*domain.WSMessage
+	disconnected []uint
+}
+
+func (h *recordingHub) CloseUserConnection(userID uint, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.disconnected = append(h.disconnected, userID)
+}
+
+func (h *recordingHub) BroadcastMessage(message *domain.WSMessage, targetUserID uint) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.sent == nil {
+		h.sent = make(map[uint][]*domain.WSMessage)
+	}
+	h.sent[targetUserID] = append(h.sent[targetUserID], message)
+	return nil
+}
@@ -1,122 +1,170 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"go-chat/internal/domain"
 	"go-chat/internal/ports/repository"
+	"go-chat/pkg"
 )
 
 type FriendsService struct {
 	repo repository.FriendsRepository
+	uow  repository.UnitOfWork
 }
 
-func NewFriendsService(repo repository.FriendsRepository) *FriendsService {
-	return &FriendsService{repo: repo}
+func NewFriendsService(repo repository.FriendsRepository, uow repository.UnitOfWork) *FriendsService {
+	return &FriendsService{repo: repo, uow: uow}
 }
 
-func (s *FriendsService) SendFriendRequest(requesterID, addresseeID uint) error {
+func (s *FriendsService) SendFriendRequest(ctx context.Context, requesterID, addresseeID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "FriendsService.SendFriendRequest")
+	defer span.End()
+
 	if requesterID == addresseeID {
 		return errors.New("cannot send friend request to yourself")
 	}
 
-	existing, err := s.repo.FindFriendshipBetweenUsers(requesterID, addresseeID)
-	if err == nil && existing != nil {
-		switch existing.Status {
-		case domain.FriendshipAccepted:
-			return errors.New("users are already friends")
-		case domain.FriendshipPending:
-			return errors.New("friend request already pending")
-		case domain.FriendshipBlocked:
-			return errors.New("cannot send friend request to blocked user")
+	// The check and insert share a transaction so two simultaneous requests
+	// between the same pair cannot both pass the check.
+	return s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		existing, err := repos.Friends.FindFriendshipBetweenUsers(ctx, requesterID, addresseeID)
+		if err == nil && existing != nil {
+			switch existing.Status {
+			case domain.FriendshipAccepted:
+				return errors.New("users are already friends")
+			case domain.FriendshipPending:
+				return errors.New("friend request already pending")
+			case domain.FriendshipBlocked:
+				return errors.New("cannot send friend request to blocked user")
+			}
 		}
-	}
 
-	friendship := &domain.Friendship{
-		RequesterID: requesterID,
-		AddresseeID: addresseeID,
-		Status:      domain.FriendshipPending,
-	}
+		friendship := &domain.Friendship{
+			RequesterID: requesterID,
+			AddresseeID: addresseeID,
+			Status:      domain.FriendshipPending,
+		}
 
-	return s.repo.CreateFriendship(friendship)
+		return repos.Friends.CreateFriendship(ctx, friendship)
+	})
 }
 
-func (s *FriendsService) AcceptFriendRequest(friendshipID, userID uint) error {
-	friendship, err := s.repo.FindFriendshipByID(friendshipID)
-	if err != nil {
-		return err
-	}
+func (s *FriendsService) AcceptFriendRequest(ctx context.Context, friendshipID, userID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "FriendsService.AcceptFriendRequest")
+	defer span.End()
 
-	if friendship.AddresseeID != userID {
-		return errors.New("unauthorized to accept this friend request")
-	}
+	return s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		friendship, err := repos.Friends.FindFriendshipByID(ctx, friendshipID)
+		if err != nil {
+			return err
+		}
 
-	if friendship.Status != domain.FriendshipPending {
-		return errors.New("friend request is not pending")
-	}
+		if friendship.AddresseeID != userID {
+			return errors.New("unauthorized to accept this friend request")
+		}
+
+		if friendship.Status != domain.FriendshipPending {
+			return errors.New("friend request is not pending")
+		}
 
-	return s.repo.UpdateFriendshipStatus(friendshipID, domain.FriendshipAccepted)
+		return repos.Friends.UpdateFriendshipStatus(ctx, friendshipID, domain.FriendshipAccepted)
+	})
 }
 
-func (s *FriendsService) RejectFriendRequest(friendshipID, userID uint) error {
-	friendship, err := s.repo.FindFriendshipByID(friendshipID)
-	if err != nil {
-		return err
-	}
+func (s *FriendsService) RejectFriendRequest(ctx context.Context, friendshipID, userID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "FriendsService.RejectFriendRequest")
+	defer span.End()
 
-	if friendship.AddresseeID != userID {
-		return errors.New("unauthorized to reject this friend request")
-	}
+	return s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		friendship, err := repos.Friends.FindFriendshipByID(ctx, friendshipID)
+		if err != nil {
+			return err
+		}
 
-	if friendship.Status != domain.FriendshipPending {
-		return errors.New("friend request is not pending")
-	}
+		if friendship.AddresseeID != userID {
+			return errors.New("unauthorized to reject this friend request")
+		}
+
+		if friendship.Status != domain.FriendshipPending {
+			return errors.New("friend request is not pending")
+		}
 
-	return s.repo.UpdateFriendshipStatus(friendshipID, domain.FriendshipRejected)
+		return repos.Friends.UpdateFriendshipStatus(ctx, friendshipID, domain.FriendshipRejected)
+	})
 }
 
-func (s *FriendsService) RemoveFriend(friendshipID, userID uint) error {
-	friendship, err := s.repo.FindFriendshipByID(friendshipID)
-	if err != nil {
-		return err
-	}
+func (s *FriendsService) RemoveFriend(ctx context.Context, friendshipID, userID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "FriendsService.RemoveFriend")
+	defer span.End()
 
-	if friendship.RequesterID != userID && friendship.AddresseeID != userID {
-		return errors.New("unauthorized to remove this friendship")
-	}
+	return s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		friendship, err := repos.Friends.FindFriendshipByID(ctx, friendshipID)
+		if err != nil {
+			return err
+		}
+
+		if friendship.RequesterID != userID && friendship.AddresseeID != userID {
+			return errors.New("unauthorized to remove this friendship")
+		}
 
-	return s.repo.DeleteFriendship(friendshipID)
+		return repos.Friends.DeleteFriendship(ctx, friendshipID)
+	})
 }
 
-func (s *FriendsService) BlockUser(blockerID, blockedID uint) error {
+func (s *FriendsService) BlockUser(ctx context.Context, blockerID, blockedID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "FriendsService.BlockUser")
+	defer span.End()
+
 	if blockerID == blockedID {
 		return errors.New("cannot block yourself")
 	}
 
-	existing, err := s.repo.FindFriendshipBetweenUsers(blockerID, blockedID)
-	if err != nil || existing == nil {
-		friendship := &domain.Friendship{
-			RequesterID: blockerID,
-			AddresseeID: blockedID,
-			Status:      domain.FriendshipBlocked,
+	return s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		existing, err := repos.Friends.FindFriendshipBetweenUsers(ctx, blockerID, blockedID)
+		if err != nil || existing == nil {
+			friendship := &domain.Friendship{
+				RequesterID: blockerID,
+				AddresseeID: blockedID,
+				Status:      domain.FriendshipBlocked,
+				BlockedByID: &blockerID,
+			}
+			return repos.Friends.CreateFriendship(ctx, friendship)
 		}
-		return s.repo.CreateFriendship(friendship)
-	}
 
-	return s.repo.UpdateFriendshipStatus(existing.ID, domain.FriendshipBlocked)
+		// A pair has a single row, so a block by the other user stands
+		// rather than being handed over.
+		if existing.Status == domain.FriendshipBlocked {
+			return nil
+		}
+		return repos.Friends.BlockFriendship(ctx, existing.ID, blockerID)
+	})
 }
 
-func (s *FriendsService) GetUserFriends(userID uint) ([]*domain.Friendship, error) {
-	return s.repo.GetUserFriends(userID)
+func (s *FriendsService) GetUserFriends(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
+	ctx, span := pkg.StartSpan(ctx, "FriendsService.GetUserFriends")
+	defer span.End()
+
+	return s.repo.GetUserFriends(ctx, userID)
 }
 
-func (s *FriendsService) GetPendingFriendRequests(userID uint) ([]*domain.Friendship, error) {
-	return s.repo.GetPendingFriendRequests(userID)
+func (s *FriendsService) GetPendingFriendRequests(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
+	ctx, span := pkg.StartSpan(ctx, "FriendsService.GetPendingFriendRequests")
+	defer span.End()
+
+	return s.repo.GetPendingFriendRequests(ctx, userID)
 }
 
-func (s *FriendsService) GetSentFriendRequests(userID uint) ([]*domain.Friendship, error) {
-	return s.repo.GetSentFriendRequests(userID)
+func (s *FriendsService) GetSentFriendRequests(ctx context.Context, userID uint) ([]*domain.Friendship, error) {
+	ctx, span := pkg.StartSpan(ctx, "FriendsService.GetSentFriendRequests")
+	defer span.End()
+
+	return s.repo.GetSentFriendRequests(ctx, userID)
 }
 
-func (s *FriendsService) AreFriends(userID1, userID2 uint) (bool, error) {
-	return s.repo.AreFriends(userID1, userID2)
+func (s *FriendsService) AreFriends(ctx context.Context, userID1, userID2 uint) (bool, error) {
+	ctx, span := pkg.StartSpan(ctx, "FriendsService.AreFriends")
+	defer span.End()
+
+	return s.repo.AreFriends(ctx, userID1, userID2)
 }
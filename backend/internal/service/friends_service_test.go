@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go-chat/internal/domain"
+)
+
+type friendsFixture struct {
+	baseFixture
+	service *FriendsService
+
+	carol *domain.User
+}
+
+func newFriendsFixture(t *testing.T) *friendsFixture {
+	t.Helper()
+	f := &friendsFixture{baseFixture: newBaseFixture(t)}
+	f.service = NewFriendsService(f.repos.Friends, f.uow)
+	f.carol = createTestUser(t, f.repos.Users, "Carol", "carol@example.com")
+	return f
+}
+
+func (f *friendsFixture) link(t *testing.T, requester, addressee *domain.User, status domain.FriendshipStatus) *domain.Friendship {
+	t.Helper()
+	friendship := &domain.Friendship{RequesterID: requester.ID, AddresseeID: addressee.ID, Status: status}
+	if err := f.repos.Friends.CreateFriendship(context.Background(), friendship); err != nil {
+		t.Fatalf("create friendship: %v", err)
+	}
+	return friendship
+}
+
+func TestSendFriendRequest(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, f *friendsFixture)
+		from    func(f *friendsFixture) *domain.User
+		wantErr string
+	}{
+		{
+			name: "new request",
+		},
+		{
+			name:    "to yourself",
+			from:    func(f *friendsFixture) *domain.User { return f.bob },
+			wantErr: "cannot send friend request to yourself",
+		},
+		{
+			name: "already pending",
+			setup: func(t *testing.T, f *friendsFixture) {
+				f.link(t, f.bob, f.alice, domain.FriendshipPending)
+			},
+			wantErr: "friend request already pending",
+		},
+		{
+			name: "already friends",
+			setup: func(t *testing.T, f *friendsFixture) {
+				f.link(t, f.alice, f.bob, domain.FriendshipAccepted)
+			},
+			wantErr: "users are already friends",
+		},
+		{
+			name: "blocked",
+			setup: func(t *testing.T, f *friendsFixture) {
+				f.link(t, f.bob, f.alice, domain.FriendshipBlocked)
+			},
+			wantErr: "cannot send friend request to blocked user",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFriendsFixture(t)
+			if tc.setup != nil {
+				tc.setup(t, f)
+			}
+			from := f.alice
+			if tc.from != nil {
+				from = tc.from(f)
+			}
+
+			err := f.service.SendFriendRequest(ctx, from.ID, f.bob.ID)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SendFriendRequest: %v", err)
+			}
+
+			pending, err := f.service.GetPendingFriendRequests(ctx, f.bob.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pending) != 1 || pending[0].RequesterID != f.alice.ID {
+				t.Errorf("pending requests for Bob = %+v, want one from Alice", pending)
+			}
+		})
+	}
+}
+
+func TestSendFriendRequestConcurrent(t *testing.T) {
+	ctx := context.Background()
+	f := newFriendsFixture(t)
+
+	// Requests in both directions race; exactly one may win.
+	const attempts = 8
+	errs := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for i := range attempts {
+		from, to := f.alice, f.bob
+		if i%2 == 1 {
+			from, to = to, from
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- f.service.SendFriendRequest(ctx, from.ID, to.ID)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d concurrent requests succeeded, want 1", succeeded)
+	}
+
+	all, err := f.repos.Friends.GetAllUserFriendships(ctx, f.alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Errorf("%d friendships between Alice and Bob, want 1", len(all))
+	}
+}
+
+func TestRespondToFriendRequest(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		status     domain.FriendshipStatus
+		responder  func(f *friendsFixture) *domain.User
+		accept     bool
+		wantErr    string
+		wantStatus domain.FriendshipStatus
+	}{
+		{
+			name:       "addressee accepts",
+			status:     domain.FriendshipPending,
+			accept:     true,
+			wantStatus: domain.FriendshipAccepted,
+		},
+		{
+			name:       "addressee rejects",
+			status:     domain.FriendshipPending,
+			wantStatus: domain.FriendshipRejected,
+		},
+		{
+			name:       "requester cannot accept",
+			status:     domain.FriendshipPending,
+			responder:  func(f *friendsFixture) *domain.User { return f.alice },
+			accept:     true,
+			wantErr:    "unauthorized to accept this friend request",
+			wantStatus: domain.FriendshipPending,
+		},
+		{
+			name:       "stranger cannot reject",
+			status:     domain.FriendshipPending,
+			responder:  func(f *friendsFixture) *domain.User { return f.carol },
+			wantErr:    "unauthorized to reject this friend request",
+			wantStatus: domain.FriendshipPending,
+		},
+		{
+			name:       "already accepted",
+			status:     domain.FriendshipAccepted,
+			accept:     true,
+			wantErr:    "friend request is not pending",
+			wantStatus: domain.FriendshipAccepted,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFriendsFixture(t)
+			friendship := f.link(t, f.alice, f.bob, tc.status)
+			responder := f.bob
+			if tc.responder != nil {
+				responder = tc.responder(f)
+			}
+
+			var err error
+			if tc.accept {
+				err = f.service.AcceptFriendRequest(ctx, friendship.ID, responder.ID)
+			} else {
+				err = f.service.RejectFriendRequest(ctx, friendship.ID, responder.ID)
+			}
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("respond: %v", err)
+			}
+
+			got, err := f.repos.Friends.FindFriendshipByID(ctx, friendship.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tc.wantStatus {
+				t.Errorf("status = %q, want %q", got.Status, tc.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRemoveFriend(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		remover func(f *friendsFixture) *domain.User
+		wantErr string
+	}{
+		{name: "requester", remover: func(f *friendsFixture) *domain.User { return f.alice }},
+		{name: "addressee", remover: func(f *friendsFixture) *domain.User { return f.bob }},
+		{
+			name:    "stranger",
+			remover: func(f *friendsFixture) *domain.User { return f.carol },
+			wantErr: "unauthorized to remove this friendship",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFriendsFixture(t)
+			friendship := f.link(t, f.alice, f.bob, domain.FriendshipAccepted)
+
+			err := f.service.RemoveFriend(ctx, friendship.ID, tc.remover(f).ID)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("RemoveFriend: %v", err)
+			}
+
+			friends, err := f.service.AreFriends(ctx, f.alice.ID, f.bob.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if friends != (tc.wantErr != "") {
+				t.Errorf("AreFriends = %v after removal attempt", friends)
+			}
+		})
+	}
+}
+
+func TestBlockUser(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, f *friendsFixture)
+		blocked func(f *friendsFixture) *domain.User
+		// blockedBy defaults to Alice.
+		blockedBy func(f *friendsFixture) *domain.User
+		wantErr   string
+	}{
+		{
+			name: "no existing relationship",
+		},
+		{
+			name: "existing friendship",
+			setup: func(t *testing.T, f *friendsFixture) {
+				f.link(t, f.bob, f.alice, domain.FriendshipAccepted)
+			},
+		},
+		{
+			name: "already blocked by them",
+			setup: func(t *testing.T, f *friendsFixture) {
+				if err := f.service.BlockUser(context.Background(), f.bob.ID, f.alice.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			blockedBy: func(f *friendsFixture) *domain.User { return f.bob },
+		},
+		{
+			name:    "yourself",
+			blocked: func(f *friendsFixture) *domain.User { return f.alice },
+			wantErr: "cannot block yourself",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newFriendsFixture(t)
+			if tc.setup != nil {
+				tc.setup(t, f)
+			}
+			blocked := f.bob
+			if tc.blocked != nil {
+				blocked = tc.blocked(f)
+			}
+
+			err := f.service.BlockUser(ctx, f.alice.ID, blocked.ID)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BlockUser: %v", err)
+			}
+
+			all, err := f.repos.Friends.GetAllUserFriendships(ctx, f.alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != 1 || all[0].Status != domain.FriendshipBlocked {
+				t.Fatalf("friendships after block = %+v, want a single blocked one", all)
+			}
+			blockedBy := f.alice
+			if tc.blockedBy != nil {
+				blockedBy = tc.blockedBy(f)
+			}
+			if !all[0].IsBlockedBy(blockedBy.ID) {
+				t.Errorf("BlockedByID = %v, want %d", all[0].BlockedByID, blockedBy.ID)
+			}
+			if err := f.service.SendFriendRequest(ctx, f.bob.ID, f.alice.ID); err == nil {
+				t.Error("blocked user could send a friend request")
+			}
+		})
+	}
+}
@@ -1,36 +1,71 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"go-chat/internal/domain"
 	"go-chat/internal/ports/repository"
+	"go-chat/pkg"
 )
 
+// maxPinnedMessages caps how many messages a conversation can have pinned.
+const maxPinnedMessages = 5
+
+// errSenderBlocked is returned by sendMessage when the receiver has blocked
+// the sender.
+var errSenderBlocked = errors.New("sender is blocked by the receiver")
+
 type MessageService struct {
-	repo     repository.MessageRepository
-	userRepo repository.UserRepository
+	repo             repository.MessageRepository
+	conversationRepo repository.ConversationRepository
+	userRepo         repository.UserRepository
+	uow              repository.UnitOfWork
+
+	// deleteForEveryoneWindow is how long after sending a sender may still
+	// delete a message for both participants.
+	deleteForEveryoneWindow time.Duration
 }
 
-func NewMessageService(repo repository.MessageRepository, userRepo repository.UserRepository) *MessageService {
+func NewMessageService(repo repository.MessageRepository, conversationRepo repository.ConversationRepository, userRepo repository.UserRepository, uow repository.UnitOfWork, deleteForEveryoneWindow time.Duration) *MessageService {
 	return &MessageService{
-		repo:     repo,
-		userRepo: userRepo,
+		repo:                    repo,
+		conversationRepo:        conversationRepo,
+		userRepo:                userRepo,
+		uow:                     uow,
+		deleteForEveryoneWindow: deleteForEveryoneWindow,
 	}
 }
 
-func (s *MessageService) SendMessage(senderID uint, req *domain.MessageRequest) (*domain.MessageResponse, error) {
-	_, err := s.userRepo.GetUserByID(req.ReceiverID)
-	if err != nil {
-		return nil, errors.New("receiver not found")
+func (s *MessageService) SendMessage(ctx context.Context, senderID uint, req *domain.MessageRequest) (*domain.MessageResponse, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.SendMessage")
+	defer span.End()
+
+	if err := req.Validate(); err != nil {
+		return nil, err
 	}
 
-	sender, err := s.userRepo.GetUserByID(senderID)
+	var response *domain.MessageResponse
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		response, err = sendMessage(ctx, repos, senderID, req)
+		return err
+	})
 	if err != nil {
-		return nil, errors.New("sender not found")
+		return nil, err
 	}
 
+	return response, nil
+}
+
+// sendMessage stores a message from senderID and records it in both
+// participants' conversation summaries, unless the receiver has blocked the
+// sender. Callers run it in a unit of work so
+// that neither participant can be deleted in between, and so the summaries
+// always agree with the messages.
+func sendMessage(ctx context.Context, repos repository.Repositories, senderID uint, req *domain.MessageRequest) (*domain.MessageResponse, error) {
 	message := &domain.Message{
 		SenderID:    senderID,
 		ReceiverID:  req.ReceiverID,
@@ -40,14 +75,44 @@ func (s *MessageService) SendMessage(senderID uint, req *domain.MessageRequest)
 		IsDelivered: false,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
+		Receipts:    []domain.MessageReceipt{{UserID: req.ReceiverID}},
 	}
 
 	if message.MessageType == "" {
 		message.MessageType = "text"
 	}
 
-	err = s.repo.CreateMessage(message)
+	if _, err := repos.Users.GetUserByID(ctx, req.ReceiverID); err != nil {
+		return nil, errors.New("receiver not found")
+	}
+
+	sender, err := repos.Users.GetUserByID(ctx, senderID)
 	if err != nil {
+		return nil, errors.New("sender not found")
+	}
+
+	friendship, err := repos.Friends.FindFriendshipBetweenUsers(ctx, senderID, req.ReceiverID)
+	if err == nil && friendship != nil && friendship.IsBlockedBy(req.ReceiverID) {
+		return nil, errSenderBlocked
+	}
+
+	// Messages disappear under the timer in force when they are sent; the
+	// notices of timer changes themselves stay.
+	if message.MessageType != domain.MessageTypeSystem {
+		after, err := repos.Conversations.GetDisappearingTimer(ctx, senderID, req.ReceiverID)
+		if err != nil {
+			return nil, err
+		}
+		if after > 0 {
+			expiresAt := message.CreatedAt.UTC().Add(after)
+			message.ExpiresAt = &expiresAt
+		}
+	}
+
+	if err := repos.Messages.CreateMessage(ctx, message); err != nil {
+		return nil, err
+	}
+	if err := repos.Conversations.RecordMessage(ctx, message); err != nil {
 		return nil, err
 	}
 
@@ -61,6 +126,7 @@ func (s *MessageService) SendMessage(senderID uint, req *domain.MessageRequest)
 		IsDelivered:    message.IsDelivered,
 		CreatedAt:      message.CreatedAt,
 		UpdatedAt:      message.UpdatedAt,
+		ExpiresAt:      message.ExpiresAt,
 		SenderName:     sender.Name,
 		SenderUsername: sender.Email,
 	}
@@ -68,18 +134,21 @@ func (s *MessageService) SendMessage(senderID uint, req *domain.MessageRequest)
 	return response, nil
 }
 
-func (s *MessageService) GetMessagesBetweenUsers(userID1, userID2 uint, limit, offset int) ([]*domain.MessageResponse, error) {
-	_, err := s.userRepo.GetUserByID(userID1)
+func (s *MessageService) GetMessagesBetweenUsers(ctx context.Context, userID1, userID2 uint, limit, offset int) ([]*domain.MessageResponse, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.GetMessagesBetweenUsers")
+	defer span.End()
+
+	_, err := s.userRepo.GetUserByID(ctx, userID1)
 	if err != nil {
 		return nil, errors.New("user1 not found")
 	}
 
-	_, err = s.userRepo.GetUserByID(userID2)
+	_, err = s.userRepo.GetUserByID(ctx, userID2)
 	if err != nil {
 		return nil, errors.New("user2 not found")
 	}
 
-	messages, err := s.repo.GetMessagesBetweenUsers(userID1, userID2, limit, offset)
+	messages, err := s.repo.GetMessagesBetweenUsers(ctx, userID1, userID2, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -92,10 +161,13 @@ func (s *MessageService) GetMessagesBetweenUsers(userID1, userID2 uint, limit, o
 			ReceiverID:     msg.ReceiverID,
 			Content:        msg.Content,
 			MessageType:    msg.MessageType,
-			IsRead:         msg.IsRead,
+			IsRead:         visibleReadState(userID1, msg),
 			IsDelivered:    msg.IsDelivered,
+			DeliveredAt:    msg.DeliveredAt,
+			ReadAt:         msg.ReadAt,
 			CreatedAt:      msg.CreatedAt,
 			UpdatedAt:      msg.UpdatedAt,
+			ExpiresAt:      msg.ExpiresAt,
 			SenderName:     msg.Sender.Name,
 			SenderUsername: msg.Sender.Email,
 		}
@@ -105,42 +177,484 @@ func (s *MessageService) GetMessagesBetweenUsers(userID1, userID2 uint, limit, o
 	return responses, nil
 }
 
-func (s *MessageService) GetUserConversations(userID uint) ([]*domain.ConversationResponse, error) {
-	_, err := s.userRepo.GetUserByID(userID)
+// GetUserConversations lists the user's archived or unarchived
+// conversations, pinned first and then most recent first. A non-empty query
+// keeps those whose peer's name or email contains it.
+func (s *MessageService) GetUserConversations(ctx context.Context, userID uint, query string, archived bool, limit, offset int) ([]*domain.ConversationResponse, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.GetUserConversations")
+	defer span.End()
+
+	_, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return nil, errors.New("user not found")
 	}
 
-	return s.repo.GetUserConversations(userID)
+	summaries, err := s.conversationRepo.ListConversations(ctx, userID, query, archived, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	conversations := make([]*domain.ConversationResponse, 0, len(summaries))
+	for _, summary := range summaries {
+		conversations = append(conversations, summary.ToResponse())
+	}
+
+	return conversations, nil
 }
 
-func (s *MessageService) MarkMessagesAsRead(senderID, receiverID uint) error {
-	return s.repo.MarkMessagesAsRead(senderID, receiverID)
+// UpdateConversationSettings archives, pins, mutes or marks unread the
+// user's conversation with peer, as set in update, and returns the resulting
+// settings.
+func (s *MessageService) UpdateConversationSettings(ctx context.Context, userID, peerID uint, update *domain.ConversationSettings) (*domain.ConversationSettings, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.UpdateConversationSettings")
+	defer span.End()
+
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+
+	var settings *domain.ConversationSettings
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		summary, err := repos.Conversations.GetConversation(ctx, userID, peerID)
+		if err != nil {
+			return errors.New("conversation not found")
+		}
+
+		// Archiving or pinning again keeps the original time, and with it the
+		// conversation's place among the pinned ones.
+		now := time.Now()
+		if update.Archived != nil && *update.Archived != (summary.ArchivedAt != nil) {
+			var archivedAt *time.Time
+			if *update.Archived {
+				archivedAt = &now
+			}
+			if err := repos.Conversations.SetArchived(ctx, userID, peerID, archivedAt); err != nil {
+				return err
+			}
+		}
+		if update.Pinned != nil && *update.Pinned != (summary.PinnedAt != nil) {
+			var pinnedAt *time.Time
+			if *update.Pinned {
+				pinnedAt = &now
+			}
+			if err := repos.Conversations.SetPinned(ctx, userID, peerID, pinnedAt); err != nil {
+				return err
+			}
+		}
+		if update.Muted != nil {
+			var mutedAt, mutedUntil *time.Time
+			if *update.Muted {
+				mutedAt, mutedUntil = &now, update.MutedUntil
+			}
+			if err := repos.Conversations.SetMuted(ctx, userID, peerID, mutedAt, mutedUntil); err != nil {
+				return err
+			}
+		}
+		if update.MarkedUnread != nil {
+			if err := repos.Conversations.SetMarkedUnread(ctx, userID, peerID, *update.MarkedUnread); err != nil {
+				return err
+			}
+		}
+
+		summary, err = repos.Conversations.GetConversation(ctx, userID, peerID)
+		if err != nil {
+			return err
+		}
+		settings = summary.Settings()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
 }
 
-func (s *MessageService) MarkMessageAsDelivered(messageID uint) error {
-	return s.repo.MarkMessageAsDelivered(messageID)
+// ClearConversation deletes every message so far in the user's conversation
+// with peer for the user only, which also removes it from their list until
+// the next message.
+func (s *MessageService) ClearConversation(ctx context.Context, userID, peerID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.ClearConversation")
+	defer span.End()
+
+	return s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if _, err := repos.Conversations.GetConversation(ctx, userID, peerID); err != nil {
+			return errors.New("conversation not found")
+		}
+
+		if err := repos.Messages.HideConversation(ctx, userID, peerID); err != nil {
+			return err
+		}
+		return repos.Conversations.RefreshConversation(ctx, userID, peerID)
+	})
 }
 
-func (s *MessageService) GetUnreadMessageCount(senderID, receiverID uint) (int, error) {
-	return s.repo.GetUnreadMessageCount(senderID, receiverID)
+// MarkMessagesAsRead moves the receiver's read cursor past the last message
+// of their conversation with sender.
+func (s *MessageService) MarkMessagesAsRead(ctx context.Context, senderID, receiverID uint) (*domain.ReadCursor, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.MarkMessagesAsRead")
+	defer span.End()
+
+	var cursor *domain.ReadCursor
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		reader, err := repos.Users.GetUserByID(ctx, receiverID)
+		if err != nil {
+			return errors.New("user not found")
+		}
+
+		readAt := time.Now()
+		if err := repos.Conversations.MarkConversationRead(ctx, receiverID, senderID, readAt); err != nil {
+			return err
+		}
+		cursor = readCursor(ctx, repos, reader, senderID)
+		return sendReadReceipts(ctx, repos, cursor, readAt)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return cursor, nil
 }
 
-func (s *MessageService) DeleteMessage(messageID, userID uint) error {
-	message, err := s.repo.GetMessageByID(messageID)
+// MarkReadUpTo marks the conversation containing messageID as read up to and
+// including it. The cursor only moves forward; see MarkUnreadFrom.
+func (s *MessageService) MarkReadUpTo(ctx context.Context, readerID, messageID uint) (*domain.ReadCursor, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.MarkReadUpTo")
+	defer span.End()
+
+	var cursor *domain.ReadCursor
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		reader, peerID, err := conversationPeer(ctx, repos, readerID, messageID)
+		if err != nil {
+			return err
+		}
+
+		cursor = readCursor(ctx, repos, reader, peerID)
+		if cursor.LastReadMessageID != nil && *cursor.LastReadMessageID >= messageID {
+			return nil
+		}
+
+		readAt := time.Now()
+		if err := repos.Conversations.SetReadCursor(ctx, readerID, peerID, &messageID, readAt); err != nil {
+			return err
+		}
+		cursor = readCursor(ctx, repos, reader, peerID)
+		return sendReadReceipts(ctx, repos, cursor, readAt)
+	})
 	if err != nil {
-		return errors.New("message not found")
+		return nil, err
 	}
 
-	if message.SenderID != userID {
-		return errors.New("unauthorized: can only delete your own messages")
+	return cursor, nil
+}
+
+// MarkUnreadFrom moves the reader's cursor back to just before messageID, so
+// that message and everything the peer sent after it count as unread again.
+// Read receipts already sent are not withdrawn.
+func (s *MessageService) MarkUnreadFrom(ctx context.Context, readerID, messageID uint) (*domain.ReadCursor, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.MarkUnreadFrom")
+	defer span.End()
+
+	var cursor *domain.ReadCursor
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		reader, peerID, err := conversationPeer(ctx, repos, readerID, messageID)
+		if err != nil {
+			return err
+		}
+
+		cursor = readCursor(ctx, repos, reader, peerID)
+		if cursor.LastReadMessageID == nil || *cursor.LastReadMessageID < messageID {
+			return nil
+		}
+
+		var previousID *uint
+		if previous, err := repos.Messages.GetMessageBefore(ctx, readerID, peerID, messageID); err == nil {
+			previousID = &previous.ID
+		}
+
+		if err := repos.Conversations.SetReadCursor(ctx, readerID, peerID, previousID, time.Now()); err != nil {
+			return err
+		}
+		cursor = readCursor(ctx, repos, reader, peerID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return s.repo.DeleteMessage(messageID)
+	return cursor, nil
 }
 
-func (s *MessageService) SearchMessages(userID uint, query string, limit, offset int) ([]*domain.MessageResponse, error) {
-	messages, err := s.repo.SearchMessages(userID, query, limit, offset)
+// AcknowledgeDelivery records that the receiver's client has the message and
+// returns it with its delivery time.
+func (s *MessageService) AcknowledgeDelivery(ctx context.Context, receiverID, messageID uint) (*domain.Message, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.AcknowledgeDelivery")
+	defer span.End()
+
+	var message *domain.Message
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		var err error
+		message, err = repos.Messages.GetMessageByID(ctx, messageID)
+		if err != nil || message.ReceiverID != receiverID {
+			return errors.New("message not found")
+		}
+		if message.IsDelivered {
+			return nil
+		}
+
+		if err := repos.Messages.MarkMessageDelivered(ctx, messageID, receiverID, time.Now()); err != nil {
+			return err
+		}
+		message, err = repos.Messages.GetMessageByID(ctx, messageID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// GetMessageReceipts returns the per-recipient receipts of a message to
+// either of its participants.
+func (s *MessageService) GetMessageReceipts(ctx context.Context, userID, messageID uint) ([]*domain.MessageReceipt, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.GetMessageReceipts")
+	defer span.End()
+
+	message, err := s.repo.GetMessageByID(ctx, messageID)
+	if err != nil || (message.SenderID != userID && message.ReceiverID != userID) {
+		return nil, errors.New("message not found")
+	}
+
+	return s.repo.GetMessageReceipts(ctx, messageID)
+}
+
+func (s *MessageService) GetUnreadMessageCount(ctx context.Context, senderID, receiverID uint) (int, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.GetUnreadMessageCount")
+	defer span.End()
+
+	summary, err := s.conversationRepo.GetConversation(ctx, receiverID, senderID)
+	if err != nil {
+		return 0, nil
+	}
+
+	return summary.UnreadCount, nil
+}
+
+// DeleteMessageForMe hides the message from userID's history, search and
+// conversation list. The other participant still sees it.
+func (s *MessageService) DeleteMessageForMe(ctx context.Context, messageID, userID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.DeleteMessageForMe")
+	defer span.End()
+
+	return s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		message, err := repos.Messages.GetMessageByID(ctx, messageID)
+		if err != nil || (message.SenderID != userID && message.ReceiverID != userID) {
+			return errors.New("message not found")
+		}
+
+		if err := repos.Messages.HideMessage(ctx, messageID, userID); err != nil {
+			return err
+		}
+		return repos.Conversations.RefreshConversation(ctx, message.SenderID, message.ReceiverID)
+	})
+}
+
+// DeleteMessageForEveryone replaces the content of a message the user sent
+// with a tombstone both participants see. It is only allowed within the
+// configured window after sending. A pinned message is unpinned, and the
+// event for the other participant is returned with it.
+func (s *MessageService) DeleteMessageForEveryone(ctx context.Context, messageID, userID uint) (*domain.Message, *domain.WSPinnedPayload, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.DeleteMessageForEveryone")
+	defer span.End()
+
+	var message *domain.Message
+	var unpinned *domain.WSPinnedPayload
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		unpinned = nil
+
+		var err error
+		message, err = repos.Messages.GetMessageByID(ctx, messageID)
+		if err != nil {
+			return errors.New("message not found")
+		}
+
+		if message.SenderID != userID {
+			return errors.New("unauthorized: can only delete your own messages")
+		}
+
+		// System notices are the record of changes to the conversation.
+		if message.MessageType == domain.MessageTypeSystem {
+			return errors.New("system messages cannot be deleted")
+		}
+
+		if time.Since(message.CreatedAt) > s.deleteForEveryoneWindow {
+			return errors.New("message can no longer be deleted for everyone")
+		}
+
+		if err := repos.Messages.TombstoneMessage(ctx, messageID); err != nil {
+			return err
+		}
+
+		// A tombstone is not worth keeping pinned.
+		wasPinned, err := repos.Messages.UnpinMessage(ctx, messageID)
+		if err != nil || !wasPinned {
+			return err
+		}
+		unpinned = &domain.WSPinnedPayload{
+			MessageID:  message.ID,
+			SenderID:   message.SenderID,
+			ReceiverID: message.ReceiverID,
+			PinnedByID: userID,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return message, unpinned, nil
+}
+
+// PinMessage pins a message of the user's conversation for both
+// participants. It returns the event for the other participant, or nil when
+// the message was already pinned.
+func (s *MessageService) PinMessage(ctx context.Context, messageID, userID uint) (*domain.WSPinnedPayload, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.PinMessage")
+	defer span.End()
+
+	var payload *domain.WSPinnedPayload
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		payload = nil
+
+		message, err := participantMessage(ctx, repos, messageID, userID)
+		if err != nil {
+			return err
+		}
+		switch message.MessageType {
+		case domain.MessageTypeTombstone:
+			return errors.New("deleted messages cannot be pinned")
+		case domain.MessageTypeSystem:
+			return errors.New("system messages cannot be pinned")
+		}
+
+		pin := &domain.PinnedMessage{MessageID: messageID, PinnedByID: userID, CreatedAt: time.Now().UTC()}
+		pinned, err := repos.Messages.PinMessage(ctx, pin)
+		if err != nil || !pinned {
+			return err
+		}
+
+		// Counted after pinning, so re-pinning a message is a no-op even at
+		// the limit; going over it rolls the pin back.
+		count, err := repos.Messages.CountPinnedMessages(ctx, message.SenderID, message.ReceiverID)
+		if err != nil {
+			return err
+		}
+		if count > maxPinnedMessages {
+			return fmt.Errorf("a conversation can have at most %d pinned messages", maxPinnedMessages)
+		}
+		payload = &domain.WSPinnedPayload{
+			MessageID:  message.ID,
+			SenderID:   message.SenderID,
+			ReceiverID: message.ReceiverID,
+			PinnedByID: userID,
+			PinnedAt:   &pin.CreatedAt,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// UnpinMessage removes a pin from the user's conversation, whoever set it.
+// It returns the event for the other participant, or nil when the message
+// was not pinned.
+func (s *MessageService) UnpinMessage(ctx context.Context, messageID, userID uint) (*domain.WSPinnedPayload, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.UnpinMessage")
+	defer span.End()
+
+	var payload *domain.WSPinnedPayload
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		payload = nil
+
+		message, err := participantMessage(ctx, repos, messageID, userID)
+		if err != nil {
+			return err
+		}
+
+		unpinned, err := repos.Messages.UnpinMessage(ctx, messageID)
+		if err != nil || !unpinned {
+			return err
+		}
+		payload = &domain.WSPinnedPayload{
+			MessageID:  message.ID,
+			SenderID:   message.SenderID,
+			ReceiverID: message.ReceiverID,
+			PinnedByID: userID,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// GetPinnedMessages lists the pinned messages of the conversation between
+// the user and peer, most recently pinned first.
+func (s *MessageService) GetPinnedMessages(ctx context.Context, userID, peerID uint, limit, offset int) ([]*domain.MessageResponse, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.GetPinnedMessages")
+	defer span.End()
+
+	if _, err := s.userRepo.GetUserByID(ctx, peerID); err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	messages, err := s.repo.GetPinnedMessages(ctx, userID, peerID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return messageResponses(userID, messages), nil
+}
+
+// StarMessage stars a message of the user's conversations for the user only.
+func (s *MessageService) StarMessage(ctx context.Context, messageID, userID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.StarMessage")
+	defer span.End()
+
+	return s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		if _, err := participantMessage(ctx, repos, messageID, userID); err != nil {
+			return err
+		}
+		return repos.Messages.StarMessage(ctx, messageID, userID)
+	})
+}
+
+func (s *MessageService) UnstarMessage(ctx context.Context, messageID, userID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.UnstarMessage")
+	defer span.End()
+
+	return s.repo.UnstarMessage(ctx, messageID, userID)
+}
+
+// GetStarredMessages lists the messages the user starred, most recently
+// starred first.
+func (s *MessageService) GetStarredMessages(ctx context.Context, userID uint, limit, offset int) ([]*domain.MessageResponse, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.GetStarredMessages")
+	defer span.End()
+
+	messages, err := s.repo.GetStarredMessages(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return messageResponses(userID, messages), nil
+}
+
+func (s *MessageService) SearchMessages(ctx context.Context, userID uint, query string, limit, offset int) ([]*domain.MessageResponse, error) {
+	ctx, span := pkg.StartSpan(ctx, "MessageService.SearchMessages")
+	defer span.End()
+
+	messages, err := s.repo.SearchMessages(ctx, userID, query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -153,10 +667,13 @@ func (s *MessageService) SearchMessages(userID uint, query string, limit, offset
 			ReceiverID:     msg.ReceiverID,
 			Content:        msg.Content,
 			MessageType:    msg.MessageType,
-			IsRead:         msg.IsRead,
+			IsRead:         visibleReadState(userID, msg),
 			IsDelivered:    msg.IsDelivered,
+			DeliveredAt:    msg.DeliveredAt,
+			ReadAt:         msg.ReadAt,
 			CreatedAt:      msg.CreatedAt,
 			UpdatedAt:      msg.UpdatedAt,
+			ExpiresAt:      msg.ExpiresAt,
 			SenderName:     msg.Sender.Name,
 			SenderUsername: msg.Sender.Email,
 		}
@@ -165,3 +682,78 @@ func (s *MessageService) SearchMessages(userID uint, query string, limit, offset
 
 	return responses, nil
 }
+
+// participantMessage returns the message if the user is one of its
+// participants. Other messages are reported as missing.
+func participantMessage(ctx context.Context, repos repository.Repositories, messageID, userID uint) (*domain.Message, error) {
+	message, err := repos.Messages.GetMessageByID(ctx, messageID)
+	if err != nil || (message.SenderID != userID && message.ReceiverID != userID) {
+		return nil, errors.New("message not found")
+	}
+	return message, nil
+}
+
+// messageResponses converts messages listed for the viewer.
+func messageResponses(viewerID uint, messages []*domain.Message) []*domain.MessageResponse {
+	responses := []*domain.MessageResponse{}
+	for _, msg := range messages {
+		response := msg.ToResponse()
+		response.IsRead = visibleReadState(viewerID, msg)
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+// conversationPeer loads the reader and returns them with the other
+// participant of the message, which the reader must be part of.
+func conversationPeer(ctx context.Context, repos repository.Repositories, readerID, messageID uint) (*domain.User, uint, error) {
+	message, err := repos.Messages.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, 0, errors.New("message not found")
+	}
+
+	var peerID uint
+	switch readerID {
+	case message.ReceiverID:
+		peerID = message.SenderID
+	case message.SenderID:
+		peerID = message.ReceiverID
+	default:
+		return nil, 0, errors.New("message not found")
+	}
+
+	reader, err := repos.Users.GetUserByID(ctx, readerID)
+	if err != nil {
+		return nil, 0, errors.New("user not found")
+	}
+	return reader, peerID, nil
+}
+
+// readCursor returns the reader's cursor in the conversation with peer; a
+// conversation without a summary has nothing read.
+func readCursor(ctx context.Context, repos repository.Repositories, reader *domain.User, peerID uint) *domain.ReadCursor {
+	cursor := &domain.ReadCursor{ReaderID: reader.ID, PeerID: peerID}
+	if summary, err := repos.Conversations.GetConversation(ctx, reader.ID, peerID); err == nil {
+		cursor = summary.ReadCursor()
+	}
+	cursor.Private = reader.ReadReceiptsDisabled
+	return cursor
+}
+
+// sendReadReceipts stamps the receipts of the messages the cursor covers,
+// unless the reader keeps their reads private.
+func sendReadReceipts(ctx context.Context, repos repository.Repositories, cursor *domain.ReadCursor, readAt time.Time) error {
+	if cursor.Private || cursor.LastReadMessageID == nil {
+		return nil
+	}
+	return repos.Messages.MarkMessagesRead(ctx, cursor.PeerID, cursor.ReaderID, *cursor.LastReadMessageID, readAt)
+}
+
+// visibleReadState hides the receiver's reads from the sender when the
+// receiver does not send read receipts.
+func visibleReadState(viewerID uint, message *domain.Message) bool {
+	if message.SenderID == viewerID && message.Receiver.ReadReceiptsDisabled {
+		return false
+	}
+	return message.IsRead
+}
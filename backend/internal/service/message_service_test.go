@@ -0,0 +1,884 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-chat/internal/domain"
+)
+
+type messageFixture struct {
+	baseFixture
+	service *MessageService
+	friends *FriendsService
+}
+
+func newMessageFixture(t *testing.T) *messageFixture {
+	t.Helper()
+	f := &messageFixture{baseFixture: newBaseFixture(t)}
+	f.service = NewMessageService(f.repos.Messages, f.repos.Conversations, f.repos.Users, f.uow, time.Hour)
+	f.friends = NewFriendsService(f.repos.Friends, f.uow)
+	return f
+}
+
+func TestSendMessage(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		setup    func(t *testing.T, f *messageFixture)
+		sender   func(f *messageFixture) uint
+		receiver func(f *messageFixture) uint
+		msgType  string
+		wantType string
+		wantErr  string
+	}{
+		{
+			name:     "defaults to text",
+			wantType: domain.MessageTypeText,
+		},
+		{
+			name:     "explicit text",
+			msgType:  domain.MessageTypeText,
+			wantType: domain.MessageTypeText,
+		},
+		{
+			name:    "client system notice",
+			msgType: domain.MessageTypeSystem,
+			wantErr: `unsupported message_type "system"`,
+		},
+		{
+			name:    "client tombstone",
+			msgType: domain.MessageTypeTombstone,
+			wantErr: `unsupported message_type "tombstone"`,
+		},
+		{
+			name:    "unknown type",
+			msgType: "image",
+			wantErr: `unsupported message_type "image"`,
+		},
+		{
+			name:     "unknown receiver",
+			receiver: func(f *messageFixture) uint { return f.bob.ID + 100 },
+			wantErr:  "receiver not found",
+		},
+		{
+			name:    "unknown sender",
+			sender:  func(f *messageFixture) uint { return f.bob.ID + 100 },
+			wantErr: "sender not found",
+		},
+		{
+			name: "deleted receiver",
+			setup: func(t *testing.T, f *messageFixture) {
+				if err := f.repos.Users.DeleteUser(context.Background(), f.bob.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "receiver not found",
+		},
+		{
+			name: "blocked by receiver",
+			setup: func(t *testing.T, f *messageFixture) {
+				if err := f.friends.BlockUser(context.Background(), f.bob.ID, f.alice.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "sender is blocked by the receiver",
+		},
+		{
+			// The friendship row keeps Alice as requester after Bob blocks.
+			name: "friends first, then blocked by receiver",
+			setup: func(t *testing.T, f *messageFixture) {
+				ctx := context.Background()
+				if err := f.friends.SendFriendRequest(ctx, f.alice.ID, f.bob.ID); err != nil {
+					t.Fatal(err)
+				}
+				requests, err := f.friends.GetPendingFriendRequests(ctx, f.bob.ID)
+				if err != nil || len(requests) != 1 {
+					t.Fatalf("GetPendingFriendRequests = %v, %v", requests, err)
+				}
+				if err := f.friends.AcceptFriendRequest(ctx, requests[0].ID, f.bob.ID); err != nil {
+					t.Fatal(err)
+				}
+				if err := f.friends.BlockUser(ctx, f.bob.ID, f.alice.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantErr: "sender is blocked by the receiver",
+		},
+		{
+			name: "sender blocked receiver",
+			setup: func(t *testing.T, f *messageFixture) {
+				if err := f.friends.BlockUser(context.Background(), f.alice.ID, f.bob.ID); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantType: domain.MessageTypeText,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newMessageFixture(t)
+			if tc.setup != nil {
+				tc.setup(t, f)
+			}
+			senderID, receiverID := f.alice.ID, f.bob.ID
+			if tc.sender != nil {
+				senderID = tc.sender(f)
+			}
+			if tc.receiver != nil {
+				receiverID = tc.receiver(f)
+			}
+
+			resp, err := f.service.SendMessage(ctx, senderID, &domain.MessageRequest{
+				ReceiverID:  receiverID,
+				Content:     "hello",
+				MessageType: tc.msgType,
+			})
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				all, _ := f.repos.Messages.GetAllUserMessages(ctx, f.alice.ID)
+				if len(all) != 0 {
+					t.Errorf("failed send stored %d messages", len(all))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SendMessage: %v", err)
+			}
+			if resp.ID == 0 || resp.MessageType != tc.wantType || resp.SenderName != "Alice" || resp.SenderUsername != "alice@example.com" {
+				t.Errorf("response = %+v", resp)
+			}
+
+			stored, err := f.repos.Messages.GetMessageByID(ctx, resp.ID)
+			if err != nil {
+				t.Fatalf("GetMessageByID: %v", err)
+			}
+			if stored.Content != "hello" || stored.IsRead || stored.IsDelivered {
+				t.Errorf("stored message = %+v", stored)
+			}
+		})
+	}
+}
+
+func TestGetMessagesBetweenUsers(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		other   func(f *messageFixture) uint
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "both users exist",
+			other: func(f *messageFixture) uint { return f.bob.ID },
+			want:  []string{"one", "two"},
+		},
+		{
+			name:    "unknown user",
+			other:   func(f *messageFixture) uint { return f.bob.ID + 100 },
+			wantErr: "user2 not found",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newMessageFixture(t)
+			for _, content := range []string{"one", "two"} {
+				if _, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: content}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := f.service.GetMessagesBetweenUsers(ctx, f.alice.ID, tc.other(f), 10, 0)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetMessagesBetweenUsers: %v", err)
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %d messages, want %d", len(got), len(tc.want))
+			}
+			for i, content := range tc.want {
+				if got[i].Content != content || got[i].SenderName != "Alice" {
+					t.Errorf("message %d = %q from %q", i, got[i].Content, got[i].SenderName)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteMessageForMe(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		deleter   func(f *messageFixture) uint
+		messageID func(id uint) uint
+		wantErr   string
+	}{
+		{
+			name:    "sender deletes",
+			deleter: func(f *messageFixture) uint { return f.alice.ID },
+		},
+		{
+			name:    "receiver deletes",
+			deleter: func(f *messageFixture) uint { return f.bob.ID },
+		},
+		{
+			name:    "outsider cannot delete",
+			deleter: func(f *messageFixture) uint { return f.bob.ID + 100 },
+			wantErr: "message not found",
+		},
+		{
+			name:      "unknown message",
+			deleter:   func(f *messageFixture) uint { return f.alice.ID },
+			messageID: func(id uint) uint { return id + 100 },
+			wantErr:   "message not found",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newMessageFixture(t)
+			sent, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "oops"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			messageID := sent.ID
+			if tc.messageID != nil {
+				messageID = tc.messageID(sent.ID)
+			}
+
+			deleter := tc.deleter(f)
+			err = f.service.DeleteMessageForMe(ctx, messageID, deleter)
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("DeleteMessageForMe: %v", err)
+			}
+
+			// The deleter loses the message and, since it was the only one,
+			// the conversation; the other participant keeps both.
+			for _, pair := range [][2]*domain.User{{f.alice, f.bob}, {f.bob, f.alice}} {
+				user, peer := pair[0], pair[1]
+				hidden := tc.wantErr == "" && user.ID == deleter
+
+				history, err := f.service.GetMessagesBetweenUsers(ctx, user.ID, peer.ID, 10, 0)
+				if err != nil {
+					t.Fatalf("GetMessagesBetweenUsers: %v", err)
+				}
+				if listed := len(history) == 1; listed == hidden {
+					t.Errorf("user %d: message listed = %v", user.ID, listed)
+				}
+
+				conversations, err := f.service.GetUserConversations(ctx, user.ID, "", false, 10, 0)
+				if err != nil {
+					t.Fatalf("GetUserConversations: %v", err)
+				}
+				if listed := len(conversations) == 1; listed == hidden {
+					t.Errorf("user %d: conversation listed = %v", user.ID, listed)
+				}
+			}
+		})
+	}
+}
+
+func TestDeleteMessageForEveryone(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		deleter   func(f *messageFixture) uint
+		messageID func(id uint) uint
+		window    time.Duration
+		wantErr   string
+	}{
+		{
+			name:    "sender deletes",
+			deleter: func(f *messageFixture) uint { return f.alice.ID },
+			window:  time.Hour,
+		},
+		{
+			name:    "receiver cannot delete",
+			deleter: func(f *messageFixture) uint { return f.bob.ID },
+			window:  time.Hour,
+			wantErr: "unauthorized: can only delete your own messages",
+		},
+		{
+			name:    "window has passed",
+			deleter: func(f *messageFixture) uint { return f.alice.ID },
+			wantErr: "message can no longer be deleted for everyone",
+		},
+		{
+			name:      "unknown message",
+			deleter:   func(f *messageFixture) uint { return f.alice.ID },
+			messageID: func(id uint) uint { return id + 100 },
+			window:    time.Hour,
+			wantErr:   "message not found",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newMessageFixture(t)
+			f.service.deleteForEveryoneWindow = tc.window
+			sent, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "oops"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			messageID := sent.ID
+			if tc.messageID != nil {
+				messageID = tc.messageID(sent.ID)
+			}
+
+			deleted, _, err := f.service.DeleteMessageForEveryone(ctx, messageID, tc.deleter(f))
+			if tc.wantErr != "" {
+				if err == nil || err.Error() != tc.wantErr {
+					t.Fatalf("error = %v, want %q", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("DeleteMessageForEveryone: %v", err)
+			} else if deleted.ReceiverID != f.bob.ID {
+				t.Errorf("deleted receiver = %d, want %d", deleted.ReceiverID, f.bob.ID)
+			}
+
+			// The message stays in place for both participants, as a tombstone
+			// when the deletion went through.
+			message, err := f.repos.Messages.GetMessageByID(ctx, sent.ID)
+			if err != nil {
+				t.Fatalf("GetMessageByID: %v", err)
+			}
+			if tombstoned := message.MessageType == domain.MessageTypeTombstone; tombstoned != (tc.wantErr == "") {
+				t.Errorf("message tombstoned = %v", tombstoned)
+			}
+			if tc.wantErr == "" && message.Content != domain.TombstoneContent {
+				t.Errorf("content = %q, want tombstone", message.Content)
+			}
+
+			conversations, err := f.service.GetUserConversations(ctx, f.bob.ID, "", false, 10, 0)
+			if err != nil {
+				t.Fatalf("GetUserConversations: %v", err)
+			}
+			if len(conversations) != 1 {
+				t.Errorf("conversations = %d, want 1", len(conversations))
+			}
+		})
+	}
+}
+
+func TestMarkMessagesAsRead(t *testing.T) {
+	ctx := context.Background()
+	f := newMessageFixture(t)
+
+	for _, content := range []string{"one", "two"} {
+		if _, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := f.service.SendMessage(ctx, f.bob.ID, &domain.MessageRequest{ReceiverID: f.alice.ID, Content: "reply"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conversations, err := f.service.GetUserConversations(ctx, f.bob.ID, "", false, 10, 0)
+	if err != nil {
+		t.Fatalf("GetUserConversations: %v", err)
+	}
+	if len(conversations) != 1 || conversations[0].UnreadCount != 2 {
+		t.Fatalf("conversations = %+v, want one with 2 unread", conversations)
+	}
+	if last := conversations[0].LastMessage; last == nil || last.Content != "reply" || last.SenderName != "Bob" {
+		t.Errorf("LastMessage = %+v, want Bob's reply", last)
+	}
+
+	cursor, err := f.service.MarkMessagesAsRead(ctx, f.alice.ID, f.bob.ID)
+	if err != nil {
+		t.Fatalf("MarkMessagesAsRead: %v", err)
+	}
+	if cursor.ReaderID != f.bob.ID || cursor.PeerID != f.alice.ID || cursor.LastReadMessageID == nil || cursor.UnreadCount != 0 {
+		t.Errorf("cursor = %+v, want Bob's, past everything from Alice", cursor)
+	}
+
+	for _, tc := range []struct {
+		sender, receiver uint
+		want             int
+	}{
+		{f.alice.ID, f.bob.ID, 0},
+		{f.bob.ID, f.alice.ID, 1},
+	} {
+		got, err := f.service.GetUnreadMessageCount(ctx, tc.sender, tc.receiver)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("unread %d -> %d = %d, want %d", tc.sender, tc.receiver, got, tc.want)
+		}
+	}
+
+	conversations, err = f.service.GetUserConversations(ctx, f.bob.ID, "", false, 10, 0)
+	if err != nil {
+		t.Fatalf("GetUserConversations: %v", err)
+	}
+	if len(conversations) != 1 || conversations[0].UnreadCount != 0 {
+		t.Errorf("conversations after read = %+v, want none unread", conversations)
+	}
+}
+
+func TestMoveReadCursor(t *testing.T) {
+	ctx := context.Background()
+	f := newMessageFixture(t)
+
+	var sent []*domain.MessageResponse
+	for _, content := range []string{"one", "two", "three"} {
+		message, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: content})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sent = append(sent, message)
+	}
+
+	assertCursor := func(t *testing.T, cursor *domain.ReadCursor, wantID uint, wantUnread int) {
+		t.Helper()
+		var gotID uint
+		if cursor.LastReadMessageID != nil {
+			gotID = *cursor.LastReadMessageID
+		}
+		if gotID != wantID || cursor.UnreadCount != wantUnread {
+			t.Errorf("cursor at %d with %d unread, want %d with %d", gotID, cursor.UnreadCount, wantID, wantUnread)
+		}
+	}
+
+	cursor, err := f.service.MarkReadUpTo(ctx, f.bob.ID, sent[1].ID)
+	if err != nil {
+		t.Fatalf("MarkReadUpTo: %v", err)
+	}
+	assertCursor(t, cursor, sent[1].ID, 1)
+
+	// Reading an earlier message never moves the cursor back.
+	cursor, err = f.service.MarkReadUpTo(ctx, f.bob.ID, sent[0].ID)
+	if err != nil {
+		t.Fatalf("MarkReadUpTo: %v", err)
+	}
+	assertCursor(t, cursor, sent[1].ID, 1)
+
+	cursor, err = f.service.MarkUnreadFrom(ctx, f.bob.ID, sent[1].ID)
+	if err != nil {
+		t.Fatalf("MarkUnreadFrom: %v", err)
+	}
+	assertCursor(t, cursor, sent[0].ID, 2)
+
+	cursor, err = f.service.MarkUnreadFrom(ctx, f.bob.ID, sent[0].ID)
+	if err != nil {
+		t.Fatalf("MarkUnreadFrom: %v", err)
+	}
+	assertCursor(t, cursor, 0, 3)
+
+	// Senders can move their own cursor, but outsiders can't see the message.
+	if _, err := f.service.MarkReadUpTo(ctx, f.alice.ID, sent[2].ID); err != nil {
+		t.Errorf("MarkReadUpTo(sender): %v", err)
+	}
+	carol := createTestUser(t, f.repos.Users, "Carol", "carol@example.com")
+	if _, err := f.service.MarkReadUpTo(ctx, carol.ID, sent[2].ID); err == nil || err.Error() != "message not found" {
+		t.Errorf("MarkReadUpTo(outsider) error = %v, want message not found", err)
+	}
+}
+
+func TestAcknowledgeDelivery(t *testing.T) {
+	ctx := context.Background()
+	f := newMessageFixture(t)
+
+	sent, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.IsDelivered {
+		t.Fatal("message delivered before any ack")
+	}
+
+	if _, err := f.service.AcknowledgeDelivery(ctx, f.alice.ID, sent.ID); err == nil || err.Error() != "message not found" {
+		t.Errorf("AcknowledgeDelivery(sender) error = %v, want message not found", err)
+	}
+
+	delivered, err := f.service.AcknowledgeDelivery(ctx, f.bob.ID, sent.ID)
+	if err != nil {
+		t.Fatalf("AcknowledgeDelivery: %v", err)
+	}
+	if !delivered.IsDelivered || delivered.DeliveredAt == nil {
+		t.Fatalf("acknowledged message = %+v, want delivered", delivered)
+	}
+
+	// A repeated ack keeps the first delivery time.
+	again, err := f.service.AcknowledgeDelivery(ctx, f.bob.ID, sent.ID)
+	if err != nil {
+		t.Fatalf("AcknowledgeDelivery again: %v", err)
+	}
+	if !again.DeliveredAt.Equal(*delivered.DeliveredAt) {
+		t.Errorf("DeliveredAt moved from %v to %v", delivered.DeliveredAt, again.DeliveredAt)
+	}
+
+	receipts, err := f.service.GetMessageReceipts(ctx, f.alice.ID, sent.ID)
+	if err != nil {
+		t.Fatalf("GetMessageReceipts: %v", err)
+	}
+	if len(receipts) != 1 || receipts[0].UserID != f.bob.ID || receipts[0].DeliveredAt == nil || receipts[0].ReadAt != nil {
+		t.Errorf("receipts = %+v, want Bob's, delivered and unread", receipts)
+	}
+	carol := createTestUser(t, f.repos.Users, "Carol", "carol@example.com")
+	if _, err := f.service.GetMessageReceipts(ctx, carol.ID, sent.ID); err == nil {
+		t.Error("GetMessageReceipts(outsider) succeeded")
+	}
+}
+
+func TestReadReceipts(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		name     string
+		disabled bool
+	}{
+		{name: "sent"},
+		{name: "disabled", disabled: true},
+	} {
+		disabled := tc.disabled
+		t.Run(tc.name, func(t *testing.T) {
+			f := newMessageFixture(t)
+			if err := f.repos.Users.SetReadReceiptsDisabled(ctx, f.bob.ID, disabled); err != nil {
+				t.Fatal(err)
+			}
+
+			sent, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "hi"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			cursor, err := f.service.MarkMessagesAsRead(ctx, f.alice.ID, f.bob.ID)
+			if err != nil {
+				t.Fatalf("MarkMessagesAsRead: %v", err)
+			}
+			if cursor.Private != disabled {
+				t.Errorf("cursor.Private = %v, want %v", cursor.Private, disabled)
+			}
+
+			// Bob's own view of the conversation is the same either way.
+			if count, _ := f.service.GetUnreadMessageCount(ctx, f.alice.ID, f.bob.ID); count != 0 {
+				t.Errorf("Bob's unread count = %d, want 0", count)
+			}
+
+			messages, err := f.service.GetMessagesBetweenUsers(ctx, f.alice.ID, f.bob.ID, 10, 0)
+			if err != nil {
+				t.Fatalf("GetMessagesBetweenUsers: %v", err)
+			}
+			if len(messages) != 1 || messages[0].ID != sent.ID {
+				t.Fatalf("messages = %+v, want the one sent", messages)
+			}
+			// Without an ack, only a read receipt implies delivery.
+			if got := messages[0]; got.IsRead == disabled || (got.ReadAt != nil) == disabled || got.IsDelivered == disabled {
+				t.Errorf("Alice sees read=%v at %v delivered=%v, want all %v", got.IsRead, got.ReadAt, got.IsDelivered, !disabled)
+			}
+
+			conversations, err := f.service.GetUserConversations(ctx, f.alice.ID, "", false, 10, 0)
+			if err != nil {
+				t.Fatalf("GetUserConversations: %v", err)
+			}
+			if len(conversations) != 1 || (conversations[0].PeerLastReadMessageID != nil) == disabled || conversations[0].LastMessage.IsRead == disabled {
+				t.Errorf("Alice's conversation = %+v, want Bob's reads hidden=%v", conversations[0], disabled)
+			}
+		})
+	}
+}
+
+func TestUpdateConversationSettings(t *testing.T) {
+	ctx := context.Background()
+	f := newMessageFixture(t)
+	yes, no := true, false
+
+	if _, err := f.service.UpdateConversationSettings(ctx, f.bob.ID, f.alice.ID, &domain.ConversationSettings{Pinned: &yes}); err == nil || err.Error() != "conversation not found" {
+		t.Fatalf("error before any message = %v, want conversation not found", err)
+	}
+
+	if _, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+	settings, err := f.service.UpdateConversationSettings(ctx, f.bob.ID, f.alice.ID, &domain.ConversationSettings{
+		Archived:     &yes,
+		Pinned:       &yes,
+		MarkedUnread: &yes,
+	})
+	if err != nil {
+		t.Fatalf("UpdateConversationSettings: %v", err)
+	}
+	if settings.PeerID != f.alice.ID || !*settings.Archived || !*settings.Pinned || !*settings.MarkedUnread {
+		t.Errorf("settings = %+v, want archived, pinned and marked unread", settings)
+	}
+
+	// Fields left out are unchanged.
+	settings, err = f.service.UpdateConversationSettings(ctx, f.bob.ID, f.alice.ID, &domain.ConversationSettings{Archived: &no})
+	if err != nil {
+		t.Fatalf("UpdateConversationSettings: %v", err)
+	}
+	if *settings.Archived || !*settings.Pinned || !*settings.MarkedUnread {
+		t.Errorf("settings after unarchiving = %+v, want pinned and marked unread", settings)
+	}
+
+	conversations, err := f.service.GetUserConversations(ctx, f.bob.ID, "", false, 10, 0)
+	if err != nil {
+		t.Fatalf("GetUserConversations: %v", err)
+	}
+	if len(conversations) != 1 || conversations[0].PinnedAt == nil || !conversations[0].MarkedUnread {
+		t.Fatalf("conversations = %+v, want one pinned and marked unread", conversations)
+	}
+
+	// Only the user's own side of the conversation changes.
+	conversations, err = f.service.GetUserConversations(ctx, f.alice.ID, "", false, 10, 0)
+	if err != nil {
+		t.Fatalf("GetUserConversations: %v", err)
+	}
+	if len(conversations) != 1 || conversations[0].PinnedAt != nil || conversations[0].MarkedUnread {
+		t.Errorf("peer's conversations = %+v, want untouched", conversations)
+	}
+}
+
+func TestClearConversation(t *testing.T) {
+	ctx := context.Background()
+	f := newMessageFixture(t)
+
+	if err := f.service.ClearConversation(ctx, f.bob.ID, f.alice.ID); err == nil || err.Error() != "conversation not found" {
+		t.Fatalf("error before any message = %v, want conversation not found", err)
+	}
+
+	for _, content := range []string{"one", "two"} {
+		if _, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.service.ClearConversation(ctx, f.bob.ID, f.alice.ID); err != nil {
+		t.Fatalf("ClearConversation: %v", err)
+	}
+
+	for _, tc := range []struct {
+		user, peer *domain.User
+		want       int
+	}{
+		{f.bob, f.alice, 0},
+		{f.alice, f.bob, 2},
+	} {
+		history, err := f.service.GetMessagesBetweenUsers(ctx, tc.user.ID, tc.peer.ID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetMessagesBetweenUsers: %v", err)
+		}
+		if len(history) != tc.want {
+			t.Errorf("%s's history = %d messages, want %d", tc.user.Name, len(history), tc.want)
+		}
+	}
+
+	// The conversation comes back with the next message, without the old ones.
+	if _, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "three"}); err != nil {
+		t.Fatal(err)
+	}
+	conversations, err := f.service.GetUserConversations(ctx, f.bob.ID, "", false, 10, 0)
+	if err != nil {
+		t.Fatalf("GetUserConversations: %v", err)
+	}
+	if len(conversations) != 1 || conversations[0].UnreadCount != 1 {
+		t.Errorf("conversations = %+v, want one with 1 unread", conversations)
+	}
+}
+
+func TestMuteConversation(t *testing.T) {
+	ctx := context.Background()
+	f := newMessageFixture(t)
+	yes, no := true, false
+
+	if _, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	if _, err := f.service.UpdateConversationSettings(ctx, f.bob.ID, f.alice.ID, &domain.ConversationSettings{Muted: &yes, MutedUntil: &past}); err == nil {
+		t.Error("muting until a past time succeeded")
+	}
+
+	until := time.Now().Add(8 * time.Hour)
+	settings, err := f.service.UpdateConversationSettings(ctx, f.bob.ID, f.alice.ID, &domain.ConversationSettings{Muted: &yes, MutedUntil: &until})
+	if err != nil {
+		t.Fatalf("UpdateConversationSettings: %v", err)
+	}
+	if !*settings.Muted || settings.MutedUntil == nil || !settings.MutedUntil.Equal(until) {
+		t.Errorf("settings = %+v, want muted until %v", settings, until)
+	}
+
+	conversations, err := f.service.GetUserConversations(ctx, f.bob.ID, "", false, 10, 0)
+	if err != nil {
+		t.Fatalf("GetUserConversations: %v", err)
+	}
+	if len(conversations) != 1 || !conversations[0].Muted {
+		t.Errorf("conversations = %+v, want one muted", conversations)
+	}
+
+	settings, err = f.service.UpdateConversationSettings(ctx, f.bob.ID, f.alice.ID, &domain.ConversationSettings{Muted: &no})
+	if err != nil {
+		t.Fatalf("UpdateConversationSettings: %v", err)
+	}
+	if *settings.Muted || settings.MutedUntil != nil {
+		t.Errorf("settings after unmuting = %+v", settings)
+	}
+}
+
+func TestPinMessage(t *testing.T) {
+	ctx := context.Background()
+	f := newMessageFixture(t)
+	carol := createTestUser(t, f.repos.Users, "Carol", "carol@example.com")
+
+	var sent []*domain.MessageResponse
+	for range maxPinnedMessages + 1 {
+		message, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "remember this"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sent = append(sent, message)
+	}
+
+	if _, err := f.service.PinMessage(ctx, sent[0].ID, carol.ID); err == nil || err.Error() != "message not found" {
+		t.Errorf("PinMessage by an outsider error = %v", err)
+	}
+
+	payload, err := f.service.PinMessage(ctx, sent[0].ID, f.bob.ID)
+	if err != nil {
+		t.Fatalf("PinMessage: %v", err)
+	}
+	if payload == nil || payload.PinnedAt == nil || payload.PinnedByID != f.bob.ID || payload.SenderID != f.alice.ID {
+		t.Fatalf("payload = %+v, want pinned by Bob", payload)
+	}
+	if event := payload.ToWSMessage(); event.Type != domain.WSMessageTypeMessagePinned {
+		t.Errorf("event type = %q, want %q", event.Type, domain.WSMessageTypeMessagePinned)
+	}
+	if again, err := f.service.PinMessage(ctx, sent[0].ID, f.alice.ID); err != nil || again != nil {
+		t.Errorf("PinMessage(pinned) = %+v, %v, want no change", again, err)
+	}
+
+	for _, message := range sent[1:maxPinnedMessages] {
+		if _, err := f.service.PinMessage(ctx, message.ID, f.alice.ID); err != nil {
+			t.Fatalf("PinMessage: %v", err)
+		}
+	}
+	if _, err := f.service.PinMessage(ctx, sent[maxPinnedMessages].ID, f.alice.ID); err == nil || err.Error() != "a conversation can have at most 5 pinned messages" {
+		t.Errorf("PinMessage over the limit error = %v", err)
+	}
+	if again, err := f.service.PinMessage(ctx, sent[0].ID, f.alice.ID); err != nil || again != nil {
+		t.Errorf("PinMessage(pinned) at the limit = %+v, %v, want no change", again, err)
+	}
+
+	// Both participants see the pins.
+	for _, userID := range []uint{f.alice.ID, f.bob.ID} {
+		pins, err := f.service.GetPinnedMessages(ctx, userID, f.alice.ID+f.bob.ID-userID, 10, 0)
+		if err != nil {
+			t.Fatalf("GetPinnedMessages: %v", err)
+		}
+		if len(pins) != maxPinnedMessages {
+			t.Fatalf("pins for %d = %d, want %d", userID, len(pins), maxPinnedMessages)
+		}
+		last := pins[len(pins)-1]
+		if last.ID != sent[0].ID || last.PinnedByID == nil || *last.PinnedByID != f.bob.ID || last.SenderName != "Alice" {
+			t.Errorf("oldest pin for %d = %+v, want Bob's pin of the first message", userID, last)
+		}
+	}
+
+	payload, err = f.service.UnpinMessage(ctx, sent[0].ID, f.alice.ID)
+	if err != nil {
+		t.Fatalf("UnpinMessage: %v", err)
+	}
+	if payload == nil || payload.ToWSMessage().Type != domain.WSMessageTypeMessageUnpinned {
+		t.Errorf("unpin payload = %+v, want message_unpinned", payload)
+	}
+	if _, err := f.service.PinMessage(ctx, sent[maxPinnedMessages].ID, f.alice.ID); err != nil {
+		t.Errorf("PinMessage after unpinning: %v", err)
+	}
+}
+
+func TestDeleteMessageForEveryoneUnpins(t *testing.T) {
+	ctx := context.Background()
+	f := newMessageFixture(t)
+
+	sent, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "remember this"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.service.PinMessage(ctx, sent.ID, f.bob.ID); err != nil {
+		t.Fatalf("PinMessage: %v", err)
+	}
+
+	_, unpinned, err := f.service.DeleteMessageForEveryone(ctx, sent.ID, f.alice.ID)
+	if err != nil {
+		t.Fatalf("DeleteMessageForEveryone: %v", err)
+	}
+	if unpinned == nil || unpinned.MessageID != sent.ID || unpinned.ToWSMessage().Type != domain.WSMessageTypeMessageUnpinned {
+		t.Errorf("unpin payload = %+v, want message_unpinned", unpinned)
+	}
+	pins, err := f.service.GetPinnedMessages(ctx, f.bob.ID, f.alice.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetPinnedMessages: %v", err)
+	}
+	if len(pins) != 0 {
+		t.Errorf("pins after deletion = %d, want 0", len(pins))
+	}
+
+	if _, err := f.service.PinMessage(ctx, sent.ID, f.bob.ID); err == nil || err.Error() != "deleted messages cannot be pinned" {
+		t.Errorf("PinMessage(tombstone) error = %v", err)
+	}
+}
+
+func TestStarMessage(t *testing.T) {
+	ctx := context.Background()
+	f := newMessageFixture(t)
+	carol := createTestUser(t, f.repos.Users, "Carol", "carol@example.com")
+
+	sent, err := f.service.SendMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "the wifi password"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.service.StarMessage(ctx, sent.ID, carol.ID); err == nil || err.Error() != "message not found" {
+		t.Errorf("StarMessage by an outsider error = %v", err)
+	}
+	if err := f.service.StarMessage(ctx, sent.ID, f.bob.ID); err != nil {
+		t.Fatalf("StarMessage: %v", err)
+	}
+
+	starred, err := f.service.GetStarredMessages(ctx, f.bob.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetStarredMessages: %v", err)
+	}
+	if len(starred) != 1 || starred[0].ID != sent.ID || starred[0].StarredAt == nil || starred[0].SenderName != "Alice" {
+		t.Fatalf("starred = %+v, want the message from Alice", starred)
+	}
+
+	// Stars are private to the user who set them.
+	starred, err = f.service.GetStarredMessages(ctx, f.alice.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetStarredMessages: %v", err)
+	}
+	if len(starred) != 0 {
+		t.Errorf("Alice's starred = %+v, want none", starred)
+	}
+
+	if err := f.service.UnstarMessage(ctx, sent.ID, f.bob.ID); err != nil {
+		t.Fatalf("UnstarMessage: %v", err)
+	}
+	starred, err = f.service.GetStarredMessages(ctx, f.bob.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("GetStarredMessages: %v", err)
+	}
+	if len(starred) != 0 {
+		t.Errorf("starred after unstar = %+v, want none", starred)
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	"go-chat/pkg"
+)
+
+// NotificationPolicy decides whether a message should alert its receiver.
+// Every notification channel asks it before notifying, so mutes and
+// preferences apply the same everywhere; messages are still delivered either
+// way.
+type NotificationPolicy struct {
+	userRepo         repository.UserRepository
+	conversationRepo repository.ConversationRepository
+	now              func() time.Time
+}
+
+func NewNotificationPolicy(userRepo repository.UserRepository, conversationRepo repository.ConversationRepository) *NotificationPolicy {
+	return &NotificationPolicy{
+		userRepo:         userRepo,
+		conversationRepo: conversationRepo,
+		now:              time.Now,
+	}
+}
+
+// ShouldNotify reports whether receiverID should be alerted about a message
+// from senderID with the given content. Nothing notifies while the
+// conversation is muted or during quiet hours; otherwise the receiver's
+// level decides, with "mentions" only letting through messages that mention
+// them.
+func (p *NotificationPolicy) ShouldNotify(ctx context.Context, receiverID, senderID uint, content string) (bool, error) {
+	ctx, span := pkg.StartSpan(ctx, "NotificationPolicy.ShouldNotify")
+	defer span.End()
+
+	receiver, err := p.userRepo.GetUserByID(ctx, receiverID)
+	if err != nil {
+		return false, err
+	}
+	preferences := receiver.Notifications
+	if preferences.EffectiveLevel() == domain.NotificationLevelNone {
+		return false, nil
+	}
+
+	now := p.now()
+	// A first message has no summary yet, so nothing can have muted it.
+	if summary, err := p.conversationRepo.GetConversation(ctx, receiverID, senderID); err == nil && summary.IsMuted(now) {
+		return false, nil
+	}
+
+	if preferences.InQuietHours(now) {
+		return false, nil
+	}
+
+	if preferences.EffectiveLevel() == domain.NotificationLevelMentions {
+		return receiver.MentionedIn(content), nil
+	}
+	return true, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-chat/internal/domain"
+)
+
+func TestShouldNotify(t *testing.T) {
+	ctx := context.Background()
+	// 23:00 in Berlin (CET) is 22:00 UTC.
+	now := time.Date(2026, time.January, 15, 22, 0, 0, 0, time.UTC)
+	later := now.Add(time.Hour)
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		preferences domain.NotificationPreferences
+		muted       bool
+		mutedUntil  *time.Time
+		content     string
+		want        bool
+	}{
+		{
+			name: "defaults notify",
+			want: true,
+		},
+		{
+			name:        "level none",
+			preferences: domain.NotificationPreferences{Level: domain.NotificationLevelNone},
+		},
+		{
+			name:        "mentions only without a mention",
+			preferences: domain.NotificationPreferences{Level: domain.NotificationLevelMentions},
+			content:     "hello there",
+		},
+		{
+			name:        "mentions only with a mention",
+			preferences: domain.NotificationPreferences{Level: domain.NotificationLevelMentions},
+			content:     "hello @bob",
+			want:        true,
+		},
+		{
+			name:  "muted for good",
+			muted: true,
+		},
+		{
+			name:       "muted for a while",
+			muted:      true,
+			mutedUntil: &later,
+		},
+		{
+			name:       "mute expired",
+			muted:      true,
+			mutedUntil: &earlier,
+			want:       true,
+		},
+		{
+			name: "quiet hours in the receiver's timezone",
+			preferences: domain.NotificationPreferences{
+				QuietHoursStart: "22:30",
+				QuietHoursEnd:   "07:00",
+				Timezone:        "Europe/Berlin",
+			},
+		},
+		{
+			name: "outside quiet hours",
+			preferences: domain.NotificationPreferences{
+				QuietHoursStart: "22:30",
+				QuietHoursEnd:   "07:00",
+				Timezone:        "UTC",
+			},
+			want: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newBaseFixture(t)
+			users, conversations := f.repos.Users, f.repos.Conversations
+			alice, bob := f.alice, f.bob
+
+			if err := users.SetNotificationPreferences(ctx, bob.ID, tc.preferences); err != nil {
+				t.Fatal(err)
+			}
+			if err := conversations.RecordMessage(ctx, &domain.Message{ID: 1, SenderID: alice.ID, ReceiverID: bob.ID, CreatedAt: earlier}); err != nil {
+				t.Fatal(err)
+			}
+			if tc.muted {
+				mutedAt := earlier.Add(-time.Hour)
+				if err := conversations.SetMuted(ctx, bob.ID, alice.ID, &mutedAt, tc.mutedUntil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			policy := NewNotificationPolicy(users, conversations)
+			policy.now = func() time.Time { return now }
+
+			content := tc.content
+			if content == "" {
+				content = "hi"
+			}
+			got, err := policy.ShouldNotify(ctx, bob.ID, alice.ID, content)
+			if err != nil {
+				t.Fatalf("ShouldNotify: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("ShouldNotify = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	"go-chat/pkg"
+)
+
+const oauthStateTTL = 10 * time.Minute
+
+type OAuthService struct {
+	identityRepo repository.IdentityRepository
+	userRepo     repository.UserRepository
+	authService  *AuthService
+	providers    map[string]*pkg.OIDCProvider
+}
+
+func NewOAuthService(identityRepo repository.IdentityRepository, userRepo repository.UserRepository, authService *AuthService, providers []*pkg.OIDCProvider) *OAuthService {
+	byName := make(map[string]*pkg.OIDCProvider, len(providers))
+	for _, p := range providers {
+		byName[p.Config.Name] = p
+	}
+
+	return &OAuthService{
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		authService:  authService,
+		providers:    byName,
+	}
+}
+
+func (s *OAuthService) ListProviders() []*domain.OAuthProviderResponse {
+	providers := make([]*domain.OAuthProviderResponse, 0, len(s.providers))
+	for _, p := range s.providers {
+		providers = append(providers, &domain.OAuthProviderResponse{
+			Name:        p.Config.Name,
+			DisplayName: p.Config.DisplayName,
+		})
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+	return providers
+}
+
+// BeginLogin starts an authorization code flow and returns the provider URL
+// to redirect to, with the stored state so the caller can bind it to the
+// browser. A non-zero linkUserID links the identity to that account instead
+// of signing in.
+func (s *OAuthService) BeginLogin(ctx context.Context, providerName string, linkUserID uint) (string, *domain.OAuthState, error) {
+	ctx, span := pkg.StartSpan(ctx, "OAuthService.BeginLogin")
+	defer span.End()
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", nil, errors.New("unknown provider")
+	}
+
+	state, err := pkg.RandomToken(32)
+	if err != nil {
+		return "", nil, errors.New("failed to generate state")
+	}
+	nonce, err := pkg.RandomToken(32)
+	if err != nil {
+		return "", nil, errors.New("failed to generate nonce")
+	}
+	verifier, err := pkg.RandomToken(48)
+	if err != nil {
+		return "", nil, errors.New("failed to generate code verifier")
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", nil, err
+	}
+
+	stored := &domain.OAuthState{
+		State:        state,
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(oauthStateTTL),
+	}
+	if err := s.identityRepo.SaveOAuthState(ctx, stored); err != nil {
+		return "", nil, errors.New("failed to store login state")
+	}
+
+	return authURL, stored, nil
+}
+
+// CompleteLogin handles the provider callback. It returns the signed-in (or
+// linked) user and, for sign-ins, a fresh token pair.
+func (s *OAuthService) CompleteLogin(ctx context.Context, providerName, code, state, clientIP, userAgent string) (*domain.User, *pkg.TokenPair, error) {
+	ctx, span := pkg.StartSpan(ctx, "OAuthService.CompleteLogin")
+	defer span.End()
+
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, nil, errors.New("unknown provider")
+	}
+
+	stored, err := s.identityRepo.ConsumeOAuthState(ctx, state)
+	if err != nil || stored.Provider != providerName || time.Now().After(stored.ExpiresAt) {
+		return nil, nil, errors.New("invalid or expired login state")
+	}
+
+	rawIDToken, err := provider.Exchange(ctx, code, stored.CodeVerifier)
+	if err != nil {
+		return nil, nil, errors.New("failed to exchange authorization code")
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, rawIDToken, stored.Nonce)
+	if err != nil {
+		return nil, nil, errors.New("failed to verify identity token")
+	}
+
+	if stored.LinkUserID != 0 {
+		user, err := s.linkIdentity(ctx, stored.LinkUserID, providerName, claims)
+		return user, nil, err
+	}
+
+	user, err := s.findOrCreateUser(ctx, providerName, claims)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if user.IsSuspended() {
+		return nil, nil, errors.New("account suspended")
+	}
+
+	if user.DeletionRequestedAt != nil {
+		if err := s.userRepo.SetDeletionRequested(ctx, user.ID, nil); err != nil {
+			return nil, nil, errors.New("failed to cancel pending account deletion")
+		}
+		user.DeletionRequestedAt = nil
+	}
+
+	tokens, err := s.authService.GenerateTokens(ctx, user, clientIP, userAgent)
+	if err != nil {
+		return nil, nil, errors.New("failed to generate tokens")
+	}
+
+	return user, tokens, nil
+}
+
+func (s *OAuthService) findOrCreateUser(ctx context.Context, providerName string, claims *pkg.OIDCIDTokenClaims) (*domain.User, error) {
+	if identity, err := s.identityRepo.FindIdentity(ctx, providerName, claims.Subject); err == nil {
+		user, err := s.userRepo.GetUserByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, errors.New("linked user not found")
+		}
+		return user, nil
+	}
+
+	email := strings.ToLower(strings.TrimSpace(claims.Email))
+	if email == "" {
+		return nil, errors.New("provider did not return an email address")
+	}
+
+	// An unverified email must never be used to take over an existing account.
+	if existing, err := s.userRepo.FindByEmail(ctx, email); err == nil && existing != nil {
+		if !claims.EmailVerified {
+			return nil, errors.New("an account with this email already exists; sign in and link the provider instead")
+		}
+		if err := s.createIdentity(ctx, existing.ID, providerName, claims.Subject, email); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = strings.Split(email, "@")[0]
+	}
+
+	user := &domain.User{
+		Name:  name,
+		Email: email,
+	}
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return nil, errors.New("failed to create user")
+	}
+
+	if err := s.createIdentity(ctx, user.ID, providerName, claims.Subject, email); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *OAuthService) linkIdentity(ctx context.Context, userID uint, providerName string, claims *pkg.OIDCIDTokenClaims) (*domain.User, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if identity, err := s.identityRepo.FindIdentity(ctx, providerName, claims.Subject); err == nil {
+		if identity.UserID != userID {
+			return nil, errors.New("this identity is already linked to another account")
+		}
+		return user, nil
+	}
+
+	if err := s.createIdentity(ctx, userID, providerName, claims.Subject, claims.Email); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *OAuthService) createIdentity(ctx context.Context, userID uint, providerName, subject, email string) error {
+	err := s.identityRepo.CreateIdentity(ctx, &domain.UserIdentity{
+		UserID:   userID,
+		Provider: providerName,
+		Subject:  subject,
+		Email:    email,
+	})
+	if err != nil {
+		return errors.New("failed to link identity")
+	}
+	return nil
+}
+
+func (s *OAuthService) GetUserIdentities(ctx context.Context, userID uint) ([]*domain.UserIdentity, error) {
+	ctx, span := pkg.StartSpan(ctx, "OAuthService.GetUserIdentities")
+	defer span.End()
+
+	return s.identityRepo.GetUserIdentities(ctx, userID)
+}
+
+// UnlinkProvider removes a linked identity, refusing to remove the last way
+// the user has of signing in.
+func (s *OAuthService) UnlinkProvider(ctx context.Context, userID uint, providerName string) error {
+	ctx, span := pkg.StartSpan(ctx, "OAuthService.UnlinkProvider")
+	defer span.End()
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	identities, err := s.identityRepo.GetUserIdentities(ctx, userID)
+	if err != nil {
+		return errors.New("failed to load linked providers")
+	}
+
+	if user.Password == "" && len(identities) <= 1 {
+		return errors.New("cannot unlink the only sign-in method; set a password with change-password first")
+	}
+
+	if err := s.identityRepo.DeleteIdentity(ctx, userID, providerName); err != nil {
+		return errors.New("provider is not linked")
+	}
+
+	return nil
+}
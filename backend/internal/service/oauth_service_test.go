@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	memory_adapters "go-chat/internal/adapters/memory"
+	"go-chat/internal/ports/repository"
+	"go-chat/pkg"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIssuer is an OpenID provider serving discovery, its signing keys and a
+// token endpoint that enforces PKCE.
+type mockIssuer struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+// mockGrant is what the issuer remembers about an authorization code.
+type mockGrant struct {
+	challenge string
+	claims    pkg.OIDCIDTokenClaims
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuer := &mockIssuer{key: key, codes: make(map[string]mockGrant)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(pkg.OIDCDiscovery{
+			Issuer:                issuer.server.URL,
+			AuthorizationEndpoint: issuer.server.URL + "/authorize",
+			TokenEndpoint:         issuer.server.URL + "/token",
+			JWKSURI:               issuer.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", issuer.token)
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+// authorize plays the user approving the request at authURL and returns the
+// code the provider would redirect back with. The ID token echoes the
+// request's nonce unless claims already carries one.
+func (i *mockIssuer) authorize(t *testing.T, authURL string, claims pkg.OIDCIDTokenClaims) (code, state string) {
+	t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("authorization request %q does not use PKCE", authURL)
+	}
+	if claims.Nonce == "" {
+		claims.Nonce = query.Get("nonce")
+	}
+
+	code, err = pkg.RandomToken(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i.mu.Lock()
+	i.codes[code] = mockGrant{challenge: query.Get("code_challenge"), claims: claims}
+	i.mu.Unlock()
+	return code, query.Get("state")
+}
+
+func (i *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	i.mu.Lock()
+	grant, ok := i.codes[r.PostForm.Get("code")]
+	delete(i.codes, r.PostForm.Get("code"))
+	i.mu.Unlock()
+	if !ok || pkg.PKCEChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	claims := grant.claims
+	claims.Issuer = i.server.URL
+	claims.Audience = jwt.ClaimStrings{r.PostForm.Get("client_id")}
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(i.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+}
+
+type oauthFixture struct {
+	service    *OAuthService
+	issuer     *mockIssuer
+	users      repository.UserRepository
+	identities repository.IdentityRepository
+}
+
+func newOAuthFixture(t *testing.T) *oauthFixture {
+	t.Helper()
+	store := memory_adapters.NewStore()
+	f := &oauthFixture{
+		issuer:     newMockIssuer(t),
+		users:      memory_adapters.NewUserMemoryRepo(store),
+		identities: memory_adapters.NewIdentityMemoryRepo(store),
+	}
+	jwt := pkg.NewJWTManager("access-secret", "refresh-secret", time.Minute, time.Hour)
+	auth := NewAuthService(f.users, memory_adapters.NewSessionMemoryRepo(store), jwt)
+	provider := pkg.NewOIDCProvider(pkg.OIDCProviderConfig{
+		Name:        "mock",
+		IssuerURL:   f.issuer.server.URL,
+		ClientID:    "go-chat",
+		RedirectURL: "http://localhost:8080/api/auth/oauth/mock/callback",
+	})
+	f.service = NewOAuthService(f.identities, f.users, auth, []*pkg.OIDCProvider{provider})
+	return f
+}
+
+// begin starts a flow and has the issuer approve it for claims.
+func (f *oauthFixture) begin(t *testing.T, linkUserID uint, claims pkg.OIDCIDTokenClaims) (code, state string) {
+	t.Helper()
+	authURL, stored, err := f.service.BeginLogin(context.Background(), "mock", linkUserID)
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	if !strings.HasPrefix(authURL, f.issuer.server.URL+"/authorize?") {
+		t.Fatalf("authorization URL = %q, want the discovered endpoint", authURL)
+	}
+	code, state = f.issuer.authorize(t, authURL, claims)
+	if state != stored.State {
+		t.Fatalf("authorization state = %q, want %q", state, stored.State)
+	}
+	return code, state
+}
+
+func oidcClaims(subject, email string) pkg.OIDCIDTokenClaims {
+	return pkg.OIDCIDTokenClaims{
+		Email:            email,
+		EmailVerified:    true,
+		Name:             "Dana",
+		RegisteredClaims: jwt.RegisteredClaims{Subject: subject},
+	}
+}
+
+func TestOAuthLogin(t *testing.T) {
+	ctx := context.Background()
+	f := newOAuthFixture(t)
+
+	code, state := f.begin(t, 0, oidcClaims("sub-1", "Dana@Example.com"))
+	user, tokens, err := f.service.CompleteLogin(ctx, "mock", code, state, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("CompleteLogin: %v", err)
+	}
+	if tokens == nil || user.Email != "dana@example.com" || user.Name != "Dana" {
+		t.Fatalf("CompleteLogin = %+v, %v, want a new signed-in user", user, tokens)
+	}
+	identity, err := f.identities.FindIdentity(ctx, "mock", "sub-1")
+	if err != nil || identity.UserID != user.ID {
+		t.Fatalf("identity = %+v, %v, want linked to %d", identity, err, user.ID)
+	}
+
+	// The state is single use.
+	if _, _, err := f.service.CompleteLogin(ctx, "mock", code, state, "127.0.0.1", "test"); err == nil || err.Error() != "invalid or expired login state" {
+		t.Errorf("replayed state error = %v", err)
+	}
+
+	// Signing in again finds the same account.
+	code, state = f.begin(t, 0, oidcClaims("sub-1", "dana@example.com"))
+	again, _, err := f.service.CompleteLogin(ctx, "mock", code, state, "127.0.0.1", "test")
+	if err != nil || again.ID != user.ID {
+		t.Errorf("second login = %+v, %v, want user %d", again, err, user.ID)
+	}
+
+	// The account has no password, so its only identity stays linked.
+	if err := f.service.UnlinkProvider(ctx, user.ID, "mock"); err == nil || err.Error() != "cannot unlink the only sign-in method; set a password with change-password first" {
+		t.Errorf("UnlinkProvider error = %v", err)
+	}
+}
+
+func TestOAuthLoginRejected(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		flow    func(t *testing.T, f *oauthFixture) (code, state string)
+		wantErr string
+	}{
+		{
+			name: "unknown state",
+			flow: func(t *testing.T, f *oauthFixture) (string, string) {
+				code, _ := f.begin(t, 0, oidcClaims("sub-1", "dana@example.com"))
+				return code, "forged"
+			},
+			wantErr: "invalid or expired login state",
+		},
+		{
+			name: "expired state",
+			flow: func(t *testing.T, f *oauthFixture) (string, string) {
+				code, state := f.begin(t, 0, oidcClaims("sub-1", "dana@example.com"))
+				stored, err := f.identities.ConsumeOAuthState(ctx, state)
+				if err != nil {
+					t.Fatal(err)
+				}
+				stored.ExpiresAt = time.Now().Add(-time.Second)
+				if err := f.identities.SaveOAuthState(ctx, stored); err != nil {
+					t.Fatal(err)
+				}
+				return code, state
+			},
+			wantErr: "invalid or expired login state",
+		},
+		{
+			// The code was issued for another flow, so this flow's verifier
+			// does not match its challenge.
+			name: "wrong code verifier",
+			flow: func(t *testing.T, f *oauthFixture) (string, string) {
+				code, _ := f.begin(t, 0, oidcClaims("sub-1", "dana@example.com"))
+				_, state := f.begin(t, 0, oidcClaims("sub-1", "dana@example.com"))
+				return code, state
+			},
+			wantErr: "failed to exchange authorization code",
+		},
+		{
+			name: "nonce mismatch",
+			flow: func(t *testing.T, f *oauthFixture) (string, string) {
+				claims := oidcClaims("sub-1", "dana@example.com")
+				claims.Nonce = "replayed-id-token"
+				return f.begin(t, 0, claims)
+			},
+			wantErr: "failed to verify identity token",
+		},
+		{
+			name: "unverified email of an existing account",
+			flow: func(t *testing.T, f *oauthFixture) (string, string) {
+				createTestUser(t, f.users, "Dana", "dana@example.com")
+				claims := oidcClaims("sub-1", "dana@example.com")
+				claims.EmailVerified = false
+				return f.begin(t, 0, claims)
+			},
+			wantErr: "an account with this email already exists; sign in and link the provider instead",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newOAuthFixture(t)
+			code, state := tc.flow(t, f)
+
+			user, tokens, err := f.service.CompleteLogin(ctx, "mock", code, state, "127.0.0.1", "test")
+			if err == nil || err.Error() != tc.wantErr {
+				t.Fatalf("CompleteLogin = %+v, %v, %v, want %q", user, tokens, err, tc.wantErr)
+			}
+			if _, err := f.identities.FindIdentity(ctx, "mock", "sub-1"); err == nil {
+				t.Error("rejected login linked an identity")
+			}
+		})
+	}
+}
+
+func TestOAuthLinkProvider(t *testing.T) {
+	ctx := context.Background()
+	f := newOAuthFixture(t)
+	alice := createTestUser(t, f.users, "Alice", "alice@example.com")
+	bob := createTestUser(t, f.users, "Bob", "bob@example.com")
+
+	// The provider's email need not match the account being linked.
+	code, state := f.begin(t, alice.ID, oidcClaims("sub-alice", "alice@elsewhere.example"))
+	user, tokens, err := f.service.CompleteLogin(ctx, "mock", code, state, "127.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("CompleteLogin(link): %v", err)
+	}
+	if user.ID != alice.ID || tokens != nil {
+		t.Fatalf("link = user %d, tokens %v, want Alice and no new session", user.ID, tokens)
+	}
+	identities, err := f.service.GetUserIdentities(ctx, alice.ID)
+	if err != nil || len(identities) != 1 || identities[0].Subject != "sub-alice" {
+		t.Fatalf("Alice's identities = %+v, %v", identities, err)
+	}
+
+	// Signing in with the linked identity reaches Alice's account.
+	code, state = f.begin(t, 0, oidcClaims("sub-alice", "alice@elsewhere.example"))
+	if user, _, err := f.service.CompleteLogin(ctx, "mock", code, state, "127.0.0.1", "test"); err != nil || user.ID != alice.ID {
+		t.Errorf("login with linked identity = %+v, %v, want Alice", user, err)
+	}
+
+	// Nobody else can claim it.
+	code, state = f.begin(t, bob.ID, oidcClaims("sub-alice", "alice@elsewhere.example"))
+	if _, _, err := f.service.CompleteLogin(ctx, "mock", code, state, "127.0.0.1", "test"); err == nil || err.Error() != "this identity is already linked to another account" {
+		t.Errorf("linking Alice's identity to Bob error = %v", err)
+	}
+
+	// Alice still has her password, so she may unlink.
+	if err := f.service.UnlinkProvider(ctx, alice.ID, "mock"); err != nil {
+		t.Fatalf("UnlinkProvider: %v", err)
+	}
+	if _, err := f.identities.FindIdentity(ctx, "mock", "sub-alice"); err == nil {
+		t.Error("identity still linked after UnlinkProvider")
+	}
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	// A document claiming another issuer must not be trusted, or that issuer
+	// could mint ID tokens for this provider.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(pkg.OIDCDiscovery{
+			Issuer:                "https://elsewhere.example",
+			AuthorizationEndpoint: "https://elsewhere.example/authorize",
+			TokenEndpoint:         "https://elsewhere.example/token",
+			JWKSURI:               "https://elsewhere.example/jwks",
+		})
+	}))
+	defer server.Close()
+
+	provider := pkg.NewOIDCProvider(pkg.OIDCProviderConfig{Name: "mock", IssuerURL: server.URL, ClientID: "go-chat"})
+	if _, err := provider.Discover(context.Background()); err == nil || !strings.Contains(err.Error(), "issuer mismatch") {
+		t.Fatalf("Discover error = %v, want issuer mismatch", err)
+	}
+}
+
+func TestOIDCDiscoveryFollowsContext(t *testing.T) {
+	// The provider hangs until the client gives up.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	provider := pkg.NewOIDCProvider(pkg.OIDCProviderConfig{Name: "mock", IssuerURL: server.URL, ClientID: "go-chat"})
+	start := time.Now()
+	if _, err := provider.Discover(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Discover error = %v, want the context deadline", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Discover took %v despite the request deadline", elapsed)
+	}
+}
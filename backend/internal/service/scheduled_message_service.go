@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	wsports "go-chat/internal/ports/websocket"
+	"go-chat/pkg"
+)
+
+const (
+	// scheduledBatchSize caps how many due messages one run of the
+	// scheduler sends.
+	scheduledBatchSize = 100
+
+	// maxScheduledAttempts is how many times the scheduler tries to send a
+	// message before marking it failed.
+	maxScheduledAttempts = 5
+)
+
+var errScheduledNotPending = errors.New("scheduled message is no longer pending")
+
+// undeliverableError is returned by send when the message can no longer be
+// sent at all, so retrying it would be pointless.
+type undeliverableError struct {
+	reason string
+}
+
+func (e *undeliverableError) Error() string {
+	return e.reason
+}
+
+// ScheduledMessageService lets users compose messages to be sent later and
+// sends them once they are due. Scheduled messages are stored, so pending
+// ones survive restarts, and every replica may run the scheduler: sending
+// claims the message in the same unit of work, so only one of them sends it.
+type ScheduledMessageService struct {
+	scheduledRepo repository.ScheduledMessageRepository
+	userRepo      repository.UserRepository
+	notifications *NotificationPolicy
+	hub           wsports.WSHandler
+	uow           repository.UnitOfWork
+}
+
+func NewScheduledMessageService(
+	scheduledRepo repository.ScheduledMessageRepository,
+	userRepo repository.UserRepository,
+	notifications *NotificationPolicy,
+	hub wsports.WSHandler,
+	uow repository.UnitOfWork,
+) *ScheduledMessageService {
+	return &ScheduledMessageService{
+		scheduledRepo: scheduledRepo,
+		userRepo:      userRepo,
+		notifications: notifications,
+		hub:           hub,
+		uow:           uow,
+	}
+}
+
+// ScheduleMessage stores req to be sent from senderID at req.SendAt.
+func (s *ScheduledMessageService) ScheduleMessage(ctx context.Context, senderID uint, req *domain.MessageRequest) (*domain.ScheduledMessage, error) {
+	ctx, span := pkg.StartSpan(ctx, "ScheduledMessageService.ScheduleMessage")
+	defer span.End()
+
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if req.SendAt == nil || !req.SendAt.After(time.Now()) {
+		return nil, errors.New("send_at must be in the future")
+	}
+
+	if _, err := s.userRepo.GetUserByID(ctx, req.ReceiverID); err != nil {
+		return nil, errors.New("receiver not found")
+	}
+
+	message := &domain.ScheduledMessage{
+		SenderID:    senderID,
+		ReceiverID:  req.ReceiverID,
+		Content:     req.Content,
+		MessageType: req.MessageType,
+		SendAt:      req.SendAt.UTC(),
+		Status:      domain.ScheduledMessagePending,
+	}
+	if message.MessageType == "" {
+		message.MessageType = "text"
+	}
+
+	if err := s.scheduledRepo.CreateScheduledMessage(ctx, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// GetScheduledMessages lists the sender's pending messages, soonest first.
+func (s *ScheduledMessageService) GetScheduledMessages(ctx context.Context, senderID uint) ([]*domain.ScheduledMessage, error) {
+	ctx, span := pkg.StartSpan(ctx, "ScheduledMessageService.GetScheduledMessages")
+	defer span.End()
+
+	return s.scheduledRepo.GetPendingScheduledMessages(ctx, senderID)
+}
+
+// UpdateScheduledMessage changes the content or send time of one of the
+// sender's pending messages.
+func (s *ScheduledMessageService) UpdateScheduledMessage(ctx context.Context, senderID, id uint, req *domain.UpdateScheduledMessageRequest) (*domain.ScheduledMessage, error) {
+	ctx, span := pkg.StartSpan(ctx, "ScheduledMessageService.UpdateScheduledMessage")
+	defer span.End()
+
+	if err := req.Validate(time.Now()); err != nil {
+		return nil, err
+	}
+
+	message, err := s.pendingMessage(ctx, senderID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Content != nil {
+		message.Content = *req.Content
+	}
+	if req.SendAt != nil {
+		message.SendAt = req.SendAt.UTC()
+	}
+
+	updated, err := s.scheduledRepo.UpdateScheduledMessage(ctx, id, message.Content, message.SendAt)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errScheduledNotPending
+	}
+	return message, nil
+}
+
+// CancelScheduledMessage stops one of the sender's pending messages from
+// being sent.
+func (s *ScheduledMessageService) CancelScheduledMessage(ctx context.Context, senderID, id uint) error {
+	ctx, span := pkg.StartSpan(ctx, "ScheduledMessageService.CancelScheduledMessage")
+	defer span.End()
+
+	if _, err := s.pendingMessage(ctx, senderID, id); err != nil {
+		return err
+	}
+
+	cancelled, err := s.scheduledRepo.CancelScheduledMessage(ctx, id, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return errScheduledNotPending
+	}
+	return nil
+}
+
+// pendingMessage returns the sender's scheduled message if it is still
+// pending. Other users' messages are reported as missing.
+func (s *ScheduledMessageService) pendingMessage(ctx context.Context, senderID, id uint) (*domain.ScheduledMessage, error) {
+	message, err := s.scheduledRepo.GetScheduledMessage(ctx, id)
+	if err != nil || message.SenderID != senderID {
+		return nil, errors.New("scheduled message not found")
+	}
+	if message.Status != domain.ScheduledMessagePending {
+		return nil, errScheduledNotPending
+	}
+	return message, nil
+}
+
+// SendDueScheduledMessages sends the messages that are due and returns how
+// many this call sent. A message another replica sent first is skipped; one
+// that fails stays pending for the next run until it runs out of attempts,
+// and one that can no longer be delivered is marked failed straight away.
+func (s *ScheduledMessageService) SendDueScheduledMessages(ctx context.Context) (int, error) {
+	ctx, span := pkg.StartSpan(ctx, "ScheduledMessageService.SendDueScheduledMessages")
+	defer span.End()
+
+	due, err := s.scheduledRepo.GetDueScheduledMessages(ctx, time.Now().UTC(), scheduledBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, scheduled := range due {
+		if ctx.Err() != nil {
+			break
+		}
+
+		response, err := s.send(ctx, scheduled)
+		if err != nil {
+			pkg.ErrorContext(ctx, "Failed to send scheduled message", err, map[string]interface{}{
+				"scheduled_message_id": scheduled.ID,
+			})
+			attempts := maxScheduledAttempts
+			var undeliverable *undeliverableError
+			if errors.As(err, &undeliverable) {
+				attempts = 1
+			}
+			if err := s.scheduledRepo.RecordScheduledMessageFailure(ctx, scheduled.ID, err.Error(), attempts); err != nil {
+				pkg.ErrorContext(ctx, "Failed to record scheduled message failure", err, map[string]interface{}{
+					"scheduled_message_id": scheduled.ID,
+				})
+			}
+			continue
+		}
+		if response == nil {
+			continue
+		}
+
+		s.broadcast(ctx, scheduled, response)
+		sent++
+	}
+
+	return sent, nil
+}
+
+// send claims the scheduled message and sends it in one unit of work, so it
+// is either sent and marked sent or neither. It returns nil if the message
+// was no longer pending. The sender may have been suspended, deleted or
+// blocked since scheduling it, so that is checked again once it is claimed.
+func (s *ScheduledMessageService) send(ctx context.Context, scheduled *domain.ScheduledMessage) (*domain.MessageResponse, error) {
+	var response *domain.MessageResponse
+	err := s.uow.Do(ctx, func(ctx context.Context, repos repository.Repositories) error {
+		response = nil
+
+		claimed, err := repos.ScheduledMessages.ClaimScheduledMessage(ctx, scheduled.ID, time.Now().UTC())
+		if err != nil || !claimed {
+			return err
+		}
+
+		if err := checkScheduledSender(ctx, repos, scheduled); err != nil {
+			return err
+		}
+
+		response, err = sendMessage(ctx, repos, scheduled.SenderID, scheduled.Request())
+		if errors.Is(err, errSenderBlocked) {
+			return &undeliverableError{reason: err.Error()}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// checkScheduledSender returns an undeliverableError if the sender has been
+// deleted or suspended since scheduling; blocks are checked by sendMessage.
+func checkScheduledSender(ctx context.Context, repos repository.Repositories, scheduled *domain.ScheduledMessage) error {
+	sender, err := repos.Users.GetUserByID(ctx, scheduled.SenderID)
+	if err != nil || sender.AnonymizedAt != nil {
+		return &undeliverableError{reason: "sender not found"}
+	}
+	if sender.IsSuspended() {
+		return &undeliverableError{reason: "sender is suspended"}
+	}
+	return nil
+}
+
+// broadcast delivers a sent scheduled message to its receiver, as if it had
+// been sent over the WebSocket, and tells the sender it went out.
+func (s *ScheduledMessageService) broadcast(ctx context.Context, scheduled *domain.ScheduledMessage, response *domain.MessageResponse) {
+	// Errors fail open, as for live messages.
+	notify, err := s.notifications.ShouldNotify(ctx, response.ReceiverID, response.SenderID, response.Content)
+	if err != nil {
+		pkg.ErrorContext(ctx, "Failed to apply notification policy", err, map[string]interface{}{
+			"message_id": response.ID,
+		})
+		notify = true
+	}
+	s.hub.BroadcastMessage(response.ToWSMessage(notify), response.ReceiverID)
+
+	s.hub.BroadcastMessage(&domain.WSMessage{
+		Type: domain.WSMessageTypeScheduledMessageSent,
+		Payload: &domain.WSScheduledSentPayload{
+			ScheduledMessageID: scheduled.ID,
+			Message:            response,
+		},
+	}, response.SenderID)
+}
+
+// RunScheduler sends due scheduled messages on every tick until ctx is done.
+func (s *ScheduledMessageService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SendDueScheduledMessages(ctx); err != nil {
+			pkg.ErrorContext(ctx, "Scheduled message worker failed", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go-chat/internal/domain"
+)
+
+type scheduledFixture struct {
+	baseFixture
+	service *ScheduledMessageService
+}
+
+func newScheduledFixture(t *testing.T) *scheduledFixture {
+	t.Helper()
+	f := &scheduledFixture{baseFixture: newBaseFixture(t)}
+	f.service = NewScheduledMessageService(f.repos.ScheduledMessages, f.repos.Users, NewNotificationPolicy(f.repos.Users, f.repos.Conversations), f.hub, f.uow)
+	return f
+}
+
+// due stores a pending message from Alice to Bob that is already due.
+func (f *scheduledFixture) due(t *testing.T, content string) *domain.ScheduledMessage {
+	t.Helper()
+	message := &domain.ScheduledMessage{
+		SenderID:   f.alice.ID,
+		ReceiverID: f.bob.ID,
+		Content:    content,
+		SendAt:     time.Now().UTC().Add(-time.Minute),
+	}
+	if err := f.repos.ScheduledMessages.CreateScheduledMessage(context.Background(), message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestScheduleMessage(t *testing.T) {
+	ctx := context.Background()
+	f := newScheduledFixture(t)
+
+	past := time.Now().Add(-time.Minute)
+	if _, err := f.service.ScheduleMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "hi", SendAt: &past}); err == nil || err.Error() != "send_at must be in the future" {
+		t.Errorf("ScheduleMessage(past) error = %v", err)
+	}
+
+	future := time.Now().Add(time.Hour)
+	if _, err := f.service.ScheduleMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "hi", MessageType: domain.MessageTypeSystem, SendAt: &future}); err == nil {
+		t.Error("ScheduleMessage accepted a system message")
+	}
+	if _, err := f.service.ScheduleMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID + 100, Content: "hi", SendAt: &future}); err == nil || err.Error() != "receiver not found" {
+		t.Errorf("ScheduleMessage(unknown receiver) error = %v", err)
+	}
+
+	scheduled, err := f.service.ScheduleMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "hi", SendAt: &future})
+	if err != nil {
+		t.Fatalf("ScheduleMessage: %v", err)
+	}
+	if scheduled.Status != domain.ScheduledMessagePending || scheduled.MessageType != domain.MessageTypeText {
+		t.Errorf("scheduled = %q %q, want pending text", scheduled.Status, scheduled.MessageType)
+	}
+	if scheduled.SendAt.Location() != time.UTC || !scheduled.SendAt.Equal(future) {
+		t.Errorf("SendAt = %v, want %v in UTC", scheduled.SendAt, future)
+	}
+
+	pending, err := f.service.GetScheduledMessages(ctx, f.alice.ID)
+	if err != nil {
+		t.Fatalf("GetScheduledMessages: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != scheduled.ID {
+		t.Errorf("GetScheduledMessages = %v, want the scheduled message", pending)
+	}
+
+	// Not due yet.
+	sent, err := f.service.SendDueScheduledMessages(ctx)
+	if err != nil || sent != 0 {
+		t.Errorf("SendDueScheduledMessages = %d, %v, want nothing sent", sent, err)
+	}
+}
+
+func TestSendDueScheduledMessages(t *testing.T) {
+	ctx := context.Background()
+	f := newScheduledFixture(t)
+	scheduled := f.due(t, "happy birthday")
+
+	sent, err := f.service.SendDueScheduledMessages(ctx)
+	if err != nil || sent != 1 {
+		t.Fatalf("SendDueScheduledMessages = %d, %v, want 1", sent, err)
+	}
+
+	history, err := f.repos.Messages.GetMessagesBetweenUsers(ctx, f.bob.ID, f.alice.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Content != "happy birthday" || history[0].SenderID != f.alice.ID {
+		t.Fatalf("history = %+v, want the scheduled message from Alice", history)
+	}
+
+	stored, err := f.repos.ScheduledMessages.GetScheduledMessage(ctx, scheduled.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != domain.ScheduledMessageSent || stored.SentAt == nil {
+		t.Errorf("scheduled message = %q at %v, want sent", stored.Status, stored.SentAt)
+	}
+
+	toBob := f.hub.sent[f.bob.ID]
+	if len(toBob) != 1 || toBob[0].Type != domain.WSMessageTypeNewMessage {
+		t.Fatalf("broadcasts to Bob = %+v, want one new_message", toBob)
+	}
+	if payload := toBob[0].Payload.(*domain.WSMessagePayload); payload.MessageID != history[0].ID || !payload.Notify {
+		t.Errorf("new_message payload = %+v", payload)
+	}
+	toAlice := f.hub.sent[f.alice.ID]
+	if len(toAlice) != 1 || toAlice[0].Type != domain.WSMessageTypeScheduledMessageSent {
+		t.Fatalf("broadcasts to Alice = %+v, want one scheduled_message_sent", toAlice)
+	}
+	if payload := toAlice[0].Payload.(*domain.WSScheduledSentPayload); payload.ScheduledMessageID != scheduled.ID || payload.Message.ID != history[0].ID {
+		t.Errorf("scheduled_message_sent payload = %+v", payload)
+	}
+
+	sent, err = f.service.SendDueScheduledMessages(ctx)
+	if err != nil || sent != 0 {
+		t.Errorf("second SendDueScheduledMessages = %d, %v, want nothing sent", sent, err)
+	}
+}
+
+func TestSendDueScheduledMessagesOnce(t *testing.T) {
+	ctx := context.Background()
+	f := newScheduledFixture(t)
+	f.due(t, "one")
+	f.due(t, "two")
+
+	// Replicas running the scheduler at the same time each see both
+	// messages as due, but every message goes out once.
+	const replicas = 4
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	total := 0
+	for range replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sent, err := f.service.SendDueScheduledMessages(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			total += sent
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if total != 2 {
+		t.Errorf("sent %d messages in total, want 2", total)
+	}
+	history, err := f.repos.Messages.GetMessagesBetweenUsers(ctx, f.bob.ID, f.alice.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 {
+		t.Errorf("history has %d messages, want 2", len(history))
+	}
+}
+
+func TestSendDueScheduledMessagesFailure(t *testing.T) {
+	ctx := context.Background()
+	f := newScheduledFixture(t)
+	scheduled := f.due(t, "hello?")
+	if err := f.repos.Users.DeleteUser(ctx, f.bob.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for attempt := 1; attempt <= maxScheduledAttempts; attempt++ {
+		sent, err := f.service.SendDueScheduledMessages(ctx)
+		if err != nil || sent != 0 {
+			t.Fatalf("SendDueScheduledMessages = %d, %v, want nothing sent", sent, err)
+		}
+
+		stored, err := f.repos.ScheduledMessages.GetScheduledMessage(ctx, scheduled.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Attempts != attempt || stored.LastError != "receiver not found" {
+			t.Errorf("after attempt %d = %d attempts, %q", attempt, stored.Attempts, stored.LastError)
+		}
+		wantStatus := domain.ScheduledMessagePending
+		if attempt == maxScheduledAttempts {
+			wantStatus = domain.ScheduledMessageFailed
+		}
+		if stored.Status != wantStatus {
+			t.Errorf("after attempt %d status = %q, want %q", attempt, stored.Status, wantStatus)
+		}
+	}
+
+	if len(f.hub.sent) != 0 {
+		t.Errorf("failed sends broadcast %+v", f.hub.sent)
+	}
+}
+
+func TestSendDueScheduledMessagesUndeliverable(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(ctx context.Context, f *scheduledFixture) error
+		reason string
+	}{
+		{
+			name: "suspended sender",
+			change: func(ctx context.Context, f *scheduledFixture) error {
+				now := time.Now()
+				return f.repos.Users.SetUserSuspension(ctx, f.alice.ID, &now, "spam")
+			},
+			reason: "sender is suspended",
+		},
+		{
+			name: "anonymized sender",
+			change: func(ctx context.Context, f *scheduledFixture) error {
+				return f.repos.Users.AnonymizeUser(ctx, f.alice.ID, time.Now())
+			},
+			reason: "sender not found",
+		},
+		{
+			name: "blocked by receiver",
+			change: func(ctx context.Context, f *scheduledFixture) error {
+				return NewFriendsService(f.repos.Friends, f.uow).BlockUser(ctx, f.bob.ID, f.alice.ID)
+			},
+			reason: "sender is blocked by the receiver",
+		},
+		{
+			// The friendship row keeps Alice as requester after Bob blocks.
+			name: "friends first, then blocked by receiver",
+			change: func(ctx context.Context, f *scheduledFixture) error {
+				friends := NewFriendsService(f.repos.Friends, f.uow)
+				if err := friends.SendFriendRequest(ctx, f.alice.ID, f.bob.ID); err != nil {
+					return err
+				}
+				friendship, err := f.repos.Friends.FindFriendshipBetweenUsers(ctx, f.alice.ID, f.bob.ID)
+				if err != nil {
+					return err
+				}
+				if err := friends.AcceptFriendRequest(ctx, friendship.ID, f.bob.ID); err != nil {
+					return err
+				}
+				return friends.BlockUser(ctx, f.bob.ID, f.alice.ID)
+			},
+			reason: "sender is blocked by the receiver",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newScheduledFixture(t)
+			scheduled := f.due(t, "hello?")
+			if err := tt.change(ctx, f); err != nil {
+				t.Fatal(err)
+			}
+
+			sent, err := f.service.SendDueScheduledMessages(ctx)
+			if err != nil || sent != 0 {
+				t.Fatalf("SendDueScheduledMessages = %d, %v, want nothing sent", sent, err)
+			}
+
+			// Undeliverable messages fail at once rather than being retried.
+			stored, err := f.repos.ScheduledMessages.GetScheduledMessage(ctx, scheduled.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != domain.ScheduledMessageFailed || stored.LastError != tt.reason {
+				t.Errorf("stored = %q, %q, want failed, %q", stored.Status, stored.LastError, tt.reason)
+			}
+			if stored.SentAt != nil {
+				t.Errorf("undeliverable message was claimed at %v", stored.SentAt)
+			}
+
+			history, err := f.repos.Messages.GetMessagesBetweenUsers(ctx, f.bob.ID, f.alice.ID, 10, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(history) != 0 || len(f.hub.sent) != 0 {
+				t.Errorf("undeliverable message was sent: %d stored, %+v broadcast", len(history), f.hub.sent)
+			}
+		})
+	}
+}
+
+func TestUpdateAndCancelScheduledMessage(t *testing.T) {
+	ctx := context.Background()
+	f := newScheduledFixture(t)
+
+	sendAt := time.Now().Add(time.Hour)
+	scheduled, err := f.service.ScheduleMessage(ctx, f.alice.ID, &domain.MessageRequest{ReceiverID: f.bob.ID, Content: "draft", SendAt: &sendAt})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := "final"
+	later := sendAt.Add(time.Hour)
+	if _, err := f.service.UpdateScheduledMessage(ctx, f.bob.ID, scheduled.ID, &domain.UpdateScheduledMessageRequest{Content: &content}); err == nil || err.Error() != "scheduled message not found" {
+		t.Errorf("UpdateScheduledMessage by the receiver error = %v", err)
+	}
+	if err := f.service.CancelScheduledMessage(ctx, f.bob.ID, scheduled.ID); err == nil || err.Error() != "scheduled message not found" {
+		t.Errorf("CancelScheduledMessage by the receiver error = %v", err)
+	}
+
+	empty := ""
+	if _, err := f.service.UpdateScheduledMessage(ctx, f.alice.ID, scheduled.ID, &domain.UpdateScheduledMessageRequest{Content: &empty}); err == nil {
+		t.Error("UpdateScheduledMessage accepted empty content")
+	}
+
+	updated, err := f.service.UpdateScheduledMessage(ctx, f.alice.ID, scheduled.ID, &domain.UpdateScheduledMessageRequest{Content: &content, SendAt: &later})
+	if err != nil {
+		t.Fatalf("UpdateScheduledMessage: %v", err)
+	}
+	if updated.Content != "final" || !updated.SendAt.Equal(later) {
+		t.Errorf("updated = %q at %v", updated.Content, updated.SendAt)
+	}
+
+	if err := f.service.CancelScheduledMessage(ctx, f.alice.ID, scheduled.ID); err != nil {
+		t.Fatalf("CancelScheduledMessage: %v", err)
+	}
+	if _, err := f.service.UpdateScheduledMessage(ctx, f.alice.ID, scheduled.ID, &domain.UpdateScheduledMessageRequest{Content: &content}); err == nil || err.Error() != "scheduled message is no longer pending" {
+		t.Errorf("UpdateScheduledMessage after cancel error = %v", err)
+	}
+
+	pending, err := f.service.GetScheduledMessages(ctx, f.alice.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Errorf("GetScheduledMessages after cancel = %v, want none", pending)
+	}
+}
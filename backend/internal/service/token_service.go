@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go-chat/internal/domain"
+	"go-chat/internal/ports/repository"
+	"go-chat/pkg"
+)
+
+const (
+	maxTokenLifetimeDays = 365
+	tokenTouchInterval   = time.Minute
+)
+
+type TokenService struct {
+	tokenRepo repository.TokenRepository
+	userRepo  repository.UserRepository
+}
+
+func NewTokenService(tokenRepo repository.TokenRepository, userRepo repository.UserRepository) *TokenService {
+	return &TokenService{
+		tokenRepo: tokenRepo,
+		userRepo:  userRepo,
+	}
+}
+
+// CreateToken issues a token for targetUserID, which must be the actor
+// themselves or a bot the actor owns. The plaintext token is only returned here.
+func (s *TokenService) CreateToken(ctx context.Context, actorID, targetUserID uint, req *domain.CreateTokenRequest) (*domain.PersonalAccessTokenResponse, error) {
+	ctx, span := pkg.StartSpan(ctx, "TokenService.CreateToken")
+	defer span.End()
+
+	if err := s.authorizeTarget(ctx, actorID, targetUserID); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > 100 {
+		return nil, errors.New("token name must be between 1 and 100 characters")
+	}
+
+	if len(req.Scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		if !slices.Contains(domain.ValidTokenScopes, scope) {
+			return nil, fmt.Errorf("invalid scope: %s", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxTokenLifetimeDays {
+		return nil, fmt.Errorf("expires_in_days must be between 0 and %d", maxTokenLifetimeDays)
+	}
+
+	secret, err := pkg.RandomToken(32)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	raw := pkg.PersonalAccessTokenPrefix + secret
+
+	token := &domain.PersonalAccessToken{
+		UserID:    targetUserID,
+		CreatedBy: actorID,
+		Name:      name,
+		Prefix:    raw[:len(pkg.PersonalAccessTokenPrefix)+6],
+		TokenHash: pkg.HashToken(raw),
+		Scopes:    strings.Join(scopes, ","),
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.tokenRepo.CreateToken(ctx, token); err != nil {
+		return nil, errors.New("failed to create token")
+	}
+
+	response := token.ToResponse()
+	response.Token = raw
+	return response, nil
+}
+
+func (s *TokenService) ListTokens(ctx context.Context, actorID, targetUserID uint) ([]*domain.PersonalAccessTokenResponse, error) {
+	ctx, span := pkg.StartSpan(ctx, "TokenService.ListTokens")
+	defer span.End()
+
+	if err := s.authorizeTarget(ctx, actorID, targetUserID); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.tokenRepo.GetUserTokens(ctx, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*domain.PersonalAccessTokenResponse, len(tokens))
+	for i, token := range tokens {
+		responses[i] = token.ToResponse()
+	}
+	return responses, nil
+}
+
+func (s *TokenService) RevokeToken(ctx context.Context, actorID, targetUserID, tokenID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "TokenService.RevokeToken")
+	defer span.End()
+
+	if err := s.authorizeTarget(ctx, actorID, targetUserID); err != nil {
+		return err
+	}
+
+	if err := s.tokenRepo.RevokeToken(ctx, targetUserID, tokenID); err != nil {
+		return errors.New("token not found")
+	}
+	return nil
+}
+
+// AuthenticateToken resolves a raw personal access token to its user and
+// granted scopes, recording when and from where it was last used.
+func (s *TokenService) AuthenticateToken(ctx context.Context, raw, clientIP string) (*domain.User, []string, error) {
+	ctx, span := pkg.StartSpan(ctx, "TokenService.AuthenticateToken")
+	defer span.End()
+
+	token, err := s.tokenRepo.FindTokenByHash(ctx, pkg.HashToken(raw))
+	if err != nil {
+		return nil, nil, errors.New("invalid token")
+	}
+
+	now := time.Now()
+	if !token.IsActive(now) {
+		return nil, nil, errors.New("token expired or revoked")
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		return nil, nil, errors.New("user not found")
+	}
+
+	// Bots act for their owner, so they lose access along with them.
+	if user.OwnerID != nil {
+		owner, err := s.userRepo.GetUserByID(ctx, *user.OwnerID)
+		if err != nil || owner.AnonymizedAt != nil || owner.DeletionRequestedAt != nil {
+			return nil, nil, errors.New("bot owner not found")
+		}
+		if owner.IsSuspended() {
+			return nil, nil, errors.New("bot owner is suspended")
+		}
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > tokenTouchInterval || token.LastUsedIP != clientIP {
+		if err := s.tokenRepo.TouchToken(ctx, token.ID, now, clientIP); err != nil {
+			pkg.WarnContext(ctx, "Failed to record token usage", map[string]interface{}{
+				"token_id": token.ID,
+				"error":    err.Error(),
+			})
+		}
+	}
+
+	return user, token.ScopeList(), nil
+}
+
+func (s *TokenService) CreateBot(ctx context.Context, ownerID uint, name string) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "TokenService.CreateBot")
+	defer span.End()
+
+	owner, err := s.userRepo.GetUserByID(ctx, ownerID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if owner.IsBot {
+		return nil, errors.New("bots cannot create other bots")
+	}
+
+	name = strings.TrimSpace(name)
+	if len(name) < 2 || len(name) > 50 {
+		return nil, errors.New("bot name must be between 2 and 50 characters")
+	}
+
+	handle, err := pkg.RandomToken(9)
+	if err != nil {
+		return nil, errors.New("failed to create bot")
+	}
+
+	bot := &domain.User{
+		Name:    name,
+		Email:   fmt.Sprintf("bot-%s@bots.go-chat.local", strings.ToLower(handle)),
+		IsBot:   true,
+		OwnerID: &ownerID,
+	}
+	if err := s.userRepo.Create(ctx, bot); err != nil {
+		return nil, errors.New("failed to create bot")
+	}
+
+	return bot, nil
+}
+
+func (s *TokenService) ListBots(ctx context.Context, ownerID uint) ([]*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "TokenService.ListBots")
+	defer span.End()
+
+	return s.userRepo.GetBotsByOwner(ctx, ownerID)
+}
+
+func (s *TokenService) DeleteBot(ctx context.Context, ownerID, botID uint) error {
+	ctx, span := pkg.StartSpan(ctx, "TokenService.DeleteBot")
+	defer span.End()
+
+	if err := s.authorizeTarget(ctx, ownerID, botID); err != nil {
+		return err
+	}
+	if ownerID == botID {
+		return errors.New("not a bot")
+	}
+
+	if err := s.tokenRepo.RevokeUserTokens(ctx, botID); err != nil {
+		return errors.New("failed to revoke bot tokens")
+	}
+
+	return s.userRepo.DeleteUser(ctx, botID)
+}
+
+func (s *TokenService) authorizeTarget(ctx context.Context, actorID, targetUserID uint) error {
+	if actorID == targetUserID {
+		return nil
+	}
+
+	target, err := s.userRepo.GetUserByID(ctx, targetUserID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+
+	if !target.IsBot || target.OwnerID == nil || *target.OwnerID != actorID {
+		return errors.New("unauthorized: you do not own this bot")
+	}
+
+	return nil
+}
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"go-chat/internal/domain"
 	"go-chat/internal/ports/repository"
+	"go-chat/pkg"
 )
 
 type UserService struct {
@@ -13,31 +17,106 @@ func NewUserService(repo repository.UserRepository) *UserService {
 	return &UserService{repo: repo}
 }
 
-func (s *UserService) Signup(name, email, hashedPassword string) error {
+func (s *UserService) Signup(ctx context.Context, name, email, hashedPassword string) error {
+	ctx, span := pkg.StartSpan(ctx, "UserService.Signup")
+	defer span.End()
+
 	user := &domain.User{
 		Name:     name,
 		Email:    email,
 		Password: hashedPassword,
 	}
-	return s.repo.Create(user)
+	return s.repo.Create(ctx, user)
 }
 
-func (s *UserService) Login(email string) (*domain.User, error) {
-	return s.repo.FindByEmail(email)
+func (s *UserService) Login(ctx context.Context, email string) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "UserService.Login")
+	defer span.End()
+
+	return s.repo.FindByEmail(ctx, email)
 }
 
-func (s *UserService) GetUserByID(id uint) (*domain.User, error) {
-	return s.repo.GetUserByID(id)
+func (s *UserService) GetUserByID(ctx context.Context, id uint) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "UserService.GetUserByID")
+	defer span.End()
+
+	return s.repo.GetUserByID(ctx, id)
 }
 
-func (s *UserService) UpdatePasswordByID(id uint, password string) (*domain.User, error) {
-	return s.repo.UpdatePassword(id, password)
+func (s *UserService) UpdatePasswordByID(ctx context.Context, id uint, password string) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "UserService.UpdatePasswordByID")
+	defer span.End()
+
+	return s.repo.UpdatePassword(ctx, id, password)
 }
 
-func (s *UserService) UpdateProfile(userID uint, name, email string) (*domain.User, error) {
-	return s.repo.UpdateUserProfile(userID, name, email)
+func (s *UserService) UpdateProfile(ctx context.Context, userID uint, name, email string) (*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "UserService.UpdateProfile")
+	defer span.End()
+
+	return s.repo.UpdateUserProfile(ctx, userID, name, email)
 }
 
-func (s *UserService) SearchUsers(query string, userID uint) ([]*domain.User, error) {
-	return s.repo.SearchUsers(query, userID)
+// UpdateSettings applies the settings present in the update and returns the
+// resulting settings.
+func (s *UserService) UpdateSettings(ctx context.Context, userID uint, update *domain.UserSettings) (*domain.UserSettings, error) {
+	ctx, span := pkg.StartSpan(ctx, "UserService.UpdateSettings")
+	defer span.End()
+
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+
+	if update.ReadReceiptsDisabled != nil {
+		if err := s.repo.SetReadReceiptsDisabled(ctx, userID, *update.ReadReceiptsDisabled); err != nil {
+			return nil, err
+		}
+	}
+	if update.Notifications != nil {
+		preferences := *update.Notifications
+		preferences.Level = preferences.EffectiveLevel()
+		if preferences.Timezone == "" {
+			preferences.Timezone = "UTC"
+		}
+		if err := s.repo.SetNotificationPreferences(ctx, userID, preferences); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return user.Settings(), nil
+}
+
+func (s *UserService) SearchUsers(ctx context.Context, query string, userID uint) ([]*domain.User, error) {
+	ctx, span := pkg.StartSpan(ctx, "UserService.SearchUsers")
+	defer span.End()
+
+	return s.repo.SearchUsers(ctx, query, userID)
+}
+
+// EnsureAdmins promotes the given existing accounts to admin so a fresh
+// deployment has someone who can reach the admin API. Accounts that do not
+// exist yet are skipped; failed promotions are returned.
+func (s *UserService) EnsureAdmins(ctx context.Context, emails []string) error {
+	ctx, span := pkg.StartSpan(ctx, "UserService.EnsureAdmins")
+	defer span.End()
+
+	var errs []error
+	for _, email := range emails {
+		user, err := s.repo.FindByEmail(ctx, email)
+		if err != nil {
+			pkg.WarnContext(ctx, "Bootstrap admin account not found", map[string]interface{}{"email": email})
+			continue
+		}
+		if user.EffectiveRole() == domain.RoleAdmin {
+			continue
+		}
+		if err := s.repo.UpdateUserRole(ctx, user.ID, domain.RoleAdmin); err != nil {
+			errs = append(errs, fmt.Errorf("promote bootstrap admin %s: %w", email, err))
+		}
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS update_messages_updated_at ON messages;
+DROP TABLE IF EXISTS messages;
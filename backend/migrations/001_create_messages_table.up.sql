@@ -32,5 +32,6 @@ BEGIN
 END;
 $$ language 'plpgsql';
 
+DROP TRIGGER IF EXISTS update_messages_updated_at ON messages;
 CREATE TRIGGER update_messages_updated_at BEFORE UPDATE ON messages 
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column(); 
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS update_friendships_updated_at ON friendships;
+DROP TABLE IF EXISTS friendships;
@@ -14,13 +14,13 @@ CREATE TABLE IF NOT EXISTS friendships (
     -- Ensure valid status values
     CONSTRAINT friendships_status_check CHECK (status IN ('pending', 'accepted', 'rejected', 'blocked')),
     
-    -- Prevent duplicate friendship requests between same users
-    CONSTRAINT friendships_unique_pair UNIQUE (requester_id, addressee_id),
-    
     -- Prevent self-friendship
     CONSTRAINT friendships_no_self_friend CHECK (requester_id != addressee_id)
 );
 
+-- Databases created by AutoMigrate had no soft-delete column
+ALTER TABLE friendships ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
 -- Create indexes for better performance
 CREATE INDEX IF NOT EXISTS idx_friendships_requester_id ON friendships(requester_id);
 CREATE INDEX IF NOT EXISTS idx_friendships_addressee_id ON friendships(addressee_id);
@@ -28,9 +28,14 @@ CREATE INDEX IF NOT EXISTS idx_friendships_status ON friendships(status);
 CREATE INDEX IF NOT EXISTS idx_friendships_user_pair ON friendships(requester_id, addressee_id);
 CREATE INDEX IF NOT EXISTS idx_friendships_deleted_at ON friendships(deleted_at);
 
+-- Prevent duplicate friendship requests between same users; removed
+-- friendships are soft-deleted, so only live rows take part
+CREATE UNIQUE INDEX IF NOT EXISTS idx_friendships_unique_pair ON friendships(requester_id, addressee_id) WHERE deleted_at IS NULL;
+
 -- Index for finding all friendships for a user (both directions)
 CREATE INDEX IF NOT EXISTS idx_friendships_user_relationships ON friendships(requester_id, addressee_id, status);
 
 -- Add trigger to update updated_at timestamp
+DROP TRIGGER IF EXISTS update_friendships_updated_at ON friendships;
 CREATE TRIGGER update_friendships_updated_at BEFORE UPDATE ON friendships 
     FOR EACH ROW EXECUTE FUNCTION update_updated_at_column(); 
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+DROP TABLE IF EXISTS audit_logs;
+DROP FUNCTION IF EXISTS prevent_audit_log_modification();
@@ -0,0 +1,24 @@
+-- Create per-user conversation summaries; fill them for existing data with
+-- `go-chat backfill conversations`
+CREATE TABLE IF NOT EXISTS conversation_summaries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    peer_id INTEGER NOT NULL,
+    last_message_id INTEGER,
+    last_activity_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    unread_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (peer_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (last_message_id) REFERENCES messages(id) ON DELETE SET NULL
+);
+
+-- Create indexes for better performance
+CREATE UNIQUE INDEX IF NOT EXISTS idx_conversation_summaries_user_peer ON conversation_summaries(user_id, peer_id);
+CREATE INDEX IF NOT EXISTS idx_conversation_summaries_user_activity ON conversation_summaries(user_id, last_activity_at DESC, id DESC);
+
+DROP TRIGGER IF EXISTS update_conversation_summaries_updated_at ON conversation_summaries;
+CREATE TRIGGER update_conversation_summaries_updated_at BEFORE UPDATE ON conversation_summaries
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,9 @@
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS is_read BOOLEAN DEFAULT FALSE;
+
+UPDATE messages SET is_read = EXISTS (SELECT 1 FROM conversation_summaries cs
+    WHERE cs.user_id = messages.receiver_id
+        AND cs.peer_id = messages.sender_id
+        AND messages.id <= cs.last_read_message_id);
+
+ALTER TABLE conversation_summaries DROP COLUMN IF EXISTS last_read_at;
+ALTER TABLE conversation_summaries DROP COLUMN IF EXISTS last_read_message_id;
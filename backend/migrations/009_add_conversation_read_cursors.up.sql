@@ -0,0 +1,46 @@
+-- Replace per-message read flags with per-conversation read cursors
+ALTER TABLE conversation_summaries ADD COLUMN IF NOT EXISTS last_read_message_id INTEGER;
+ALTER TABLE conversation_summaries ADD COLUMN IF NOT EXISTS last_read_at TIMESTAMP WITH TIME ZONE;
+
+-- Summarise conversations that predate summaries so no read state is lost
+INSERT INTO conversation_summaries (user_id, peer_id, last_message_id, last_activity_at, unread_count, created_at, updated_at)
+SELECT
+    pairs.user_id,
+    pairs.peer_id,
+    (SELECT m.id FROM messages m
+        WHERE m.deleted_at IS NULL
+            AND ((m.sender_id = pairs.user_id AND m.receiver_id = pairs.peer_id)
+                OR (m.sender_id = pairs.peer_id AND m.receiver_id = pairs.user_id))
+        ORDER BY m.created_at DESC, m.id DESC
+        LIMIT 1),
+    MAX(pairs.created_at),
+    0,
+    NOW(),
+    NOW()
+FROM (
+    SELECT sender_id AS user_id, receiver_id AS peer_id, created_at FROM messages WHERE deleted_at IS NULL
+    UNION ALL
+    SELECT receiver_id AS user_id, sender_id AS peer_id, created_at FROM messages WHERE deleted_at IS NULL
+) pairs
+GROUP BY pairs.user_id, pairs.peer_id
+ON CONFLICT (user_id, peer_id) DO NOTHING;
+
+-- Start each cursor at the newest message the user had read
+UPDATE conversation_summaries SET
+    last_read_message_id = (SELECT MAX(m.id) FROM messages m
+        WHERE m.sender_id = conversation_summaries.peer_id
+            AND m.receiver_id = conversation_summaries.user_id
+            AND m.is_read = TRUE),
+    last_read_at = (SELECT MAX(m.updated_at) FROM messages m
+        WHERE m.sender_id = conversation_summaries.peer_id
+            AND m.receiver_id = conversation_summaries.user_id
+            AND m.is_read = TRUE);
+
+UPDATE conversation_summaries SET
+    unread_count = (SELECT COUNT(*) FROM messages m
+        WHERE m.deleted_at IS NULL
+            AND m.sender_id = conversation_summaries.peer_id
+            AND m.receiver_id = conversation_summaries.user_id
+            AND m.id > COALESCE(conversation_summaries.last_read_message_id, 0));
+
+ALTER TABLE messages DROP COLUMN IF EXISTS is_read;
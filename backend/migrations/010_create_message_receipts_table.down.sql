@@ -0,0 +1,11 @@
+ALTER TABLE users DROP COLUMN IF EXISTS read_receipts_disabled;
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS is_delivered BOOLEAN DEFAULT FALSE;
+
+UPDATE messages SET is_delivered = EXISTS (SELECT 1 FROM message_receipts r
+    WHERE r.message_id = messages.id
+        AND r.user_id = messages.receiver_id
+        AND r.delivered_at IS NOT NULL);
+
+DROP TRIGGER IF EXISTS update_message_receipts_updated_at ON message_receipts;
+DROP TABLE IF EXISTS message_receipts;
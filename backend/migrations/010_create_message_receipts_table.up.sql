@@ -0,0 +1,37 @@
+-- Per-recipient delivery and read receipts, replacing messages.is_delivered
+CREATE TABLE IF NOT EXISTS message_receipts (
+    id SERIAL PRIMARY KEY,
+    message_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_message_receipts_message_user ON message_receipts(message_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_message_receipts_user_id ON message_receipts(user_id);
+
+DROP TRIGGER IF EXISTS update_message_receipts_updated_at ON message_receipts;
+CREATE TRIGGER update_message_receipts_updated_at BEFORE UPDATE ON message_receipts
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Existing messages get a receipt for their receiver; the old flags and read
+-- cursors only say whether, so their times are the best available guess
+INSERT INTO message_receipts (message_id, user_id, delivered_at, read_at, created_at, updated_at)
+SELECT
+    m.id,
+    m.receiver_id,
+    CASE WHEN m.is_delivered OR m.id <= cs.last_read_message_id THEN m.updated_at END,
+    CASE WHEN m.id <= cs.last_read_message_id THEN cs.last_read_at END,
+    m.created_at,
+    m.created_at
+FROM messages m
+LEFT JOIN conversation_summaries cs ON cs.user_id = m.receiver_id AND cs.peer_id = m.sender_id;
+
+ALTER TABLE messages DROP COLUMN IF EXISTS is_delivered;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS read_receipts_disabled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,13 @@
+-- Messages a participant deleted for themselves only
+CREATE TABLE IF NOT EXISTS hidden_messages (
+    id SERIAL PRIMARY KEY,
+    message_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_hidden_messages_message_user ON hidden_messages(message_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_hidden_messages_user_id ON hidden_messages(user_id);
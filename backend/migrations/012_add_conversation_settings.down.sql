@@ -0,0 +1,3 @@
+ALTER TABLE conversation_summaries DROP COLUMN IF EXISTS marked_unread;
+ALTER TABLE conversation_summaries DROP COLUMN IF EXISTS pinned_at;
+ALTER TABLE conversation_summaries DROP COLUMN IF EXISTS archived_at;
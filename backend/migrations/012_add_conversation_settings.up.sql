@@ -0,0 +1,4 @@
+-- Per-user archive, pin and mark-as-unread state of conversations
+ALTER TABLE conversation_summaries ADD COLUMN IF NOT EXISTS archived_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE conversation_summaries ADD COLUMN IF NOT EXISTS pinned_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE conversation_summaries ADD COLUMN IF NOT EXISTS marked_unread BOOLEAN NOT NULL DEFAULT FALSE;
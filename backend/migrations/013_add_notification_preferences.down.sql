@@ -0,0 +1,7 @@
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_hours_end;
+ALTER TABLE users DROP COLUMN IF EXISTS quiet_hours_start;
+ALTER TABLE users DROP COLUMN IF EXISTS notification_level;
+
+ALTER TABLE conversation_summaries DROP COLUMN IF EXISTS muted_until;
+ALTER TABLE conversation_summaries DROP COLUMN IF EXISTS muted_at;
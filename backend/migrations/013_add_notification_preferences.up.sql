@@ -0,0 +1,8 @@
+-- Per-conversation mutes and per-user notification preferences
+ALTER TABLE conversation_summaries ADD COLUMN IF NOT EXISTS muted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE conversation_summaries ADD COLUMN IF NOT EXISTS muted_until TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS notification_level VARCHAR(20) NOT NULL DEFAULT 'all';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_hours_start VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS quiet_hours_end VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,25 @@
+-- Disappearing messages: a timer shared by both participants of a
+-- conversation, and the time each message sent under it is purged
+CREATE TABLE IF NOT EXISTS conversation_timers (
+    id SERIAL PRIMARY KEY,
+    user_low_id INTEGER NOT NULL,
+    user_high_id INTEGER NOT NULL,
+    disappear_after_seconds INTEGER NOT NULL DEFAULT 0,
+    set_by_id INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    FOREIGN KEY (user_low_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_high_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (set_by_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (user_low_id < user_high_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_conversation_timers_users ON conversation_timers(user_low_id, user_high_id);
+
+DROP TRIGGER IF EXISTS update_conversation_timers_updated_at ON conversation_timers;
+CREATE TRIGGER update_conversation_timers_updated_at BEFORE UPDATE ON conversation_timers
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX IF NOT EXISTS idx_messages_expires_at ON messages(expires_at);
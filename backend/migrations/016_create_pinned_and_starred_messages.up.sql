@@ -0,0 +1,26 @@
+-- Messages pinned to the top of a conversation for both participants
+CREATE TABLE IF NOT EXISTS pinned_messages (
+    id SERIAL PRIMARY KEY,
+    message_id INTEGER NOT NULL,
+    pinned_by_id INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    FOREIGN KEY (pinned_by_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_pinned_messages_message_id ON pinned_messages(message_id);
+
+-- Messages a participant starred for themselves
+CREATE TABLE IF NOT EXISTS starred_messages (
+    id SERIAL PRIMARY KEY,
+    message_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_starred_messages_message_user ON starred_messages(message_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_starred_messages_user_id ON starred_messages(user_id);
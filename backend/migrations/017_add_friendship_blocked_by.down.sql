@@ -0,0 +1 @@
+ALTER TABLE friendships DROP COLUMN IF EXISTS blocked_by_id;
@@ -0,0 +1,7 @@
+-- Record who blocked whom: blocking an existing friendship keeps its
+-- original requester
+ALTER TABLE friendships ADD COLUMN IF NOT EXISTS blocked_by_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+
+-- Rows blocked before this column existed were only known to be blocked by
+-- their requester
+UPDATE friendships SET blocked_by_id = requester_id WHERE status = 'blocked' AND blocked_by_id IS NULL;
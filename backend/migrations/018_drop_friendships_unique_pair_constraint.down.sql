@@ -0,0 +1,2 @@
+-- Nothing to restore: the constraint was never part of the versioned schema,
+-- and idx_friendships_unique_pair still prevents duplicate live pairs.
@@ -0,0 +1,5 @@
+-- Databases created before the versioned migrations carry the original
+-- UNIQUE constraint on (requester_id, addressee_id). It also covers
+-- soft-deleted rows, so a declined or removed pair could never request
+-- again; idx_friendships_unique_pair only covers live rows and replaces it.
+ALTER TABLE friendships DROP CONSTRAINT IF EXISTS friendships_unique_pair;
@@ -0,0 +1,425 @@
+// Package migrations embeds the versioned SQL schema and applies it.
+//
+// Files are named NNN_description.up.sql with a matching
+// NNN_description.down.sql, and versions run without gaps. Applied versions
+// are recorded in the schema_migrations table together with a checksum of
+// the up script, so an edited migration is detected instead of silently
+// diverging.
+//
+// The top-level scripts target Postgres. SQLite databases use the scripts in
+// the sqlite directory instead, which keep the same versions and names.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed *.sql sqlite/*.sql
+var files embed.FS
+
+// sqliteDir holds the SQLite variant of every migration.
+const sqliteDir = "sqlite"
+
+// advisoryLockKey serialises migration runs across replicas sharing a database.
+const advisoryLockKey = 7_426_000
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Modified  bool
+	Missing   bool
+}
+
+type appliedMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	Checksum  string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+func (appliedMigration) TableName() string {
+	return "schema_migrations"
+}
+
+type Runner struct {
+	db         *gorm.DB
+	migrations []*Migration
+}
+
+// NewRunner returns a runner for the migrations embedded in the binary,
+// choosing the script set that matches the database dialect.
+func NewRunner(db *gorm.DB) (*Runner, error) {
+	var fsys fs.FS = files
+	if db.Dialector.Name() == "sqlite" {
+		sub, err := fs.Sub(files, sqliteDir)
+		if err != nil {
+			return nil, err
+		}
+		fsys = sub
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Runner{db: db, migrations: migrations}, nil
+}
+
+// Load reads and orders every migration in fsys, rejecting duplicate
+// versions, gaps between versions and scripts without a rollback.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		key := fmt.Sprintf("%d.%s", version, match[3])
+		if prev, ok := seen[key]; ok {
+			return nil, fmt.Errorf("migration %d is defined twice, by %q and %q", version, prev, entry.Name())
+		}
+		seen[key] = entry.Name()
+
+		if match[3] == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i := 1; i < len(migrations); i++ {
+		if prev := migrations[i-1].Version; migrations[i].Version != prev+1 {
+			return nil, fmt.Errorf("migration versions skip from %d to %d", prev, migrations[i].Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in order and returns the ones applied.
+func (r *Runner) Up() ([]*Migration, error) {
+	var applied []*Migration
+
+	err := r.withLock(func(conn *gorm.DB) error {
+		done, err := r.verify(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range r.migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Up).Error; err != nil {
+					return err
+				}
+				return tx.Create(&appliedMigration{
+					Version:   m.Version,
+					Name:      m.Name,
+					Checksum:  m.Checksum,
+					AppliedAt: time.Now().UTC(),
+				}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down rolls back the most recently applied migrations, at most steps of them.
+func (r *Runner) Down(steps int) ([]*Migration, error) {
+	var reverted []*Migration
+
+	err := r.withLock(func(conn *gorm.DB) error {
+		done, err := r.verify(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(r.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := r.migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s has no down script", m.Version, m.Name)
+			}
+
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&appliedMigration{}, m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("rollback of %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists every known migration alongside what the database has
+// recorded, including applied versions whose files are gone.
+func (r *Runner) Status() ([]*MigrationStatus, error) {
+	if err := r.ensureTable(r.db); err != nil {
+		return nil, err
+	}
+
+	records, err := r.appliedMigrations(r.db)
+	if err != nil {
+		return nil, err
+	}
+	return r.statuses(records), nil
+}
+
+// StatusContext is Status for readiness probes: it runs under ctx and only
+// reads, reporting every migration pending while schema_migrations does not
+// exist yet instead of creating it.
+func (r *Runner) StatusContext(ctx context.Context) ([]*MigrationStatus, error) {
+	db := r.db.WithContext(ctx)
+
+	records := map[int64]appliedMigration{}
+	if db.Migrator().HasTable(&appliedMigration{}) {
+		var err error
+		if records, err = r.appliedMigrations(db); err != nil {
+			return nil, err
+		}
+	}
+	// HasTable reports a failed lookup as a missing table.
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return r.statuses(records), nil
+}
+
+// statuses merges the known migrations with the recorded ones.
+func (r *Runner) statuses(records map[int64]appliedMigration) []*MigrationStatus {
+	statuses := make([]*MigrationStatus, 0, len(r.migrations))
+	known := make(map[int64]bool, len(r.migrations))
+	for _, m := range r.migrations {
+		known[m.Version] = true
+		status := &MigrationStatus{Version: m.Version, Name: m.Name}
+		if rec, ok := records[m.Version]; ok {
+			appliedAt := rec.AppliedAt
+			status.Applied = true
+			status.AppliedAt = &appliedAt
+			status.Modified = rec.Checksum != m.Checksum
+		}
+		statuses = append(statuses, status)
+	}
+
+	for version, rec := range records {
+		if known[version] {
+			continue
+		}
+		appliedAt := rec.AppliedAt
+		statuses = append(statuses, &MigrationStatus{
+			Version:   version,
+			Name:      rec.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	return statuses
+}
+
+// Create writes an empty up/down pair into dir using the next free version,
+// plus a matching pair in its sqlite directory when there is one.
+func Create(dir, name string) ([]string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return nil, fmt.Errorf("migration name is required")
+	}
+
+	dirs := []string{dir}
+	if info, err := os.Stat(filepath.Join(dir, sqliteDir)); err == nil && info.IsDir() {
+		dirs = append(dirs, filepath.Join(dir, sqliteDir))
+	}
+
+	var next int64
+	for _, d := range dirs {
+		existing, err := Load(os.DirFS(d))
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 && existing[len(existing)-1].Version >= next {
+			next = existing[len(existing)-1].Version + 1
+		}
+	}
+
+	base := fmt.Sprintf("%03d_%s", next, name)
+	contents := map[string]string{
+		".up.sql":   fmt.Sprintf("-- %s\n", strings.ReplaceAll(name, "_", " ")),
+		".down.sql": fmt.Sprintf("-- Revert %s\n", strings.ReplaceAll(name, "_", " ")),
+	}
+
+	var paths []string
+	for _, d := range dirs {
+		for _, suffix := range []string{".up.sql", ".down.sql"} {
+			path := filepath.Join(d, base+suffix)
+			if err := os.WriteFile(path, []byte(contents[suffix]), 0o644); err != nil {
+				return nil, err
+			}
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
+
+// withLock runs fn on a single pooled connection holding a session-level
+// advisory lock, so only one replica migrates at a time.
+func (r *Runner) withLock(fn func(conn *gorm.DB) error) error {
+	if r.db.Dialector.Name() != "postgres" {
+		if err := r.ensureTable(r.db); err != nil {
+			return err
+		}
+		return fn(r.db)
+	}
+
+	return r.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", advisoryLockKey).Error; err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", advisoryLockKey)
+
+		if err := r.ensureTable(conn); err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+func (r *Runner) ensureTable(db *gorm.DB) error {
+	// SQLite drivers only decode timestamps declared with a bare type name.
+	timestampType := "TIMESTAMP WITH TIME ZONE"
+	if db.Dialector.Name() == "sqlite" {
+		timestampType = "DATETIME"
+	}
+	return db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    applied_at ` + timestampType + ` NOT NULL
+)`).Error
+}
+
+func (r *Runner) appliedMigrations(db *gorm.DB) (map[int64]appliedMigration, error) {
+	var records []appliedMigration
+	if err := db.Order("version ASC").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]appliedMigration, len(records))
+	for _, rec := range records {
+		byVersion[rec.Version] = rec
+	}
+	return byVersion, nil
+}
+
+// verify refuses to run when an applied migration was edited or deleted,
+// since the recorded schema no longer matches the files in the binary.
+func (r *Runner) verify(db *gorm.DB) (map[int64]appliedMigration, error) {
+	records, err := r.appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]*Migration, len(r.migrations))
+	for _, m := range r.migrations {
+		known[m.Version] = m
+	}
+
+	for version, rec := range records {
+		m, ok := known[version]
+		if !ok {
+			return nil, fmt.Errorf("applied migration %d_%s is missing from this build", version, rec.Name)
+		}
+		if rec.Checksum != m.Checksum {
+			return nil, fmt.Errorf("migration %d_%s was modified after it was applied", version, m.Name)
+		}
+	}
+
+	return records, nil
+}
@@ -0,0 +1,330 @@
+package migrations
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func script(sql string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(sql)}
+}
+
+// testScripts is a two-step schema used in place of the embedded one.
+func testScripts() fstest.MapFS {
+	return fstest.MapFS{
+		"000_create_widgets.up.sql":    script("CREATE TABLE widgets (id INTEGER PRIMARY KEY);"),
+		"000_create_widgets.down.sql":  script("DROP TABLE widgets;"),
+		"001_add_widget_name.up.sql":   script("ALTER TABLE widgets ADD COLUMN name TEXT;"),
+		"001_add_widget_name.down.sql": script("ALTER TABLE widgets DROP COLUMN name;"),
+		"notes/ignored_directory.sql":  script("not a migration"),
+	}
+}
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func newTestRunner(t *testing.T, db *gorm.DB, fsys fstest.MapFS) *Runner {
+	t.Helper()
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return &Runner{db: db, migrations: migrations}
+}
+
+func versions(migrations []*Migration) []int64 {
+	out := make([]int64, 0, len(migrations))
+	for _, m := range migrations {
+		out = append(out, m.Version)
+	}
+	return out
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testScripts())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := versions(migrations); len(got) != 2 || got[0] != 0 || got[1] != 1 {
+		t.Fatalf("versions = %v, want [0 1]", got)
+	}
+	if m := migrations[1]; m.Name != "add_widget_name" || m.Down == "" || len(m.Checksum) != 64 {
+		t.Errorf("migration 1 = %+v", m)
+	}
+}
+
+func TestLoadRejectsInvalidScripts(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(fsys fstest.MapFS)
+		wantErr string
+	}{
+		{
+			name: "gap between versions",
+			edit: func(fsys fstest.MapFS) {
+				fsys["003_add_widget_size.up.sql"] = script("ALTER TABLE widgets ADD COLUMN size INTEGER;")
+				fsys["003_add_widget_size.down.sql"] = script("ALTER TABLE widgets DROP COLUMN size;")
+			},
+			wantErr: "migration versions skip from 1 to 3",
+		},
+		{
+			name: "duplicate version with another name",
+			edit: func(fsys fstest.MapFS) {
+				fsys["001_add_widget_size.up.sql"] = script("ALTER TABLE widgets ADD COLUMN size INTEGER;")
+			},
+			wantErr: `migration 1 has conflicting names "add_widget_name" and "add_widget_size"`,
+		},
+		{
+			name: "duplicate version with another prefix",
+			edit: func(fsys fstest.MapFS) {
+				fsys["1_add_widget_name.up.sql"] = script("ALTER TABLE widgets ADD COLUMN name TEXT;")
+			},
+			wantErr: `migration 1 is defined twice, by "001_add_widget_name.up.sql" and "1_add_widget_name.up.sql"`,
+		},
+		{
+			name: "missing down script",
+			edit: func(fsys fstest.MapFS) {
+				delete(fsys, "001_add_widget_name.down.sql")
+			},
+			wantErr: "migration 1_add_widget_name has no down script",
+		},
+		{
+			name: "missing up script",
+			edit: func(fsys fstest.MapFS) {
+				delete(fsys, "001_add_widget_name.up.sql")
+			},
+			wantErr: "migration 1_add_widget_name has no up script",
+		},
+		{
+			name: "invalid file name",
+			edit: func(fsys fstest.MapFS) {
+				fsys["002-add-widget-size.sql"] = script("")
+			},
+			wantErr: `invalid migration file name "002-add-widget-size.sql"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fsys := testScripts()
+			tc.edit(fsys)
+			if _, err := Load(fsys); err == nil || err.Error() != tc.wantErr {
+				t.Fatalf("Load error = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	db := openTestDB(t)
+	runner, err := NewRunner(db)
+	if err != nil {
+		t.Fatalf("NewRunner: %v", err)
+	}
+	if _, err := runner.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if _, err := runner.Down(len(runner.migrations)); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	db := openTestDB(t)
+	runner := newTestRunner(t, db, testScripts())
+
+	applied, err := runner.Up()
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if got := versions(applied); len(got) != 2 {
+		t.Fatalf("applied = %v, want both migrations", got)
+	}
+	if !db.Migrator().HasColumn("widgets", "name") {
+		t.Fatal("widgets.name was not created")
+	}
+	if applied, err := runner.Up(); err != nil || len(applied) != 0 {
+		t.Fatalf("second Up = %v, %v, want nothing to apply", versions(applied), err)
+	}
+
+	reverted, err := runner.Down(1)
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if got := versions(reverted); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("reverted = %v, want [1]", got)
+	}
+	if db.Migrator().HasColumn("widgets", "name") || !db.Migrator().HasTable("widgets") {
+		t.Fatal("Down(1) did not revert only the newest migration")
+	}
+
+	statuses, err := runner.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != 2 || !statuses[0].Applied || statuses[1].Applied {
+		t.Fatalf("status after Down(1) = %+v, %+v", statuses[0], statuses[1])
+	}
+
+	// Asking for more steps than were applied stops at the first migration.
+	reverted, err = runner.Down(5)
+	if err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+	if got := versions(reverted); len(got) != 1 || got[0] != 0 {
+		t.Fatalf("reverted = %v, want [0]", got)
+	}
+	if db.Migrator().HasTable("widgets") {
+		t.Fatal("widgets table survived a full rollback")
+	}
+}
+
+func TestModifiedMigrationIsRejected(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := newTestRunner(t, db, testScripts()).Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	edited := testScripts()
+	edited["001_add_widget_name.up.sql"] = script("ALTER TABLE widgets ADD COLUMN title TEXT;")
+	runner := newTestRunner(t, db, edited)
+
+	const wantErr = "migration 1_add_widget_name was modified after it was applied"
+	if _, err := runner.Up(); err == nil || err.Error() != wantErr {
+		t.Errorf("Up error = %v, want %q", err, wantErr)
+	}
+	if _, err := runner.Down(1); err == nil || err.Error() != wantErr {
+		t.Errorf("Down error = %v, want %q", err, wantErr)
+	}
+
+	statuses, err := runner.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if statuses[0].Modified || !statuses[1].Modified {
+		t.Errorf("Modified = %v, %v, want only migration 1", statuses[0].Modified, statuses[1].Modified)
+	}
+}
+
+func TestMissingMigrationIsRejected(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := newTestRunner(t, db, testScripts()).Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	older := testScripts()
+	delete(older, "001_add_widget_name.up.sql")
+	delete(older, "001_add_widget_name.down.sql")
+	runner := newTestRunner(t, db, older)
+
+	const wantErr = "applied migration 1_add_widget_name is missing from this build"
+	if _, err := runner.Up(); err == nil || err.Error() != wantErr {
+		t.Errorf("Up error = %v, want %q", err, wantErr)
+	}
+
+	statuses, err := runner.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if len(statuses) != 2 || !statuses[1].Missing {
+		t.Errorf("statuses = %+v, want migration 1 reported missing", statuses)
+	}
+}
+
+func TestStatusContextIsReadOnly(t *testing.T) {
+	db := openTestDB(t)
+	runner := newTestRunner(t, db, testScripts())
+
+	statuses, err := runner.StatusContext(context.Background())
+	if err != nil {
+		t.Fatalf("StatusContext: %v", err)
+	}
+	if len(statuses) != 2 || statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("statuses = %+v, %+v, want both pending", statuses[0], statuses[1])
+	}
+	if db.Migrator().HasTable("schema_migrations") {
+		t.Error("StatusContext created schema_migrations")
+	}
+
+	if _, err := runner.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	statuses, err = runner.StatusContext(context.Background())
+	if err != nil {
+		t.Fatalf("StatusContext: %v", err)
+	}
+	if !statuses[0].Applied || !statuses[1].Applied {
+		t.Errorf("statuses after Up = %+v, %+v, want both applied", statuses[0], statuses[1])
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := runner.StatusContext(ctx); err == nil {
+		t.Error("StatusContext with a cancelled context succeeded")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, sqliteDir), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, file := range testScripts() {
+		if strings.Contains(name, "/") {
+			continue
+		}
+		for _, d := range []string{dir, filepath.Join(dir, sqliteDir)} {
+			if err := os.WriteFile(filepath.Join(d, name), file.Data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	paths, err := Create(dir, "  Add Widget Colour! ")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "002_add_widget_colour.up.sql"),
+		filepath.Join(dir, "002_add_widget_colour.down.sql"),
+		filepath.Join(dir, sqliteDir, "002_add_widget_colour.up.sql"),
+		filepath.Join(dir, sqliteDir, "002_add_widget_colour.down.sql"),
+	}
+	if strings.Join(paths, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("paths = %v, want %v", paths, want)
+	}
+
+	// Both sets must still load, so the new pair needs no renumbering.
+	for _, d := range []string{dir, filepath.Join(dir, sqliteDir)} {
+		migrations, err := Load(os.DirFS(d))
+		if err != nil {
+			t.Fatalf("Load(%s): %v", d, err)
+		}
+		if last := migrations[len(migrations)-1]; last.Version != 2 || last.Name != "add_widget_colour" {
+			t.Errorf("newest migration in %s = %d_%s", d, last.Version, last.Name)
+		}
+	}
+
+	if _, err := Create(dir, "!!!"); err == nil || err.Error() != "migration name is required" {
+		t.Errorf("Create with an empty name error = %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+-- Create friendships table
+CREATE TABLE IF NOT EXISTS friendships (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    requester_id INTEGER NOT NULL,
+    addressee_id INTEGER NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'pending',
+    created_at DATETIME,
+    updated_at DATETIME,
+    deleted_at DATETIME,
+
+    FOREIGN KEY (requester_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (addressee_id) REFERENCES users(id) ON DELETE CASCADE,
+
+    CONSTRAINT friendships_status_check CHECK (status IN ('pending', 'accepted', 'rejected', 'blocked')),
+    CONSTRAINT friendships_no_self_friend CHECK (requester_id != addressee_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_friendships_requester_id ON friendships(requester_id);
+CREATE INDEX IF NOT EXISTS idx_friendships_addressee_id ON friendships(addressee_id);
+CREATE INDEX IF NOT EXISTS idx_friendships_status ON friendships(status);
+CREATE INDEX IF NOT EXISTS idx_friendships_deleted_at ON friendships(deleted_at);
+
+-- Only live rows take part, as removed friendships are soft-deleted
+CREATE UNIQUE INDEX IF NOT EXISTS idx_friendships_unique_pair ON friendships(requester_id, addressee_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_friendships_user_relationships ON friendships(requester_id, addressee_id, status);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_users_deletion_requested_at;
+DROP INDEX IF EXISTS idx_users_owner_id;
+
+ALTER TABLE users DROP COLUMN anonymized_at;
+ALTER TABLE users DROP COLUMN deletion_requested_at;
+ALTER TABLE users DROP COLUMN password_reset_required;
+ALTER TABLE users DROP COLUMN suspension_reason;
+ALTER TABLE users DROP COLUMN suspended_at;
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE users DROP COLUMN owner_id;
+ALTER TABLE users DROP COLUMN is_bot;
@@ -0,0 +1,13 @@
+-- Bot accounts, roles, moderation and account deletion. SQLite cannot drop
+-- a column that takes part in a foreign key, so owner_id has none here.
+ALTER TABLE users ADD COLUMN is_bot BOOLEAN DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN owner_id INTEGER;
+ALTER TABLE users ADD COLUMN role VARCHAR(50) NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin'));
+ALTER TABLE users ADD COLUMN suspended_at DATETIME;
+ALTER TABLE users ADD COLUMN suspension_reason TEXT;
+ALTER TABLE users ADD COLUMN password_reset_required BOOLEAN DEFAULT FALSE;
+ALTER TABLE users ADD COLUMN deletion_requested_at DATETIME;
+ALTER TABLE users ADD COLUMN anonymized_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_users_owner_id ON users(owner_id);
+CREATE INDEX IF NOT EXISTS idx_users_deletion_requested_at ON users(deletion_requested_at);
@@ -0,0 +1,10 @@
+ALTER TABLE users DROP COLUMN read_receipts_disabled;
+
+ALTER TABLE messages ADD COLUMN is_delivered BOOLEAN DEFAULT FALSE;
+
+UPDATE messages SET is_delivered = EXISTS (SELECT 1 FROM message_receipts r
+    WHERE r.message_id = messages.id
+        AND r.user_id = messages.receiver_id
+        AND r.delivered_at IS NOT NULL);
+
+DROP TABLE IF EXISTS message_receipts;
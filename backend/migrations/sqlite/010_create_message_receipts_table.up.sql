@@ -0,0 +1,33 @@
+-- Per-recipient delivery and read receipts, replacing messages.is_delivered
+CREATE TABLE IF NOT EXISTS message_receipts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    delivered_at DATETIME,
+    read_at DATETIME,
+    created_at DATETIME,
+    updated_at DATETIME,
+
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_message_receipts_message_user ON message_receipts(message_id, user_id);
+CREATE INDEX IF NOT EXISTS idx_message_receipts_user_id ON message_receipts(user_id);
+
+-- Existing messages get a receipt for their receiver; the old flags and read
+-- cursors only say whether, so their times are the best available guess
+INSERT INTO message_receipts (message_id, user_id, delivered_at, read_at, created_at, updated_at)
+SELECT
+    m.id,
+    m.receiver_id,
+    CASE WHEN m.is_delivered OR m.id <= cs.last_read_message_id THEN m.updated_at END,
+    CASE WHEN m.id <= cs.last_read_message_id THEN cs.last_read_at END,
+    m.created_at,
+    m.created_at
+FROM messages m
+LEFT JOIN conversation_summaries cs ON cs.user_id = m.receiver_id AND cs.peer_id = m.sender_id;
+
+ALTER TABLE messages DROP COLUMN is_delivered;
+
+ALTER TABLE users ADD COLUMN read_receipts_disabled BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,3 @@
+ALTER TABLE conversation_summaries DROP COLUMN marked_unread;
+ALTER TABLE conversation_summaries DROP COLUMN pinned_at;
+ALTER TABLE conversation_summaries DROP COLUMN archived_at;
@@ -0,0 +1,4 @@
+-- Per-user archive, pin and mark-as-unread state of conversations
+ALTER TABLE conversation_summaries ADD COLUMN archived_at DATETIME;
+ALTER TABLE conversation_summaries ADD COLUMN pinned_at DATETIME;
+ALTER TABLE conversation_summaries ADD COLUMN marked_unread BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,7 @@
+ALTER TABLE users DROP COLUMN timezone;
+ALTER TABLE users DROP COLUMN quiet_hours_end;
+ALTER TABLE users DROP COLUMN quiet_hours_start;
+ALTER TABLE users DROP COLUMN notification_level;
+
+ALTER TABLE conversation_summaries DROP COLUMN muted_until;
+ALTER TABLE conversation_summaries DROP COLUMN muted_at;
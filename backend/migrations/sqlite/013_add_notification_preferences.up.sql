@@ -0,0 +1,8 @@
+-- Per-conversation mutes and per-user notification preferences
+ALTER TABLE conversation_summaries ADD COLUMN muted_at DATETIME;
+ALTER TABLE conversation_summaries ADD COLUMN muted_until DATETIME;
+
+ALTER TABLE users ADD COLUMN notification_level VARCHAR(20) NOT NULL DEFAULT 'all';
+ALTER TABLE users ADD COLUMN quiet_hours_start VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN quiet_hours_end VARCHAR(5) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_messages_expires_at;
+ALTER TABLE messages DROP COLUMN expires_at;
+DROP TABLE IF EXISTS conversation_timers;
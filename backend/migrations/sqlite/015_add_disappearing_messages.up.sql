@@ -0,0 +1,21 @@
+-- Disappearing messages: a timer shared by both participants of a
+-- conversation, and the time each message sent under it is purged
+CREATE TABLE IF NOT EXISTS conversation_timers (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_low_id INTEGER NOT NULL,
+    user_high_id INTEGER NOT NULL,
+    disappear_after_seconds INTEGER NOT NULL DEFAULT 0,
+    set_by_id INTEGER NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME,
+
+    FOREIGN KEY (user_low_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_high_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (set_by_id) REFERENCES users(id) ON DELETE CASCADE,
+    CHECK (user_low_id < user_high_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_conversation_timers_users ON conversation_timers(user_low_id, user_high_id);
+
+ALTER TABLE messages ADD COLUMN expires_at DATETIME;
+CREATE INDEX IF NOT EXISTS idx_messages_expires_at ON messages(expires_at);
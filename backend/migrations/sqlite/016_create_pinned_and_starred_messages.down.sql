@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS starred_messages;
+DROP TABLE IF EXISTS pinned_messages;
@@ -0,0 +1 @@
+ALTER TABLE friendships DROP COLUMN blocked_by_id;
@@ -0,0 +1,3 @@
+-- SQLite databases were always created by the versioned migrations, which
+-- never added the friendships_unique_pair constraint; kept so both script
+-- sets share the same versions.
@@ -2,25 +2,43 @@ package pkg
 
 import (
 	"net/http"
-	"os"
+	"strings"
+	"time"
 )
 
-func SetTokenCookies(w http.ResponseWriter, tokens *TokenPair) {
-	isProduction := os.Getenv("ENV") == "production"
-	domain := ""
-	if isProduction {
-		domain = os.Getenv("COOKIE_DOMAIN")
+// OAuthStateCookie holds the state of the OAuth flow the browser started.
+const OAuthStateCookie = "oauth_state"
+
+// CookieSettings describes how the auth cookies are written.
+type CookieSettings struct {
+	Domain     string
+	Secure     bool
+	SameSite   http.SameSite
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+func ParseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
 	}
+}
 
+func (c CookieSettings) SetTokenCookies(w http.ResponseWriter, tokens *TokenPair) {
 	accessCookie := &http.Cookie{
 		Name:     "access_token",
 		Value:    tokens.AccessToken,
 		Path:     "/",
-		Domain:   domain,
-		MaxAge:   int(GetAccessTokenTTL().Seconds()),
+		Domain:   c.Domain,
+		MaxAge:   int(c.AccessTTL.Seconds()),
 		HttpOnly: true,
-		Secure:   isProduction,
-		SameSite: http.SameSiteLaxMode,
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
 	}
 	http.SetCookie(w, accessCookie)
 
@@ -28,31 +46,25 @@ func SetTokenCookies(w http.ResponseWriter, tokens *TokenPair) {
 		Name:     "refresh_token",
 		Value:    tokens.RefreshToken,
 		Path:     "/auth",
-		Domain:   domain,
-		MaxAge:   int(GetRefreshTokenTTL().Seconds()),
+		Domain:   c.Domain,
+		MaxAge:   int(c.RefreshTTL.Seconds()),
 		HttpOnly: true,
-		Secure:   isProduction,
-		SameSite: http.SameSiteLaxMode,
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
 	}
 	http.SetCookie(w, refreshCookie)
 }
 
-func ClearTokenCookies(w http.ResponseWriter) {
-	isProduction := os.Getenv("ENV") == "production"
-	domain := ""
-	if isProduction {
-		domain = os.Getenv("COOKIE_DOMAIN")
-	}
-
+func (c CookieSettings) ClearTokenCookies(w http.ResponseWriter) {
 	accessCookie := &http.Cookie{
 		Name:     "access_token",
 		Value:    "",
 		Path:     "/",
-		Domain:   domain,
+		Domain:   c.Domain,
 		MaxAge:   -1,
 		HttpOnly: true,
-		Secure:   isProduction,
-		SameSite: http.SameSiteLaxMode,
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
 	}
 	http.SetCookie(w, accessCookie)
 
@@ -60,11 +72,41 @@ func ClearTokenCookies(w http.ResponseWriter) {
 		Name:     "refresh_token",
 		Value:    "",
 		Path:     "/auth",
-		Domain:   domain,
+		Domain:   c.Domain,
 		MaxAge:   -1,
 		HttpOnly: true,
-		Secure:   isProduction,
-		SameSite: http.SameSiteLaxMode,
+		Secure:   c.Secure,
+		SameSite: c.SameSite,
 	}
 	http.SetCookie(w, refreshCookie)
 }
+
+// SetOAuthStateCookie binds an OAuth flow to the browser that started it, so
+// a callback carrying a state issued to someone else is refused. It is always
+// Lax, whatever SameSite is configured, so that it is sent on the redirect
+// back from the provider.
+func (c CookieSettings) SetOAuthStateCookie(w http.ResponseWriter, state string, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OAuthStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oauth",
+		Domain:   c.Domain,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   c.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (c CookieSettings) ClearOAuthStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OAuthStateCookie,
+		Value:    "",
+		Path:     "/api/auth/oauth",
+		Domain:   c.Domain,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   c.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
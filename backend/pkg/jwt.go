@@ -2,15 +2,11 @@ package pkg
 
 import (
 	"fmt"
-	"log"
 	"net/http"
-	"os"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
 )
 
 type TokenPair struct {
@@ -20,9 +16,10 @@ type TokenPair struct {
 }
 
 type Claims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Name   string `json:"name"`
+	UserID    uint   `json:"user_id"`
+	Email     string `json:"email"`
+	Name      string `json:"name"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -31,59 +28,35 @@ type RefreshClaims struct {
 	jwt.RegisteredClaims
 }
 
-var (
-	jwtSecret       []byte
+// JWTManager signs and verifies access and refresh tokens.
+type JWTManager struct {
+	accessSecret    []byte
 	refreshSecret   []byte
 	accessTokenTTL  time.Duration
 	refreshTokenTTL time.Duration
-)
-
-func init() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Printf("Warning: Error loading .env file: %v", err)
-	}
-
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Println("Warning: JWT_SECRET not set, using default (not secure for production)")
-		secret = "your-super-secret-jwt-key-change-this-in-production"
-	}
-	jwtSecret = []byte(secret)
-
-	refreshSecretStr := os.Getenv("JWT_REFRESH_SECRET")
-	if refreshSecretStr == "" {
-		log.Println("Warning: JWT_REFRESH_SECRET not set, using default (not secure for production)")
-		refreshSecretStr = "your-super-secret-refresh-key-change-this-in-production"
-	}
-	refreshSecret = []byte(refreshSecretStr)
-
-	accessTokenTTL = 15 * time.Minute
-	if ttlStr := os.Getenv("JWT_ACCESS_TTL"); ttlStr != "" {
-		if minutes, err := strconv.Atoi(ttlStr); err == nil {
-			accessTokenTTL = time.Duration(minutes) * time.Minute
-		}
-	}
+}
 
-	refreshTokenTTL = 7 * 24 * time.Hour
-	if ttlStr := os.Getenv("JWT_REFRESH_TTL"); ttlStr != "" {
-		if hours, err := strconv.Atoi(ttlStr); err == nil {
-			refreshTokenTTL = time.Duration(hours) * time.Hour
-		}
+func NewJWTManager(accessSecret, refreshSecret string, accessTTL, refreshTTL time.Duration) *JWTManager {
+	return &JWTManager{
+		accessSecret:    []byte(accessSecret),
+		refreshSecret:   []byte(refreshSecret),
+		accessTokenTTL:  accessTTL,
+		refreshTokenTTL: refreshTTL,
 	}
-
-	log.Printf("✅ JWT service initialized - Access TTL: %v, Refresh TTL: %v", accessTokenTTL, refreshTokenTTL)
 }
 
-func GenerateTokenPair(userID uint, email, name string) (*TokenPair, error) {
+// GenerateTokenPair issues an access/refresh pair. sessionID becomes the
+// refresh token's jti so refreshes can be tied back to a revocable session.
+func (m *JWTManager) GenerateTokenPair(userID uint, email, name, sessionID string) (*TokenPair, error) {
 	now := time.Now()
 
 	accessClaims := &Claims{
-		UserID: userID,
-		Email:  email,
-		Name:   name,
+		UserID:    userID,
+		Email:     email,
+		Name:      name,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "go-chat-api",
@@ -92,7 +65,7 @@ func GenerateTokenPair(userID uint, email, name string) (*TokenPair, error) {
 	}
 
 	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString(jwtSecret)
+	accessTokenString, err := accessToken.SignedString(m.accessSecret)
 	if err != nil {
 		return nil, fmt.Errorf("could not create access token: %w", err)
 	}
@@ -100,7 +73,8 @@ func GenerateTokenPair(userID uint, email, name string) (*TokenPair, error) {
 	refreshClaims := &RefreshClaims{
 		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(refreshTokenTTL)),
+			ID:        sessionID,
+			ExpiresAt: jwt.NewNumericDate(now.Add(m.refreshTokenTTL)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    "go-chat-api",
@@ -109,7 +83,7 @@ func GenerateTokenPair(userID uint, email, name string) (*TokenPair, error) {
 	}
 
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, err := refreshToken.SignedString(refreshSecret)
+	refreshTokenString, err := refreshToken.SignedString(m.refreshSecret)
 	if err != nil {
 		return nil, fmt.Errorf("could not create refresh token: %w", err)
 	}
@@ -117,16 +91,16 @@ func GenerateTokenPair(userID uint, email, name string) (*TokenPair, error) {
 	return &TokenPair{
 		AccessToken:  accessTokenString,
 		RefreshToken: refreshTokenString,
-		ExpiresIn:    int64(accessTokenTTL.Seconds()),
+		ExpiresIn:    int64(m.accessTokenTTL.Seconds()),
 	}, nil
 }
 
-func ValidateAccessToken(tokenString string) (*Claims, error) {
+func (m *JWTManager) ValidateAccessToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return jwtSecret, nil
+		return m.accessSecret, nil
 	})
 
 	if err != nil {
@@ -140,12 +114,12 @@ func ValidateAccessToken(tokenString string) (*Claims, error) {
 	return nil, fmt.Errorf("invalid token")
 }
 
-func ValidateRefreshToken(tokenString string) (*RefreshClaims, error) {
+func (m *JWTManager) ValidateRefreshToken(tokenString string) (*RefreshClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &RefreshClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return refreshSecret, nil
+		return m.refreshSecret, nil
 	})
 
 	if err != nil {
@@ -159,13 +133,13 @@ func ValidateRefreshToken(tokenString string) (*RefreshClaims, error) {
 	return nil, fmt.Errorf("invalid refresh token")
 }
 
-func ExtractUserIDFromRequest(r *http.Request) (uint, error) {
+func (m *JWTManager) ExtractUserIDFromRequest(r *http.Request) (uint, error) {
 	tokenString, err := ExtractTokenFromRequest(r)
 	if err != nil {
 		return 0, err
 	}
 
-	claims, err := ValidateAccessToken(tokenString)
+	claims, err := m.ValidateAccessToken(tokenString)
 	if err != nil {
 		return 0, err
 	}
@@ -192,14 +166,10 @@ func ExtractTokenFromRequest(r *http.Request) (string, error) {
 	return "", fmt.Errorf("no authentication token provided")
 }
 
-func ValidateTokenAndGetClaims(tokenString string) (*Claims, error) {
-	return ValidateAccessToken(tokenString)
-}
-
-func GetAccessTokenTTL() time.Duration {
-	return accessTokenTTL
+func (m *JWTManager) AccessTokenTTL() time.Duration {
+	return m.accessTokenTTL
 }
 
-func GetRefreshTokenTTL() time.Duration {
-	return refreshTokenTTL
+func (m *JWTManager) RefreshTokenTTL() time.Duration {
+	return m.refreshTokenTTL
 }
@@ -1,12 +1,15 @@
 package pkg
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -37,6 +40,21 @@ func (l LogLevel) String() string {
 	}
 }
 
+func ParseLogLevel(level string) (LogLevel, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return DEBUG, nil
+	case "info":
+		return INFO, nil
+	case "warn", "warning":
+		return WARN, nil
+	case "error":
+		return ERROR, nil
+	default:
+		return INFO, fmt.Errorf("unknown log level %q", level)
+	}
+}
+
 type LogEntry struct {
 	Timestamp string                 `json:"timestamp"`
 	Level     string                 `json:"level"`
@@ -44,22 +62,33 @@ type LogEntry struct {
 	Fields    map[string]interface{} `json:"fields,omitempty"`
 	Caller    string                 `json:"caller,omitempty"`
 	Error     string                 `json:"error,omitempty"`
+	TraceID   string                 `json:"trace_id,omitempty"`
+	SpanID    string                 `json:"span_id,omitempty"`
 }
 
+// Logger writes log entries, optionally enriched with a fixed set of
+// fields. Loggers derived with With share level and format with their
+// parent, so runtime changes apply to every request logger at once.
 type Logger struct {
-	level      LogLevel
-	structured bool
+	core *loggerCore
+
+	mu     sync.RWMutex
+	fields map[string]interface{}
+}
+
+type loggerCore struct {
+	level      atomic.Int32
+	structured atomic.Bool
 	logger     *log.Logger
 }
 
 var globalLogger *Logger
 
 func InitLogger(level LogLevel, structured bool) {
-	globalLogger = &Logger{
-		level:      level,
-		structured: structured,
-		logger:     log.New(os.Stdout, "", 0),
-	}
+	core := &loggerCore{logger: log.New(os.Stdout, "", 0)}
+	core.level.Store(int32(level))
+	core.structured.Store(structured)
+	globalLogger = &Logger{core: core}
 }
 
 func GetLogger() *Logger {
@@ -69,8 +98,48 @@ func GetLogger() *Logger {
 	return globalLogger
 }
 
-func (l *Logger) log(level LogLevel, message string, fields map[string]interface{}, err error) {
-	if level < l.level {
+// With returns a logger that adds fields to every entry.
+func (l *Logger) With(fields map[string]interface{}) *Logger {
+	return &Logger{core: l.core, fields: l.mergeFields(fields)}
+}
+
+// addFields adds fields to this logger in place.
+func (l *Logger) addFields(fields map[string]interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	merged := make(map[string]interface{}, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	l.fields = merged
+}
+
+// mergeFields combines the logger's fields with per-call fields, which win
+// on conflict.
+func (l *Logger) mergeFields(fields map[string]interface{}) map[string]interface{} {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if len(l.fields) == 0 {
+		return fields
+	}
+
+	merged := make(map[string]interface{}, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return merged
+}
+
+func (l *Logger) log(ctx context.Context, level LogLevel, message string, fields map[string]interface{}, err error) {
+	if level < LogLevel(l.core.level.Load()) {
 		return
 	}
 
@@ -78,8 +147,9 @@ func (l *Logger) log(level LogLevel, message string, fields map[string]interface
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Level:     level.String(),
 		Message:   message,
-		Fields:    fields,
+		Fields:    l.mergeFields(fields),
 	}
+	entry.TraceID, entry.SpanID = traceFields(ctx)
 
 	if level >= ERROR {
 		if _, file, line, ok := runtime.Caller(3); ok {
@@ -91,7 +161,7 @@ func (l *Logger) log(level LogLevel, message string, fields map[string]interface
 		entry.Error = err.Error()
 	}
 
-	if l.structured {
+	if l.core.structured.Load() {
 		l.outputStructured(entry)
 	} else {
 		l.outputText(entry)
@@ -101,10 +171,10 @@ func (l *Logger) log(level LogLevel, message string, fields map[string]interface
 func (l *Logger) outputStructured(entry LogEntry) {
 	jsonData, err := json.Marshal(entry)
 	if err != nil {
-		l.logger.Printf("[%s] %s %s", entry.Level, entry.Timestamp, entry.Message)
+		l.core.logger.Printf("[%s] %s %s", entry.Level, entry.Timestamp, entry.Message)
 		return
 	}
-	l.logger.Println(string(jsonData))
+	l.core.logger.Println(string(jsonData))
 }
 
 func (l *Logger) outputText(entry LogEntry) {
@@ -128,7 +198,11 @@ func (l *Logger) outputText(entry LogEntry) {
 		parts = append(parts, fmt.Sprintf("error=%s", entry.Error))
 	}
 
-	l.logger.Println(strings.Join(parts, " "))
+	if entry.TraceID != "" {
+		parts = append(parts, fmt.Sprintf("trace_id=%s span_id=%s", entry.TraceID, entry.SpanID))
+	}
+
+	l.core.logger.Println(strings.Join(parts, " "))
 }
 
 func (l *Logger) Debug(message string, fields ...map[string]interface{}) {
@@ -136,7 +210,7 @@ func (l *Logger) Debug(message string, fields ...map[string]interface{}) {
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	l.log(DEBUG, message, f, nil)
+	l.log(context.Background(), DEBUG, message, f, nil)
 }
 
 func (l *Logger) Info(message string, fields ...map[string]interface{}) {
@@ -144,7 +218,7 @@ func (l *Logger) Info(message string, fields ...map[string]interface{}) {
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	l.log(INFO, message, f, nil)
+	l.log(context.Background(), INFO, message, f, nil)
 }
 
 func (l *Logger) Warn(message string, fields ...map[string]interface{}) {
@@ -152,7 +226,7 @@ func (l *Logger) Warn(message string, fields ...map[string]interface{}) {
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	l.log(WARN, message, f, nil)
+	l.log(context.Background(), WARN, message, f, nil)
 }
 
 func (l *Logger) Error(message string, err error, fields ...map[string]interface{}) {
@@ -160,7 +234,7 @@ func (l *Logger) Error(message string, err error, fields ...map[string]interface
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	l.log(ERROR, message, f, err)
+	l.log(context.Background(), ERROR, message, f, err)
 }
 
 func (l *Logger) Fatal(message string, err error, fields ...map[string]interface{}) {
@@ -168,10 +242,44 @@ func (l *Logger) Fatal(message string, err error, fields ...map[string]interface
 	if len(fields) > 0 {
 		f = fields[0]
 	}
-	l.log(FATAL, message, f, err)
+	l.log(context.Background(), FATAL, message, f, err)
 	os.Exit(1)
 }
 
+// DebugContext, InfoContext, WarnContext and ErrorContext behave like their
+// plain counterparts but tag the entry with the trace and span in ctx.
+func (l *Logger) DebugContext(ctx context.Context, message string, fields ...map[string]interface{}) {
+	var f map[string]interface{}
+	if len(fields) > 0 {
+		f = fields[0]
+	}
+	l.log(ctx, DEBUG, message, f, nil)
+}
+
+func (l *Logger) InfoContext(ctx context.Context, message string, fields ...map[string]interface{}) {
+	var f map[string]interface{}
+	if len(fields) > 0 {
+		f = fields[0]
+	}
+	l.log(ctx, INFO, message, f, nil)
+}
+
+func (l *Logger) WarnContext(ctx context.Context, message string, fields ...map[string]interface{}) {
+	var f map[string]interface{}
+	if len(fields) > 0 {
+		f = fields[0]
+	}
+	l.log(ctx, WARN, message, f, nil)
+}
+
+func (l *Logger) ErrorContext(ctx context.Context, message string, err error, fields ...map[string]interface{}) {
+	var f map[string]interface{}
+	if len(fields) > 0 {
+		f = fields[0]
+	}
+	l.log(ctx, ERROR, message, f, err)
+}
+
 func Debug(message string, fields ...map[string]interface{}) {
 	GetLogger().Debug(message, fields...)
 }
@@ -192,39 +300,51 @@ func Fatal(message string, err error, fields ...map[string]interface{}) {
 	GetLogger().Fatal(message, err, fields...)
 }
 
-func LogHTTPRequest(method, path, userAgent, clientIP string, userID uint, duration time.Duration) {
-	Info("HTTP request", map[string]interface{}{
-		"method":     method,
-		"path":       path,
-		"user_agent": userAgent,
-		"client_ip":  clientIP,
-		"user_id":    userID,
-		"duration":   duration.String(),
+func DebugContext(ctx context.Context, message string, fields ...map[string]interface{}) {
+	LoggerFromContext(ctx).DebugContext(ctx, message, fields...)
+}
+
+func InfoContext(ctx context.Context, message string, fields ...map[string]interface{}) {
+	LoggerFromContext(ctx).InfoContext(ctx, message, fields...)
+}
+
+func WarnContext(ctx context.Context, message string, fields ...map[string]interface{}) {
+	LoggerFromContext(ctx).WarnContext(ctx, message, fields...)
+}
+
+func ErrorContext(ctx context.Context, message string, err error, fields ...map[string]interface{}) {
+	LoggerFromContext(ctx).ErrorContext(ctx, message, err, fields...)
+}
+
+// LogHTTPRequest and LogHTTPError expect the request logger in ctx to
+// carry the client IP, request ID and user ID.
+func LogHTTPRequest(ctx context.Context, method, path, userAgent string, statusCode int, duration time.Duration) {
+	InfoContext(ctx, "HTTP request", map[string]interface{}{
+		"method":      method,
+		"path":        path,
+		"user_agent":  userAgent,
+		"status_code": statusCode,
+		"duration":    duration.String(),
 	})
 }
 
-func LogHTTPError(method, path, clientIP string, statusCode int, err error, userID uint) {
-	Error("HTTP error", err, map[string]interface{}{
+func LogHTTPError(ctx context.Context, method, path string, statusCode int, err error) {
+	ErrorContext(ctx, "HTTP error", err, map[string]interface{}{
 		"method":      method,
 		"path":        path,
-		"client_ip":   clientIP,
 		"status_code": statusCode,
-		"user_id":     userID,
 	})
 }
 
-func LogAuthEvent(event, email, clientIP string, userID uint, success bool) {
+func LogAuthEvent(ctx context.Context, event string, success bool) {
 	level := INFO
 	if !success {
 		level = WARN
 	}
 
-	GetLogger().log(level, fmt.Sprintf("Auth event: %s", event), map[string]interface{}{
-		"event":     event,
-		"email":     email,
-		"client_ip": clientIP,
-		"user_id":   userID,
-		"success":   success,
+	LoggerFromContext(ctx).log(ctx, level, fmt.Sprintf("Auth event: %s", event), map[string]interface{}{
+		"event":   event,
+		"success": success,
 	}, nil)
 }
 
@@ -259,7 +379,7 @@ func LogRateLimitEvent(clientIP string, userID uint, endpoint string, exceeded b
 		level = WARN
 	}
 
-	GetLogger().log(level, "Rate limit check", map[string]interface{}{
+	GetLogger().log(context.Background(), level, "Rate limit check", map[string]interface{}{
 		"client_ip": clientIP,
 		"user_id":   userID,
 		"endpoint":  endpoint,
@@ -277,13 +397,45 @@ func getFileName(path string) string {
 }
 
 func SetLogLevel(level LogLevel) {
-	if globalLogger != nil {
-		globalLogger.level = level
-	}
+	GetLogger().core.level.Store(int32(level))
 }
 
 func SetStructuredLogging(enabled bool) {
-	if globalLogger != nil {
-		globalLogger.structured = enabled
+	GetLogger().core.structured.Store(enabled)
+}
+
+func GetLogLevel() LogLevel {
+	return LogLevel(GetLogger().core.level.Load())
+}
+
+func IsStructuredLogging() bool {
+	return GetLogger().core.structured.Load()
+}
+
+type loggerContextKey struct{}
+
+// WithLogger returns a copy of ctx carrying logger.
+func WithLogger(ctx context.Context, logger *Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// AddLogFields enriches the request logger in ctx in place, so fields
+// learned deep in the chain (such as the user ID after authentication) also
+// appear in entries written by outer middleware. It is a no-op when ctx has
+// no logger of its own.
+func AddLogFields(ctx context.Context, fields map[string]interface{}) {
+	if logger, ok := ctx.Value(loggerContextKey{}).(*Logger); ok {
+		logger.addFields(fields)
+	}
+}
+
+// LoggerFromContext returns the logger stored in ctx by WithLogger, or the
+// global logger.
+func LoggerFromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(loggerContextKey{}).(*Logger); ok {
+			return logger
+		}
 	}
+	return GetLogger()
 } 
\ No newline at end of file
@@ -0,0 +1,20 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// PersonalAccessTokenPrefix marks opaque API tokens so they can be told
+// apart from JWTs without a database lookup.
+const PersonalAccessTokenPrefix = "gct_"
+
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, PersonalAccessTokenPrefix)
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -19,7 +19,7 @@ func HashPassword(passwordToHash string) []byte {
 }
 
 func ComparePassword(passwordToCompare string, dbPassword []byte) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(passwordToCompare), dbPassword)
+	err := bcrypt.CompareHashAndPassword(dbPassword, []byte(passwordToCompare))
 
 	if err != nil {
 		fmt.Printf("Passwords not same! %s", err)
@@ -0,0 +1,51 @@
+package pkg
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set at link time:
+//
+//	go build -ldflags "-X go-chat/pkg.Version=1.4.0 -X go-chat/pkg.Commit=$(git rev-parse --short HEAD) -X go-chat/pkg.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// GetBuildInfo returns the linked-in build metadata, falling back to the VCS
+// stamp the Go toolchain embeds when no commit was provided.
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					info.Commit = setting.Value
+				case "vcs.time":
+					if info.BuildDate == "" {
+						info.BuildDate = setting.Value
+					}
+				}
+			}
+		}
+	}
+
+	return info
+}